@@ -0,0 +1,424 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"unicode/utf8"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// scanFlags 是 list 和 export 共用的范围参数
+type scanFlags struct {
+	channel   string
+	chaincode string
+	prefix    string
+	start     string
+	limit     string
+	max       int
+	raw       bool
+}
+
+func (s *scanFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&s.channel, "channel", "", "only keys of this channel")
+	fs.StringVar(&s.chaincode, "chaincode", "", "only keys of this chaincode (needs -channel)")
+	fs.StringVar(&s.prefix, "prefix", "", "only keys with this raw prefix")
+	fs.StringVar(&s.start, "start", "", "first key (inclusive)")
+	fs.StringVar(&s.limit, "limit", "", "last key (exclusive)")
+	fs.IntVar(&s.max, "n", 0, "stop after n records (0 = all)")
+	fs.BoolVar(&s.raw, "raw", false, "do not decode the fabric version prefix")
+}
+
+// iterator 按参数构造迭代器。goleveldb 的迭代器自带隐式快照，遍历期间看到的是一致的数据
+func (s *scanFlags) iterator(db *leveldb.DB) (iterator.Iterator, error) {
+	if s.chaincode != "" && s.channel == "" {
+		return nil, errors.New("-chaincode needs -channel")
+	}
+	prefix := nsPrefix(s.channel, s.chaincode)
+	if s.prefix != "" {
+		p, err := parseBytes(s.prefix)
+		if err != nil {
+			return nil, err
+		}
+		prefix = append(prefix, p...)
+	}
+
+	r := util.BytesPrefix(prefix)
+	if s.start != "" {
+		start, err := parseBytes(s.start)
+		if err != nil {
+			return nil, err
+		}
+		if bytes.Compare(start, r.Start) > 0 {
+			r.Start = start
+		}
+	}
+	if s.limit != "" {
+		limit, err := parseBytes(s.limit)
+		if err != nil {
+			return nil, err
+		}
+		if r.Limit == nil || bytes.Compare(limit, r.Limit) < 0 {
+			r.Limit = limit
+		}
+	}
+	return db.NewIterator(r, nil), nil
+}
+
+// record 是 export/import 的一行。Key、Value 为原始字节（JSON 中为 base64），
+// 其余字段只是方便阅读，import 时忽略
+type record struct {
+	Key   []byte `json:"key"`
+	Value []byte `json:"value"`
+
+	Channel   string `json:"channel,omitempty"`
+	Chaincode string `json:"chaincode,omitempty"`
+	NsKey     string `json:"ns_key,omitempty"`
+	Version   string `json:"version,omitempty"`
+	Data      string `json:"data,omitempty"`
+}
+
+func newRecord(k, v []byte, raw bool) record {
+	r := record{Key: k, Value: v}
+	ck := splitKey(k)
+	r.Channel = quote(ck.Channel)
+	r.Chaincode = quote(ck.Chaincode)
+	r.NsKey = quote(ck.Key)
+	data := v
+	if !raw {
+		if ver, value, err := decodeValue(v); err == nil {
+			r.Version = ver.String()
+			data = value
+		}
+	}
+	if utf8.Valid(data) {
+		r.Data = string(data)
+	}
+	return r
+}
+
+func cmdList(args []string) error {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	var scan scanFlags
+	scan.register(fs)
+	fs.Parse(args)
+
+	db, err := openDB(false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	iter, err := scan.iterator(db)
+	if err != nil {
+		return err
+	}
+	defer iter.Release()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, ' ', 0)
+	fmt.Fprintln(w, "CHANNEL\tCHAINCODE\tKEY\tVERSION\tVALUE")
+	n := 0
+	for iter.Next() && (scan.max == 0 || n < scan.max) {
+		printRow(w, iter.Key(), iter.Value(), scan.raw)
+		n++
+	}
+	w.Flush()
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%d records\n", n)
+	return nil
+}
+
+func printRow(w io.Writer, k, v []byte, raw bool) {
+	ck := splitKey(k)
+	ver, value := "-", v
+	if !raw {
+		if decoded, data, err := decodeValue(v); err == nil {
+			ver, value = decoded.String(), data
+		}
+	}
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", quote(ck.Channel), quote(ck.Chaincode), quote(ck.Key), ver, quote(value))
+}
+
+func cmdGet(args []string) error {
+	fs := flag.NewFlagSet("get", flag.ExitOnError)
+	raw := fs.Bool("raw", false, "do not decode the fabric version prefix")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errors.New("need exactly one key")
+	}
+	key, err := parseBytes(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	db, err := openDB(false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	v, err := db.Get(key, nil)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, ' ', 0)
+	fmt.Fprintln(w, "CHANNEL\tCHAINCODE\tKEY\tVERSION\tVALUE")
+	printRow(w, key, v, *raw)
+	return w.Flush()
+}
+
+func cmdExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	var scan scanFlags
+	scan.register(fs)
+	fs.Parse(args)
+
+	db, err := openDB(false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	iter, err := scan.iterator(db)
+	if err != nil {
+		return err
+	}
+	defer iter.Release()
+
+	w := bufio.NewWriter(os.Stdout)
+	enc := json.NewEncoder(w)
+	n := 0
+	for iter.Next() && (scan.max == 0 || n < scan.max) {
+		// 迭代器复用缓冲区，编码前不需要拷贝
+		if err := enc.Encode(newRecord(iter.Key(), iter.Value(), scan.raw)); err != nil {
+			return err
+		}
+		n++
+	}
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "exported %d records\n", n)
+	return w.Flush()
+}
+
+// change 描述一次写入，Old 为 nil 表示新增，New 为 nil 表示删除
+type change struct {
+	Key []byte
+	Old []byte
+	New []byte
+}
+
+func cmdPut(args []string) error {
+	fs := flag.NewFlagSet("put", flag.ExitOnError)
+	block := fs.Int64("block", -1, "fabric block num; with -tx the value is prefixed with the version")
+	tx := fs.Int64("tx", -1, "fabric tx num")
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		return errors.New("need key and value")
+	}
+	key, err := parseBytes(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	value, err := parseBytes(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	if (*block < 0) != (*tx < 0) {
+		return errors.New("-block and -tx must be given together")
+	}
+	if *block >= 0 {
+		value = encodeValue(version{BlockNum: uint64(*block), TxNum: uint64(*tx)}, value)
+	}
+	return applyChanges(func(db *leveldb.DB) ([]change, error) {
+		return diff(db, []change{{Key: key, New: value}})
+	})
+}
+
+func cmdDelete(args []string) error {
+	if len(args) != 1 {
+		return errors.New("need exactly one key")
+	}
+	key, err := parseBytes(args[0])
+	if err != nil {
+		return err
+	}
+	return applyChanges(func(db *leveldb.DB) ([]change, error) {
+		return diff(db, []change{{Key: key}})
+	})
+}
+
+func cmdImport(args []string) error {
+	if len(args) != 1 {
+		return errors.New("need a file (or - for stdin)")
+	}
+	in := os.Stdin
+	if args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	var changes []change
+	dec := json.NewDecoder(bufio.NewReader(in))
+	for line := 1; ; line++ {
+		var r record
+		if err := dec.Decode(&r); err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("record %d: %v", line, err)
+		}
+		if len(r.Key) == 0 {
+			return fmt.Errorf("record %d: empty key", line)
+		}
+		if r.Value == nil {
+			r.Value = []byte{}
+		}
+		changes = append(changes, change{Key: r.Key, New: r.Value})
+	}
+	return applyChanges(func(db *leveldb.DB) ([]change, error) {
+		return diff(db, changes)
+	})
+}
+
+// diff 补全 Old 并去掉没有实际变化的项
+func diff(db *leveldb.DB, changes []change) ([]change, error) {
+	var out []change
+	for _, c := range changes {
+		old, err := db.Get(c.Key, nil)
+		if err == leveldb.ErrNotFound {
+			old = nil
+		} else if err != nil {
+			return nil, err
+		}
+		if c.New == nil && old == nil {
+			continue
+		}
+		if c.New != nil && old != nil && bytes.Equal(old, c.New) {
+			continue
+		}
+		c.Old = old
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// applyChanges 打印差异，只有 -write 时才以读写方式打开并在一个 batch 里提交
+func applyChanges(plan func(db *leveldb.DB) ([]change, error)) error {
+	db, err := openDB(*write)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	changes, err := plan(db)
+	if err != nil {
+		return err
+	}
+	for _, c := range changes {
+		printChange(os.Stdout, c)
+	}
+	if len(changes) == 0 {
+		fmt.Fprintln(os.Stderr, "no changes")
+		return nil
+	}
+	if !*write {
+		fmt.Fprintf(os.Stderr, "dry run: %d changes not applied, rerun with -write\n", len(changes))
+		return nil
+	}
+
+	batch := new(leveldb.Batch)
+	for _, c := range changes {
+		if c.New == nil {
+			batch.Delete(c.Key)
+		} else {
+			batch.Put(c.Key, c.New)
+		}
+	}
+	if err := db.Write(batch, &opt.WriteOptions{Sync: true}); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "applied %d changes\n", len(changes))
+	return nil
+}
+
+func printChange(w io.Writer, c change) {
+	if c.Old != nil {
+		fmt.Fprintf(w, "- %s\t%s\n", quote(c.Key), describeValue(c.Old))
+	}
+	if c.New != nil {
+		fmt.Fprintf(w, "+ %s\t%s\n", quote(c.Key), describeValue(c.New))
+	}
+}
+
+func describeValue(v []byte) string {
+	if ver, value, err := decodeValue(v); err == nil {
+		return fmt.Sprintf("[%s] %s", ver, quote(value))
+	}
+	return quote(v)
+}
+
+// cmdSnapshot 在快照上遍历整个库并写入新目录，得到一个一致的副本
+func cmdSnapshot(args []string) error {
+	if len(args) != 1 {
+		return errors.New("need a target directory")
+	}
+	db, err := openDB(false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	snap, err := db.GetSnapshot()
+	if err != nil {
+		return err
+	}
+	defer snap.Release()
+
+	dst, err := leveldb.OpenFile(args[0], &opt.Options{ErrorIfExist: true})
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	iter := snap.NewIterator(nil, nil)
+	defer iter.Release()
+
+	const batchSize = 4096
+	batch := new(leveldb.Batch)
+	n := 0
+	for iter.Next() {
+		batch.Put(iter.Key(), iter.Value())
+		n++
+		if batch.Len() >= batchSize {
+			if err := dst.Write(batch, nil); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	if err := dst.Write(batch, &opt.WriteOptions{Sync: true}); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "copied %d records to %s\n", n, args[0])
+	return nil
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+const (
+	keyA = "mychannel\x00mycc\x00a"
+	keyB = "mychannel\x00mycc\x00b"
+	keyC = "mychannel\x00other\x00c"
+)
+
+// tempDB 在临时目录里建一个库并写入 records，返回库的路径，同时设置 -db 和 -write
+func tempDB(t *testing.T, records map[string]string, writable bool) string {
+	path := filepath.Join(t.TempDir(), "db")
+	db, err := leveldb.OpenFile(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range records {
+		if err := db.Put([]byte(k), []byte(v), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	oldPath, oldWrite := *dbPath, *write
+	*dbPath, *write = path, writable
+	t.Cleanup(func() { *dbPath, *write = oldPath, oldWrite })
+	return path
+}
+
+// contents 返回库中的全部记录
+func contents(t *testing.T, path string) map[string]string {
+	db, err := leveldb.OpenFile(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	m := make(map[string]string)
+	iter := db.NewIterator(nil, nil)
+	for iter.Next() {
+		m[string(iter.Key())] = string(iter.Value())
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// capture 运行 f，返回它写到标准输出和标准错误的内容
+func capture(t *testing.T, f func() error) (stdout, stderr string, err error) {
+	outFile, err1 := os.CreateTemp("", "stdout")
+	errFile, err2 := os.CreateTemp("", "stderr")
+	if err1 != nil || err2 != nil {
+		t.Fatal(err1, err2)
+	}
+	defer os.Remove(outFile.Name())
+	defer os.Remove(errFile.Name())
+
+	oldOut, oldErr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = outFile, errFile
+	err = f()
+	os.Stdout, os.Stderr = oldOut, oldErr
+	outFile.Close()
+	errFile.Close()
+
+	o, _ := os.ReadFile(outFile.Name())
+	e, _ := os.ReadFile(errFile.Name())
+	return string(o), string(e), err
+}
+
+func TestDiff(t *testing.T) {
+	tempDB(t, map[string]string{keyA: "1", keyB: "2"}, false)
+	db, err := openDB(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	got, err := diff(db, []change{
+		{Key: []byte(keyA), New: []byte("1")}, // 没有变化
+		{Key: []byte(keyB), New: []byte("3")},
+		{Key: []byte(keyC), New: []byte("4")},
+		{Key: []byte("missing")}, // 删除不存在的 key
+		{Key: []byte(keyA)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []change{
+		{Key: []byte(keyB), Old: []byte("2"), New: []byte("3")},
+		{Key: []byte(keyC), New: []byte("4")},
+		{Key: []byte(keyA), Old: []byte("1")},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("diff = %q, want %q", got, want)
+	}
+}
+
+func TestApplyChangesDryRun(t *testing.T) {
+	records := map[string]string{keyA: "1"}
+	path := tempDB(t, records, false)
+
+	stdout, stderr, err := capture(t, func() error {
+		return cmdPut([]string{"-block", "6", "-tx", "0", quote([]byte(keyA)), "1000"})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "- mychannel\\x00mycc\\x00a\t1\n+ mychannel\\x00mycc\\x00a\t[6:0] 1000\n"
+	if stdout != want {
+		t.Errorf("put printed %q, want %q", stdout, want)
+	}
+	if !strings.Contains(stderr, "dry run: 1 changes not applied") {
+		t.Errorf("put without -write reported %q", stderr)
+	}
+	if got := contents(t, path); !reflect.DeepEqual(got, records) {
+		t.Errorf("put without -write changed the database: %q", got)
+	}
+}
+
+func TestApplyChangesWrite(t *testing.T) {
+	path := tempDB(t, map[string]string{keyA: "1", keyB: "2"}, true)
+
+	if _, _, err := capture(t, func() error {
+		return cmdPut([]string{"-block", "6", "-tx", "1", quote([]byte(keyA)), "1000"})
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := capture(t, func() error {
+		return cmdDelete([]string{quote([]byte(keyB))})
+	}); err != nil {
+		t.Fatal(err)
+	}
+	_, stderr, err := capture(t, func() error {
+		return cmdDelete([]string{quote([]byte(keyB))})
+	})
+	if err != nil || !strings.Contains(stderr, "no changes") {
+		t.Errorf("deleting a missing key: %v, %q", err, stderr)
+	}
+
+	want := map[string]string{keyA: string(encodeValue(version{6, 1}, []byte("1000")))}
+	if got := contents(t, path); !reflect.DeepEqual(got, want) {
+		t.Errorf("database is %q, want %q", got, want)
+	}
+
+	if _, _, err := capture(t, func() error {
+		return cmdPut([]string{"-block", "6", "k", "v"})
+	}); err == nil {
+		t.Errorf("put with -block but no -tx succeeded")
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	records := map[string]string{
+		keyA: string(encodeValue(version{1, 0}, []byte("100"))),
+		keyB: "\xff\x00binary",
+		keyC: "",
+	}
+	tempDB(t, records, false)
+	exported, _, err := capture(t, func() error { return cmdExport(nil) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(exported, "\n"); n != len(records) {
+		t.Fatalf("exported %d records, want %d:\n%s", n, len(records), exported)
+	}
+	if !strings.Contains(exported, `"version":"1:0","data":"100"`) {
+		t.Errorf("export does not decode the fabric value:\n%s", exported)
+	}
+
+	file := filepath.Join(t.TempDir(), "state.jsonl")
+	if err := os.WriteFile(file, []byte(exported), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// 导入到一个只有一条旧记录的库，旧记录被覆盖，其余新增
+	path := tempDB(t, map[string]string{keyA: "old"}, true)
+	stdout, _, err := capture(t, func() error { return cmdImport([]string{file}) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(stdout, "+ ") != 3 || strings.Count(stdout, "- ") != 1 {
+		t.Errorf("import printed:\n%s", stdout)
+	}
+	if got := contents(t, path); !reflect.DeepEqual(got, records) {
+		t.Errorf("imported database is %q, want %q", got, records)
+	}
+
+	// 再导入一次没有变化
+	_, stderr, err := capture(t, func() error { return cmdImport([]string{file}) })
+	if err != nil || !strings.Contains(stderr, "no changes") {
+		t.Errorf("second import: %v, %q", err, stderr)
+	}
+
+	bad := filepath.Join(t.TempDir(), "bad.jsonl")
+	if err := os.WriteFile(bad, []byte(`{"value":"AA=="}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := capture(t, func() error { return cmdImport([]string{bad}) }); err == nil ||
+		!strings.Contains(err.Error(), "record 1: empty key") {
+		t.Errorf("importing a record without key: %v", err)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	records := map[string]string{keyA: "1", keyB: "2", keyC: "3"}
+	tempDB(t, records, false)
+
+	dst := filepath.Join(t.TempDir(), "copy")
+	_, stderr, err := capture(t, func() error { return cmdSnapshot([]string{dst}) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stderr, "copied 3 records") {
+		t.Errorf("snapshot reported %q", stderr)
+	}
+	if got := contents(t, dst); !reflect.DeepEqual(got, records) {
+		t.Errorf("snapshot is %q, want %q", got, records)
+	}
+
+	// 不覆盖已有的库
+	if _, _, err := capture(t, func() error { return cmdSnapshot([]string{dst}) }); err == nil {
+		t.Errorf("snapshot into an existing database succeeded")
+	}
+}
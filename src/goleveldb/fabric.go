@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// Fabric 1.x 的 stateLeveldb 把所有通道放在同一个库里，key 的格式为
+//
+//	channel \x00 chaincode \x00 key
+//
+// value 以版本号开头，版本号是 (blockNum, txNum) 两个保序变长整数：
+// 第一个字节为后续大端字节数，0 表示数值为 0，例如
+//
+//	\x01\x06\x00 1000  =>  block 6, tx 0, value "1000"
+//
+// 通道自己的 savepoint 等元数据没有 chaincode 部分，拆分时 chaincode 为空。
+
+const sep = 0x00
+
+type compositeKey struct {
+	Channel   []byte
+	Chaincode []byte
+	Key       []byte
+	// Parts 为 key 中 \x00 的段数，不足 3 段时只有 Channel（以及 Chaincode）有效
+	Parts int
+}
+
+func splitKey(k []byte) compositeKey {
+	parts := bytes.SplitN(k, []byte{sep}, 3)
+	ck := compositeKey{Parts: len(parts), Channel: parts[0]}
+	if len(parts) > 1 {
+		ck.Chaincode = parts[1]
+	}
+	if len(parts) > 2 {
+		ck.Key = parts[2]
+	}
+	return ck
+}
+
+// nsPrefix 返回按通道、链码过滤用的前缀，chaincode 为空时只按通道过滤
+func nsPrefix(channel, chaincode string) []byte {
+	if channel == "" {
+		return nil
+	}
+	if chaincode == "" {
+		return []byte(channel + "\x00")
+	}
+	return []byte(channel + "\x00" + chaincode + "\x00")
+}
+
+type version struct {
+	BlockNum uint64
+	TxNum    uint64
+}
+
+func (v version) String() string {
+	return fmt.Sprintf("%d:%d", v.BlockNum, v.TxNum)
+}
+
+// decodeValue 拆出版本号和原始值
+func decodeValue(v []byte) (version, []byte, error) {
+	blockNum, n, err := decodeVarUint64(v)
+	if err != nil {
+		return version{}, nil, fmt.Errorf("block num: %v", err)
+	}
+	txNum, m, err := decodeVarUint64(v[n:])
+	if err != nil {
+		return version{}, nil, fmt.Errorf("tx num: %v", err)
+	}
+	return version{BlockNum: blockNum, TxNum: txNum}, v[n+m:], nil
+}
+
+func encodeValue(ver version, value []byte) []byte {
+	buf := encodeVarUint64(ver.BlockNum)
+	buf = append(buf, encodeVarUint64(ver.TxNum)...)
+	return append(buf, value...)
+}
+
+// 与 fabric common/ledger/util.EncodeOrderPreservingVarUint64 一致
+func encodeVarUint64(n uint64) []byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], n)
+	i := 0
+	for i < 8 && b[i] == 0 {
+		i++
+	}
+	return append([]byte{byte(8 - i)}, b[i:]...)
+}
+
+func decodeVarUint64(b []byte) (uint64, int, error) {
+	if len(b) == 0 {
+		return 0, 0, fmt.Errorf("empty")
+	}
+	size := int(b[0])
+	if size > 8 {
+		return 0, 0, fmt.Errorf("size byte %d > 8", size)
+	}
+	if len(b) < 1+size {
+		return 0, 0, fmt.Errorf("need %d bytes, have %d", size, len(b)-1)
+	}
+	var buf [8]byte
+	copy(buf[8-size:], b[1:1+size])
+	return binary.BigEndian.Uint64(buf[:]), 1 + size, nil
+}
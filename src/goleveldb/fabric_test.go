@@ -0,0 +1,59 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestDecodeValue(t *testing.T) {
+	tests := []struct {
+		in    string
+		ver   version
+		value string
+	}{
+		{"\x01\x06\x001000", version{6, 0}, "1000"},
+		{"\x00\x00", version{0, 0}, ""},
+		{"\x02\x01\x00\x01\x03{\"a\":1}", version{256, 3}, `{"a":1}`},
+	}
+	for _, test := range tests {
+		ver, value, err := decodeValue([]byte(test.in))
+		if err != nil {
+			t.Errorf("decodeValue(%q): %v", test.in, err)
+			continue
+		}
+		if ver != test.ver || string(value) != test.value {
+			t.Errorf("decodeValue(%q) = %v %q, want %v %q", test.in, ver, value, test.ver, test.value)
+		}
+		if got := encodeValue(ver, value); string(got) != test.in {
+			t.Errorf("encodeValue(%v, %q) = %q, want %q", ver, value, got, test.in)
+		}
+	}
+
+	for _, bad := range []string{"", "\x09", "\x02\x01"} {
+		if _, _, err := decodeValue([]byte(bad)); err == nil {
+			t.Errorf("decodeValue(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestSplitKey(t *testing.T) {
+	ck := splitKey([]byte("mychannel\x00mycc\x00a\x00b"))
+	if string(ck.Channel) != "mychannel" || string(ck.Chaincode) != "mycc" || string(ck.Key) != "a\x00b" {
+		t.Errorf("splitKey = %q %q %q", ck.Channel, ck.Chaincode, ck.Key)
+	}
+	// savepoint 之类的元数据没有 chaincode
+	if ck := splitKey([]byte("mychannel")); ck.Parts != 1 || ck.Chaincode != nil {
+		t.Errorf("splitKey(mychannel) = %+v", ck)
+	}
+}
+
+func TestParseBytes(t *testing.T) {
+	for _, raw := range []string{"mychannel\x00mycc\x00a", `say "hi"`, "\xff\\"} {
+		got, err := parseBytes(quote([]byte(raw)))
+		if err != nil || string(got) != raw {
+			t.Errorf("parseBytes(quote(%q)) = %q, %v", raw, got, err)
+		}
+	}
+	if got, _ := parseBytes(`a"b`); string(got) != `a"b` {
+		t.Errorf(`parseBytes(a"b) = %q`, got)
+	}
+}
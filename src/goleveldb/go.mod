@@ -0,0 +1,7 @@
+module github.com/gerryyang/goinaction/src/goleveldb
+
+go 1.20
+
+require github.com/syndtr/goleveldb v1.0.0
+
+require github.com/golang/snappy v0.0.0-20180518054509-2e65f85255db // indirect
@@ -11,6 +11,9 @@ import (
 	"sort"
 )
 
+// 注意：参数按 %v 拼接，嵌套值、浮点数和空字符串的表示有歧义，且没有验签和防重放，
+// 仅为兼容已接入的合作方保留。新接入请使用 billing_sign 包。
+//
 // 入参：
 // appkey: 加盐参数
 // privateKey: 计算签名的私钥
@@ -0,0 +1,182 @@
+// Package billing_sign 实现计费请求的签名和验签。
+//
+// 签名方在请求参数中写入时间戳 ts 和随机串 nonce，对规范化字符串（见 Canonicalize）
+// 用 rsa_tools 的私钥签名，结果 base64 后放入 sign 字段。
+// 验签方检查签名、时间戳是否在允许的时间窗口内，并通过 NonceStore 拒绝重放的请求。
+package billing_sign
+
+import (
+	"crypto"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"rsa_tools"
+	"strconv"
+	"time"
+)
+
+const (
+	FieldSign      = "sign"
+	FieldTimestamp = "ts"
+	FieldNonce     = "nonce"
+)
+
+// DefaultWindow 允许的时间戳偏差，超出窗口的请求视为过期
+const DefaultWindow = 5 * time.Minute
+
+var (
+	ErrUnsupportedValue = errors.New("unsupported param value")
+	ErrMissingSign      = errors.New("missing sign")
+	ErrMissingTimestamp = errors.New("missing or invalid timestamp")
+	ErrMissingNonce     = errors.New("missing nonce")
+	ErrExpired          = errors.New("timestamp out of window")
+	ErrReplayed         = errors.New("nonce already used")
+	ErrBadSign          = errors.New("sign mismatch")
+)
+
+type Signer struct {
+	signer rsa_tools.Signer
+	hash   crypto.Hash
+
+	// Now 返回当前时间，测试时可替换
+	Now func() time.Time
+}
+
+func NewSigner(signer rsa_tools.Signer, hash crypto.Hash) *Signer {
+	return &Signer{signer: signer, hash: hash, Now: time.Now}
+}
+
+// Sign 对 params 签名。params 中没有 ts 和 nonce 时会先写入，
+// 签名结果同时写入 params[FieldSign] 并返回。
+func (this *Signer) Sign(params map[string]interface{}) (string, error) {
+	if _, ok := params[FieldTimestamp]; !ok {
+		params[FieldTimestamp] = this.Now().Unix()
+	}
+	if _, ok := params[FieldNonce]; !ok {
+		nonce, err := newNonce()
+		if err != nil {
+			return "", err
+		}
+		params[FieldNonce] = nonce
+	}
+
+	plain, err := Canonicalize(params, FieldSign)
+	if err != nil {
+		return "", err
+	}
+	signBytes, err := this.signer.Sign([]byte(plain), this.hash)
+	if err != nil {
+		return "", err
+	}
+	sign := base64.StdEncoding.EncodeToString(signBytes)
+	params[FieldSign] = sign
+	return sign, nil
+}
+
+type Verifier struct {
+	verifier rsa_tools.Verifier
+	hash     crypto.Hash
+	nonces   NonceStore
+
+	// Window 允许的时间戳偏差，默认 DefaultWindow
+	Window time.Duration
+	// Now 返回当前时间，测试时可替换
+	Now func() time.Time
+}
+
+// NewVerifier 创建验签器，nonces 为 nil 时不做重放检查
+func NewVerifier(verifier rsa_tools.Verifier, hash crypto.Hash, nonces NonceStore) *Verifier {
+	return &Verifier{verifier: verifier, hash: hash, nonces: nonces, Window: DefaultWindow, Now: time.Now}
+}
+
+// Verify 依次检查时间戳、签名和 nonce。签名通过后才记录 nonce，
+// 伪造的请求不会占用合法请求的 nonce。
+func (this *Verifier) Verify(params map[string]interface{}) error {
+	sign, _ := params[FieldSign].(string)
+	if sign == "" {
+		return ErrMissingSign
+	}
+	ts, err := timestamp(params[FieldTimestamp])
+	if err != nil {
+		return err
+	}
+	nonce, _ := params[FieldNonce].(string)
+	if nonce == "" {
+		return ErrMissingNonce
+	}
+
+	now := this.Now()
+	if d := now.Sub(ts); d > this.Window || d < -this.Window {
+		return fmt.Errorf("%w: ts=%d now=%d", ErrExpired, ts.Unix(), now.Unix())
+	}
+
+	signBytes, err := base64.StdEncoding.DecodeString(sign)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBadSign, err)
+	}
+	plain, err := Canonicalize(params, FieldSign)
+	if err != nil {
+		return err
+	}
+	if err := this.verifier.Verify([]byte(plain), signBytes, this.hash); err != nil {
+		return fmt.Errorf("%w: %v", ErrBadSign, err)
+	}
+
+	if this.nonces == nil {
+		return nil
+	}
+	// 超出窗口的请求已被拒绝，nonce 只需保存到 ts+Window
+	fresh, err := this.nonces.Add(nonce, ts.Add(this.Window))
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return ErrReplayed
+	}
+	return nil
+}
+
+// timestamp 解析秒级时间戳，兼容字符串、整数以及 JSON 解码得到的 float64/json.Number
+func timestamp(v interface{}) (time.Time, error) {
+	var sec int64
+	switch v := v.(type) {
+	case string:
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return time.Time{}, ErrMissingTimestamp
+		}
+		sec = n
+	case json.Number:
+		n, err := v.Int64()
+		if err != nil {
+			return time.Time{}, ErrMissingTimestamp
+		}
+		sec = n
+	case float64:
+		if v != math.Trunc(v) {
+			return time.Time{}, ErrMissingTimestamp
+		}
+		sec = int64(v)
+	case int:
+		sec = int64(v)
+	case int64:
+		sec = v
+	case uint32:
+		sec = int64(v)
+	default:
+		return time.Time{}, ErrMissingTimestamp
+	}
+	return time.Unix(sec, 0), nil
+}
+
+func newNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,219 @@
+package billing_sign
+
+import (
+	"crypto"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math"
+	"rsa_tools"
+	"testing"
+	"time"
+)
+
+// 规范化字符串的金样例，修改规则时必须同步通知所有接入方
+var canonicalTests = []struct {
+	name   string
+	params map[string]interface{}
+	want   string
+}{
+	{
+		"flat",
+		map[string]interface{}{"user_id": "oEIpN5c8e34o6jaV5KG48vJTDpBA", "action": "query", "channel": "wechat", "ts": 1538231718},
+		"action=query&channel=wechat&ts=1538231718&user_id=oEIpN5c8e34o6jaV5KG48vJTDpBA",
+	},
+	{
+		"escaping",
+		map[string]interface{}{"q": "a b&c=d/é~", "k y": "+"},
+		"k%20y=%2B&q=a%20b%26c%3Dd%2F%C3%A9~",
+	},
+	{
+		"empty values",
+		map[string]interface{}{"a": "", "b": nil, "c": map[string]interface{}{}, "d": []interface{}{}, "e": "0", "f": false, "g": 0},
+		"e=0&f=false&g=0",
+	},
+	{
+		"numbers",
+		map[string]interface{}{"f1": 1.50, "f2": 100.0, "f3": 0.1, "f4": 1e21, "f5": float32(0.1), "i": int64(-7), "u": uint8(255), "n": json.Number("12.30")},
+		"f1=1.5&f2=100&f3=0.1&f4=1000000000000000000000&f5=0.1&i=-7&n=12.30&u=255",
+	},
+	{
+		"nested",
+		map[string]interface{}{"b": 1.50, "a": map[string]interface{}{"y": "x y", "x": []interface{}{1, 2}}, "c": ""},
+		"a[x][0]=1&a[x][1]=2&a[y]=x%20y&b=1.5",
+	},
+	{
+		"typed containers",
+		map[string]interface{}{"s": []string{"b", "", "a"}, "m": map[string]int{"z": 1, "a": 2}, "raw": []byte("hi")},
+		"m[a]=2&m[z]=1&raw=aGk%3D&s[0]=b&s[2]=a",
+	},
+	{
+		"brackets in keys",
+		map[string]interface{}{"a[x]": "1", "a": map[string]interface{}{"x": "2"}},
+		"a[x]=2&a%5Bx%5D=1",
+	},
+	{
+		"sign excluded",
+		map[string]interface{}{"a": "1", "sign": "xxx"},
+		"a=1",
+	},
+}
+
+func TestCanonicalize(t *testing.T) {
+	for _, test := range canonicalTests {
+		got, err := Canonicalize(test.params, FieldSign)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%s:\ngot  %s\nwant %s", test.name, got, test.want)
+		}
+	}
+}
+
+func TestCanonicalizeErrors(t *testing.T) {
+	for _, v := range []interface{}{math.NaN(), math.Inf(1), struct{}{}, map[int]string{1: "a"}} {
+		_, err := Canonicalize(map[string]interface{}{"v": v})
+		if !errors.Is(err, ErrUnsupportedValue) {
+			t.Errorf("Canonicalize(%#v) = %v, want ErrUnsupportedValue", v, err)
+		}
+	}
+}
+
+func loadCipher(t *testing.T) rsa_tools.Cipher {
+	priv, err := ioutil.ReadFile("testdata/private.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := ioutil.ReadFile("testdata/public.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cipher, err := rsa_tools.New(priv, pub, rsa_tools.PKCS8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cipher
+}
+
+// PKCS#1 v1.5 签名是确定性的，可以固定签名结果
+const goldenCanonical = "action=query&channel=wechat&nonce=fTcCu9Qi5QtjaarYkXS4u1zTxtF4igXX&ts=1538231718&user_id=oEIpN5c8e34o6jaV5KG48vJTDpBA"
+
+const goldenSign = "" +
+	"s/sc9+fF3OQv83n80b4ogbr6K3uK7Nwg6myTo5O180u6DXwxA17B2RQSje+bINKV" +
+	"kWOPGO6ZWSZFxyXjciCo71pgKcOgKXOiqrSt9BG7TNY4jd61TthHja3jhESzb4ZY" +
+	"PlqmrhNr0Sdpbbtwxb9Jjlvx9o0DMZD+aXWxBVun8fwhG1UNbb2Om2TxAp/VUxeY" +
+	"utwZJKcrZgdN/POz8U6TW1eJMt6fjuCgBQmfXxnlj48KD1fIKwKeyLWUcsMDiTy7" +
+	"s/twWaZCa+6jt9nokxq5p1cpix38JDMF196RNaBVDiyRjROc7EAsmCpmwP9wFUzQ" +
+	"zhpztlNJ8Cm0PWOOO9cH6A=="
+
+func TestSignGolden(t *testing.T) {
+	cipher := loadCipher(t)
+	s := NewSigner(cipher, crypto.SHA256)
+	params := map[string]interface{}{
+		"action":  "query",
+		"channel": "wechat",
+		"user_id": "oEIpN5c8e34o6jaV5KG48vJTDpBA",
+		"ts":      "1538231718",
+		"nonce":   "fTcCu9Qi5QtjaarYkXS4u1zTxtF4igXX",
+	}
+	plain, err := Canonicalize(params, FieldSign)
+	if err != nil || plain != goldenCanonical {
+		t.Fatalf("Canonicalize = %q, %v; want %q", plain, err, goldenCanonical)
+	}
+	sign, err := s.Sign(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sign != goldenSign {
+		t.Errorf("Sign = %s, want %s", sign, goldenSign)
+	}
+	if params[FieldSign] != sign {
+		t.Errorf("params[sign] = %v, want %s", params[FieldSign], sign)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	cipher := loadCipher(t)
+	now := time.Unix(1538231718, 0)
+	clock := func() time.Time { return now }
+
+	s := NewSigner(cipher, crypto.SHA256)
+	s.Now = clock
+	store := NewMemoryNonceStore()
+	store.Now = clock
+	v := NewVerifier(cipher, crypto.SHA256, store)
+	v.Now = clock
+
+	params := map[string]interface{}{"action": "pay", "amount": 12.5, "items": []interface{}{"a", "b"}}
+	if _, err := s.Sign(params); err != nil {
+		t.Fatal(err)
+	}
+
+	// 模拟经过 JSON 传输，数字变为 float64
+	data, _ := json.Marshal(params)
+	var received map[string]interface{}
+	if err := json.Unmarshal(data, &received); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := v.Verify(received); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if err := v.Verify(received); !errors.Is(err, ErrReplayed) {
+		t.Errorf("Verify(replay) = %v, want ErrReplayed", err)
+	}
+
+	tampered := copyParams(received)
+	tampered["amount"] = 1250.0
+	tampered[FieldNonce] = "another"
+	if err := v.Verify(tampered); !errors.Is(err, ErrBadSign) {
+		t.Errorf("Verify(tampered) = %v, want ErrBadSign", err)
+	}
+	// 签名失败的请求不应占用 nonce
+	if store.Len() != 1 {
+		t.Errorf("nonce store has %d entries, want 1", store.Len())
+	}
+
+	now = now.Add(DefaultWindow + time.Second)
+	if err := v.Verify(received); !errors.Is(err, ErrExpired) {
+		t.Errorf("Verify(late) = %v, want ErrExpired", err)
+	}
+
+	for field, want := range map[string]error{FieldSign: ErrMissingSign, FieldTimestamp: ErrMissingTimestamp, FieldNonce: ErrMissingNonce} {
+		p := copyParams(received)
+		delete(p, field)
+		if err := v.Verify(p); !errors.Is(err, want) {
+			t.Errorf("Verify(without %s) = %v, want %v", field, err, want)
+		}
+	}
+}
+
+func TestMemoryNonceStoreExpire(t *testing.T) {
+	now := time.Unix(1000, 0)
+	store := NewMemoryNonceStore()
+	store.Now = func() time.Time { return now }
+
+	if ok, _ := store.Add("n", now.Add(time.Minute)); !ok {
+		t.Fatal("first Add = false")
+	}
+	if ok, _ := store.Add("n", now.Add(time.Minute)); ok {
+		t.Fatal("second Add = true")
+	}
+	now = now.Add(2 * time.Minute)
+	if ok, _ := store.Add("n", now.Add(time.Minute)); !ok {
+		t.Fatal("Add after expiry = false")
+	}
+	if ok, _ := store.Add("m", now.Add(time.Minute)); !ok || store.Len() != 2 {
+		t.Fatalf("Len = %d, want 2", store.Len())
+	}
+}
+
+func copyParams(params map[string]interface{}) map[string]interface{} {
+	p := make(map[string]interface{}, len(params))
+	for k, v := range params {
+		p[k] = v
+	}
+	return p
+}
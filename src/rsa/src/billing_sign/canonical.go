@@ -0,0 +1,159 @@
+package billing_sign
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 规范化规则（签名双方必须完全一致）：
+//
+//  1. 顶层 key 按字节序升序排列，嵌套 map 的 key 同样升序，数组保持原有顺序
+//  2. 嵌套 map 展开为 parent[child]=v，数组展开为 parent[0]=v、parent[1]=v
+//  3. key 的每一段和 value 都按 RFC 3986 转义，只保留 A-Z a-z 0-9 - _ . ~，
+//     其余字节编码为大写 %XX（空格为 %20，不是 +）
+//  4. nil、空字符串、空 map、空数组不参与签名；签名字段本身（sign）不参与签名
+//  5. bool 为 true/false，整数为十进制，浮点数为不带指数的最短表示（1.5、100、0.1），
+//     NaN 和 Inf 报错；json.Number 原样使用；[]byte 先做标准 base64 编码
+//  6. 各项以 & 连接
+//
+// 例如 {"b": 1.50, "a": {"y": "x y", "x": [1, 2]}, "c": ""}
+// 规范化为 a[x][0]=1&a[x][1]=2&a[y]=x%20y&b=1.5
+
+// Canonicalize 返回 params 的规范化字符串，exclude 中的顶层 key 不参与
+func Canonicalize(params map[string]interface{}, exclude ...string) (string, error) {
+	skip := make(map[string]bool, len(exclude))
+	for _, k := range exclude {
+		skip[k] = true
+	}
+
+	var pairs []string
+	keys := sortedKeys(params)
+	for _, k := range keys {
+		if skip[k] {
+			continue
+		}
+		var err error
+		pairs, err = appendValue(pairs, escape(k), params[k])
+		if err != nil {
+			return "", err
+		}
+	}
+	return strings.Join(pairs, "&"), nil
+}
+
+func appendValue(pairs []string, name string, v interface{}) ([]string, error) {
+	switch v := v.(type) {
+	case nil:
+		return pairs, nil
+	case string:
+		if v == "" {
+			return pairs, nil
+		}
+		return append(pairs, name+"="+escape(v)), nil
+	case json.Number:
+		if v == "" {
+			return pairs, nil
+		}
+		return append(pairs, name+"="+escape(string(v))), nil
+	case []byte:
+		if len(v) == 0 {
+			return pairs, nil
+		}
+		return append(pairs, name+"="+escape(base64.StdEncoding.EncodeToString(v))), nil
+	case bool:
+		return append(pairs, name+"="+strconv.FormatBool(v)), nil
+	case float32:
+		return appendFloat(pairs, name, float64(v), 32)
+	case float64:
+		return appendFloat(pairs, name, v, 64)
+	case map[string]interface{}:
+		for _, k := range sortedKeys(v) {
+			var err error
+			pairs, err = appendValue(pairs, name+"["+escape(k)+"]", v[k])
+			if err != nil {
+				return nil, err
+			}
+		}
+		return pairs, nil
+	case []interface{}:
+		for i, elem := range v {
+			var err error
+			pairs, err = appendValue(pairs, name+"["+strconv.Itoa(i)+"]", elem)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return pairs, nil
+	}
+
+	// 其余整数类型、[]string 等具体类型的切片和 map[string]T 走反射
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return append(pairs, name+"="+strconv.FormatInt(rv.Int(), 10)), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return append(pairs, name+"="+strconv.FormatUint(rv.Uint(), 10)), nil
+	case reflect.String:
+		return appendValue(pairs, name, rv.String())
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			var err error
+			pairs, err = appendValue(pairs, name+"["+strconv.Itoa(i)+"]", rv.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+		}
+		return pairs, nil
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			break
+		}
+		m := make(map[string]interface{}, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			m[iter.Key().String()] = iter.Value().Interface()
+		}
+		return appendValue(pairs, name, m)
+	}
+	return nil, fmt.Errorf("%w: %s is %T", ErrUnsupportedValue, name, v)
+}
+
+func appendFloat(pairs []string, name string, f float64, bitSize int) ([]string, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, fmt.Errorf("%w: %s is %v", ErrUnsupportedValue, name, f)
+	}
+	return append(pairs, name+"="+strconv.FormatFloat(f, 'f', -1, bitSize)), nil
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// escape 按 RFC 3986 转义；url.QueryEscape 会把空格转成 +，不能直接用
+func escape(s string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hex[c>>4])
+		b.WriteByte(hex[c&15])
+	}
+	return b.String()
+}
@@ -0,0 +1,59 @@
+package billing_sign
+
+import (
+	"sync"
+	"time"
+)
+
+// NonceStore 记录已使用的 nonce。多实例部署时应实现为共享存储，
+// 例如 redis 的 SET key value NX PX ttl。
+type NonceStore interface {
+	// Add 记录 nonce 直到 expire；nonce 已存在时返回 false
+	Add(nonce string, expire time.Time) (bool, error)
+}
+
+// MemoryNonceStore 进程内的 NonceStore，只适合单实例
+type MemoryNonceStore struct {
+	mu        sync.Mutex
+	nonces    map[string]time.Time
+	lastPrune time.Time
+	// Now 返回当前时间，测试时可替换
+	Now func() time.Time
+}
+
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{nonces: make(map[string]time.Time), Now: time.Now}
+}
+
+func (this *MemoryNonceStore) Add(nonce string, expire time.Time) (bool, error) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	now := this.Now()
+	if exp, ok := this.nonces[nonce]; ok && exp.After(now) {
+		return false, nil
+	}
+	this.nonces[nonce] = expire
+	this.prune(now)
+	return true, nil
+}
+
+// Len 返回当前保存的 nonce 数量
+func (this *MemoryNonceStore) Len() int {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	return len(this.nonces)
+}
+
+// prune 清理过期的 nonce，最多每秒一次
+func (this *MemoryNonceStore) prune(now time.Time) {
+	if now.Sub(this.lastPrune) < time.Second {
+		return
+	}
+	this.lastPrune = now
+	for nonce, exp := range this.nonces {
+		if !exp.After(now) {
+			delete(this.nonces, nonce)
+		}
+	}
+}
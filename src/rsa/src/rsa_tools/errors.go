@@ -0,0 +1,53 @@
+package rsa_tools
+
+import (
+	"errors"
+)
+
+var (
+	// ErrNoKeyData 输入中既没有PEM块也不是可识别的DER数据
+	ErrNoKeyData = errors.New("no key data")
+	// ErrUnsupportedKey 密钥格式或算法不支持
+	ErrUnsupportedKey = errors.New("unsupported key")
+	// ErrKeyMismatch 密钥算法与所请求的操作不匹配，例如对ECDSA公钥做OAEP加密
+	ErrKeyMismatch = errors.New("key does not support operation")
+	// ErrUnsupportedHash 哈希算法不可用或与密钥算法不匹配
+	ErrUnsupportedHash = errors.New("unsupported hash")
+	// ErrVerification 签名校验失败
+	ErrVerification = errors.New("verification failed")
+)
+
+// KeyError 描述密钥加载失败的原因。
+// Kind 为 "private" 或 "public"，Format 为尝试解析的格式（如 PKCS1、PKCS8、PKIX、X509），
+// Err 可通过 errors.Is 与上面的哨兵错误比较。
+type KeyError struct {
+	Kind   string
+	Format string
+	Err    error
+}
+
+func (e *KeyError) Error() string {
+	if e.Format == "" {
+		return e.Kind + " key error: " + e.Err.Error()
+	}
+	return e.Kind + " key error (" + e.Format + "): " + e.Err.Error()
+}
+
+func (e *KeyError) Unwrap() error {
+	return e.Err
+}
+
+// OpError 描述签名、验签、加解密过程中的错误。
+type OpError struct {
+	Op  string
+	Alg string
+	Err error
+}
+
+func (e *OpError) Error() string {
+	return e.Op + " " + e.Alg + ": " + e.Err.Error()
+}
+
+func (e *OpError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,168 @@
+package rsa_tools
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+)
+
+// 密钥输入支持三种形式：
+//   1. PEM 文本，按块类型识别格式（RSA PRIVATE KEY、EC PRIVATE KEY、PRIVATE KEY、
+//      PUBLIC KEY、RSA PUBLIC KEY、CERTIFICATE）
+//   2. 去掉头尾的 base64 文本（计费平台下发的私钥即为这种形式）
+//   3. 原始 DER 字节
+// 后两种没有块类型可用，按 Auto 规则逐个格式尝试。
+
+// ParsePrivateKey 解析私钥，返回 *rsa.PrivateKey、*ecdsa.PrivateKey 或 ed25519.PrivateKey。
+// privateKeyType 为 Auto 时根据PEM块类型自动识别。
+func ParsePrivateKey(privateKey []byte, privateKeyType Type) (crypto.Signer, error) {
+	der, blockType, err := decodeKey(privateKey)
+	if err != nil {
+		return nil, &KeyError{Kind: "private", Err: err}
+	}
+
+	if privateKeyType == Auto {
+		switch blockType {
+		case "RSA PRIVATE KEY":
+			privateKeyType = PKCS1
+		case "EC PRIVATE KEY":
+			privateKeyType = SEC1
+		case "PRIVATE KEY":
+			privateKeyType = PKCS8
+		case "":
+			// 无块类型，按常见程度依次尝试
+			for _, t := range []Type{PKCS8, PKCS1, SEC1} {
+				if key, err := parsePrivateDER(der, t); err == nil {
+					return key, nil
+				}
+			}
+			return nil, &KeyError{Kind: "private", Err: ErrUnsupportedKey}
+		default:
+			return nil, &KeyError{Kind: "private", Format: blockType, Err: ErrUnsupportedKey}
+		}
+	}
+
+	return parsePrivateDER(der, privateKeyType)
+}
+
+// ParsePublicKey 解析公钥，支持 PKIX、PKCS1 以及 X.509 证书，
+// 返回 *rsa.PublicKey、*ecdsa.PublicKey 或 ed25519.PublicKey。
+func ParsePublicKey(publicKey []byte) (crypto.PublicKey, error) {
+	der, blockType, err := decodeKey(publicKey)
+	if err != nil {
+		return nil, &KeyError{Kind: "public", Err: err}
+	}
+
+	switch blockType {
+	case "PUBLIC KEY":
+		return parsePublicDER(der, "PKIX")
+	case "RSA PUBLIC KEY":
+		return parsePublicDER(der, "PKCS1")
+	case "CERTIFICATE":
+		return parsePublicDER(der, "X509")
+	case "":
+		for _, format := range []string{"PKIX", "X509", "PKCS1"} {
+			if key, err := parsePublicDER(der, format); err == nil {
+				return key, nil
+			}
+		}
+		return nil, &KeyError{Kind: "public", Err: ErrUnsupportedKey}
+	default:
+		return nil, &KeyError{Kind: "public", Format: blockType, Err: ErrUnsupportedKey}
+	}
+}
+
+func parsePrivateDER(der []byte, privateKeyType Type) (crypto.Signer, error) {
+	var key interface{}
+	var err error
+	var format string
+	switch privateKeyType {
+	case PKCS1:
+		format = "PKCS1"
+		key, err = x509.ParsePKCS1PrivateKey(der)
+	case PKCS8:
+		format = "PKCS8"
+		key, err = x509.ParsePKCS8PrivateKey(der)
+	case SEC1:
+		format = "SEC1"
+		key, err = x509.ParseECPrivateKey(der)
+	default:
+		return nil, &KeyError{Kind: "private", Format: fmt.Sprint(privateKeyType), Err: ErrUnsupportedKey}
+	}
+	if err != nil {
+		return nil, &KeyError{Kind: "private", Format: format, Err: err}
+	}
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return k, nil
+	case *ecdsa.PrivateKey:
+		return k, nil
+	case ed25519.PrivateKey:
+		return k, nil
+	}
+	return nil, &KeyError{Kind: "private", Format: format, Err: fmt.Errorf("%w: %T", ErrUnsupportedKey, key)}
+}
+
+func parsePublicDER(der []byte, format string) (crypto.PublicKey, error) {
+	var key interface{}
+	var err error
+	switch format {
+	case "PKIX":
+		key, err = x509.ParsePKIXPublicKey(der)
+	case "PKCS1":
+		key, err = x509.ParsePKCS1PublicKey(der)
+	case "X509":
+		var cert *x509.Certificate
+		cert, err = x509.ParseCertificate(der)
+		if err == nil {
+			key = cert.PublicKey
+		}
+	}
+	if err != nil {
+		return nil, &KeyError{Kind: "public", Format: format, Err: err}
+	}
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return k, nil
+	case *ecdsa.PublicKey:
+		return k, nil
+	case ed25519.PublicKey:
+		return k, nil
+	}
+	return nil, &KeyError{Kind: "public", Format: format, Err: fmt.Errorf("%w: %T", ErrUnsupportedKey, key)}
+}
+
+// decodeKey 取出密钥的DER字节，PEM输入时同时返回块类型
+func decodeKey(data []byte) ([]byte, string, error) {
+	text := bytes.TrimSpace(data)
+	if len(text) == 0 {
+		return nil, "", ErrNoKeyData
+	}
+
+	if block, _ := pem.Decode(text); block != nil {
+		if _, ok := block.Headers["Proc-Type"]; ok {
+			return nil, "", fmt.Errorf("%w: encrypted PEM block", ErrUnsupportedKey)
+		}
+		return block.Bytes, block.Type, nil
+	}
+
+	// 去掉头尾的 base64 文本，允许中间有换行
+	compact := bytes.Join(bytes.Fields(text), nil)
+	if der, err := base64.StdEncoding.DecodeString(string(compact)); err == nil {
+		return der, "", nil
+	}
+
+	// 原始DER，以 SEQUENCE 标签开头；不能去空白，DER 末尾可能正好是 0x20 等字节
+	if data[0] == 0x30 {
+		return data, "", nil
+	}
+	return nil, "", ErrNoKeyData
+}
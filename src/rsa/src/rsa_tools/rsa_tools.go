@@ -2,98 +2,288 @@ package rsa_tools
 
 import (
 	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rand"
 	"crypto/rsa"
-	"crypto/x509"
-	"encoding/pem"
-	"errors"
+	"fmt"
 )
 
+// Type 私钥编码格式
 type Type int64
 
 const (
 	PKCS1 Type = iota
 	PKCS8
+	SEC1 // EC 私钥
+	Auto // 根据PEM块类型自动识别
 )
 
-type pkcsClient struct {
-	privateKey *rsa.PrivateKey
-	publicKey  *rsa.PublicKey
+func (t Type) String() string {
+	switch t {
+	case PKCS1:
+		return "PKCS1"
+	case PKCS8:
+		return "PKCS8"
+	case SEC1:
+		return "SEC1"
+	case Auto:
+		return "Auto"
+	}
+	return fmt.Sprintf("Type(%d)", int64(t))
 }
 
-type Cipher interface {
+// Scheme RSA签名填充方式，对 ECDSA、Ed25519 密钥无效
+type Scheme int64
+
+const (
+	PKCS1v15 Scheme = iota
+	PSS
+)
+
+type Signer interface {
 	Sign(src []byte, hash crypto.Hash) ([]byte, error)
+}
+
+type Verifier interface {
 	Verify(src []byte, sign []byte, hash crypto.Hash) error
 }
 
-func (this *pkcsClient) Sign(src []byte, hash crypto.Hash) ([]byte, error) {
-	h := hash.New()
-	h.Write(src)
-	hashed := h.Sum(nil)
-	return rsa.SignPKCS1v15(rand.Reader, this.privateKey, hash, hashed)
+type Cipher interface {
+	Signer
+	Verifier
 }
 
-func (this *pkcsClient) Verify(src []byte, sign []byte, hash crypto.Hash) error {
-	h := hash.New()
-	h.Write(src)
-	hashed := h.Sum(nil)
-	return rsa.VerifyPKCS1v15(this.publicKey, hash, hashed, sign)
+// Encrypter 使用 RSA-OAEP 加密，label 可以为 nil
+type Encrypter interface {
+	Encrypt(src []byte, hash crypto.Hash, label []byte) ([]byte, error)
 }
 
-func New(privateKey []byte, publicKey []byte, privateKeyType Type) (Cipher, error) {
+type Decrypter interface {
+	Decrypt(src []byte, hash crypto.Hash, label []byte) ([]byte, error)
+}
+
+type pkcsClient struct {
+	Signer
+	Verifier
+}
+
+type signer struct {
+	privateKey crypto.Signer
+	scheme     Scheme
+}
+
+type verifier struct {
+	publicKey crypto.PublicKey
+	scheme    Scheme
+}
+
+type oaepClient struct {
+	privateKey *rsa.PrivateKey
+	publicKey  *rsa.PublicKey
+}
 
-	blockPri, _ := pem.Decode([]byte(privateKey))
-	if blockPri == nil {
-		return nil, errors.New("private key error")
+// New 同时加载私钥和公钥，使用 PKCS#1 v1.5 签名，兼容旧接口
+func New(privateKey []byte, publicKey []byte, privateKeyType Type) (Cipher, error) {
+	s, err := NewSigner(privateKey, privateKeyType, PKCS1v15)
+	if err != nil {
+		return nil, err
+	}
+	v, err := NewVerifier(publicKey, PKCS1v15)
+	if err != nil {
+		return nil, err
 	}
+	return &pkcsClient{Signer: s, Verifier: v}, nil
+}
 
-	blockPub, _ := pem.Decode([]byte(publicKey))
-	if blockPub == nil {
-		return nil, errors.New("public key error")
+// NewSigner 只加载私钥，供只需要签名的调用方使用
+func NewSigner(privateKey []byte, privateKeyType Type, scheme Scheme) (Signer, error) {
+	priKey, err := ParsePrivateKey(privateKey, privateKeyType)
+	if err != nil {
+		return nil, err
 	}
+	return &signer{privateKey: priKey, scheme: scheme}, nil
+}
 
-	priKey, err := genPriKey(blockPri.Bytes, privateKeyType)
+// NewVerifier 只加载公钥（PKIX、PKCS1 或 X.509 证书），供只需要验签的调用方使用
+func NewVerifier(publicKey []byte, scheme Scheme) (Verifier, error) {
+	pubKey, err := ParsePublicKey(publicKey)
 	if err != nil {
 		return nil, err
 	}
-	pubKey, err := genPubKey(blockPub.Bytes)
+	return &verifier{publicKey: pubKey, scheme: scheme}, nil
+}
+
+// NewEncrypter 加载RSA公钥用于 OAEP 加密
+func NewEncrypter(publicKey []byte) (Encrypter, error) {
+	pubKey, err := ParsePublicKey(publicKey)
 	if err != nil {
 		return nil, err
 	}
-	return &pkcsClient{privateKey: priKey, publicKey: pubKey}, nil
+	rsaKey, ok := pubKey.(*rsa.PublicKey)
+	if !ok {
+		return nil, &KeyError{Kind: "public", Err: fmt.Errorf("%w: OAEP requires RSA, got %T", ErrKeyMismatch, pubKey)}
+	}
+	return &oaepClient{publicKey: rsaKey}, nil
 }
 
-func genPubKey(publicKey []byte) (*rsa.PublicKey, error) {
-	pub, err := x509.ParsePKIXPublicKey(publicKey)
+// NewDecrypter 加载RSA私钥用于 OAEP 解密
+func NewDecrypter(privateKey []byte, privateKeyType Type) (Decrypter, error) {
+	priKey, err := ParsePrivateKey(privateKey, privateKeyType)
 	if err != nil {
 		return nil, err
 	}
-	return pub.(*rsa.PublicKey), nil
+	rsaKey, ok := priKey.(*rsa.PrivateKey)
+	if !ok {
+		return nil, &KeyError{Kind: "private", Err: fmt.Errorf("%w: OAEP requires RSA, got %T", ErrKeyMismatch, priKey)}
+	}
+	return &oaepClient{privateKey: rsaKey, publicKey: &rsaKey.PublicKey}, nil
 }
 
-func genPriKey(privateKey []byte, privateKeyType Type) (*rsa.PrivateKey, error) {
-	var priKey *rsa.PrivateKey
-	var err error
-	switch privateKeyType {
-	case PKCS1:
-		{
-			priKey, err = x509.ParsePKCS1PrivateKey([]byte(privateKey))
-			if err != nil {
-				return nil, err
-			}
+func (this *signer) Sign(src []byte, hash crypto.Hash) ([]byte, error) {
+	alg := algorithm(this.privateKey.Public(), this.scheme)
+
+	if key, ok := this.privateKey.(ed25519.PrivateKey); ok {
+		return signEd25519(key, src, hash)
+	}
+
+	hashed, err := digest(src, hash)
+	if err != nil {
+		return nil, &OpError{Op: "sign", Alg: alg, Err: err}
+	}
+
+	var sign []byte
+	switch key := this.privateKey.(type) {
+	case *rsa.PrivateKey:
+		if this.scheme == PSS {
+			opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: hash}
+			sign, err = rsa.SignPSS(rand.Reader, key, hash, hashed, opts)
+		} else {
+			sign, err = rsa.SignPKCS1v15(rand.Reader, key, hash, hashed)
 		}
-	case PKCS8:
-		{
-			prkI, err := x509.ParsePKCS8PrivateKey([]byte(privateKey))
-			if err != nil {
-				return nil, err
-			}
-			priKey = prkI.(*rsa.PrivateKey)
+	case *ecdsa.PrivateKey:
+		sign, err = ecdsa.SignASN1(rand.Reader, key, hashed)
+	default:
+		err = ErrUnsupportedKey
+	}
+	if err != nil {
+		return nil, &OpError{Op: "sign", Alg: alg, Err: err}
+	}
+	return sign, nil
+}
+
+func (this *verifier) Verify(src []byte, sign []byte, hash crypto.Hash) error {
+	alg := algorithm(this.publicKey, this.scheme)
+
+	if key, ok := this.publicKey.(ed25519.PublicKey); ok {
+		return verifyEd25519(key, src, sign, hash)
+	}
+
+	hashed, err := digest(src, hash)
+	if err != nil {
+		return &OpError{Op: "verify", Alg: alg, Err: err}
+	}
+
+	switch key := this.publicKey.(type) {
+	case *rsa.PublicKey:
+		if this.scheme == PSS {
+			// 对端的盐长度不一定等于哈希长度，验签时自动识别
+			opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto, Hash: hash}
+			err = rsa.VerifyPSS(key, hash, hashed, sign, opts)
+		} else {
+			err = rsa.VerifyPKCS1v15(key, hash, hashed, sign)
+		}
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(key, hashed, sign) {
+			err = ErrVerification
 		}
 	default:
-		{
-			return nil, errors.New("unsupport private key type")
+		return &OpError{Op: "verify", Alg: alg, Err: ErrUnsupportedKey}
+	}
+	if err != nil {
+		return &OpError{Op: "verify", Alg: alg, Err: ErrVerification}
+	}
+	return nil
+}
+
+func (this *oaepClient) Encrypt(src []byte, hash crypto.Hash, label []byte) ([]byte, error) {
+	if !hash.Available() {
+		return nil, &OpError{Op: "encrypt", Alg: "RSA-OAEP", Err: ErrUnsupportedHash}
+	}
+	out, err := rsa.EncryptOAEP(hash.New(), rand.Reader, this.publicKey, src, label)
+	if err != nil {
+		return nil, &OpError{Op: "encrypt", Alg: "RSA-OAEP", Err: err}
+	}
+	return out, nil
+}
+
+func (this *oaepClient) Decrypt(src []byte, hash crypto.Hash, label []byte) ([]byte, error) {
+	if this.privateKey == nil {
+		return nil, &OpError{Op: "decrypt", Alg: "RSA-OAEP", Err: ErrKeyMismatch}
+	}
+	if !hash.Available() {
+		return nil, &OpError{Op: "decrypt", Alg: "RSA-OAEP", Err: ErrUnsupportedHash}
+	}
+	out, err := rsa.DecryptOAEP(hash.New(), rand.Reader, this.privateKey, src, label)
+	if err != nil {
+		return nil, &OpError{Op: "decrypt", Alg: "RSA-OAEP", Err: err}
+	}
+	return out, nil
+}
+
+// Ed25519 直接对原文签名（hash 传 0），或使用 Ed25519ph（hash 传 crypto.SHA512）
+func signEd25519(key ed25519.PrivateKey, src []byte, hash crypto.Hash) ([]byte, error) {
+	switch hash {
+	case 0:
+		return ed25519.Sign(key, src), nil
+	case crypto.SHA512:
+		hashed, _ := digest(src, hash)
+		sign, err := key.Sign(rand.Reader, hashed, &ed25519.Options{Hash: crypto.SHA512})
+		if err != nil {
+			return nil, &OpError{Op: "sign", Alg: "Ed25519ph", Err: err}
+		}
+		return sign, nil
+	}
+	return nil, &OpError{Op: "sign", Alg: "Ed25519", Err: ErrUnsupportedHash}
+}
+
+func verifyEd25519(key ed25519.PublicKey, src []byte, sign []byte, hash crypto.Hash) error {
+	switch hash {
+	case 0:
+		if !ed25519.Verify(key, src, sign) {
+			return &OpError{Op: "verify", Alg: "Ed25519", Err: ErrVerification}
+		}
+		return nil
+	case crypto.SHA512:
+		hashed, _ := digest(src, hash)
+		if err := ed25519.VerifyWithOptions(key, hashed, sign, &ed25519.Options{Hash: crypto.SHA512}); err != nil {
+			return &OpError{Op: "verify", Alg: "Ed25519ph", Err: ErrVerification}
+		}
+		return nil
+	}
+	return &OpError{Op: "verify", Alg: "Ed25519", Err: ErrUnsupportedHash}
+}
+
+func digest(src []byte, hash crypto.Hash) ([]byte, error) {
+	if hash == 0 || !hash.Available() {
+		return nil, ErrUnsupportedHash
+	}
+	h := hash.New()
+	h.Write(src)
+	return h.Sum(nil), nil
+}
+
+func algorithm(publicKey crypto.PublicKey, scheme Scheme) string {
+	switch publicKey.(type) {
+	case *rsa.PublicKey:
+		if scheme == PSS {
+			return "RSA-PSS"
 		}
+		return "RSA-PKCS1v15"
+	case *ecdsa.PublicKey:
+		return "ECDSA"
+	case ed25519.PublicKey:
+		return "Ed25519"
 	}
-	return priKey, nil
+	return fmt.Sprintf("%T", publicKey)
 }
@@ -0,0 +1,197 @@
+package rsa_tools
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+)
+
+var rsaKey, _ = rsa.GenerateKey(rand.Reader, 2048)
+
+func pemBytes(blockType string, der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+}
+
+func pkcs8DER(t *testing.T, key interface{}) []byte {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+func pkixDER(t *testing.T, key interface{}) []byte {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+func TestSignVerify(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	edPub, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	src := []byte("action=query&channel=wechat&ts=1538231718")
+
+	tests := []struct {
+		name    string
+		private []byte
+		public  []byte
+		scheme  Scheme
+		hash    crypto.Hash
+	}{
+		{"rsa-pkcs1v15", pemBytes("PRIVATE KEY", pkcs8DER(t, rsaKey)), pemBytes("PUBLIC KEY", pkixDER(t, &rsaKey.PublicKey)), PKCS1v15, crypto.SHA256},
+		{"rsa-pss", pemBytes("RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey)), pemBytes("RSA PUBLIC KEY", x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey)), PSS, crypto.SHA256},
+		{"ecdsa", pemBytes("PRIVATE KEY", pkcs8DER(t, ecKey)), pemBytes("PUBLIC KEY", pkixDER(t, &ecKey.PublicKey)), PKCS1v15, crypto.SHA256},
+		{"ed25519", pemBytes("PRIVATE KEY", pkcs8DER(t, edKey)), pemBytes("PUBLIC KEY", pkixDER(t, edPub)), PKCS1v15, 0},
+		{"ed25519ph", pemBytes("PRIVATE KEY", pkcs8DER(t, edKey)), pemBytes("PUBLIC KEY", pkixDER(t, edPub)), PKCS1v15, crypto.SHA512},
+	}
+	for _, test := range tests {
+		s, err := NewSigner(test.private, Auto, test.scheme)
+		if err != nil {
+			t.Errorf("%s: NewSigner: %v", test.name, err)
+			continue
+		}
+		v, err := NewVerifier(test.public, test.scheme)
+		if err != nil {
+			t.Errorf("%s: NewVerifier: %v", test.name, err)
+			continue
+		}
+		sign, err := s.Sign(src, test.hash)
+		if err != nil {
+			t.Errorf("%s: Sign: %v", test.name, err)
+			continue
+		}
+		if err := v.Verify(src, sign, test.hash); err != nil {
+			t.Errorf("%s: Verify: %v", test.name, err)
+		}
+		if err := v.Verify([]byte("tampered"), sign, test.hash); !errors.Is(err, ErrVerification) {
+			t.Errorf("%s: Verify(tampered) = %v, want ErrVerification", test.name, err)
+		}
+	}
+}
+
+func TestNewCompat(t *testing.T) {
+	c, err := New(pemBytes("PRIVATE KEY", pkcs8DER(t, rsaKey)), pemBytes("PUBLIC KEY", pkixDER(t, &rsaKey.PublicKey)), PKCS8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sign, err := c.Sign([]byte("x"), crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// New 保持旧行为：用标准库按 PKCS#1 v1.5 校验
+	hashed := crypto.SHA256.New()
+	hashed.Write([]byte("x"))
+	if err := rsa.VerifyPKCS1v15(&rsaKey.PublicKey, crypto.SHA256, hashed.Sum(nil), sign); err != nil {
+		t.Errorf("New signature is not PKCS#1 v1.5: %v", err)
+	}
+}
+
+func TestKeyFormats(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ecDER, _ := x509.MarshalECPrivateKey(ecKey)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "billing"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &rsaKey.PublicKey, rsaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	privates := map[string][]byte{
+		"pkcs1-pem":    pemBytes("RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey)),
+		"pkcs8-pem":    pemBytes("PRIVATE KEY", pkcs8DER(t, rsaKey)),
+		"sec1-pem":     pemBytes("EC PRIVATE KEY", ecDER),
+		"pkcs8-b64":    []byte(base64.StdEncoding.EncodeToString(pkcs8DER(t, rsaKey))),
+		"pkcs1-der":    x509.MarshalPKCS1PrivateKey(rsaKey),
+		"sec1-der":     ecDER,
+		"pkcs8-ec-b64": []byte(base64.StdEncoding.EncodeToString(pkcs8DER(t, ecKey))),
+	}
+	for name, data := range privates {
+		if _, err := ParsePrivateKey(data, Auto); err != nil {
+			t.Errorf("ParsePrivateKey(%s): %v", name, err)
+		}
+	}
+
+	publics := map[string][]byte{
+		"pkix-pem":  pemBytes("PUBLIC KEY", pkixDER(t, &rsaKey.PublicKey)),
+		"pkcs1-pem": pemBytes("RSA PUBLIC KEY", x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey)),
+		"cert-pem":  pemBytes("CERTIFICATE", cert),
+		"cert-der":  cert,
+		"pkix-b64":  []byte(base64.StdEncoding.EncodeToString(pkixDER(t, &ecKey.PublicKey))),
+	}
+	for name, data := range publics {
+		if _, err := ParsePublicKey(data); err != nil {
+			t.Errorf("ParsePublicKey(%s): %v", name, err)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	var keyErr *KeyError
+
+	_, err := ParsePrivateKey(nil, Auto)
+	if !errors.As(err, &keyErr) || !errors.Is(err, ErrNoKeyData) {
+		t.Errorf("ParsePrivateKey(nil) = %v, want KeyError wrapping ErrNoKeyData", err)
+	}
+
+	_, err = ParsePublicKey(pemBytes("DH PARAMETERS", []byte{0x30, 0}))
+	if !errors.Is(err, ErrUnsupportedKey) {
+		t.Errorf("ParsePublicKey(DH) = %v, want ErrUnsupportedKey", err)
+	}
+
+	// 旧实现在这里对非RSA公钥做类型断言会panic
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, err = NewEncrypter(pemBytes("PUBLIC KEY", pkixDER(t, &ecKey.PublicKey)))
+	if !errors.Is(err, ErrKeyMismatch) {
+		t.Errorf("NewEncrypter(ecdsa) = %v, want ErrKeyMismatch", err)
+	}
+
+	s, _ := NewSigner(pemBytes("PRIVATE KEY", pkcs8DER(t, rsaKey)), PKCS8, PSS)
+	if _, err := s.Sign([]byte("x"), 0); !errors.Is(err, ErrUnsupportedHash) {
+		t.Errorf("Sign(hash=0) = %v, want ErrUnsupportedHash", err)
+	}
+}
+
+func TestOAEP(t *testing.T) {
+	enc, err := NewEncrypter(pemBytes("PUBLIC KEY", pkixDER(t, &rsaKey.PublicKey)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec, err := NewDecrypter(pemBytes("RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey)), Auto)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plain := []byte("user_id=oEIpN5c8e34o6jaV5KG48vJTDpBA")
+	out, err := enc.Encrypt(plain, crypto.SHA256, []byte("billing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := dec.Decrypt(out, crypto.SHA256, []byte("billing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(plain) {
+		t.Errorf("Decrypt = %q, want %q", got, plain)
+	}
+	if _, err := dec.Decrypt(out, crypto.SHA256, []byte("other")); err == nil {
+		t.Errorf("Decrypt with wrong label succeeded")
+	}
+}
@@ -27,6 +27,7 @@ var (
 type UncheckedErrors struct {
 	// Errors is a list of all the unchecked errors in the package.
 	// Printing an error reports its position within the file and the contents of the line.
+	// Each error is an UncheckedError.
 	Errors []error
 }
 
@@ -34,6 +35,44 @@ func (e UncheckedErrors) Error() string {
 	return fmt.Sprintf("%d unchecked errors", len(e.Errors))
 }
 
+// UncheckedError describes an error that is not checked.
+type UncheckedError struct {
+	Pos  token.Position // the position of the call, or of the blank identifier
+	Line string         // the source line, with surrounding white space removed
+	// FuncName is the fully qualified name of the called function, such as
+	// "os.Remove" or "(*bytes.Buffer).Write", or "" for calls of function
+	// values and for type assertions.
+	FuncName string
+	Blank    bool // whether the error is assigned to the blank identifier
+	Assert   bool // whether it is the result of a type assertion rather than a call
+}
+
+func (e UncheckedError) Error() string {
+	pos := e.Pos.String()
+	if i := strings.Index(pos, "/src/"); i != -1 {
+		pos = pos[i+len("/src/"):]
+	}
+	return fmt.Sprintf("%s\t%s", pos, e.Line)
+}
+
+// Checker checks packages for unchecked errors.
+type Checker struct {
+	// Ignore is a map of package names to regular expressions. Identifiers from
+	// a package are checked against its regular expressions and if any of the
+	// expressions match the call is not checked.
+	Ignore map[string]*regexp.Regexp
+	// Exclude is a set of fully qualified function names, as described for
+	// UncheckedError.FuncName, whose errors are not checked.
+	Exclude map[string]bool
+	// Blank makes assignments to the blank identifier count as ignored errors.
+	Blank bool
+	// Asserts makes ignored type assertion results count as ignored errors.
+	Asserts bool
+	// SkipDefer makes calls in defer and go statements not count as ignored
+	// errors, since their results can't be checked.
+	SkipDefer bool
+}
+
 // CheckPackages checks packages for errors.
 // ignore is a map of package names to regular expressions. Identifiers from a package are
 // checked against its regular expressions and if any of the expressions match the call
@@ -42,6 +81,37 @@ func (e UncheckedErrors) Error() string {
 // ignored errors.
 // If types is true then ignored type assertion results are also checked
 func CheckPackages(pkgPaths []string, ignore map[string]*regexp.Regexp, blank bool, types bool) error {
+	c := Checker{Ignore: ignore, Blank: blank, Asserts: types}
+	return c.CheckPackages(pkgPaths)
+}
+
+// ReadExcludes reads a list of fully qualified function names to exclude from
+// checking, one per line. Empty lines and lines starting with "//" are ignored.
+func ReadExcludes(filename string) (map[string]bool, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	excludes := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		name := strings.TrimSpace(scanner.Text())
+		if name == "" || strings.HasPrefix(name, "//") {
+			continue
+		}
+		excludes[name] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return excludes, nil
+}
+
+// CheckPackages checks the packages with the given import paths for errors.
+// It returns an UncheckedErrors if any are found.
+func (c *Checker) CheckPackages(pkgPaths []string) error {
 	loadcfg := loader.Config{SourceImports: true}
 	for _, p := range pkgPaths {
 		loadcfg.Import(p)
@@ -52,7 +122,7 @@ func CheckPackages(pkgPaths []string, ignore map[string]*regexp.Regexp, blank bo
 		return fmt.Errorf("could not type check: %s", err)
 	}
 
-	visitor := &checker{program, nil, ignore, blank, types, make(map[string][]string), []error{}}
+	visitor := &visitor{program, nil, c, make(map[string][]string), nil}
 	for _, p := range pkgPaths {
 		if p == "unsafe" { // not a real package
 			continue
@@ -69,59 +139,67 @@ func CheckPackages(pkgPaths []string, ignore map[string]*regexp.Regexp, blank bo
 	return nil
 }
 
-// checker implements the errcheck algorithm
-type checker struct {
-	prog   *loader.Program
-	pkg    *loader.PackageInfo
-	ignore map[string]*regexp.Regexp
-	blank  bool
-	types  bool
-	lines  map[string][]string
+// visitor implements the errcheck algorithm
+type visitor struct {
+	prog  *loader.Program
+	pkg   *loader.PackageInfo
+	c     *Checker
+	lines map[string][]string
 
 	errors []error
 }
 
-type uncheckedError struct {
-	pos  token.Position
-	line string
-}
-
-func (e uncheckedError) Error() string {
-	pos := e.pos.String()
-	if i := strings.Index(pos, "/src/"); i != -1 {
-		pos = pos[i+len("/src/"):]
-	}
-	return fmt.Sprintf("%s\t%s", pos, e.line)
-}
-
-func (c *checker) ignoreCall(call *ast.CallExpr) bool {
-	// Try to get an identifier.
-	// Currently only supports simple expressions:
-	//     1. f()
-	//     2. x.y.f()
-	var id *ast.Ident
+// callee returns the identifier of the function called.
+// Currently only supports simple expressions:
+//  1. f()
+//  2. x.y.f()
+func callee(call *ast.CallExpr) *ast.Ident {
 	switch exp := call.Fun.(type) {
 	case (*ast.Ident):
-		id = exp
+		return exp
 	case (*ast.SelectorExpr):
-		id = exp.Sel
+		return exp.Sel
 	default:
 		// eg: *ast.SliceExpr, *ast.IndexExpr
 	}
+	return nil
+}
 
+// fullName returns the fully qualified name of the function called,
+// or "" if it isn't a declared function or method.
+func (v *visitor) fullName(call *ast.CallExpr) string {
+	id := callee(call)
+	if id == nil {
+		return ""
+	}
+	switch obj := v.pkg.Uses[id].(type) {
+	case *types.Func:
+		return obj.FullName()
+	case *types.Builtin:
+		return obj.Name()
+	}
+	return ""
+}
+
+func (v *visitor) ignoreCall(call *ast.CallExpr) bool {
+	id := callee(call)
 	if id == nil {
 		return false
 	}
 
 	// If we got an identifier for the function, see if it is ignored
 
-	if re, ok := c.ignore[""]; ok && re.MatchString(id.Name) {
+	if v.c.Exclude[v.fullName(call)] {
 		return true
 	}
 
-	if obj := c.pkg.Uses[id]; obj != nil {
+	if re, ok := v.c.Ignore[""]; ok && re.MatchString(id.Name) {
+		return true
+	}
+
+	if obj := v.pkg.Uses[id]; obj != nil {
 		if pkg := obj.Pkg(); pkg != nil {
-			if re, ok := c.ignore[pkg.Path()]; ok {
+			if re, ok := v.c.Ignore[pkg.Path()]; ok {
 				return re.MatchString(id.Name)
 			}
 		}
@@ -133,8 +211,8 @@ func (c *checker) ignoreCall(call *ast.CallExpr) bool {
 // errorsByArg returns a slice s such that
 // len(s) == number of return types of call
 // s[i] == true iff return type at position i from left is an error type
-func (c *checker) errorsByArg(call *ast.CallExpr) []bool {
-	switch t := c.pkg.Types[call].Type.(type) {
+func (v *visitor) errorsByArg(call *ast.CallExpr) []bool {
+	switch t := v.pkg.Types[call].Type.(type) {
 	case *types.Named:
 		// Single return
 		return []bool{isErrorType(t.Obj())}
@@ -150,11 +228,11 @@ func (c *checker) errorsByArg(call *ast.CallExpr) []bool {
 	return []bool{false}
 }
 
-func (c *checker) callReturnsError(call *ast.CallExpr) bool {
-	if c.isRecover(call) {
+func (v *visitor) callReturnsError(call *ast.CallExpr) bool {
+	if v.isRecover(call) {
 		return true
 	}
-	for _, isError := range c.errorsByArg(call) {
+	for _, isError := range v.errorsByArg(call) {
 		if isError {
 			return true
 		}
@@ -163,28 +241,34 @@ func (c *checker) callReturnsError(call *ast.CallExpr) bool {
 }
 
 // isRecover returns true if the given CallExpr is a call to the built-in recover() function.
-func (c *checker) isRecover(call *ast.CallExpr) bool {
+func (v *visitor) isRecover(call *ast.CallExpr) bool {
 	if fun, ok := call.Fun.(*ast.Ident); ok {
-		if _, ok := c.pkg.Uses[fun].(*types.Builtin); ok {
+		if _, ok := v.pkg.Uses[fun].(*types.Builtin); ok {
 			return fun.Name == "recover"
 		}
 	}
 	return false
 }
 
-func (c *checker) addErrorAtPosition(position token.Pos) {
-	pos := c.prog.Fset.Position(position)
-	lines, ok := c.lines[pos.Filename]
+// addErrorAtPosition records an unchecked error of call at position.
+// call is nil for type assertions.
+func (v *visitor) addErrorAtPosition(position token.Pos, call *ast.CallExpr, blank bool) {
+	pos := v.prog.Fset.Position(position)
+	lines, ok := v.lines[pos.Filename]
 	if !ok {
 		lines = readfile(pos.Filename)
-		c.lines[pos.Filename] = lines
+		v.lines[pos.Filename] = lines
 	}
 
 	line := "??"
 	if pos.Line-1 < len(lines) {
 		line = strings.TrimSpace(lines[pos.Line-1])
 	}
-	c.errors = append(c.errors, uncheckedError{pos, line})
+	e := UncheckedError{Pos: pos, Line: line, Blank: blank, Assert: call == nil}
+	if call != nil {
+		e.FuncName = v.fullName(call)
+	}
+	v.errors = append(v.errors, e)
 }
 
 func readfile(filename string) []string {
@@ -192,6 +276,7 @@ func readfile(filename string) []string {
 	if err != nil {
 		return nil
 	}
+	defer f.Close()
 
 	var lines []string
 	var scanner = bufio.NewScanner(f)
@@ -201,44 +286,44 @@ func readfile(filename string) []string {
 	return lines
 }
 
-func (c *checker) Visit(node ast.Node) ast.Visitor {
+func (v *visitor) Visit(node ast.Node) ast.Visitor {
 	switch stmt := node.(type) {
 	case *ast.ExprStmt:
 		if call, ok := stmt.X.(*ast.CallExpr); ok {
-			if !c.ignoreCall(call) && c.callReturnsError(call) {
-				c.addErrorAtPosition(call.Lparen)
+			if !v.ignoreCall(call) && v.callReturnsError(call) {
+				v.addErrorAtPosition(call.Lparen, call, false)
 			}
 		}
 	case *ast.GoStmt:
-		if !c.ignoreCall(stmt.Call) && c.callReturnsError(stmt.Call) {
-			c.addErrorAtPosition(stmt.Call.Lparen)
+		if !v.c.SkipDefer && !v.ignoreCall(stmt.Call) && v.callReturnsError(stmt.Call) {
+			v.addErrorAtPosition(stmt.Call.Lparen, stmt.Call, false)
 		}
 	case *ast.DeferStmt:
-		if !c.ignoreCall(stmt.Call) && c.callReturnsError(stmt.Call) {
-			c.addErrorAtPosition(stmt.Call.Lparen)
+		if !v.c.SkipDefer && !v.ignoreCall(stmt.Call) && v.callReturnsError(stmt.Call) {
+			v.addErrorAtPosition(stmt.Call.Lparen, stmt.Call, false)
 		}
 	case *ast.AssignStmt:
 		if len(stmt.Rhs) == 1 {
 			// single value on rhs; check against lhs identifiers
 			if call, ok := stmt.Rhs[0].(*ast.CallExpr); ok {
-				if !c.blank {
+				if !v.c.Blank {
 					break
 				}
-				if c.ignoreCall(call) {
+				if v.ignoreCall(call) {
 					break
 				}
-				isError := c.errorsByArg(call)
+				isError := v.errorsByArg(call)
 				for i := 0; i < len(stmt.Lhs); i++ {
 					if id, ok := stmt.Lhs[i].(*ast.Ident); ok {
 						// We shortcut calls to recover() because errorsByArg can't
 						// check its return types for errors since it returns interface{}.
-						if id.Name == "_" && (c.isRecover(call) || isError[i]) {
-							c.addErrorAtPosition(id.NamePos)
+						if id.Name == "_" && (v.isRecover(call) || isError[i]) {
+							v.addErrorAtPosition(id.NamePos, call, true)
 						}
 					}
 				}
 			} else if assert, ok := stmt.Rhs[0].(*ast.TypeAssertExpr); ok {
-				if !c.types {
+				if !v.c.Asserts {
 					break
 				}
 				if assert.Type == nil {
@@ -247,10 +332,10 @@ func (c *checker) Visit(node ast.Node) ast.Visitor {
 				}
 				if len(stmt.Lhs) < 2 {
 					// assertion result not read
-					c.addErrorAtPosition(stmt.Rhs[0].Pos())
-				} else if id, ok := stmt.Lhs[1].(*ast.Ident); ok && c.blank && id.Name == "_" {
+					v.addErrorAtPosition(stmt.Rhs[0].Pos(), nil, false)
+				} else if id, ok := stmt.Lhs[1].(*ast.Ident); ok && v.c.Blank && id.Name == "_" {
 					// assertion result ignored
-					c.addErrorAtPosition(id.NamePos)
+					v.addErrorAtPosition(id.NamePos, nil, true)
 				}
 			}
 		} else {
@@ -259,31 +344,31 @@ func (c *checker) Visit(node ast.Node) ast.Visitor {
 			for i := 0; i < len(stmt.Lhs); i++ {
 				if id, ok := stmt.Lhs[i].(*ast.Ident); ok {
 					if call, ok := stmt.Rhs[i].(*ast.CallExpr); ok {
-						if !c.blank {
+						if !v.c.Blank {
 							continue
 						}
-						if c.ignoreCall(call) {
+						if v.ignoreCall(call) {
 							continue
 						}
-						if id.Name == "_" && c.callReturnsError(call) {
-							c.addErrorAtPosition(id.NamePos)
+						if id.Name == "_" && v.callReturnsError(call) {
+							v.addErrorAtPosition(id.NamePos, call, true)
 						}
 					} else if assert, ok := stmt.Rhs[i].(*ast.TypeAssertExpr); ok {
-						if !c.types {
+						if !v.c.Asserts {
 							continue
 						}
 						if assert.Type == nil {
 							// Shouldn't happen anyway, no multi assignment in type switches
 							continue
 						}
-						c.addErrorAtPosition(id.NamePos)
+						v.addErrorAtPosition(id.NamePos, nil, id.Name == "_")
 					}
 				}
 			}
 		}
 	default:
 	}
-	return c
+	return v
 }
 
 type obj interface {
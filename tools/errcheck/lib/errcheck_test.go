@@ -1,9 +1,14 @@
 package errcheck
 
 import (
+	"fmt"
+	"go/ast"
 	"go/build"
 	"go/parser"
 	"go/token"
+	"io/ioutil"
+	"os"
+	"reflect"
 	"regexp"
 	"testing"
 )
@@ -13,6 +18,7 @@ const testPackage = "github.com/kisielk/errcheck/example"
 var (
 	unchecked map[marker]bool
 	blank     map[marker]bool
+	deferred  map[marker]bool
 )
 
 type marker struct {
@@ -23,6 +29,7 @@ type marker struct {
 func init() {
 	unchecked = make(map[marker]bool)
 	blank = make(map[marker]bool)
+	deferred = make(map[marker]bool)
 
 	pkg, err := build.Import(testPackage, "", 0)
 	if err != nil {
@@ -35,6 +42,20 @@ func init() {
 	}
 
 	for _, file := range astPkg["main"].Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			var call *ast.CallExpr
+			switch stmt := n.(type) {
+			case *ast.GoStmt:
+				call = stmt.Call
+			case *ast.DeferStmt:
+				call = stmt.Call
+			default:
+				return true
+			}
+			pos := fset.Position(call.Lparen)
+			deferred[marker{pos.Filename, pos.Line}] = true
+			return true
+		})
 		for _, comment := range file.Comments {
 			text := comment.Text()
 			pos := fset.Position(comment.Pos())
@@ -66,14 +87,13 @@ func TestUnchecked(t *testing.T) {
 	}
 
 	for i, err := range uerr.Errors {
-		uerr, ok := err.(uncheckedError)
-		if !ok {
-			t.Errorf("%d: not an uncheckedError, got %v", i, err)
-			continue
-		}
-		m := marker{uerr.pos.Filename, uerr.pos.Line}
+		err := err.(UncheckedError)
+		m := marker{err.Pos.Filename, err.Pos.Line}
 		if !unchecked[m] {
-			t.Errorf("%d: unexpected error: %v", i, uerr)
+			t.Errorf("%d: unexpected error: %v", i, err)
+		}
+		if err.Blank {
+			t.Errorf("%d: error reported as assigned to blank: %v", i, err)
 		}
 	}
 }
@@ -96,14 +116,112 @@ func TestBlank(t *testing.T) {
 	}
 
 	for i, err := range uerr.Errors {
-		uerr, ok := err.(uncheckedError)
-		if !ok {
-			t.Errorf("%d: not an uncheckedError, got %v", i, err)
-			continue
-		}
-		m := marker{uerr.pos.Filename, uerr.pos.Line}
+		err := err.(UncheckedError)
+		m := marker{err.Pos.Filename, err.Pos.Line}
 		if !unchecked[m] && !blank[m] {
-			t.Errorf("%d: unexpected error: %v", i, uerr)
+			t.Errorf("%d: unexpected error: %v", i, err)
+		}
+		if err.Blank != blank[m] {
+			t.Errorf("%d: Blank = %t for %v", i, err.Blank, err)
+		}
+	}
+}
+
+// TestSkipDefer is like TestUnchecked but ensures calls in defer and go statements are skipped.
+func TestSkipDefer(t *testing.T) {
+	c := Checker{Ignore: make(map[string]*regexp.Regexp), Asserts: true, SkipDefer: true}
+	err := c.CheckPackages([]string{testPackage})
+	uerr, ok := err.(UncheckedErrors)
+	if !ok {
+		t.Fatal("wrong error type returned")
+	}
+
+	numErrors := 0
+	for m := range unchecked {
+		if !deferred[m] {
+			numErrors++
+		}
+	}
+	if numErrors == len(unchecked) {
+		t.Fatal("no unchecked errors in defer or go statements in test package")
+	}
+	if len(uerr.Errors) != numErrors {
+		t.Errorf("got %d errors, want %d", len(uerr.Errors), numErrors)
+		for i, err := range uerr.Errors {
+			t.Errorf("%d: %v", i, err)
+		}
+		return
+	}
+
+	for i, err := range uerr.Errors {
+		err := err.(UncheckedError)
+		m := marker{err.Pos.Filename, err.Pos.Line}
+		if !unchecked[m] || deferred[m] {
+			t.Errorf("%d: unexpected error: %v", i, err)
+		}
+	}
+}
+
+// TestFuncName ensures errors report the full names of the called functions.
+func TestFuncName(t *testing.T) {
+	c := Checker{Ignore: make(map[string]*regexp.Regexp)}
+	err := c.CheckPackages([]string{testPackage})
+	uerr, ok := err.(UncheckedErrors)
+	if !ok {
+		t.Fatal("wrong error type returned")
+	}
+
+	names := make(map[string]bool)
+	for _, err := range uerr.Errors {
+		names[err.(UncheckedError).FuncName] = true
+	}
+	for _, name := range []string{
+		testPackage + ".a",
+		testPackage + ".b",
+		"(" + testPackage + ".t).a",
+		"fmt.Println",
+		"recover",
+		"", // m1["a"]()
+	} {
+		if !names[name] {
+			t.Errorf("no error with function name %q", name)
 		}
 	}
 }
+
+// TestExclude ensures excluded functions are not checked.
+func TestExclude(t *testing.T) {
+	c := Checker{
+		Ignore:  make(map[string]*regexp.Regexp),
+		Exclude: map[string]bool{"fmt.Println": true, testPackage + ".a": true},
+	}
+	err := c.CheckPackages([]string{testPackage})
+	uerr, ok := err.(UncheckedErrors)
+	if !ok {
+		t.Fatal("wrong error type returned")
+	}
+	for i, err := range uerr.Errors {
+		if c.Exclude[err.(UncheckedError).FuncName] {
+			t.Errorf("%d: error of excluded function: %v", i, err)
+		}
+	}
+}
+
+func TestReadExcludes(t *testing.T) {
+	f, err := ioutil.TempFile("", "errcheck")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	fmt.Fprint(f, "// Never fails.\n(*bytes.Buffer).Write\n\n  os.Remove  \n")
+	f.Close()
+
+	excludes, err := ReadExcludes(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]bool{"(*bytes.Buffer).Write": true, "os.Remove": true}
+	if !reflect.DeepEqual(excludes, want) {
+		t.Errorf("ReadExcludes = %v, want %v", excludes, want)
+	}
+}
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"regexp"
 	"strings"
@@ -70,6 +72,10 @@ func main() {
 	ignorePkg := flag.String("ignorepkg", "", "comma-separated list of package paths to ignore")
 	blank := flag.Bool("blank", false, "if true, check for errors assigned to blank identifier")
 	asserts := flag.Bool("asserts", false, "if true, check for ignored type assertion results")
+	deferred := flag.Bool("defer", true, "if true, check for errors of calls in defer and go statements")
+	exclude := flag.String("exclude", "", "path to a file listing fully qualified functions to ignore, one per line,\n"+
+		"            such as (*bytes.Buffer).Write")
+	jsonOutput := flag.Bool("json", false, "if true, print the unchecked errors as a JSON array")
 	flag.Parse()
 
 	for _, pkg := range strings.Split(*ignorePkg, ",") {
@@ -78,11 +84,26 @@ func main() {
 		}
 	}
 
+	checker := errcheck.Checker{Ignore: ignore, Blank: *blank, Asserts: *asserts, SkipDefer: !*deferred}
+	if *exclude != "" {
+		excludes, err := errcheck.ReadExcludes(*exclude)
+		if err != nil {
+			Fatalf("could not read exclude file: %s", err)
+		}
+		checker.Exclude = excludes
+	}
+
 	var pkgPaths = gotool.ImportPaths(flag.Args())
-	if err := errcheck.CheckPackages(pkgPaths, ignore, *blank, *asserts); err != nil {
+	if err := checker.CheckPackages(pkgPaths); err != nil {
 		if e, ok := err.(errcheck.UncheckedErrors); ok {
-			for _, uncheckedError := range e.Errors {
-				fmt.Println(uncheckedError)
+			if *jsonOutput {
+				if err := writeJSON(os.Stdout, e.Errors); err != nil {
+					Fatalf("%s", err)
+				}
+			} else {
+				for _, uncheckedError := range e.Errors {
+					fmt.Println(uncheckedError)
+				}
 			}
 			os.Exit(1)
 		} else if err == errcheck.ErrNoGoFiles {
@@ -91,5 +112,41 @@ func main() {
 		}
 		Fatalf("failed to check package: %s", err)
 	}
+	if *jsonOutput {
+		writeJSON(os.Stdout, nil)
+	}
 	os.Exit(0)
 }
+
+// jsonError is the JSON form of an errcheck.UncheckedError.
+type jsonError struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Code     string `json:"code"`
+	FuncName string `json:"func,omitempty"`
+	Blank    bool   `json:"blank"`
+	Assert   bool   `json:"assert"`
+}
+
+func writeJSON(w io.Writer, errs []error) error {
+	out := make([]jsonError, 0, len(errs))
+	for _, err := range errs {
+		e := err.(errcheck.UncheckedError)
+		out = append(out, jsonError{
+			File:     e.Pos.Filename,
+			Line:     e.Pos.Line,
+			Column:   e.Pos.Column,
+			Code:     e.Line,
+			FuncName: e.FuncName,
+			Blank:    e.Blank,
+			Assert:   e.Assert,
+		})
+	}
+	b, err := json.MarshalIndent(out, "", "\t")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", b)
+	return err
+}
@@ -25,14 +25,29 @@ type candidate struct {
 	Name  string
 	Type  string
 	Class decl_class
+	Score int // fuzzy match score, 0 unless the "fuzzy" option is on
+
+	rank candidate_rank
 }
 
+// With the "fuzzy" option on, candidates are ordered by rank first.
+type candidate_rank int
+
+const (
+	rank_local candidate_rank = iota
+	rank_recent
+	rank_member
+	rank_builtin
+)
+
 type out_buffers struct {
 	tmpbuf     *bytes.Buffer
 	candidates []candidate
 	ctx        *auto_complete_context
 	tmpns      map[string]bool
 	ignorecase bool
+	fuzzy      bool
+	locals     map[string]bool // names declared inside of the current function
 }
 
 func new_out_buffers(ctx *auto_complete_context) *out_buffers {
@@ -40,6 +55,7 @@ func new_out_buffers(ctx *auto_complete_context) *out_buffers {
 	b.tmpbuf = bytes.NewBuffer(make([]byte, 0, 1024))
 	b.candidates = make([]candidate, 0, 64)
 	b.ctx = ctx
+	b.fuzzy = g_config.Fuzzy
 	return b
 }
 
@@ -50,6 +66,14 @@ func (b *out_buffers) Len() int {
 func (b *out_buffers) Less(i, j int) bool {
 	x := b.candidates[i]
 	y := b.candidates[j]
+	if b.fuzzy {
+		if x.rank != y.rank {
+			return x.rank < y.rank
+		}
+		if x.Score != y.Score {
+			return x.Score > y.Score
+		}
+	}
 	if x.Class == y.Class {
 		return x.Name < y.Name
 	}
@@ -63,19 +87,41 @@ func (b *out_buffers) Swap(i, j int) {
 func (b *out_buffers) append_decl(p, name string, decl *decl, class decl_class) {
 	c1 := !g_config.ProposeBuiltins && decl.scope == g_universe_scope && decl.name != "Error"
 	c2 := class != decl_invalid && decl.class != class
-	c3 := class == decl_invalid && !has_prefix(name, p, b.ignorecase)
+	c3 := false
 	c4 := !decl.matches()
 	c5 := !check_type_expr(decl.typ)
 
+	score := 0
+	if class == decl_invalid {
+		if b.fuzzy {
+			score = fuzzy_score(p, name)
+			c3 = score == 0
+		} else {
+			c3 = !has_prefix(name, p, b.ignorecase)
+		}
+	}
+
 	if c1 || c2 || c3 || c4 || c5 {
 		return
 	}
 
+	rank := rank_member
+	switch {
+	case b.locals[name]:
+		rank = rank_local
+	case b.ctx.recent.contains(name):
+		rank = rank_recent
+	case decl.scope == g_universe_scope:
+		rank = rank_builtin
+	}
+
 	decl.pretty_print_type(b.tmpbuf)
 	b.candidates = append(b.candidates, candidate{
 		Name:  name,
 		Type:  b.tmpbuf.String(),
 		Class: decl.class,
+		Score: score,
+		rank:  rank,
 	})
 	b.tmpbuf.Reset()
 }
@@ -140,6 +186,7 @@ type auto_complete_context struct {
 
 	pcache    package_cache // packages cache
 	declcache *decl_cache   // top-level declarations cache
+	recent    *recent_identifiers
 }
 
 func new_auto_complete_context(pcache package_cache, declcache *decl_cache) *auto_complete_context {
@@ -147,6 +194,7 @@ func new_auto_complete_context(pcache package_cache, declcache *decl_cache) *aut
 	c.current = new_auto_complete_file("", declcache.context)
 	c.pcache = pcache
 	c.declcache = declcache
+	c.recent = new_recent_identifiers()
 	return c
 }
 
@@ -162,6 +210,9 @@ func (c *auto_complete_context) update_caches() {
 		c.pcache.append_packages(ps, other.packages)
 	}
 
+	if g_config.Source && g_daemon != nil {
+		g_daemon.srcimporter.new_walk()
+	}
 	update_packages(ps)
 
 	// fix imports for all files
@@ -191,6 +242,21 @@ func (c *auto_complete_context) make_decl_set(scope *scope) map[string]*decl {
 	return set
 }
 
+// Returns names declared in the function scopes between 'scope' and the file
+// scope.
+func (c *auto_complete_context) make_locals_set(scope *scope) map[string]bool {
+	set := make(map[string]bool)
+	for s := scope; s != nil; s = s.parent {
+		if s == c.current.filescope || s == c.pkg || s == g_universe_scope {
+			break
+		}
+		for name := range s.entities {
+			set[name] = true
+		}
+	}
+	return set
+}
+
 func (c *auto_complete_context) get_candidates_from_set(set map[string]*decl, partial string, class decl_class, b *out_buffers) {
 	for key, value := range set {
 		if value == nil {
@@ -276,8 +342,11 @@ func (c *auto_complete_context) apropos(file []byte, filename string, cursor int
 		class = decl_package
 	}
 
+	c.recent.update(file, filename, cursor-len(cc.partial))
+
 	if cc.decl == nil {
 		// In case if no declaraion is a subject of completion, propose all:
+		b.locals = c.make_locals_set(c.current.scope)
 		set := c.make_decl_set(c.current.scope)
 		c.get_candidates_from_set(set, cc.partial, class, b)
 		if cc.partial != "" && len(b.candidates) == 0 {
@@ -318,6 +387,78 @@ func (c *auto_complete_context) cursor_type_pkg(file []byte, filename string, cu
 	return tmp.String(), pkg
 }
 
+// fields must be exported for RPC
+type signature_info struct {
+	Name   string   // name of the called function
+	Type   string   // its pretty-printed type
+	Params []string // pretty-printed parameters
+	Active int      // index of the parameter under the cursor
+}
+
+// Returns the signature of the function whose call's parentheses the cursor is
+// in, an empty signature_info if there is none.
+func (c *auto_complete_context) signature(file []byte, filename string, cursor int) signature_info {
+	c.current.cursor = cursor
+	c.current.name = filename
+	c.current.process_data(file)
+	c.update_caches()
+
+	if cursor <= 0 {
+		return signature_info{}
+	}
+	iter := new_token_iterator(file, cursor)
+	e, active, ok := iter.extract_call_expr()
+	if !ok {
+		return signature_info{}
+	}
+	expr, err := parser.ParseExpr(string(e))
+	if err != nil {
+		return signature_info{}
+	}
+	typ, _, _ := infer_type(expr, c.current.scope, -1)
+	ft, ok := typ.(*ast.FuncType)
+	if !ok {
+		return signature_info{}
+	}
+
+	var si signature_info
+	switch t := expr.(type) {
+	case *ast.Ident:
+		si.Name = t.Name
+	case *ast.SelectorExpr:
+		si.Name = t.Sel.Name
+	}
+
+	var tmp bytes.Buffer
+	pretty_print_type_expr(&tmp, ft)
+	si.Type = tmp.String()
+
+	variadic := false
+	for _, field := range ft.Params.List {
+		_, variadic = field.Type.(*ast.Ellipsis)
+		names := []string{"?"}
+		if field.Names != nil {
+			names = names[:0]
+			for _, name := range field.Names {
+				names = append(names, name.Name)
+			}
+		}
+		for _, name := range names {
+			tmp.Reset()
+			if name != "?" {
+				tmp.WriteString(name + " ")
+			}
+			pretty_print_type_expr(&tmp, field.Type)
+			si.Params = append(si.Params, tmp.String())
+		}
+	}
+	if variadic && active >= len(si.Params) {
+		active = len(si.Params) - 1
+	}
+	si.Active = active
+	return si
+}
+
 func update_packages(ps map[string]*package_file_cache) {
 	// initiate package cache update
 	done := make(chan bool)
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"go/build"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// The source of the signature tests, the body of main is replaced by the
+// call of each test.
+const signature_test_src = `package p
+
+type T struct{}
+
+func (t *T) m(s string, n int) {}
+
+func f(a, b int) int { return a }
+func g(x int) int { return x }
+func v(format string, args ...interface{}) {}
+
+func main() {
+	var t T
+	var n int
+	BODY
+}
+`
+
+func TestSignature(t *testing.T) {
+	f_sig := signature_info{
+		Name:   "f",
+		Type:   "func(a, b int) int",
+		Params: []string{"a int", "b int"},
+	}
+	tests := []struct {
+		body string // the cursor is at '@'
+		want signature_info
+	}{
+		{"f(@)", f_sig},
+		{"f(1, @)", with_active(f_sig, 1)},
+		{"f(1,@ 2)", with_active(f_sig, 1)},
+		{"f(n+1@, 2)", f_sig},
+		// the innermost call under the cursor
+		{"f(g(@), 2)", signature_info{
+			Name:   "g",
+			Type:   "func(x int) int",
+			Params: []string{"x int"},
+		}},
+		{"f(g(1), @)", with_active(f_sig, 1)},
+		{"f([]int{1, 2}[0], @)", with_active(f_sig, 1)},
+		// methods
+		{"t.m(\"a\", @)", signature_info{
+			Name:   "m",
+			Type:   "func(s string, n int)",
+			Params: []string{"s string", "n int"},
+			Active: 1,
+		}},
+		// the variadic parameter stays active for any further argument
+		{"v(\"%d %d\", 1, 2, @)", signature_info{
+			Name:   "v",
+			Type:   "func(format string, args ...interface{})",
+			Params: []string{"format string", "args ...interface{}"},
+			Active: 1,
+		}},
+		// no call of a function under the cursor
+		{"n = 1@", signature_info{}},
+		{"f(1, 2)@", signature_info{}},
+		{"n = int(@)", signature_info{}},
+		{"undefined(@)", signature_info{}},
+	}
+
+	// the other files of the package are looked for in the file's directory
+	tmp, err := ioutil.TempDir("", "gocode")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	filename := filepath.Join(tmp, "p.go")
+
+	c := new_auto_complete_context(new_package_cache(), new_decl_cache(build.Default))
+	for _, test := range tests {
+		src := strings.Replace(signature_test_src, "BODY", test.body, 1)
+		cursor := strings.Index(src, "@")
+		src = src[:cursor] + src[cursor+1:]
+		got := c.signature([]byte(src), filename, cursor)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("signature at %q = %+v, want %+v", test.body, got, test.want)
+		}
+	}
+}
+
+func with_active(si signature_info, active int) signature_info {
+	si.Active = active
+	return si
+}
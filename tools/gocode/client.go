@@ -44,6 +44,8 @@ func do_client() int {
 			cmd_auto_complete(client)
 		case "cursortype":
 			cmd_cursor_type_pkg(client)
+		case "signature":
+			cmd_signature(client)
 		case "close":
 			cmd_close(client)
 		case "status":
@@ -152,6 +154,17 @@ func cmd_cursor_type_pkg(c *rpc.Client) {
 	fmt.Printf("%s,,%s\n", typ, pkg)
 }
 
+func cmd_signature(c *rpc.Client) {
+	context := pack_build_context(&build.Default)
+	file, filename, cursor := prepare_file_filename_cursor()
+	s := client_signature(c, file, filename, cursor, context)
+	f, ok := get_formatter(*g_format).(signature_formatter)
+	if !ok {
+		f = new(nice_formatter)
+	}
+	f.write_signature(s)
+}
+
 func cmd_close(c *rpc.Client) {
 	client_close(c, 0)
 }
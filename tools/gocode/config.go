@@ -22,12 +22,16 @@ type config struct {
 	ProposeBuiltins bool   `json:"propose-builtins"`
 	LibPath         string `json:"lib-path"`
 	Autobuild       bool   `json:"autobuild"`
+	Source          bool   `json:"source"`
+	Fuzzy           bool   `json:"fuzzy"`
 }
 
 var g_config = config{
 	ProposeBuiltins: false,
 	LibPath:         "",
 	Autobuild:       false,
+	Source:          false,
+	Fuzzy:           false,
 }
 
 var g_string_to_bool = map[string]bool{
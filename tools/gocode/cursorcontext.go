@@ -152,6 +152,43 @@ loop:
 	return make_expr(exprT)
 }
 
+// starting from the cursor, move backwards to the opening parenthesis of the
+// call the cursor is in and return the callee expression along with the index
+// of the argument under the cursor
+func (this *token_iterator) extract_call_expr() ([]byte, int, bool) {
+	active := 0
+	for this.previous_token() {
+		switch this.token().tok {
+		case token.RPAREN, token.RBRACK:
+			if !this.skip_to_bracket_pair() {
+				return nil, 0, false
+			}
+		case token.RBRACE:
+			if !this.skip_to_left_bracket(token.LBRACE, token.RBRACE) {
+				return nil, 0, false
+			}
+		case token.COMMA:
+			active++
+		case token.LPAREN:
+			e := this.extract_go_expr()
+			if len(e) == 0 {
+				return nil, 0, false
+			}
+			return e, active, true
+		case token.LBRACE, token.LBRACK:
+			// inside of a composite literal or an index expression
+			return nil, 0, false
+		case token.SEMICOLON:
+			// automatically inserted semicolons may end lines inside
+			// of the argument list, real ones can't
+			if this.token().lit == ";" {
+				return nil, 0, false
+			}
+		}
+	}
+	return nil, 0, false
+}
+
 // Given a slice of token_item, reassembles them into the original literal expression.
 func make_expr(tokens []token_item) []byte {
 	e := ""
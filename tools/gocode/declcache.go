@@ -52,8 +52,9 @@ func collect_package_imports(filename string, decls []ast.Decl, context build.Co
 //-------------------------------------------------------------------------
 
 type decl_file_cache struct {
-	name  string // file name
-	mtime int64  // last modification time
+	name    string // file name
+	mtime   int64  // last modification time
+	overlay int64  // version of the unsaved contents in use, if any
 
 	decls     map[string]*decl // top-level declarations
 	error     error            // last error
@@ -72,6 +73,18 @@ func new_decl_file_cache(name string, context build.Context) *decl_file_cache {
 }
 
 func (f *decl_file_cache) update() {
+	if data, version, ok := g_overlays.get(f.name); ok {
+		if f.overlay != version {
+			f.overlay = version
+			f.mtime = 0
+			f.error = nil
+			data, _ = filter_out_shebang(data)
+			f.process_data(data)
+		}
+		return
+	}
+	f.overlay = 0
+
 	stat, err := os.Stat(f.name)
 	if err != nil {
 		f.decls = nil
@@ -153,6 +166,9 @@ func abs_path_for_package(filename, p string, context build.Context) (string, bo
 	if len(p) == 0 {
 		return "", false
 	}
+	if g_config.Source {
+		return find_source_package(p, filepath.Clean(dir), context)
+	}
 	if p[0] == '.' {
 		return fmt.Sprintf("%s.a", filepath.Join(dir, p)), true
 	}
@@ -306,6 +322,46 @@ func package_name(file *ast.File) string {
 	return ""
 }
 
+//-------------------------------------------------------------------------
+// overlays
+//
+// Thread-safe collection of unsaved file contents. Editors talking to gocode
+// over LSP keep them up to date, they take precedence over the files on disk.
+//-------------------------------------------------------------------------
+
+type overlay struct {
+	data    []byte
+	version int64
+}
+
+type overlays struct {
+	files   map[string]overlay
+	version int64 // last version given out, never reused
+	sync.Mutex
+}
+
+var g_overlays = &overlays{files: make(map[string]overlay)}
+
+func (o *overlays) set(filename string, data []byte) {
+	o.Lock()
+	defer o.Unlock()
+	o.version++
+	o.files[filename] = overlay{data, o.version}
+}
+
+func (o *overlays) remove(filename string) {
+	o.Lock()
+	defer o.Unlock()
+	delete(o.files, filename)
+}
+
+func (o *overlays) get(filename string) ([]byte, int64, bool) {
+	o.Lock()
+	defer o.Unlock()
+	ov, ok := o.files[filename]
+	return ov.data, ov.version, ok
+}
+
 //-------------------------------------------------------------------------
 // decl_cache
 //
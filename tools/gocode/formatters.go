@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 )
@@ -13,6 +14,12 @@ type formatter interface {
 	write_candidates(candidates []candidate, num int)
 }
 
+// implemented by the formatters supporting the "signature" command, the
+// others fall back to nice_formatter
+type signature_formatter interface {
+	write_signature(s signature_info)
+}
+
 //-------------------------------------------------------------------------
 // nice_formatter (just for testing, simple textual output)
 //-------------------------------------------------------------------------
@@ -35,6 +42,18 @@ func (*nice_formatter) write_candidates(candidates []candidate, num int) {
 	}
 }
 
+func (*nice_formatter) write_signature(s signature_info) {
+	if s.Type == "" {
+		fmt.Printf("Not inside of a function call.\n")
+		return
+	}
+
+	fmt.Printf("func %s%s\n", s.Name, s.Type[len("func"):])
+	if s.Active < len(s.Params) {
+		fmt.Printf("  parameter %d: %s\n", s.Active, s.Params[s.Active])
+	}
+}
+
 //-------------------------------------------------------------------------
 // vim_formatter
 //-------------------------------------------------------------------------
@@ -140,12 +159,41 @@ func (*json_formatter) write_candidates(candidates []candidate, num int) {
 		if i != 0 {
 			fmt.Printf(", ")
 		}
+		if c.Score != 0 {
+			fmt.Printf(`{"class": "%s", "name": "%s", "type": "%s", "score": %d}`,
+				c.Class, c.Name, c.Type, c.Score)
+			continue
+		}
 		fmt.Printf(`{"class": "%s", "name": "%s", "type": "%s"}`,
 			c.Class, c.Name, c.Type)
 	}
 	fmt.Print("]]")
 }
 
+func (*json_formatter) write_signature(s signature_info) {
+	if s.Type == "" {
+		fmt.Print("{}")
+		return
+	}
+
+	params := make([]string, len(s.Params))
+	for i, p := range s.Params {
+		params[i] = json_quote(p)
+	}
+	fmt.Printf(`{"name": %s, "type": %s, "params": [%s], "active": %d}`,
+		json_quote(s.Name), json_quote(s.Type), strings.Join(params, ", "), s.Active)
+}
+
+// Returns 's' as a JSON string, strconv.Quote isn't enough as Go escapes
+// aren't all valid in JSON.
+func json_quote(s string) string {
+	data, err := json.Marshal(s)
+	if err != nil {
+		panic(err)
+	}
+	return string(data)
+}
+
 //-------------------------------------------------------------------------
 
 func get_formatter(name string) formatter {
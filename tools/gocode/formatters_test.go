@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// Returns what 'f' prints to the standard output.
+func capture_stdout(t *testing.T, f func()) []byte {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	f()
+	os.Stdout = stdout
+	w.Close()
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestJSONSignature(t *testing.T) {
+	tests := []signature_info{
+		{},
+		{
+			Name:   "Printf",
+			Type:   "func(format string, a ...interface{}) (n int, err error)",
+			Params: []string{"format string", "a ...interface{}"},
+			Active: 1,
+		},
+		{
+			Name:   "Decode",
+			Type:   "func(v struct{ X int \"json:\\\"x\\\"\" }, sep string)",
+			Params: []string{"v struct{ X int \"json:\\\"x\\\"\" }", "sep string"},
+		},
+		{
+			Name:   "Send",
+			Type:   "func(c chan<- string, s string)",
+			Params: []string{"c chan<- string", "s string"},
+		},
+	}
+	for _, test := range tests {
+		out := capture_stdout(t, func() {
+			new(json_formatter).write_signature(test)
+		})
+		var got signature_info
+		if err := json.Unmarshal(out, &got); err != nil {
+			t.Errorf("invalid JSON %s: %s", out, err)
+			continue
+		}
+		if test.Params == nil {
+			test.Params = got.Params
+		}
+		if !reflect.DeepEqual(got, test) {
+			t.Errorf("%s decodes to %+v, want %+v", out, got, test)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+//-------------------------------------------------------------------------
+// fuzzy matching
+//
+// With the "fuzzy" option on, a candidate matches if the partial identifier
+// is a subsequence of its name, ignoring case. Matches at the start of the
+// name or of a camel-case/underscore hump and runs of consecutive
+// characters score higher, so "nr" ranks NewReader above Inner, and a plain
+// prefix beats everything else.
+//-------------------------------------------------------------------------
+
+const (
+	fuzzy_match_score       = 1
+	fuzzy_same_case_score   = 1
+	fuzzy_start_score       = 8
+	fuzzy_hump_score        = 6
+	fuzzy_consecutive_score = 4
+	fuzzy_prefix_score      = 10
+)
+
+// Returns true if the character at index 'i' of 'name' starts a word.
+func is_hump(name []rune, i int) bool {
+	if i == 0 {
+		return true
+	}
+	prev, cur := name[i-1], name[i]
+	switch {
+	case prev == '_':
+		return cur != '_'
+	case unicode.IsUpper(cur):
+		return !unicode.IsUpper(prev)
+	case unicode.IsDigit(cur):
+		return !unicode.IsDigit(prev)
+	}
+	return false
+}
+
+// fuzzy_score returns how well 'partial' matches 'name', 0 means it doesn't
+// match at all. Every character of 'partial' is matched at the position which
+// gives the best total score.
+func fuzzy_score(partial, name string) int {
+	if partial == "" {
+		return fuzzy_match_score
+	}
+	p, n := []rune(partial), []rune(name)
+	if len(p) > len(n) {
+		return 0
+	}
+
+	// best[j] is the best score of the partial so far with its last
+	// character matched at n[j], -1 if impossible
+	best := make([]int, len(n))
+	next := make([]int, len(n))
+	for i := range p {
+		for j := range n {
+			next[j] = -1
+			if unicode.ToLower(p[i]) != unicode.ToLower(n[j]) {
+				continue
+			}
+			s := fuzzy_match_score
+			if p[i] == n[j] {
+				s += fuzzy_same_case_score
+			}
+			if j == 0 {
+				s += fuzzy_start_score
+			} else if is_hump(n, j) {
+				s += fuzzy_hump_score
+			}
+
+			if i == 0 {
+				next[j] = s
+				continue
+			}
+			prev := -1
+			for k := 0; k < j; k++ {
+				if best[k] < 0 {
+					continue
+				}
+				score := best[k]
+				if k == j-1 {
+					score += fuzzy_consecutive_score
+				}
+				if score > prev {
+					prev = score
+				}
+			}
+			if prev >= 0 {
+				next[j] = prev + s
+			}
+		}
+		best, next = next, best
+	}
+
+	score := 0
+	for _, s := range best {
+		if s > score {
+			score = s
+		}
+	}
+	if score == 0 {
+		return 0
+	}
+	if has_prefix(name, partial, false) {
+		score += fuzzy_prefix_score
+	}
+	// among equal matches shorter names win
+	score = score*8 - (len(n) - len(p))
+	if score < 1 {
+		score = 1
+	}
+	return score
+}
+
+//-------------------------------------------------------------------------
+// recent_identifiers
+//
+// Remembers which identifiers were recently completed. The identifier that
+// ends up at the place of the previous completion request is what the user
+// picked (or typed), it's ranked higher next time.
+//-------------------------------------------------------------------------
+
+const recent_identifiers_max = 64
+
+type recent_identifiers struct {
+	stamps map[string]int // identifier -> use counter
+	clock  int
+
+	// previous completion request
+	filename string
+	start    int // offset of the identifier being completed
+}
+
+func new_recent_identifiers() *recent_identifiers {
+	return &recent_identifiers{stamps: make(map[string]int)}
+}
+
+// Looks at the identifier at the place of the previous completion request in
+// the new contents of the file and remembers it as used.
+func (r *recent_identifiers) update(file []byte, filename string, start int) {
+	if r.filename == filename && r.start < len(file) && r.start != start {
+		end := r.start
+		for end < len(file) {
+			c, size := utf8.DecodeRune(file[end:])
+			if c != '_' && !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+				break
+			}
+			end += size
+		}
+		if end > r.start {
+			r.mark(string(file[r.start:end]))
+		}
+	}
+	r.filename = filename
+	r.start = start
+}
+
+func (r *recent_identifiers) mark(name string) {
+	r.clock++
+	r.stamps[name] = r.clock
+	if len(r.stamps) <= recent_identifiers_max {
+		return
+	}
+	// forget the least recently used one
+	oldest := ""
+	for k, v := range r.stamps {
+		if oldest == "" || v < r.stamps[oldest] {
+			oldest = k
+		}
+	}
+	delete(r.stamps, oldest)
+}
+
+func (r *recent_identifiers) contains(name string) bool {
+	_, ok := r.stamps[name]
+	return ok
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+)
+
+func TestFuzzyScoreRanking(t *testing.T) {
+	tests := []struct {
+		partial string
+		names   []string // best match first
+	}{
+		// a prefix beats humps, humps beat characters in the middle of words
+		{"nr", []string{"nrOpen", "NewReader", "Inner"}},
+		{"rd", []string{"ReadDir", "reader", "Ordered"}},
+		{"Read", []string{"Read", "ReadAll", "RemoveAddr", "ThreadID"}},
+		// same case first, then shorter names
+		{"buf", []string{"buf", "bufio", "Buffer", "Rebuff"}},
+	}
+	for _, test := range tests {
+		prev := 0
+		for i, name := range test.names {
+			score := fuzzy_score(test.partial, name)
+			if score == 0 {
+				t.Errorf("fuzzy_score(%q, %q) = 0, want a match", test.partial, name)
+			}
+			if i > 0 && score >= prev {
+				t.Errorf("fuzzy_score(%q, %q) = %d, want less than %d for %q",
+					test.partial, name, score, prev, test.names[i-1])
+			}
+			prev = score
+		}
+	}
+
+	if score := fuzzy_score("", "Name"); score != fuzzy_match_score {
+		t.Errorf("fuzzy_score(\"\", \"Name\") = %d, want %d", score, fuzzy_match_score)
+	}
+}
+
+func TestFuzzyScoreMismatch(t *testing.T) {
+	tests := []struct {
+		partial, name string
+	}{
+		{"xyz", "Reader"},
+		{"rdx", "Reader"},
+		{"abc", "ab"},
+		{"ba", "ab"},
+	}
+	for _, test := range tests {
+		if score := fuzzy_score(test.partial, test.name); score != 0 {
+			t.Errorf("fuzzy_score(%q, %q) = %d, want 0", test.partial, test.name, score)
+		}
+	}
+}
+
+func TestCandidateOrder(t *testing.T) {
+	candidates := []candidate{
+		{Name: "Builtin", Class: decl_func, Score: 90, rank: rank_builtin},
+		{Name: "Member", Class: decl_func, Score: 50, rank: rank_member},
+		{Name: "BetterMember", Class: decl_var, Score: 80, rank: rank_member},
+		{Name: "recent", Class: decl_var, Score: 10, rank: rank_recent},
+		{Name: "local", Class: decl_var, Score: 1, rank: rank_local},
+	}
+	b := &out_buffers{candidates: append([]candidate{}, candidates...), fuzzy: true}
+	sort.Sort(b)
+	want := "[local recent BetterMember Member Builtin]"
+	if got := candidate_names(b.candidates); got != want {
+		t.Errorf("fuzzy order is %s, want %s", got, want)
+	}
+
+	// without the "fuzzy" option the rank and the score don't matter
+	b = &out_buffers{candidates: append([]candidate{}, candidates...)}
+	sort.Sort(b)
+	want = "[Builtin Member BetterMember local recent]"
+	if got := candidate_names(b.candidates); got != want {
+		t.Errorf("order is %s, want %s", got, want)
+	}
+}
+
+func candidate_names(candidates []candidate) string {
+	names := make([]string, len(candidates))
+	for i, c := range candidates {
+		names[i] = c.Name
+	}
+	return fmt.Sprint(names)
+}
+
+func TestRecentIdentifiers(t *testing.T) {
+	r := new_recent_identifiers()
+	r.update([]byte("fmt.Pr"), "a.go", 4)
+	if len(r.stamps) != 0 {
+		t.Fatalf("identifiers used before any completion: %v", r.stamps)
+	}
+
+	// the completion at offset 4 ended up as Println
+	r.update([]byte("fmt.Println(x.F"), "a.go", 14)
+	if !r.contains("Println") {
+		t.Errorf("Println isn't recent after it was completed")
+	}
+
+	// the previous request was in another file
+	r.update([]byte("fmt.Println(x.Foo)"), "b.go", 14)
+	if r.contains("Foo") {
+		t.Errorf("Foo is recent after a request in another file")
+	}
+
+	// another request at the same place
+	r.update([]byte("fmt.Println(x.Foo)"), "b.go", 14)
+	if r.contains("Foo") {
+		t.Errorf("Foo is recent after a request at the same place")
+	}
+
+	// the least recently used identifier is forgotten first
+	r.mark("Println")
+	for i := 0; i < recent_identifiers_max; i++ {
+		r.mark(fmt.Sprintf("name%d", i))
+	}
+	if len(r.stamps) != recent_identifiers_max {
+		t.Errorf("%d recent identifiers, want %d", len(r.stamps), recent_identifiers_max)
+	}
+	if r.contains("Println") {
+		t.Errorf("least recently used Println wasn't forgotten")
+	}
+	if !r.contains("name0") {
+		t.Errorf("name0 was forgotten")
+	}
+}
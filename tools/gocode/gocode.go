@@ -12,6 +12,7 @@ import (
 
 var (
 	g_is_server = flag.Bool("s", false, "run a server instead of a client")
+	g_lsp       = flag.Bool("lsp", false, "run a Language Server Protocol server on stdin/stdout")
 	g_format    = flag.String("f", "nice", "output format (vim | emacs | nice | csv | json)")
 	g_input     = flag.String("in", "", "use this file instead of stdin input")
 	g_sock      = create_sock_flag("sock", "socket type (unix | tcp)")
@@ -30,7 +31,7 @@ func get_socket_filename() string {
 
 func show_usage() {
 	fmt.Fprintf(os.Stderr,
-		"Usage: %s [-s] [-f=<format>] [-in=<path>] [-sock=<type>] [-addr=<addr>]\n"+
+		"Usage: %s [-s | -lsp] [-f=<format>] [-in=<path>] [-sock=<type>] [-addr=<addr>]\n"+
 			"       <command> [<args>]\n\n",
 		os.Args[0])
 	fmt.Fprintf(os.Stderr,
@@ -39,6 +40,7 @@ func show_usage() {
 	fmt.Fprintf(os.Stderr,
 		"\nCommands:\n"+
 			"  autocomplete [<path>] <offset>     main autocompletion command\n"+
+			"  signature [<path>] <offset>        signature of the call at the cursor\n"+
 			"  close                              close the gocode daemon\n"+
 			"  status                             gocode daemon status report\n"+
 			"  drop-cache                         drop gocode daemon's cache\n"+
@@ -65,6 +67,8 @@ func main() {
 			log.Print(http.ListenAndServe(addr, nil))
 		}()
 		retval = do_server()
+	} else if *g_lsp {
+		retval = do_lsp()
 	} else {
 		retval = do_client()
 	}
@@ -0,0 +1,500 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/build"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+//-------------------------------------------------------------------------
+// Language Server Protocol front end
+//
+// 'gocode -lsp' speaks JSON-RPC 2.0 over stdin/stdout instead of the net/rpc
+// protocol of the daemon. The autocompletion context and its caches live in
+// the same process, unsaved buffers are kept as overlays and take
+// precedence over the files on disk.
+//-------------------------------------------------------------------------
+
+// JSON-RPC error codes
+const (
+	lsp_parse_error      = -32700
+	lsp_invalid_params   = -32602
+	lsp_method_not_found = -32601
+	lsp_internal_error   = -32603
+)
+
+// LSP CompletionItemKind values
+var g_lsp_completion_kinds = [...]int{
+	decl_const:        21, // Constant
+	decl_var:          6,  // Variable
+	decl_type:         7,  // Class
+	decl_func:         3,  // Function
+	decl_package:      9,  // Module
+	decl_methods_stub: 2,  // Method
+}
+
+type lsp_message struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  interface{}      `json:"result,omitempty"`
+	Error   *lsp_error       `json:"error,omitempty"`
+}
+
+type lsp_error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type lsp_position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lsp_range struct {
+	Start lsp_position `json:"start"`
+	End   lsp_position `json:"end"`
+}
+
+type lsp_text_document struct {
+	URI  string `json:"uri"`
+	Text string `json:"text,omitempty"`
+}
+
+type lsp_text_document_position struct {
+	TextDocument lsp_text_document `json:"textDocument"`
+	Position     lsp_position      `json:"position"`
+}
+
+type lsp_did_open struct {
+	TextDocument lsp_text_document `json:"textDocument"`
+}
+
+type lsp_did_change struct {
+	TextDocument   lsp_text_document `json:"textDocument"`
+	ContentChanges []struct {
+		Range *lsp_range `json:"range"`
+		Text  string     `json:"text"`
+	} `json:"contentChanges"`
+}
+
+type lsp_text_edit struct {
+	Range   lsp_range `json:"range"`
+	NewText string    `json:"newText"`
+}
+
+type lsp_completion_item struct {
+	Label    string        `json:"label"`
+	Kind     int           `json:"kind,omitempty"`
+	Detail   string        `json:"detail,omitempty"`
+	TextEdit lsp_text_edit `json:"textEdit"`
+}
+
+type lsp_completion_list struct {
+	IsIncomplete bool                  `json:"isIncomplete"`
+	Items        []lsp_completion_item `json:"items"`
+}
+
+type lsp_markup_content struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+type lsp_hover struct {
+	Contents lsp_markup_content `json:"contents"`
+}
+
+//-------------------------------------------------------------------------
+// lsp_server
+//-------------------------------------------------------------------------
+
+type lsp_server struct {
+	in       *bufio.Reader
+	out      io.Writer
+	shutdown bool
+}
+
+func do_lsp() int {
+	g_config.read()
+
+	// the protocol owns stdout, backtraces and such go to stderr
+	out := os.Stdout
+	os.Stdout = os.Stderr
+
+	// same as a daemon, but without the listener
+	g_daemon = new(daemon)
+	g_daemon.context = build.Default
+	g_daemon.drop_cache()
+
+	s := &lsp_server{in: bufio.NewReader(os.Stdin), out: out}
+	return s.loop()
+}
+
+func (s *lsp_server) loop() int {
+	for {
+		data, err := s.read_message()
+		if err != nil {
+			if err != io.EOF {
+				log.Printf("lsp: %s\n", err)
+			}
+			return 1
+		}
+
+		var msg lsp_message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			s.reply_error(nil, lsp_parse_error, err.Error())
+			continue
+		}
+		if *g_debug {
+			log.Printf("lsp: <- %s\n", data)
+		}
+
+		if msg.Method == "exit" {
+			if s.shutdown {
+				return 0
+			}
+			return 1
+		}
+		s.handle(&msg)
+	}
+}
+
+// Messages are preceded by a header, the only field we care about is
+// Content-Length.
+func (s *lsp_server) read_message() ([]byte, error) {
+	length := -1
+	for {
+		line, err := s.in.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		if i := strings.Index(line, ":"); i != -1 && strings.EqualFold(line[:i], "Content-Length") {
+			length, err = strconv.Atoi(strings.TrimSpace(line[i+1:]))
+			if err != nil {
+				return nil, fmt.Errorf("invalid header: %q", line)
+			}
+		}
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("missing Content-Length header")
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(s.in, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (s *lsp_server) write_message(msg *lsp_message) {
+	msg.JSONRPC = "2.0"
+	data, err := json.Marshal(msg)
+	if err != nil {
+		panic(err)
+	}
+	if *g_debug {
+		log.Printf("lsp: -> %s\n", data)
+	}
+	fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(data), data)
+}
+
+func (s *lsp_server) reply(id *json.RawMessage, result interface{}) {
+	if id == nil {
+		// notifications have no replies
+		return
+	}
+	if result == nil {
+		// "result" must be present, even if it's null
+		result = json.RawMessage("null")
+	}
+	s.write_message(&lsp_message{ID: id, Result: result})
+}
+
+func (s *lsp_server) reply_error(id *json.RawMessage, code int, message string) {
+	if id == nil {
+		id = new(json.RawMessage)
+		*id = json.RawMessage("null")
+	}
+	s.write_message(&lsp_message{ID: id, Error: &lsp_error{code, message}})
+}
+
+func (s *lsp_server) handle(msg *lsp_message) {
+	defer func() {
+		if err := recover(); err != nil {
+			print_backtrace(err)
+			g_daemon.drop_cache()
+			s.reply_error(msg.ID, lsp_internal_error, fmt.Sprint(err))
+		}
+	}()
+
+	var result interface{}
+	var err error
+	switch msg.Method {
+	case "initialize":
+		result = map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync": map[string]interface{}{
+					"openClose": true,
+					"change":    2, // incremental
+				},
+				"completionProvider": map[string]interface{}{
+					"triggerCharacters": []string{"."},
+				},
+				"hoverProvider": true,
+			},
+			"serverInfo": map[string]string{"name": "gocode"},
+		}
+	case "shutdown":
+		s.shutdown = true
+	case "textDocument/didOpen":
+		err = s.did_open(msg.Params)
+	case "textDocument/didChange":
+		err = s.did_change(msg.Params)
+	case "textDocument/didClose":
+		err = s.did_close(msg.Params)
+	case "textDocument/completion":
+		result, err = s.completion(msg.Params)
+	case "textDocument/hover":
+		result, err = s.hover(msg.Params)
+	default:
+		if msg.ID != nil {
+			s.reply_error(msg.ID, lsp_method_not_found, "method not supported: "+msg.Method)
+		}
+		return
+	}
+
+	if err != nil {
+		if msg.ID != nil {
+			s.reply_error(msg.ID, lsp_invalid_params, err.Error())
+		} else {
+			log.Printf("lsp: %s: %s\n", msg.Method, err)
+		}
+		return
+	}
+	s.reply(msg.ID, result)
+}
+
+func (s *lsp_server) did_open(params json.RawMessage) error {
+	var p lsp_did_open
+	if err := json.Unmarshal(params, &p); err != nil {
+		return err
+	}
+	filename, err := uri_to_filename(p.TextDocument.URI)
+	if err != nil {
+		return err
+	}
+	g_overlays.set(filename, []byte(p.TextDocument.Text))
+	return nil
+}
+
+func (s *lsp_server) did_change(params json.RawMessage) error {
+	var p lsp_did_change
+	if err := json.Unmarshal(params, &p); err != nil {
+		return err
+	}
+	filename, err := uri_to_filename(p.TextDocument.URI)
+	if err != nil {
+		return err
+	}
+	data, _, ok := g_overlays.get(filename)
+	if !ok {
+		return fmt.Errorf("change of a document which is not open: %s", p.TextDocument.URI)
+	}
+	for _, change := range p.ContentChanges {
+		if change.Range == nil {
+			data = []byte(change.Text)
+			continue
+		}
+		start := position_to_offset(data, change.Range.Start)
+		end := position_to_offset(data, change.Range.End)
+		if end < start {
+			return fmt.Errorf("invalid range: %v", *change.Range)
+		}
+		buf := make([]byte, 0, len(data)-(end-start)+len(change.Text))
+		buf = append(buf, data[:start]...)
+		buf = append(buf, change.Text...)
+		buf = append(buf, data[end:]...)
+		data = buf
+	}
+	g_overlays.set(filename, data)
+	return nil
+}
+
+func (s *lsp_server) did_close(params json.RawMessage) error {
+	var p lsp_did_open
+	if err := json.Unmarshal(params, &p); err != nil {
+		return err
+	}
+	filename, err := uri_to_filename(p.TextDocument.URI)
+	if err != nil {
+		return err
+	}
+	g_overlays.remove(filename)
+	return nil
+}
+
+// Returns the contents, file name and cursor offset of the document the
+// request refers to.
+func (s *lsp_server) document_position(params json.RawMessage) ([]byte, string, int, error) {
+	var p lsp_text_document_position
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, "", 0, err
+	}
+	filename, err := uri_to_filename(p.TextDocument.URI)
+	if err != nil {
+		return nil, "", 0, err
+	}
+	data, _, ok := g_overlays.get(filename)
+	if !ok {
+		data, err = file_reader.read_file(filename)
+		if err != nil {
+			return nil, "", 0, err
+		}
+	}
+	return data, filename, position_to_offset(data, p.Position), nil
+}
+
+func (s *lsp_server) completion(params json.RawMessage) (interface{}, error) {
+	file, filename, cursor, err := s.document_position(params)
+	if err != nil {
+		return nil, err
+	}
+	file, skipped := filter_out_shebang(file)
+	cursor -= skipped
+
+	candidates, partial := g_daemon.autocomplete.apropos(file, filename, cursor)
+	end := offset_to_position(file, cursor)
+	start := offset_to_position(file, cursor-partial)
+	if skipped > 0 {
+		// the shebang line is filtered out, it's always a single line
+		start.Line++
+		end.Line++
+	}
+
+	list := lsp_completion_list{Items: make([]lsp_completion_item, 0, len(candidates))}
+	for _, c := range candidates {
+		list.Items = append(list.Items, lsp_completion_item{
+			Label:  c.Name,
+			Kind:   g_lsp_completion_kinds[c.Class],
+			Detail: c.Type,
+			TextEdit: lsp_text_edit{
+				Range:   lsp_range{start, end},
+				NewText: c.Name,
+			},
+		})
+	}
+	return list, nil
+}
+
+func (s *lsp_server) hover(params json.RawMessage) (interface{}, error) {
+	file, filename, cursor, err := s.document_position(params)
+	if err != nil {
+		return nil, err
+	}
+	file, skipped := filter_out_shebang(file)
+	cursor -= skipped
+
+	// the cursortype query deduces the type of the expression ending at the
+	// cursor, move it to the end of the identifier under it
+	for cursor >= 0 && cursor < len(file) {
+		r, size := utf8.DecodeRune(file[cursor:])
+		if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			break
+		}
+		cursor += size
+	}
+
+	typ, pkg := g_daemon.autocomplete.cursor_type_pkg(file, filename, cursor)
+	if typ == "" {
+		return nil, nil
+	}
+	value := "```go\n" + typ + "\n```"
+	if pkg != "" {
+		value += "\npackage " + pkg
+	}
+	return lsp_hover{lsp_markup_content{"markdown", value}}, nil
+}
+
+//-------------------------------------------------------------------------
+// URIs and positions
+//-------------------------------------------------------------------------
+
+func uri_to_filename(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "file" {
+		return "", fmt.Errorf("unsupported URI scheme: %s", uri)
+	}
+	path := u.Path
+	// file:///C:/dir/file.go
+	if len(path) > 2 && path[0] == '/' && path[2] == ':' {
+		path = path[1:]
+	}
+	return filepath.Clean(filepath.FromSlash(path)), nil
+}
+
+// LSP positions count characters in UTF-16 code units, convert them to byte
+// offsets and back. Positions past the end of a line or of the file are
+// clamped. The '\r' of a "\r\n" line ending belongs to the line ending, not
+// to the line.
+func position_to_offset(data []byte, pos lsp_position) int {
+	offset := 0
+	for line := 0; line < pos.Line; line++ {
+		i := bytes.IndexByte(data[offset:], '\n')
+		if i == -1 {
+			return len(data)
+		}
+		offset += i + 1
+	}
+	for units := 0; units < pos.Character && !is_line_end(data, offset); {
+		r, size := utf8.DecodeRune(data[offset:])
+		units += len(utf16.Encode([]rune{r}))
+		offset += size
+	}
+	return offset
+}
+
+func offset_to_position(data []byte, offset int) lsp_position {
+	if offset > len(data) {
+		offset = len(data)
+	}
+	var pos lsp_position
+	for i := 0; i < offset; {
+		r, size := utf8.DecodeRune(data[i:])
+		if r == '\n' {
+			pos.Line++
+			pos.Character = 0
+		} else if !is_line_end(data, i) {
+			pos.Character += len(utf16.Encode([]rune{r}))
+		}
+		i += size
+	}
+	return pos
+}
+
+// Reports whether 'offset' is at the end of a line or of the file.
+func is_line_end(data []byte, offset int) bool {
+	if offset >= len(data) || data[offset] == '\n' {
+		return true
+	}
+	return data[offset] == '\r' && offset+1 < len(data) && data[offset+1] == '\n'
+}
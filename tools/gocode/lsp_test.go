@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+)
+
+// "é" is 2 bytes and 1 UTF-16 code unit, "😀" is 4 bytes and 2 code units.
+const lsp_test_text = "a := \"é😀\"\r\nb\n\nlast"
+
+func TestPositionToOffset(t *testing.T) {
+	tests := []struct {
+		line, character int
+		offset          int
+	}{
+		{0, 0, 0},
+		{0, 5, 5},
+		{0, 7, 8},   // after "é"
+		{0, 8, 12},  // inside "😀", the rune isn't split
+		{0, 9, 12},  // after "😀"
+		{0, 10, 13}, // end of the line, before "\r\n"
+		{0, 99, 13},
+		{1, 0, 15},
+		{1, 1, 16},
+		{1, 99, 16},
+		{2, 0, 17},
+		{2, 5, 17},
+		{3, 0, 18},
+		{3, 4, 22}, // end of file
+		{3, 99, 22},
+		{99, 0, 22},
+	}
+	data := []byte(lsp_test_text)
+	for _, test := range tests {
+		pos := lsp_position{test.line, test.character}
+		if got := position_to_offset(data, pos); got != test.offset {
+			t.Errorf("position_to_offset(%v) = %d, want %d", pos, got, test.offset)
+		}
+	}
+}
+
+func TestOffsetToPosition(t *testing.T) {
+	tests := []struct {
+		offset          int
+		line, character int
+	}{
+		{0, 0, 0},
+		{8, 0, 7},
+		{12, 0, 9},
+		{13, 0, 10},
+		{14, 0, 10}, // between '\r' and '\n'
+		{15, 1, 0},
+		{16, 1, 1},
+		{17, 2, 0},
+		{22, 3, 4},
+		{99, 3, 4},
+	}
+	data := []byte(lsp_test_text)
+	for _, test := range tests {
+		want := lsp_position{test.line, test.character}
+		if got := offset_to_position(data, test.offset); got != want {
+			t.Errorf("offset_to_position(%d) = %v, want %v", test.offset, got, want)
+		}
+	}
+
+	// positions of rune boundaries survive the round trip
+	for offset := range lsp_test_text {
+		pos := offset_to_position(data, offset)
+		if data[offset] == '\n' && offset > 0 && data[offset-1] == '\r' {
+			continue
+		}
+		if got := position_to_offset(data, pos); got != offset {
+			t.Errorf("position_to_offset(offset_to_position(%d)) = %d", offset, got)
+		}
+	}
+}
+
+func TestDidChange(t *testing.T) {
+	filename := filepath.FromSlash("/lsp_test/file.go")
+	uri := "file:///lsp_test/file.go"
+	defer g_overlays.remove(filename)
+
+	type change struct {
+		Range *lsp_range `json:"range,omitempty"`
+		Text  string     `json:"text"`
+	}
+	at := func(l1, c1, l2, c2 int) *lsp_range {
+		return &lsp_range{lsp_position{l1, c1}, lsp_position{l2, c2}}
+	}
+	tests := []struct {
+		changes []change
+		want    string
+	}{
+		// replace the whole document
+		{[]change{{nil, "x😀y\r\nz"}}, "x😀y\r\nz"},
+		// after a multi-byte rune
+		{[]change{{at(0, 3, 0, 4), "Y"}}, "x😀Y\r\nz"},
+		// insert at the end of a CRLF line
+		{[]change{{at(0, 4, 0, 4), "!"}}, "x😀Y!\r\nz"},
+		// insert at the end of the file
+		{[]change{{at(1, 1, 1, 1), "\n"}}, "x😀Y!\r\nz\n"},
+		{[]change{{at(2, 0, 2, 0), "end"}}, "x😀Y!\r\nz\nend"},
+		// past the end of the file
+		{[]change{{at(9, 0, 9, 0), "."}}, "x😀Y!\r\nz\nend."},
+		// join lines across "\r\n", the changes apply in order
+		{[]change{{at(0, 5, 1, 0), ""}, {at(0, 0, 0, 1), ""}}, "😀Y!z\nend."},
+	}
+
+	s := new(lsp_server)
+	g_overlays.set(filename, []byte(""))
+	for i, test := range tests {
+		params, err := json.Marshal(map[string]interface{}{
+			"textDocument":   lsp_text_document{URI: uri},
+			"contentChanges": test.changes,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.did_change(params); err != nil {
+			t.Fatalf("%d: %s", i, err)
+		}
+		data, _, _ := g_overlays.get(filename)
+		if string(data) != test.want {
+			t.Fatalf("%d: document is %q, want %q", i, data, test.want)
+		}
+	}
+
+	params := []byte(`{"textDocument": {"uri": "file:///lsp_test/closed.go"}, "contentChanges": []}`)
+	if err := s.did_change(params); err == nil {
+		t.Errorf("change of a closed document succeeded")
+	}
+}
@@ -24,6 +24,7 @@ type package_file_cache struct {
 	name     string // file name
 	mtime    int64
 	defalias string
+	key      string // source package key, see source_importer
 
 	scope  *scope
 	main   *decl // package declaration
@@ -74,6 +75,10 @@ func (m *package_file_cache) update_cache() {
 	if m.mtime == -1 {
 		return
 	}
+	if g_config.Source {
+		m.update_source_cache()
+		return
+	}
 	fname := m.find_file()
 	stat, err := os.Stat(fname)
 	if err != nil {
@@ -118,20 +123,30 @@ func (m *package_file_cache) process_package_data(data []byte) {
 	p.path_to_alias["unsafe"] = "unsafe"
 	// create map for other packages
 	m.others = make(map[string]*decl)
-	p.parse_export(func(pkg string, decl ast.Decl) {
-		anonymify_ast(decl, decl_foreign, m.scope)
-		if pkg == "" {
-			// main package
-			add_ast_decl_to_package(m.main, decl, m.scope)
-		} else {
-			// others
-			if _, ok := m.others[pkg]; !ok {
-				m.others[pkg] = new_decl(pkg, decl_package, nil)
-			}
-			add_ast_decl_to_package(m.others[pkg], decl, m.scope)
+	p.parse_export(m.add_foreign_decl)
+
+	m.resolve_package_aliases()
+}
+
+// add_foreign_decl adds an exported declaration either to the package itself
+// (pkg == "") or to one of the packages it refers to.
+func (m *package_file_cache) add_foreign_decl(pkg string, decl ast.Decl) {
+	anonymify_ast(decl, decl_foreign, m.scope)
+	if pkg == "" {
+		// main package
+		add_ast_decl_to_package(m.main, decl, m.scope)
+	} else {
+		// others
+		if _, ok := m.others[pkg]; !ok {
+			m.others[pkg] = new_decl(pkg, decl_package, nil)
 		}
-	})
+		add_ast_decl_to_package(m.others[pkg], decl, m.scope)
+	}
+}
 
+// resolve_package_aliases replaces package aliases in the package scope with
+// the actual package declarations, once all of them are known.
+func (m *package_file_cache) resolve_package_aliases() {
 	// hack, add ourselves to the package scope
 	m.add_package_to_scope("#"+m.defalias, m.name)
 
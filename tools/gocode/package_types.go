@@ -0,0 +1,316 @@
+package main
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"log"
+	"strconv"
+	"time"
+)
+
+//-------------------------------------------------------------------------
+// package_file_cache, source mode
+//
+// When the "source" option is on, package_file_cache.name is the source
+// directory of the package instead of an archive file. Packages are
+// type-checked by the daemon's source_importer and the resulting
+// types.Package is turned into the same AST declarations gc_parser produces
+// from the export data, so the rest of gocode doesn't see the difference.
+//-------------------------------------------------------------------------
+
+func (m *package_file_cache) update_source_cache() {
+	p, err := g_daemon.srcimporter.import_dir(m.name)
+	if err != nil || p.pkg == nil {
+		return
+	}
+	if m.key == p.key {
+		return
+	}
+	m.key = p.key
+	m.mtime = p.mtime
+	m.process_types_package(p.pkg)
+	if *g_debug {
+		mtime := time.Unix(0, m.mtime)
+		log.Printf("Updated %q from source (newest file: %s)\n", m.name, mtime)
+	}
+}
+
+func (m *package_file_cache) process_types_package(pkg *types.Package) {
+	m.scope = new_scope(g_universe_scope)
+	m.main = new_decl(m.name, decl_package, nil)
+	m.others = make(map[string]*decl)
+	m.defalias = pkg.Name()
+
+	e := types_exporter{
+		pkg:     pkg,
+		pfc:     m,
+		aliases: map[*types.Package]string{pkg: "#" + pkg.Name()},
+		used:    map[string]bool{"unsafe": true},
+		done:    make(map[*types.TypeName]bool),
+	}
+	e.export_package()
+
+	m.resolve_package_aliases()
+}
+
+//-------------------------------------------------------------------------
+// types_exporter
+//
+// Converts the exported declarations of a types.Package to AST. Like the gc
+// export data it includes declarations of the types from other packages which
+// are reachable from the package's declarations.
+//-------------------------------------------------------------------------
+
+type types_exporter struct {
+	pkg     *types.Package
+	pfc     *package_file_cache
+	aliases map[*types.Package]string
+	used    map[string]bool // aliases in use
+	queue   []*types.TypeName
+	done    map[*types.TypeName]bool
+}
+
+func (e *types_exporter) export_package() {
+	scope := e.pkg.Scope()
+	for _, name := range scope.Names() {
+		switch obj := scope.Lookup(name).(type) {
+		case *types.Const:
+			e.pfc.add_foreign_decl("", e.const_decl(obj))
+		case *types.Var:
+			e.pfc.add_foreign_decl("", e.var_decl(obj))
+		case *types.Func:
+			e.pfc.add_foreign_decl("", &ast.FuncDecl{
+				Name: ast.NewIdent(obj.Name()),
+				Type: e.func_type(obj.Type().(*types.Signature)),
+			})
+		case *types.TypeName:
+			e.export_type("", obj)
+		}
+	}
+
+	// types of other packages referred to by the ones exported above
+	for len(e.queue) > 0 {
+		obj := e.queue[0]
+		e.queue = e.queue[1:]
+		e.export_type(obj.Pkg().Path(), obj)
+	}
+}
+
+func (e *types_exporter) export_type(pkg string, obj *types.TypeName) {
+	e.done[obj] = true
+	e.pfc.add_foreign_decl(pkg, &ast.GenDecl{
+		Tok: token.TYPE,
+		Specs: []ast.Spec{
+			&ast.TypeSpec{
+				Name: ast.NewIdent(obj.Name()),
+				Type: e.type_expr(obj.Type().Underlying()),
+			},
+		},
+	})
+
+	named, ok := obj.Type().(*types.Named)
+	if !ok || obj.IsAlias() {
+		return
+	}
+	for i := 0; i < named.NumMethods(); i++ {
+		f := named.Method(i)
+		sig := f.Type().(*types.Signature)
+		recvname := "?"
+		var recvtype ast.Expr = ast.NewIdent(obj.Name())
+		if recv := sig.Recv(); recv != nil {
+			if recv.Name() != "" {
+				recvname = recv.Name()
+			}
+			if _, ok := recv.Type().(*types.Pointer); ok {
+				recvtype = &ast.StarExpr{X: recvtype}
+			}
+		}
+		e.pfc.add_foreign_decl(pkg, &ast.FuncDecl{
+			Recv: &ast.FieldList{List: []*ast.Field{{
+				Names: []*ast.Ident{ast.NewIdent(recvname)},
+				Type:  recvtype,
+			}}},
+			Name: ast.NewIdent(f.Name()),
+			Type: e.func_type(sig),
+		})
+	}
+}
+
+func (e *types_exporter) const_decl(obj *types.Const) ast.Decl {
+	var typ ast.Expr
+	var value ast.Expr = &ast.BasicLit{Kind: token.INT, Value: "0"}
+	if b, ok := obj.Type().(*types.Basic); ok && b.Info()&types.IsUntyped != 0 {
+		// keep the kind of the untyped constant, the type is deduced from it
+		switch obj.Val().Kind() {
+		case constant.Bool:
+			value = ast.NewIdent("false")
+		case constant.String:
+			value = &ast.BasicLit{Kind: token.STRING, Value: `""`}
+		case constant.Float:
+			value = &ast.BasicLit{Kind: token.FLOAT, Value: "0.0"}
+		case constant.Complex:
+			value = &ast.BasicLit{Kind: token.IMAG, Value: "0i"}
+		}
+		if b.Kind() == types.UntypedRune {
+			value = &ast.BasicLit{Kind: token.CHAR, Value: "'0'"}
+		}
+	} else {
+		typ = e.type_expr(obj.Type())
+	}
+	return &ast.GenDecl{
+		Tok: token.CONST,
+		Specs: []ast.Spec{
+			&ast.ValueSpec{
+				Names:  []*ast.Ident{ast.NewIdent(obj.Name())},
+				Type:   typ,
+				Values: []ast.Expr{value},
+			},
+		},
+	}
+}
+
+func (e *types_exporter) var_decl(obj *types.Var) ast.Decl {
+	return &ast.GenDecl{
+		Tok: token.VAR,
+		Specs: []ast.Spec{
+			&ast.ValueSpec{
+				Names: []*ast.Ident{ast.NewIdent(obj.Name())},
+				Type:  e.type_expr(obj.Type()),
+			},
+		},
+	}
+}
+
+// Returns the name the package 'pkg' is referred to by, registering it in the
+// package scope the way import declarations of the export data do.
+func (e *types_exporter) alias(pkg *types.Package) string {
+	if alias, ok := e.aliases[pkg]; ok {
+		return alias
+	}
+	alias := pkg.Name()
+	for i := 1; e.used[alias]; i++ {
+		alias = pkg.Name() + strconv.Itoa(i)
+	}
+	e.used[alias] = true
+	e.aliases[pkg] = alias
+	e.pfc.add_package_to_scope(alias, pkg.Path())
+	return alias
+}
+
+func (e *types_exporter) type_expr(t types.Type) ast.Expr {
+	switch t := t.(type) {
+	case *types.Basic:
+		if t.Kind() == types.UnsafePointer {
+			return &ast.SelectorExpr{X: ast.NewIdent("unsafe"), Sel: ast.NewIdent("Pointer")}
+		}
+		return ast.NewIdent(t.Name())
+	case *types.Named:
+		obj := t.Obj()
+		if obj.Pkg() == nil {
+			// error
+			return ast.NewIdent(obj.Name())
+		}
+		if obj.Pkg() != e.pkg && !e.done[obj] {
+			e.done[obj] = true
+			e.queue = append(e.queue, obj)
+		}
+		return &ast.SelectorExpr{X: ast.NewIdent(e.alias(obj.Pkg())), Sel: ast.NewIdent(obj.Name())}
+	case *types.TypeParam:
+		return ast.NewIdent(t.Obj().Name())
+	case *types.Pointer:
+		return &ast.StarExpr{X: e.type_expr(t.Elem())}
+	case *types.Slice:
+		return &ast.ArrayType{Elt: e.type_expr(t.Elem())}
+	case *types.Array:
+		return &ast.ArrayType{
+			Len: &ast.BasicLit{Kind: token.INT, Value: strconv.FormatInt(t.Len(), 10)},
+			Elt: e.type_expr(t.Elem()),
+		}
+	case *types.Map:
+		return &ast.MapType{Key: e.type_expr(t.Key()), Value: e.type_expr(t.Elem())}
+	case *types.Chan:
+		dir := ast.SEND | ast.RECV
+		switch t.Dir() {
+		case types.SendOnly:
+			dir = ast.SEND
+		case types.RecvOnly:
+			dir = ast.RECV
+		}
+		return &ast.ChanType{Dir: dir, Value: e.type_expr(t.Elem())}
+	case *types.Signature:
+		return e.func_type(t)
+	case *types.Struct:
+		fields := make([]*ast.Field, 0, t.NumFields())
+		for i := 0; i < t.NumFields(); i++ {
+			v := t.Field(i)
+			var names []*ast.Ident
+			if !v.Embedded() {
+				names = []*ast.Ident{ast.NewIdent(v.Name())}
+			}
+			tag := ""
+			if t.Tag(i) != "" {
+				tag = strconv.Quote(t.Tag(i))
+			}
+			fields = append(fields, &ast.Field{
+				Names: names,
+				Type:  e.type_expr(v.Type()),
+				Tag:   &ast.BasicLit{Kind: token.STRING, Value: tag},
+			})
+		}
+		return &ast.StructType{Fields: &ast.FieldList{List: fields}}
+	case *types.Interface:
+		var methods []*ast.Field
+		for i := 0; i < t.NumEmbeddeds(); i++ {
+			// constraint type sets have nothing to complete
+			embedded := t.EmbeddedType(i)
+			if _, ok := embedded.Underlying().(*types.Interface); ok {
+				methods = append(methods, &ast.Field{Type: e.type_expr(embedded)})
+			}
+		}
+		for i := 0; i < t.NumExplicitMethods(); i++ {
+			f := t.ExplicitMethod(i)
+			methods = append(methods, &ast.Field{
+				Names: []*ast.Ident{ast.NewIdent(f.Name())},
+				Type:  e.func_type(f.Type().(*types.Signature)),
+			})
+		}
+		return &ast.InterfaceType{Methods: &ast.FieldList{List: methods}}
+	}
+	if u := t.Underlying(); u != t {
+		return e.type_expr(u)
+	}
+	return ast.NewIdent("?")
+}
+
+func (e *types_exporter) func_type(sig *types.Signature) *ast.FuncType {
+	params := e.field_list(sig.Params(), sig.Variadic())
+	var results *ast.FieldList
+	if r := sig.Results(); r.Len() == 1 && r.At(0).Name() == "" {
+		results = &ast.FieldList{List: []*ast.Field{{Type: e.type_expr(r.At(0).Type())}}}
+	} else if r.Len() > 0 {
+		results = e.field_list(r, false)
+	}
+	return &ast.FuncType{Params: params, Results: results}
+}
+
+func (e *types_exporter) field_list(tuple *types.Tuple, variadic bool) *ast.FieldList {
+	fields := make([]*ast.Field, 0, tuple.Len())
+	for i := 0; i < tuple.Len(); i++ {
+		v := tuple.At(i)
+		name := v.Name()
+		if name == "" {
+			name = "?"
+		}
+		typ := e.type_expr(v.Type())
+		if s, ok := v.Type().(*types.Slice); ok && variadic && i == tuple.Len()-1 {
+			typ = &ast.Ellipsis{Elt: e.type_expr(s.Elem())}
+		}
+		fields = append(fields, &ast.Field{
+			Names: []*ast.Ident{ast.NewIdent(name)},
+			Type:  typ,
+		})
+	}
+	return &ast.FieldList{List: fields}
+}
@@ -68,6 +68,36 @@ func client_cursor_type_pkg(cli *rpc.Client, Arg0 []byte, Arg1 string, Arg2 int)
 	return reply.Arg0, reply.Arg1
 }
 
+// wrapper for: server_signature
+
+type Args_signature struct {
+	Arg0 []byte
+	Arg1 string
+	Arg2 int
+	Arg3 go_build_context
+}
+type Reply_signature struct {
+	Arg0 signature_info
+}
+
+func (r *RPC) RPC_signature(args *Args_signature, reply *Reply_signature) error {
+	reply.Arg0 = server_signature(args.Arg0, args.Arg1, args.Arg2, args.Arg3)
+	return nil
+}
+func client_signature(cli *rpc.Client, Arg0 []byte, Arg1 string, Arg2 int, Arg3 go_build_context) (s signature_info) {
+	var args Args_signature
+	var reply Reply_signature
+	args.Arg0 = Arg0
+	args.Arg1 = Arg1
+	args.Arg2 = Arg2
+	args.Arg3 = Arg3
+	err := cli.Call("RPC.RPC_signature", &args, &reply)
+	if err != nil {
+		panic(err)
+	}
+	return reply.Arg0
+}
+
 // wrapper for: server_close
 
 type Args_close struct {
@@ -45,6 +45,7 @@ type daemon struct {
 	autocomplete *auto_complete_context
 	pkgcache     package_cache
 	declcache    *decl_cache
+	srcimporter  *source_importer
 	context      build.Context
 }
 
@@ -60,6 +61,7 @@ func new_daemon(network, address string) *daemon {
 	d.cmd_in = make(chan int, 1)
 	d.pkgcache = new_package_cache()
 	d.declcache = new_decl_cache(d.context)
+	d.srcimporter = new_source_importer(d.context)
 	d.autocomplete = new_auto_complete_context(d.pkgcache, d.declcache)
 	return d
 }
@@ -67,6 +69,7 @@ func new_daemon(network, address string) *daemon {
 func (this *daemon) drop_cache() {
 	this.pkgcache = new_package_cache()
 	this.declcache = new_decl_cache(this.context)
+	this.srcimporter = new_source_importer(this.context)
 	this.autocomplete = new_auto_complete_context(this.pkgcache, this.declcache)
 }
 
@@ -112,25 +115,31 @@ var g_daemon *daemon
 // Corresponding client_* functions are autogenerated by goremote.
 //-------------------------------------------------------------------------
 
+// Drops the cache if the build context of the client differs from the one it
+// was built with.
+func update_daemon_context(context build.Context) {
+	// TODO: Probably we don't care about comparing all the fields, checking GOROOT and GOPATH
+	// should be enough.
+	if !reflect.DeepEqual(g_daemon.context, context) {
+		g_daemon.context = context
+		g_daemon.drop_cache()
+	}
+}
+
 func server_auto_complete(file []byte, filename string, cursor int, context_packed go_build_context) (c []candidate, d int) {
 	context := unpack_build_context(&context_packed)
 	defer func() {
 		if err := recover(); err != nil {
 			print_backtrace(err)
 			c = []candidate{
-				{"PANIC", "PANIC", decl_invalid},
+				{Name: "PANIC", Type: "PANIC", Class: decl_invalid},
 			}
 
 			// drop cache
 			g_daemon.drop_cache()
 		}
 	}()
-	// TODO: Probably we don't care about comparing all the fields, checking GOROOT and GOPATH
-	// should be enough.
-	if !reflect.DeepEqual(g_daemon.context, context) {
-		g_daemon.context = context
-		g_daemon.drop_cache()
-	}
+	update_daemon_context(context)
 	if *g_debug {
 		var buf bytes.Buffer
 		log.Printf("Got autocompletion request for '%s'\n", filename)
@@ -171,6 +180,20 @@ func server_cursor_type_pkg(file []byte, filename string, cursor int) (typ, pkg
 	return g_daemon.autocomplete.cursor_type_pkg(file, filename, cursor)
 }
 
+func server_signature(file []byte, filename string, cursor int, context_packed go_build_context) (s signature_info) {
+	context := unpack_build_context(&context_packed)
+	defer func() {
+		if err := recover(); err != nil {
+			print_backtrace(err)
+
+			// drop cache
+			g_daemon.drop_cache()
+		}
+	}()
+	update_daemon_context(context)
+	return g_daemon.autocomplete.signature(file, filename, cursor)
+}
+
 func server_close(notused int) int {
 	g_daemon.close()
 	return 0
@@ -192,5 +215,19 @@ func server_set(key, value string) string {
 	} else if value == "\x00" {
 		return g_config.list_option(key)
 	}
-	return g_config.set_option(key, value)
+	// options like "source" and "lib-path" change the way imports are
+	// resolved, start from scratch if one of them changes
+	old := g_config.list_option(key)
+	result := g_config.set_option(key, value)
+	if g_import_options[key] && result != old {
+		g_daemon.drop_cache()
+	}
+	return result
+}
+
+// Options which change the way imports are resolved, see server_set.
+var g_import_options = map[string]bool{
+	"lib-path":  true,
+	"autobuild": true,
+	"source":    true,
 }
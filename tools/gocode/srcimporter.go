@@ -0,0 +1,632 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//-------------------------------------------------------------------------
+// module_file
+//
+// The parts of a go.mod file gocode cares about: the module path and where
+// the required modules live.
+//-------------------------------------------------------------------------
+
+type module_file struct {
+	dir      string            // module root
+	path     string            // module path
+	requires map[string]string // module path -> version
+	replaces map[string]string // module path -> directory or "path@version"
+	mtime    int64
+}
+
+func parse_module_file(dir string, data []byte) *module_file {
+	mf := &module_file{
+		dir:      dir,
+		requires: make(map[string]string),
+		replaces: make(map[string]string),
+	}
+
+	block := ""
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		line := s.Text()
+		if i := strings.Index(line, "//"); i != -1 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		for i, f := range fields {
+			if uq, err := strconv.Unquote(f); err == nil {
+				fields[i] = uq
+			}
+		}
+
+		if block != "" {
+			if fields[0] == ")" {
+				block = ""
+				continue
+			}
+			fields = append([]string{block}, fields...)
+		} else if len(fields) == 2 && fields[1] == "(" {
+			block = fields[0]
+			continue
+		}
+
+		switch fields[0] {
+		case "module":
+			if len(fields) > 1 {
+				mf.path = fields[1]
+			}
+		case "require":
+			if len(fields) > 2 {
+				mf.requires[fields[1]] = fields[2]
+			}
+		case "replace":
+			// replace old [version] => new [version]
+			for i, f := range fields {
+				if f != "=>" || i+1 >= len(fields) {
+					continue
+				}
+				target := fields[i+1]
+				if i+2 < len(fields) {
+					target += "@" + fields[i+2]
+				}
+				mf.replaces[fields[1]] = target
+			}
+		}
+	}
+	return mf
+}
+
+// Returns the directory of the package with the import path 'imp' if it
+// belongs to this module or to one of its dependencies.
+func (mf *module_file) find_package(imp, modcache string) (string, bool) {
+	if mf.path != "" && (imp == mf.path || strings.HasPrefix(imp, mf.path+"/")) {
+		return filepath.Join(mf.dir, filepath.FromSlash(imp[len(mf.path):])), true
+	}
+
+	// the longest module path wins
+	mod := ""
+	for m := range mf.requires {
+		if len(m) > len(mod) && (imp == m || strings.HasPrefix(imp, m+"/")) {
+			mod = m
+		}
+	}
+	for m := range mf.replaces {
+		if len(m) > len(mod) && (imp == m || strings.HasPrefix(imp, m+"/")) {
+			mod = m
+		}
+	}
+	if mod == "" {
+		return "", false
+	}
+	rest := filepath.FromSlash(imp[len(mod):])
+
+	if target, ok := mf.replaces[mod]; ok {
+		if build.IsLocalImport(target) || filepath.IsAbs(target) {
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(mf.dir, target)
+			}
+			return filepath.Join(target, rest), true
+		}
+		if i := strings.LastIndex(target, "@"); i != -1 {
+			return filepath.Join(modcache, escape_module_path(target[:i])+target[i:], rest), true
+		}
+		mod = target
+	}
+	version, ok := mf.requires[mod]
+	if !ok || modcache == "" {
+		return "", false
+	}
+	return filepath.Join(modcache, escape_module_path(mod)+"@"+version, rest), true
+}
+
+// Module paths are stored in the module cache with upper case letters
+// replaced by an exclamation mark followed by the lower case letter.
+func escape_module_path(path string) string {
+	var buf bytes.Buffer
+	for _, r := range path {
+		if 'A' <= r && r <= 'Z' {
+			buf.WriteByte('!')
+			r += 'a' - 'A'
+		}
+		buf.WriteRune(r)
+	}
+	return filepath.FromSlash(buf.String())
+}
+
+func unescape_module_path(path string) string {
+	var buf bytes.Buffer
+	upper := false
+	for _, r := range path {
+		if r == '!' {
+			upper = true
+			continue
+		}
+		if upper && 'a' <= r && r <= 'z' {
+			r -= 'a' - 'A'
+		}
+		upper = false
+		buf.WriteRune(r)
+	}
+	return buf.String()
+}
+
+func module_cache_dir(context build.Context) string {
+	if dir := os.Getenv("GOMODCACHE"); dir != "" {
+		return dir
+	}
+	gopath := filepath.SplitList(context.GOPATH)
+	if len(gopath) == 0 {
+		return ""
+	}
+	return filepath.Join(gopath[0], "pkg", "mod")
+}
+
+//-------------------------------------------------------------------------
+// module_cache
+//
+// Thread-safe cache of parsed go.mod files.
+//-------------------------------------------------------------------------
+
+type module_cache struct {
+	files map[string]*module_file // go.mod file name -> parsed go.mod
+	sync.Mutex
+}
+
+var g_module_cache = &module_cache{files: make(map[string]*module_file)}
+
+// Returns the go.mod file of the module containing 'dir', nil if there is
+// none.
+func (c *module_cache) find(dir string) *module_file {
+	for {
+		name := filepath.Join(dir, "go.mod")
+		if stat, err := os.Stat(name); err == nil && !stat.IsDir() {
+			return c.get(name, stat.ModTime().UnixNano())
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil
+		}
+		dir = parent
+	}
+}
+
+func (c *module_cache) get(name string, mtime int64) *module_file {
+	c.Lock()
+	defer c.Unlock()
+
+	if mf, ok := c.files[name]; ok && mf.mtime == mtime {
+		return mf
+	}
+	data, err := file_reader.read_file(name)
+	if err != nil {
+		return nil
+	}
+	mf := parse_module_file(filepath.Dir(name), data)
+	mf.mtime = mtime
+	c.files[name] = mf
+	return mf
+}
+
+//-------------------------------------------------------------------------
+// source package lookup
+//-------------------------------------------------------------------------
+
+func dir_exists(dir string) bool {
+	stat, err := os.Stat(dir)
+	return err == nil && stat.IsDir()
+}
+
+// Returns the directory containing 'dir' which is either a module root or
+// one of the GOROOT/GOPATH source directories. Vendor directories are not
+// looked up above it.
+func source_root(dir string, context build.Context) string {
+	if mf := g_module_cache.find(dir); mf != nil {
+		return mf.dir
+	}
+	roots := []string{filepath.Join(context.GOROOT, "src")}
+	for _, p := range filepath.SplitList(context.GOPATH) {
+		roots = append(roots, filepath.Join(p, "src"))
+	}
+	for _, root := range roots {
+		if dir == root || strings.HasPrefix(dir, root+string(filepath.Separator)) {
+			return root
+		}
+	}
+	return ""
+}
+
+// find_source_package returns the source directory of the package imported
+// as 'imp' from a file in 'srcdir'. Vendor directories are tried first, then
+// the module containing 'srcdir' and its requirements, then GOROOT and
+// GOPATH.
+func find_source_package(imp, srcdir string, context build.Context) (string, bool) {
+	switch {
+	case imp == "unsafe":
+		// see find_global_file
+		return "unsafe", true
+	case imp == "C":
+		return "", false
+	case build.IsLocalImport(imp):
+		dir := filepath.Join(srcdir, imp)
+		return dir, dir_exists(dir)
+	}
+
+	root := source_root(srcdir, context)
+	for dir := srcdir; ; {
+		vendor := filepath.Join(dir, "vendor", filepath.FromSlash(imp))
+		if dir_exists(vendor) {
+			log_found_package_maybe(imp, vendor)
+			return vendor, true
+		}
+		parent := filepath.Dir(dir)
+		if dir == root || root == "" || parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	if mf := g_module_cache.find(srcdir); mf != nil {
+		if dir, ok := mf.find_package(imp, module_cache_dir(context)); ok && dir_exists(dir) {
+			log_found_package_maybe(imp, dir)
+			return dir, true
+		}
+	}
+
+	dirs := []string{context.GOROOT}
+	dirs = append(dirs, filepath.SplitList(context.GOPATH)...)
+	for _, d := range dirs {
+		dir := filepath.Join(d, "src", filepath.FromSlash(imp))
+		if dir_exists(dir) {
+			log_found_package_maybe(imp, dir)
+			return dir, true
+		}
+	}
+
+	if *g_debug {
+		log.Printf("Import path %q was not resolved\n", imp)
+		log.Println("Gocode's build context is:")
+		log_build_context(context)
+	}
+	return "", false
+}
+
+// import_path_for_dir is the inverse of find_source_package, it guesses the
+// import path of the package in 'dir'.
+func import_path_for_dir(dir string, context build.Context) string {
+	slashdir := filepath.ToSlash(dir)
+	if i := strings.LastIndex(slashdir, "/vendor/"); i != -1 {
+		return slashdir[i+len("/vendor/"):]
+	}
+
+	rel := func(root string) (string, bool) {
+		r, err := filepath.Rel(root, dir)
+		if err != nil || r == ".." || strings.HasPrefix(r, ".."+string(filepath.Separator)) {
+			return "", false
+		}
+		return filepath.ToSlash(r), true
+	}
+
+	if r, ok := rel(filepath.Join(context.GOROOT, "src")); ok {
+		return r
+	}
+	if mf := g_module_cache.find(dir); mf != nil && mf.path != "" {
+		if r, ok := rel(mf.dir); ok {
+			if r == "." {
+				return mf.path
+			}
+			return mf.path + "/" + r
+		}
+	}
+	if modcache := module_cache_dir(context); modcache != "" {
+		if r, ok := rel(modcache); ok {
+			// strip the version: path@version/sub
+			if i := strings.Index(r, "@"); i != -1 {
+				sub := ""
+				if j := strings.Index(r[i:], "/"); j != -1 {
+					sub = r[i+j:]
+				}
+				r = r[:i] + sub
+			}
+			return unescape_module_path(r)
+		}
+	}
+	for _, p := range filepath.SplitList(context.GOPATH) {
+		if r, ok := rel(filepath.Join(p, "src")); ok {
+			return r
+		}
+	}
+	return slashdir
+}
+
+//-------------------------------------------------------------------------
+// source_importer
+//
+// Type-checks packages from their sources using go/types. Results are cached
+// per package directory and are keyed by the hashes of the package files and
+// the keys of its dependencies, so a package is checked again only when it or
+// one of the packages it imports has actually changed.
+//
+// Bringing a package up to date walks the graph of its dependencies. The
+// packages visited are remembered until the next call of new_walk, so that
+// the walk happens once per request rather than once per imported package,
+// and the directories of the imports of each package are remembered until
+// the package or its go.mod file changes.
+//-------------------------------------------------------------------------
+
+type file_hash struct {
+	mtime int64
+	size  int64
+	hash  string
+}
+
+type source_package struct {
+	dir      string
+	path     string
+	files    []string // file names, relative to dir
+	hashes   []string // hashes of the files, in the same order
+	imports  []string
+	mtime    int64 // modification time of the newest file
+	dirmtime int64
+	key      string
+	pkg      *types.Package
+
+	deps     map[string]string // import path -> directory, see resolve_deps
+	modmtime int64             // modification time of go.mod when deps were resolved
+}
+
+type source_importer struct {
+	context  build.Context
+	packages map[string]*source_package // directory -> package
+	hashes   map[string]file_hash       // file name -> hash
+	walked   map[string]*source_package // directory -> package checked during the current walk
+	sync.Mutex
+}
+
+func new_source_importer(context build.Context) *source_importer {
+	return &source_importer{
+		context:  context,
+		packages: make(map[string]*source_package),
+		hashes:   make(map[string]file_hash),
+	}
+}
+
+// Returns the up to date type-checked package in 'dir'. Packages already
+// checked during the current walk aren't checked again.
+func (si *source_importer) import_dir(dir string) (*source_package, error) {
+	si.Lock()
+	defer si.Unlock()
+	if si.walked == nil {
+		si.walked = make(map[string]*source_package)
+	}
+	return si.check(dir, import_path_for_dir(dir, si.context), si.walked)
+}
+
+// Starts a new walk, the next import_dir checks the packages for changes
+// again. Called once per request, before the packages are imported.
+func (si *source_importer) new_walk() {
+	si.Lock()
+	defer si.Unlock()
+	si.walked = nil
+}
+
+// hash_file returns the hash of the contents of the file, it's only read
+// again if its size or modification time has changed.
+func (si *source_importer) hash_file(filename string) (file_hash, error) {
+	stat, err := os.Stat(filename)
+	if err != nil {
+		return file_hash{}, err
+	}
+	fh, ok := si.hashes[filename]
+	if ok && fh.mtime == stat.ModTime().UnixNano() && fh.size == stat.Size() {
+		return fh, nil
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return file_hash{}, err
+	}
+	defer f.Close()
+	h := sha1.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return file_hash{}, err
+	}
+	fh = file_hash{
+		mtime: stat.ModTime().UnixNano(),
+		size:  stat.Size(),
+		hash:  hex.EncodeToString(h.Sum(nil)),
+	}
+	si.hashes[filename] = fh
+	return fh, nil
+}
+
+// Returns true if a file was added to or removed from the package directory
+// or if any of the package files changed.
+func (p *source_package) stale(hash func(string) (file_hash, error)) bool {
+	if p.files == nil {
+		return true
+	}
+	stat, err := os.Stat(p.dir)
+	if err != nil || stat.ModTime().UnixNano() != p.dirmtime {
+		return true
+	}
+	for i, name := range p.files {
+		fh, err := hash(filepath.Join(p.dir, name))
+		if err != nil || fh.hash != p.hashes[i] {
+			return true
+		}
+	}
+	return false
+}
+
+func (si *source_importer) scan_dir(p *source_package) error {
+	bp, err := si.context.ImportDir(p.dir, 0)
+	if bp == nil || len(bp.GoFiles)+len(bp.CgoFiles) == 0 {
+		if err == nil {
+			err = fmt.Errorf("no buildable Go source files in %s", p.dir)
+		}
+		return err
+	}
+
+	stat, err := os.Stat(p.dir)
+	if err != nil {
+		return err
+	}
+	p.dirmtime = stat.ModTime().UnixNano()
+	p.files = append(append([]string{}, bp.GoFiles...), bp.CgoFiles...)
+	p.imports = bp.Imports
+	p.deps = nil
+	p.hashes = make([]string, len(p.files))
+	p.mtime = 0
+	for i, name := range p.files {
+		fh, err := si.hash_file(filepath.Join(p.dir, name))
+		if err != nil {
+			return err
+		}
+		p.hashes[i] = fh.hash
+		if fh.mtime > p.mtime {
+			p.mtime = fh.mtime
+		}
+	}
+	return nil
+}
+
+// check brings the package in 'dir' and all of its dependencies up to date.
+// 'checked' contains the packages which are known to be up to date during
+// this import, a nil entry marks a package that is being checked right now.
+func (si *source_importer) check(dir, path string, checked map[string]*source_package) (*source_package, error) {
+	if p, ok := checked[dir]; ok {
+		if p == nil {
+			return nil, fmt.Errorf("import cycle through %s", path)
+		}
+		return p, nil
+	}
+	checked[dir] = nil
+
+	p, ok := si.packages[dir]
+	if !ok {
+		p = &source_package{dir: dir, path: path}
+	}
+	if p.stale(si.hash_file) {
+		if err := si.scan_dir(p); err != nil {
+			delete(checked, dir)
+			delete(si.packages, dir)
+			return nil, err
+		}
+	}
+
+	// bring dependencies up to date first, their keys are part of ours
+	h := sha1.New()
+	for i, name := range p.files {
+		fmt.Fprintf(h, "%s %s\n", name, p.hashes[i])
+	}
+	si.resolve_deps(p)
+	deps := make(map[string]*types.Package, len(p.imports))
+	for _, imp := range p.imports {
+		switch imp {
+		case "C":
+			continue
+		case "unsafe":
+			deps[imp] = types.Unsafe
+			continue
+		}
+		depdir, ok := p.deps[imp]
+		if !ok {
+			continue
+		}
+		dep, err := si.check(depdir, imp, checked)
+		if err != nil {
+			continue
+		}
+		deps[imp] = dep.pkg
+		fmt.Fprintf(h, "%s %s\n", imp, dep.key)
+	}
+	key := hex.EncodeToString(h.Sum(nil))
+
+	if key != p.key || p.pkg == nil {
+		p.pkg = si.type_check(p, deps)
+		p.key = key
+	}
+	si.packages[dir] = p
+	checked[dir] = p
+	return p, nil
+}
+
+// resolve_deps finds the directories of the packages imported by 'p', unless
+// they were found already and the go.mod file of its module, which decides
+// the versions of the imported modules, hasn't changed since.
+func (si *source_importer) resolve_deps(p *source_package) {
+	var modmtime int64
+	if mf := g_module_cache.find(p.dir); mf != nil {
+		modmtime = mf.mtime
+	}
+	if p.deps != nil && p.modmtime == modmtime {
+		return
+	}
+	p.deps = make(map[string]string, len(p.imports))
+	p.modmtime = modmtime
+	for _, imp := range p.imports {
+		if imp == "C" || imp == "unsafe" {
+			continue
+		}
+		if dir, ok := find_source_package(imp, p.dir, si.context); ok {
+			p.deps[imp] = dir
+		}
+	}
+}
+
+type importer_func func(path string) (*types.Package, error)
+
+func (f importer_func) Import(path string) (*types.Package, error) { return f(path) }
+
+func (si *source_importer) type_check(p *source_package, deps map[string]*types.Package) *types.Package {
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range p.files {
+		data, err := file_reader.read_file(filepath.Join(p.dir, name))
+		if err != nil {
+			continue
+		}
+		file, _ := parser.ParseFile(fset, name, data, 0)
+		if file != nil {
+			files = append(files, file)
+		}
+	}
+
+	conf := types.Config{
+		IgnoreFuncBodies: true,
+		FakeImportC:      true,
+		// the package may be broken, we're interested in what's left
+		Error: func(error) {},
+		Importer: importer_func(func(path string) (*types.Package, error) {
+			if pkg, ok := deps[path]; ok && pkg != nil {
+				return pkg, nil
+			}
+			return nil, fmt.Errorf("can't find import: %q", path)
+		}),
+	}
+	pkg, _ := conf.Check(p.path, fset, files, nil)
+	if *g_debug {
+		log.Printf("Type-checked %q in %q\n", p.path, p.dir)
+	}
+	return pkg
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"go/build"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseModuleFile(t *testing.T) {
+	tests := []struct {
+		data     string
+		path     string
+		requires map[string]string
+		replaces map[string]string
+	}{
+		{
+			data:     "module example.com/m\n",
+			path:     "example.com/m",
+			requires: map[string]string{},
+			replaces: map[string]string{},
+		},
+		{
+			data: `module "example.com/m" // quoted
+
+require example.com/a v1.0.0
+require (
+	example.com/b v1.2.0 // indirect
+	"example.com/c" v0.1.0
+)
+`,
+			path: "example.com/m",
+			requires: map[string]string{
+				"example.com/a": "v1.0.0",
+				"example.com/b": "v1.2.0",
+				"example.com/c": "v0.1.0",
+			},
+			replaces: map[string]string{},
+		},
+		{
+			data: `module example.com/m
+
+replace example.com/a => ../a
+replace example.com/b v1.2.0 => example.com/fork v1.3.0
+replace (
+	example.com/c => /abs/c
+	example.com/d v0.1.0 => ./d
+)
+`,
+			path:     "example.com/m",
+			requires: map[string]string{},
+			replaces: map[string]string{
+				"example.com/a": "../a",
+				"example.com/b": "example.com/fork@v1.3.0",
+				"example.com/c": "/abs/c",
+				"example.com/d": "./d",
+			},
+		},
+	}
+	for i, test := range tests {
+		mf := parse_module_file("/m", []byte(test.data))
+		if mf.path != test.path {
+			t.Errorf("%d: path = %q, want %q", i, mf.path, test.path)
+		}
+		if !reflect.DeepEqual(mf.requires, test.requires) {
+			t.Errorf("%d: requires = %v, want %v", i, mf.requires, test.requires)
+		}
+		if !reflect.DeepEqual(mf.replaces, test.replaces) {
+			t.Errorf("%d: replaces = %v, want %v", i, mf.replaces, test.replaces)
+		}
+	}
+}
+
+func TestModulePathEscaping(t *testing.T) {
+	tests := []struct {
+		path, escaped string
+	}{
+		{"example.com/m", "example.com/m"},
+		{"github.com/Azure/azure-sdk", "github.com/!azure/azure-sdk"},
+		{"github.com/BurntSushi/TOML", "github.com/!burnt!sushi/!t!o!m!l"},
+	}
+	for _, test := range tests {
+		if got := escape_module_path(test.path); got != filepath.FromSlash(test.escaped) {
+			t.Errorf("escape_module_path(%q) = %q, want %q", test.path, got, test.escaped)
+		}
+		if got := unescape_module_path(test.escaped); got != test.path {
+			t.Errorf("unescape_module_path(%q) = %q, want %q", test.escaped, got, test.path)
+		}
+	}
+}
+
+func TestFindPackage(t *testing.T) {
+	mf := parse_module_file("/m", []byte(`module example.com/m
+
+require (
+	example.com/a v1.0.0
+	example.com/a/nested v1.1.0
+	github.com/BurntSushi/toml v0.3.1
+	example.com/forked v1.0.0
+)
+
+replace example.com/local => ../local
+replace example.com/abs => /abs
+replace example.com/forked => github.com/Fork/forked v1.2.0
+replace example.com/moved => example.com/other
+`))
+	modcache := filepath.FromSlash("/cache")
+	tests := []struct {
+		imp string
+		dir string // "" if not found
+	}{
+		{"example.com/m", "/m"},
+		{"example.com/m/sub/pkg", "/m/sub/pkg"},
+		{"example.com/a", "/cache/example.com/a@v1.0.0"},
+		{"example.com/a/sub", "/cache/example.com/a@v1.0.0/sub"},
+		{"example.com/a/nested/sub", "/cache/example.com/a/nested@v1.1.0/sub"},
+		{"example.com/ab", ""},
+		{"github.com/BurntSushi/toml", "/cache/github.com/!burnt!sushi/toml@v0.3.1"},
+		{"example.com/local/sub", "/local/sub"},
+		{"example.com/abs", "/abs"},
+		{"example.com/forked/sub", "/cache/github.com/!fork/forked@v1.2.0/sub"},
+		{"example.com/moved", ""}, // example.com/other isn't required
+		{"fmt", ""},
+	}
+	for _, test := range tests {
+		dir, ok := mf.find_package(test.imp, modcache)
+		if want := filepath.FromSlash(test.dir); ok != (test.dir != "") || dir != want {
+			t.Errorf("find_package(%q) = %q, %t, want %q", test.imp, dir, ok, want)
+		}
+	}
+}
+
+func TestImportPathForDir(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "gocode")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	for _, dir := range []string{"goroot/src", "gopath/src", "gopath/pkg/mod", "mod"} {
+		if err := os.MkdirAll(filepath.Join(tmp, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	gomod := filepath.Join(tmp, "mod", "go.mod")
+	if err := ioutil.WriteFile(gomod, []byte("module example.com/m\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Setenv("GOMODCACHE", os.Getenv("GOMODCACHE"))
+	os.Setenv("GOMODCACHE", "")
+
+	context := build.Default
+	context.GOROOT = filepath.Join(tmp, "goroot")
+	context.GOPATH = filepath.Join(tmp, "gopath")
+	tests := []struct {
+		dir string
+		imp string
+	}{
+		{"goroot/src/fmt", "fmt"},
+		{"goroot/src/vendor/golang.org/x/net/http2", "golang.org/x/net/http2"},
+		{"gopath/src/example.com/p", "example.com/p"},
+		{"gopath/src/example.com/p/vendor/example.com/v", "example.com/v"},
+		{"mod", "example.com/m"},
+		{"mod/sub/pkg", "example.com/m/sub/pkg"},
+		{"mod/vendor/example.com/v/sub", "example.com/v/sub"},
+		{"gopath/pkg/mod/example.com/a@v1.0.0", "example.com/a"},
+		{"gopath/pkg/mod/example.com/a@v1.0.0/sub", "example.com/a/sub"},
+		{"gopath/pkg/mod/github.com/!burnt!sushi/toml@v0.3.1", "github.com/BurntSushi/toml"},
+	}
+	for _, test := range tests {
+		dir := filepath.Join(tmp, filepath.FromSlash(test.dir))
+		if got := import_path_for_dir(dir, context); got != test.imp {
+			t.Errorf("import_path_for_dir(%q) = %q, want %q", test.dir, got, test.imp)
+		}
+	}
+}
+
+func TestSourceImporterWalk(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "gocode")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	write := func(name, data string) {
+		name = filepath.Join(tmp, "src", filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a/a.go", "package a\n\nimport \"b\"\n\nvar V = b.V\n")
+	write("b/b.go", "package b\n\nvar V int\n")
+
+	context := build.Default
+	context.GOROOT = filepath.Join(tmp, "goroot")
+	context.GOPATH = tmp
+	si := new_source_importer(context)
+	adir := filepath.Join(tmp, "src", "a")
+	typ := func() string {
+		p, err := si.import_dir(adir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p.pkg.Scope().Lookup("V").Type().String()
+	}
+
+	if got := typ(); got != "int" {
+		t.Fatalf("a.V has type %s, want int", got)
+	}
+	if want := filepath.Join(tmp, "src", "b"); si.packages[adir].deps["b"] != want {
+		t.Errorf("deps of a = %v, want b in %s", si.packages[adir].deps, want)
+	}
+
+	// b isn't looked at again until the next walk
+	write("b/b.go", "package b\n\nvar V string\n")
+	if got := typ(); got != "int" {
+		t.Errorf("a.V has type %s during the same walk, want int", got)
+	}
+	si.new_walk()
+	if got := typ(); got != "string" {
+		t.Errorf("a.V has type %s after a new walk, want string", got)
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// cacheEntry holds the tags produced for a single file together with the
+// information needed to decide whether the file has changed since.
+type cacheEntry struct {
+	ModTime time.Time
+	Size    int64
+	Hash    string
+	Tags    []Tag
+}
+
+// tagCache is a persistent per-file cache of parsed tags. A file is only
+// re-parsed when its size, modification time and content hash no longer match
+// the cached entry.
+type tagCache struct {
+	Version  string
+	Relative bool
+	Basedir  string
+	Files    map[string]*cacheEntry
+
+	seen    map[string]bool // files requested during this run
+	changed bool            // whether the cache needs to be written back
+}
+
+// loadCache reads the cache from path. A missing, unreadable or incompatible
+// cache file results in an empty cache, so the next run behaves like a full
+// run and rebuilds it.
+func loadCache(path string, relative bool, basedir string) *tagCache {
+	empty := &tagCache{
+		Version:  Version,
+		Relative: relative,
+		Basedir:  basedir,
+		Files:    make(map[string]*cacheEntry),
+		seen:     make(map[string]bool),
+		changed:  true,
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return empty
+	}
+
+	var c tagCache
+	if err := json.Unmarshal(data, &c); err != nil {
+		return empty
+	}
+	if c.Version != Version || c.Relative != relative || c.Basedir != basedir || c.Files == nil {
+		return empty
+	}
+	c.seen = make(map[string]bool)
+	return &c
+}
+
+// Parse returns the tags for filename, from the cache if the file is unchanged
+// or by calling Parse otherwise.
+func (c *tagCache) Parse(filename string, relative bool, basepath string) ([]Tag, error) {
+	c.seen[filename] = true
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		c.forget(filename)
+		return nil, err
+	}
+
+	entry := c.Files[filename]
+	if entry != nil && entry.Size == info.Size() && entry.ModTime.Equal(info.ModTime()) {
+		return entry.Tags, nil
+	}
+
+	hash, err := hashFile(filename)
+	if err != nil {
+		c.forget(filename)
+		return nil, err
+	}
+	if entry != nil && entry.Hash == hash {
+		// touched but not modified
+		entry.ModTime = info.ModTime()
+		entry.Size = info.Size()
+		c.changed = true
+		return entry.Tags, nil
+	}
+
+	tags, err := Parse(filename, relative, basepath)
+	if err != nil {
+		c.forget(filename)
+		return nil, err
+	}
+	c.Files[filename] = &cacheEntry{
+		ModTime: info.ModTime(),
+		Size:    info.Size(),
+		Hash:    hash,
+		Tags:    tags,
+	}
+	c.changed = true
+	return tags, nil
+}
+
+// prune drops the entries of all files that were not requested during this
+// run, i.e. files that were deleted or are no longer part of the file list.
+func (c *tagCache) prune() {
+	for name := range c.Files {
+		if !c.seen[name] {
+			c.forget(name)
+		}
+	}
+}
+
+func (c *tagCache) forget(filename string) {
+	if _, ok := c.Files[filename]; ok {
+		delete(c.Files, filename)
+		c.changed = true
+	}
+}
+
+// save writes the cache to path if anything changed.
+func (c *tagCache) save(path string) error {
+	if !c.changed {
+		return nil
+	}
+	return writeFileAtomic(path, func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(c)
+	})
+}
+
+func hashFile(filename string) (string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha1.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeFileAtomic writes to a temporary file in the same directory as name and
+// renames it over name once write succeeds, so readers never observe a
+// partially written file.
+func writeFileAtomic(name string, write func(w io.Writer) error) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(name), "."+filepath.Base(name)+".")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op after a successful rename
+
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	// TempFile creates the file with mode 0600, keep the mode of the file
+	// being replaced instead.
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(name); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeSource(t *testing.T, name, src string, mtime time.Time) {
+	if err := ioutil.WriteFile(name, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(name, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	a := filepath.Join(dir, "a.go")
+	b := filepath.Join(dir, "b.go")
+	cacheFile := filepath.Join(dir, "tags.cache")
+	mtime := time.Unix(1400000000, 0)
+	writeSource(t, a, "package a\nfunc A() {}\n", mtime)
+	writeSource(t, b, "package a\nfunc B() {}\n", mtime)
+
+	c := loadCache(cacheFile, false, "")
+	for _, f := range []string{a, b} {
+		if _, err := c.Parse(f, false, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.save(cacheFile); err != nil {
+		t.Fatal(err)
+	}
+
+	// Mark the cached tags, so we can tell whether a file was re-parsed.
+	c = loadCache(cacheFile, false, "")
+	if len(c.Files) != 2 {
+		t.Fatalf("loaded %d cache entries, want 2", len(c.Files))
+	}
+	for _, e := range c.Files {
+		e.Tags[0].Name = "cached"
+	}
+
+	tags, _ := c.Parse(a, false, "")
+	if tags[0].Name != "cached" {
+		t.Errorf("unchanged file was re-parsed")
+	}
+
+	// Touching a file without modifying it does not re-parse it either.
+	writeSource(t, a, "package a\nfunc A() {}\n", mtime.Add(time.Hour))
+	tags, _ = c.Parse(a, false, "")
+	if tags[0].Name != "cached" {
+		t.Errorf("touched file was re-parsed")
+	}
+
+	writeSource(t, a, "package a\nfunc A2() {}\n", mtime.Add(2*time.Hour))
+	tags, _ = c.Parse(a, false, "")
+	if len(tags) != 2 || tags[0].Name != "a" || tags[1].Name != "A2" {
+		t.Errorf("modified file: got tags %v", tags)
+	}
+
+	// b.go was not requested in this run and is dropped.
+	c.prune()
+	if _, ok := c.Files[b]; ok || len(c.Files) != 1 {
+		t.Errorf("prune kept %d entries, want only %s", len(c.Files), a)
+	}
+
+	// A deleted file loses its entry.
+	os.Remove(a)
+	if _, err := c.Parse(a, false, ""); err == nil {
+		t.Errorf("parsing deleted file succeeded")
+	}
+	if len(c.Files) != 0 {
+		t.Errorf("deleted file is still cached")
+	}
+}
+
+func TestCacheVersion(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cacheFile := filepath.Join(dir, "tags.cache")
+	c := loadCache(cacheFile, false, "")
+	c.Files["x.go"] = &cacheEntry{}
+	if err := c.save(cacheFile); err != nil {
+		t.Fatal(err)
+	}
+
+	if c := loadCache(cacheFile, true, dir); len(c.Files) != 0 {
+		t.Errorf("cache built without -tag-relative was reused with it")
+	}
+	if c := loadCache(cacheFile, false, ""); len(c.Files) != 1 {
+		t.Errorf("compatible cache was not reused")
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	a := filepath.Join(dir, "a.go")
+	b := filepath.Join(dir, "b.go")
+	writeSource(t, a, "package a\nfunc A() {}\n", time.Now())
+	writeSource(t, b, "package a\nfunc B() {}\n", time.Now())
+
+	defer func(f string) { outputFile = f }(outputFile)
+	outputFile = filepath.Join(dir, "tags")
+
+	if err := generate([]string{a, b}, nil, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	writeSource(t, b, "package a\nfunc C() {}\n", time.Now())
+	if err := update(b, nil, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(outputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(data)
+	for _, want := range []string{"A\t" + a, "C\t" + b, "!_TAG_FILE_FORMAT"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("tag file does not contain %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "B\t"+b) {
+		t.Errorf("tag file still contains the old tag of %s:\n%s", b, got)
+	}
+	if n := strings.Count(got, "!_TAG_FILE_FORMAT"); n != 1 {
+		t.Errorf("tag file contains %d meta tag headers, want 1", n)
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Output formats.
+const (
+	FormatCtags = "ctags" // classic tab separated tags file
+	FormatJSON  = "json"  // JSON lines, as Universal Ctags --output-format=json
+	FormatEtags = "etags" // Emacs TAGS file
+)
+
+// formatWriters maps each output format to the function writing tags in it.
+var formatWriters = map[string]func(w io.Writer, tags []Tag) error{
+	FormatCtags: writeCtags,
+	FormatJSON:  writeJSON,
+	FormatEtags: writeEtags,
+}
+
+// ctagsLines returns the lines of a classic tags file, including the meta tags.
+func ctagsLines(tags []Tag) []string {
+	output := createMetaTags()
+	for _, tag := range tags {
+		output = append(output, tag.String())
+	}
+
+	if sortOutput {
+		sort.Sort(sort.StringSlice(output))
+	}
+	return output
+}
+
+func writeLines(w io.Writer, lines []string) error {
+	bw := bufio.NewWriter(w)
+	for _, s := range lines {
+		fmt.Fprintln(bw, s)
+	}
+	return bw.Flush()
+}
+
+func writeCtags(w io.Writer, tags []Tag) error {
+	return writeLines(w, ctagsLines(tags))
+}
+
+// jsonTag is a single line of the JSON output. The field names follow the
+// Universal Ctags JSON output format.
+type jsonTag struct {
+	Type      string `json:"_type"`
+	Name      string `json:"name"`
+	Path      string `json:"path"`
+	Pattern   string `json:"pattern,omitempty"`
+	Line      int    `json:"line,omitempty"`
+	Kind      string `json:"kind,omitempty"`
+	Scope     string `json:"scope,omitempty"`
+	ScopeKind string `json:"scopeKind,omitempty"`
+	Access    string `json:"access,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	TypeRef   string `json:"typeref,omitempty"`
+	End       int    `json:"end,omitempty"`
+	Ctype     string `json:"ctype,omitempty"`
+}
+
+func newJSONTag(t Tag) jsonTag {
+	line, _ := strconv.Atoi(t.Fields[Line])
+	end, _ := strconv.Atoi(t.Fields[End])
+	j := jsonTag{
+		Type:      "tag",
+		Name:      t.Name,
+		Path:      t.File,
+		Pattern:   searchPattern(t.Pattern),
+		Line:      line,
+		Kind:      kindNames[t.Type],
+		Scope:     t.Scope,
+		ScopeKind: t.ScopeKind,
+		Access:    t.Fields[Access],
+		Signature: t.Fields[Signature],
+		End:       end,
+	}
+	if typ := t.Fields[TypeField]; len(typ) > 0 {
+		j.TypeRef = "typename:" + typ
+	}
+	// constructors belong to a type without being scoped by it
+	if len(t.Scope) == 0 {
+		j.Ctype = t.Fields[ReceiverType]
+	}
+	return j
+}
+
+// searchPattern returns the ex search command matching line, escaped the way
+// ctags does it.
+func searchPattern(line string) string {
+	if len(line) == 0 {
+		return ""
+	}
+	r := strings.NewReplacer(`\`, `\\`, `/`, `\/`)
+	return "/^" + r.Replace(line) + "$/"
+}
+
+func writeJSON(w io.Writer, tags []Tag) error {
+	sorted := 0
+	if sortOutput {
+		sorted = 1
+		tags = append([]Tag(nil), tags...)
+		sort.Stable(byName(tags))
+	}
+
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	ptags := []jsonTag{
+		{Type: "ptag", Name: "JSON_OUTPUT_VERSION", Path: "0.0", Pattern: "in development"},
+		{Type: "ptag", Name: "TAG_FILE_SORTED", Path: strconv.Itoa(sorted), Pattern: "0=unsorted, 1=sorted, 2=foldcase"},
+		{Type: "ptag", Name: "TAG_PROGRAM_AUTHOR", Path: AuthorName, Pattern: AuthorEmail},
+		{Type: "ptag", Name: "TAG_PROGRAM_NAME", Path: Name},
+		{Type: "ptag", Name: "TAG_PROGRAM_URL", Path: URL},
+		{Type: "ptag", Name: "TAG_PROGRAM_VERSION", Path: Version},
+	}
+	for _, p := range ptags {
+		if err := enc.Encode(p); err != nil {
+			return err
+		}
+	}
+	for _, t := range tags {
+		if err := enc.Encode(newJSONTag(t)); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// byName sorts tags by name, then file and line, like a sorted tags file.
+type byName []Tag
+
+func (t byName) Len() int      { return len(t) }
+func (t byName) Swap(i, j int) { t[i], t[j] = t[j], t[i] }
+func (t byName) Less(i, j int) bool {
+	if t[i].Name != t[j].Name {
+		return t[i].Name < t[j].Name
+	}
+	if t[i].File != t[j].File {
+		return t[i].File < t[j].File
+	}
+	li, _ := strconv.Atoi(t[i].Address)
+	lj, _ := strconv.Atoi(t[j].Address)
+	return li < lj
+}
+
+// writeEtags writes tags in the Emacs TAGS format: a section per file,
+// started by a form feed and a "file,size" header line, with one
+// "text DEL name SOH line,offset" entry per tag.
+func writeEtags(w io.Writer, tags []Tag) error {
+	var files []string
+	sections := make(map[string]*bytes.Buffer)
+	for _, t := range tags {
+		b, ok := sections[t.File]
+		if !ok {
+			b = new(bytes.Buffer)
+			sections[t.File] = b
+			files = append(files, t.File)
+		}
+		fmt.Fprintf(b, "%s\x7f%s\x01%s,%d\n", t.Pattern, t.Name, t.Address, t.Offset)
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, f := range files {
+		b := sections[f]
+		fmt.Fprintf(bw, "\x0c\n%s,%d\n", f, b.Len())
+		bw.Write(b.Bytes())
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestWriteJSON(t *testing.T) {
+	tags, err := Parse("tests/struct.go-src", false, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if err := writeJSON(&b, tags); err != nil {
+		t.Fatal(err)
+	}
+
+	var ptags int
+	found := make(map[string]map[string]interface{})
+	for _, line := range strings.Split(strings.TrimSpace(b.String()), "\n") {
+		var m map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			t.Fatalf("invalid JSON line %q: %s", line, err)
+		}
+		switch m["_type"] {
+		case "ptag":
+			ptags++
+		case "tag":
+			found[m["name"].(string)] = m
+		default:
+			t.Errorf("unexpected _type in %q", line)
+		}
+	}
+	if ptags != 6 {
+		t.Errorf("got %d pseudo tags, want 6", ptags)
+	}
+
+	want := map[string]map[string]interface{}{
+		"F1": {
+			"path": "tests/struct.go-src", "pattern": "/^func (s Struct) F1() ([]bool, [2]*string) {$/",
+			"line": 13.0, "end": 14.0, "kind": "method", "scope": "Struct", "scopeKind": "struct",
+			"access": "public", "signature": "()", "typeref": "typename:[]bool, [2]*string",
+		},
+		"field3": {
+			"kind": "field", "scope": "Struct", "scopeKind": "struct", "access": "private", "typeref": "typename:*bool",
+		},
+		"NewStruct": {
+			"kind": "function", "ctype": "Struct", "end": 11.0,
+		},
+	}
+	// field3 and field4 differ only in type, check the later one
+	want["field4"] = want["field3"]
+	delete(want, "field3")
+
+	for name, fields := range want {
+		got, ok := found[name]
+		if !ok {
+			t.Errorf("no JSON tag for %s", name)
+			continue
+		}
+		for k, v := range fields {
+			if got[k] != v {
+				t.Errorf("%s: %s = %v, want %v", name, k, got[k], v)
+			}
+		}
+	}
+	if _, ok := found["NewStruct"]["scope"]; ok {
+		t.Errorf("constructor NewStruct should not have a scope")
+	}
+}
+
+func TestWriteEtags(t *testing.T) {
+	tags, err := Parse("tests/func.go-src", false, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if err := writeEtags(&b, tags); err != nil {
+		t.Fatal(err)
+	}
+
+	body := "package Test\x7fTest\x011,0\n" +
+		"func Function1() string {\x7fFunction1\x013,14\n" +
+		"func function2(p1, p2 int, p3 *string) {\x7ffunction2\x016,43\n" +
+		"func function3() (result bool) {\x7ffunction3\x019,87\n" +
+		"func function4(p interface{}) interface{} {\x7ffunction4\x0112,123\n" +
+		"func function5() (a, b string, c error) {\x7ffunction5\x0115,170\n"
+	want := "\x0c\ntests/func.go-src," + strconv.Itoa(len(body)) + "\n" + body
+	if b.String() != want {
+		t.Errorf("writeEtags\n  is:%q\nwant:%q", b.String(), want)
+	}
+}
+
+func TestSearchPattern(t *testing.T) {
+	if got, want := searchPattern(`x := a/b \ c`), `/^x := a\/b \\ c$/`; got != want {
+		t.Errorf("searchPattern = %s, want %s", got, want)
+	}
+}
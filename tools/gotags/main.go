@@ -13,7 +13,7 @@ import (
 
 // Contants used for the meta tags
 const (
-	Version     = "1.3.0"
+	Version     = "1.4.0"
 	Name        = "gotags"
 	URL         = "https://github.com/jstemmer/gotags"
 	AuthorName  = "Joel Stemmer"
@@ -28,6 +28,9 @@ var (
 	sortOutput   bool
 	silent       bool
 	relative     bool
+	cacheFile    string
+	updateFile   string
+	format       string
 )
 
 // Initialize flags.
@@ -39,6 +42,9 @@ func init() {
 	flag.BoolVar(&sortOutput, "sort", true, "sort tags.")
 	flag.BoolVar(&silent, "silent", false, "do not produce any output on error.")
 	flag.BoolVar(&relative, "tag-relative", false, "file paths should be relative to the directory containing the tag file.")
+	flag.StringVar(&cacheFile, "cache", "", "keep parsed tags per file in the specified cache file and only re-parse files that changed.")
+	flag.StringVar(&updateFile, "update", "", "update the tags of the specified file in place in the tag file given by -f.")
+	flag.StringVar(&format, "format", FormatCtags, "output format: ctags, json (Universal Ctags JSON lines) or etags (Emacs TAGS).")
 
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "gotags version %s\n\n", Version)
@@ -132,17 +138,38 @@ func main() {
 		return
 	}
 
-	files, err := getFileNames()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "cannot get specified files\n\n")
+	if _, ok := formatWriters[format]; !ok {
+		fmt.Fprintf(os.Stderr, "unknown output format %q\n\n", format)
 		flag.Usage()
 		os.Exit(1)
 	}
 
-	if len(files) == 0 && len(inputFile) == 0 {
-		fmt.Fprintf(os.Stderr, "no file specified\n\n")
-		flag.Usage()
-		os.Exit(1)
+	var files []string
+	var err error
+	if len(updateFile) > 0 {
+		if len(outputFile) == 0 || outputFile == "-" {
+			fmt.Fprintf(os.Stderr, "-update requires a tag file specified with -f\n\n")
+			flag.Usage()
+			os.Exit(1)
+		}
+		if format != FormatCtags {
+			fmt.Fprintf(os.Stderr, "-update only supports the ctags format\n\n")
+			flag.Usage()
+			os.Exit(1)
+		}
+	} else {
+		files, err = getFileNames()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cannot get specified files\n\n")
+			flag.Usage()
+			os.Exit(1)
+		}
+
+		if len(files) == 0 && len(inputFile) == 0 {
+			fmt.Fprintf(os.Stderr, "no file specified\n\n")
+			flag.Usage()
+			os.Exit(1)
+		}
 	}
 
 	var basedir string
@@ -156,45 +183,133 @@ func main() {
 		}
 	}
 
+	var cache *tagCache
+	if len(cacheFile) > 0 {
+		cache = loadCache(cacheFile, relative, basedir)
+	}
+
+	if len(updateFile) > 0 {
+		err = update(updateFile, cache, basedir)
+	} else {
+		err = generate(files, cache, basedir)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+
+	if cache != nil {
+		if err := cache.save(cacheFile); err != nil && !silent {
+			fmt.Fprintf(os.Stderr, "could not write cache file: %s\n", err)
+		}
+	}
+}
+
+// parse returns the tags for file, using the cache if there is one. Parse
+// errors are reported unless silent is set.
+func parse(file string, cache *tagCache, basedir string) ([]Tag, bool) {
+	var ts []Tag
+	var err error
+	if cache != nil {
+		ts, err = cache.Parse(file, relative, basedir)
+	} else {
+		ts, err = Parse(file, relative, basedir)
+	}
+	if err != nil {
+		if !silent {
+			fmt.Fprintf(os.Stderr, "parse error: %s\n\n", err)
+		}
+		return nil, false
+	}
+	return ts, true
+}
+
+// generate writes the tags of all files to the output.
+func generate(files []string, cache *tagCache, basedir string) error {
 	tags := []Tag{}
 	for _, file := range files {
-		ts, err := Parse(file, relative, basedir)
-		if err != nil {
-			if !silent {
-				fmt.Fprintf(os.Stderr, "parse error: %s\n\n", err)
-			}
-			continue
+		if ts, ok := parse(file, cache, basedir); ok {
+			tags = append(tags, ts...)
 		}
-		tags = append(tags, ts...)
+	}
+	if cache != nil {
+		cache.prune()
+	}
+
+	write := formatWriters[format]
+	return writeOutput(func(w io.Writer) error {
+		return write(w, tags)
+	})
+}
+
+// update replaces the tags of file in the existing tag file and rewrites it.
+// Tags of all other files are kept as they are.
+func update(file string, cache *tagCache, basedir string) error {
+	existing, err := readTagLines(outputFile)
+	if err != nil {
+		return fmt.Errorf("could not read tag file: %s", err)
 	}
 
+	name := tagFileName(file, relative, basedir)
 	output := createMetaTags()
-	for _, tag := range tags {
-		output = append(output, tag.String())
+	for _, line := range existing {
+		if strings.HasPrefix(line, "!_TAG_") {
+			continue
+		}
+		if fields := strings.SplitN(line, "\t", 3); len(fields) > 1 && fields[1] == name {
+			continue
+		}
+		output = append(output, line)
+	}
+
+	// a file that was deleted or does not parse simply loses its tags
+	if ts, ok := parse(file, cache, basedir); ok {
+		for _, tag := range ts {
+			output = append(output, tag.String())
+		}
 	}
 
 	if sortOutput {
 		sort.Sort(sort.StringSlice(output))
 	}
 
-	var out io.Writer
+	return writeOutput(func(w io.Writer) error {
+		return writeLines(w, output)
+	})
+}
+
+// readTagLines returns the lines of an existing tag file. A missing file is
+// treated as an empty one.
+func readTagLines(name string) ([]string, error) {
+	f, err := os.Open(name)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
+
+// writeOutput calls write with standard out, or atomically replaces the output
+// file so editors never read a partially written tag file.
+func writeOutput(write func(w io.Writer) error) error {
 	if len(outputFile) == 0 || outputFile == "-" {
 		// For compatibility with older gotags versions, also write to stdout
 		// when outputFile is not specified.
-		out = os.Stdout
-	} else {
-		file, err := os.Create(outputFile)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "could not create output file: %s\n", err)
-			os.Exit(1)
-		}
-		out = file
-		defer file.Close()
+		return write(os.Stdout)
 	}
 
-	for _, s := range output {
-		fmt.Fprintln(out, s)
+	if err := writeFileAtomic(outputFile, write); err != nil {
+		return fmt.Errorf("could not create output file: %s", err)
 	}
+	return nil
 }
 
 // createMetaTags returns a list of meta tags.
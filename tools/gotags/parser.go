@@ -5,18 +5,22 @@ import (
 	"go/ast"
 	"go/parser"
 	"go/token"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 )
 
 // tagParser contains the data needed while parsing.
 type tagParser struct {
 	fset     *token.FileSet
-	tags     []Tag    // list of created tags
-	types    []string // all types we encounter, used to determine the constructors
-	relative bool     // should filenames be relative to basepath
-	basepath string   // output file directory
+	src      []byte            // source of the file being parsed
+	tags     []Tag             // list of created tags
+	types    []string          // all types we encounter, used to determine the constructors
+	kinds    map[string]string // scope kind (struct, interface or type) of each type in the file
+	relative bool              // should filenames be relative to basepath
+	basepath string            // output file directory
 }
 
 // Parse parses the source in filename and returns a list of tags. If relative
@@ -26,11 +30,18 @@ func Parse(filename string, relative bool, basepath string) ([]Tag, error) {
 		fset:     token.NewFileSet(),
 		tags:     []Tag{},
 		types:    make([]string, 0),
+		kinds:    make(map[string]string),
 		relative: relative,
 		basepath: basepath,
 	}
 
-	f, err := parser.ParseFile(p.fset, filename, nil, 0)
+	src, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	p.src = src
+
+	f, err := parser.ParseFile(p.fset, filename, src, 0)
 	if err != nil {
 		return nil, err
 	}
@@ -62,6 +73,18 @@ func (p *tagParser) parseImports(f *ast.File) {
 
 // parseDeclarations creates a tag for each function, type or value declaration.
 func (p *tagParser) parseDeclarations(f *ast.File) {
+	// record the kind of every type, so that methods can refer to the kind of
+	// their receiver's type regardless of declaration order.
+	for _, d := range f.Decls {
+		if decl, ok := d.(*ast.GenDecl); ok {
+			for _, s := range decl.Specs {
+				if ts, ok := s.(*ast.TypeSpec); ok {
+					p.kinds[ts.Name.Name] = scopeKind(ts.Type)
+				}
+			}
+		}
+	}
+
 	// first parse the type and value declarations, so that we have a list of all
 	// known types before parsing the functions.
 	for _, d := range f.Decls {
@@ -90,13 +113,20 @@ func (p *tagParser) parseFunction(f *ast.FuncDecl) {
 	tag := p.createTag(f.Name.Name, f.Pos(), Function)
 
 	tag.Fields[Access] = getAccess(tag.Name)
-	tag.Fields[Signature] = fmt.Sprintf("(%s)", getTypes(f.Type.Params, true))
+	tag.Fields[Signature] = getTypeParams(f.Type.TypeParams) + fmt.Sprintf("(%s)", getTypes(f.Type.Params, true))
 	tag.Fields[TypeField] = getTypes(f.Type.Results, false)
+	p.setEnd(&tag, f.End())
 
 	if f.Recv != nil && len(f.Recv.List) > 0 {
 		// this function has a receiver, set the type to Method
-		tag.Fields[ReceiverType] = getType(f.Recv.List[0].Type, false)
+		recv := getReceiverName(f.Recv.List[0].Type)
+		tag.Fields[ReceiverType] = recv
 		tag.Type = Method
+		tag.Scope = recv
+		if tag.ScopeKind = p.kinds[recv]; tag.ScopeKind == "" {
+			// the type is declared in another file of the package
+			tag.ScopeKind = "type"
+		}
 	} else if name, ok := p.belongsToReceiver(f.Type.Results); ok {
 		// this function does not have a receiver, but it belongs to one based
 		// on its return values; its type will be Function instead of Method.
@@ -113,6 +143,10 @@ func (p *tagParser) parseTypeDeclaration(ts *ast.TypeSpec) {
 	tag := p.createTag(ts.Name.Name, ts.Pos(), Type)
 
 	tag.Fields[Access] = getAccess(tag.Name)
+	if ts.TypeParams != nil {
+		tag.Fields[Signature] = getTypeParams(ts.TypeParams)
+	}
+	p.setEnd(&tag, ts.End())
 
 	switch s := ts.Type.(type) {
 	case *ast.StructType:
@@ -166,6 +200,7 @@ func (p *tagParser) parseStructFields(name string, s *ast.StructType) {
 				tag.Fields[Access] = getAccess(tag.Name)
 				tag.Fields[ReceiverType] = name
 				tag.Fields[TypeField] = getType(f.Type, true)
+				tag.Scope, tag.ScopeKind = name, "struct"
 				p.tags = append(p.tags, tag)
 			}
 		} else {
@@ -174,6 +209,7 @@ func (p *tagParser) parseStructFields(name string, s *ast.StructType) {
 			tag.Fields[Access] = getAccess(tag.Name)
 			tag.Fields[ReceiverType] = name
 			tag.Fields[TypeField] = getType(f.Type, true)
+			tag.Scope, tag.ScopeKind = name, "struct"
 			p.tags = append(p.tags, tag)
 		}
 	}
@@ -186,6 +222,9 @@ func (p *tagParser) parseInterfaceMethods(name string, s *ast.InterfaceType) {
 		var tag Tag
 		if len(f.Names) > 0 {
 			tag = p.createTag(f.Names[0].Name, f.Names[0].Pos(), Method)
+		} else if isTypeSetTerm(f.Type) {
+			// type set of a constraint interface, e.g. ~int | ~string
+			continue
 		} else {
 			// embedded interface
 			tag = p.createTag(getType(f.Type, true), f.Pos(), Embedded)
@@ -199,6 +238,7 @@ func (p *tagParser) parseInterfaceMethods(name string, s *ast.InterfaceType) {
 		}
 
 		tag.Fields[InterfaceType] = name
+		tag.Scope, tag.ScopeKind = name, "interface"
 
 		p.tags = append(p.tags, tag)
 	}
@@ -206,17 +246,43 @@ func (p *tagParser) parseInterfaceMethods(name string, s *ast.InterfaceType) {
 
 // createTag creates a new tag, using pos to find the filename and set the line number.
 func (p *tagParser) createTag(name string, pos token.Pos, tagType TagType) Tag {
-	f := p.fset.File(pos).Name()
-	if p.relative {
-		if abs, err := filepath.Abs(f); err != nil {
-			fmt.Fprintf(os.Stderr, "could not determine absolute path: %s\n", err)
-		} else if rel, err := filepath.Rel(p.basepath, abs); err != nil {
-			fmt.Fprintf(os.Stderr, "could not determine relative path: %s\n", err)
-		} else {
-			f = rel
-		}
+	file := p.fset.File(pos)
+	f := tagFileName(file.Name(), p.relative, p.basepath)
+	line := file.Line(pos)
+	tag := NewTag(name, f, line, tagType)
+
+	// remember the source line, it is needed for the search pattern of
+	// the JSON output and for the etags format.
+	start := file.Offset(file.LineStart(line))
+	end := start
+	for end < len(p.src) && p.src[end] != '\n' {
+		end++
+	}
+	tag.Pattern = strings.TrimRight(string(p.src[start:end]), "\r")
+	tag.Offset = start
+
+	return tag
+}
+
+// setEnd sets the end field of tag to the line of pos.
+func (p *tagParser) setEnd(tag *Tag, pos token.Pos) {
+	tag.Fields[End] = strconv.Itoa(p.fset.Position(pos).Line)
+}
+
+// tagFileName returns the file name as it appears in tags for filename. If
+// relative is true, the name is relative to basepath.
+func tagFileName(filename string, relative bool, basepath string) string {
+	if !relative {
+		return filename
 	}
-	return NewTag(name, f, p.fset.Position(pos).Line, tagType)
+	if abs, err := filepath.Abs(filename); err != nil {
+		fmt.Fprintf(os.Stderr, "could not determine absolute path: %s\n", err)
+	} else if rel, err := filepath.Rel(basepath, abs); err != nil {
+		fmt.Fprintf(os.Stderr, "could not determine relative path: %s\n", err)
+	} else {
+		return rel
+	}
+	return filename
 }
 
 // belongsToReceiver checks if a function with these return types belongs to
@@ -324,10 +390,78 @@ func getType(node ast.Node, star bool) (paramType string) {
 		paramType = fmt.Sprintf("chan %s", getType(t.Value, true))
 	case *ast.InterfaceType:
 		paramType = "interface{}"
+	case *ast.IndexExpr:
+		// instantiated generic type, e.g. List[T]
+		paramType = fmt.Sprintf("%s[%s]", getType(t.X, star), getType(t.Index, true))
+	case *ast.IndexListExpr:
+		indices := make([]string, len(t.Indices))
+		for i, index := range t.Indices {
+			indices[i] = getType(index, true)
+		}
+		paramType = fmt.Sprintf("%s[%s]", getType(t.X, star), strings.Join(indices, ", "))
+	case *ast.UnaryExpr:
+		// approximation element of a type constraint, e.g. ~int
+		if t.Op == token.TILDE {
+			paramType = "~" + getType(t.X, true)
+		}
+	case *ast.BinaryExpr:
+		// union of a type constraint, e.g. ~int | ~string
+		if t.Op == token.OR {
+			paramType = getType(t.X, true) + " | " + getType(t.Y, true)
+		}
 	}
 	return
 }
 
+// getTypeParams returns the type parameter list of a generic function or type,
+// e.g. "[K comparable, V any]", or an empty string if there is none.
+func getTypeParams(fields *ast.FieldList) string {
+	if fields == nil || len(fields.List) == 0 {
+		return ""
+	}
+	return "[" + getTypes(fields, true) + "]"
+}
+
+// getReceiverName returns the name of the receiver's base type, without pointer
+// and type parameters, so that it matches the name of the type's tag.
+func getReceiverName(node ast.Expr) string {
+	switch t := node.(type) {
+	case *ast.StarExpr:
+		return getReceiverName(t.X)
+	case *ast.ParenExpr:
+		return getReceiverName(t.X)
+	case *ast.IndexExpr:
+		return getReceiverName(t.X)
+	case *ast.IndexListExpr:
+		return getReceiverName(t.X)
+	}
+	return getType(node, false)
+}
+
+// isTypeSetTerm reports whether node is a union or approximation element of
+// a constraint interface rather than an embedded interface.
+func isTypeSetTerm(node ast.Expr) bool {
+	switch t := node.(type) {
+	case *ast.UnaryExpr:
+		return t.Op == token.TILDE
+	case *ast.BinaryExpr:
+		return t.Op == token.OR
+	}
+	return false
+}
+
+// scopeKind returns the kind of scope a type declaration opens for its
+// fields and methods.
+func scopeKind(node ast.Expr) string {
+	switch node.(type) {
+	case *ast.StructType:
+		return "struct"
+	case *ast.InterfaceType:
+		return "interface"
+	}
+	return "type"
+}
+
 // getAccess returns the string "public" if name is considered an exported name, otherwise
 // the string "private" is returned.
 func getAccess(name string) (access string) {
@@ -25,11 +25,11 @@ var testCases = []struct {
 	}},
 	{filename: "tests/func.go-src", tags: []Tag{
 		tag("Test", 1, "p", F{}),
-		tag("Function1", 3, "f", F{"access": "public", "signature": "()", "type": "string"}),
-		tag("function2", 6, "f", F{"access": "private", "signature": "(p1, p2 int, p3 *string)"}),
-		tag("function3", 9, "f", F{"access": "private", "signature": "()", "type": "bool"}),
-		tag("function4", 12, "f", F{"access": "private", "signature": "(p interface{})", "type": "interface{}"}),
-		tag("function5", 15, "f", F{"access": "private", "signature": "()", "type": "string, string, error"}),
+		tag("Function1", 3, "f", F{"access": "public", "end": "4", "signature": "()", "type": "string"}),
+		tag("function2", 6, "f", F{"access": "private", "end": "7", "signature": "(p1, p2 int, p3 *string)"}),
+		tag("function3", 9, "f", F{"access": "private", "end": "10", "signature": "()", "type": "bool"}),
+		tag("function4", 12, "f", F{"access": "private", "end": "13", "signature": "(p interface{})", "type": "interface{}"}),
+		tag("function5", 15, "f", F{"access": "private", "end": "16", "signature": "()", "type": "string, string, error"}),
 	}},
 	{filename: "tests/import.go-src", tags: []Tag{
 		tag("Test", 1, "p", F{}),
@@ -39,42 +39,42 @@ var testCases = []struct {
 	}},
 	{filename: "tests/interface.go-src", tags: []Tag{
 		tag("Test", 1, "p", F{}),
-		tag("InterfaceMethod", 4, "m", F{"access": "public", "signature": "(int)", "ntype": "Interface", "type": "string"}),
-		tag("OtherMethod", 5, "m", F{"access": "public", "signature": "()", "ntype": "Interface"}),
-		tag("io.Reader", 6, "e", F{"access": "public", "ntype": "Interface"}),
-		tag("Interface", 3, "n", F{"access": "public", "type": "interface"}),
+		tag("InterfaceMethod", 4, "m", F{"access": "public", "signature": "(int)", "interface": "Interface", "ntype": "Interface", "type": "string"}),
+		tag("OtherMethod", 5, "m", F{"access": "public", "signature": "()", "interface": "Interface", "ntype": "Interface"}),
+		tag("io.Reader", 6, "e", F{"access": "public", "interface": "Interface", "ntype": "Interface"}),
+		tag("Interface", 3, "n", F{"access": "public", "end": "7", "type": "interface"}),
 	}},
 	{filename: "tests/struct.go-src", tags: []Tag{
 		tag("Test", 1, "p", F{}),
-		tag("Field1", 4, "w", F{"access": "public", "ctype": "Struct", "type": "int"}),
-		tag("Field2", 4, "w", F{"access": "public", "ctype": "Struct", "type": "int"}),
-		tag("field3", 5, "w", F{"access": "private", "ctype": "Struct", "type": "string"}),
-		tag("field4", 6, "w", F{"access": "private", "ctype": "Struct", "type": "*bool"}),
-		tag("Struct", 3, "t", F{"access": "public", "type": "struct"}),
-		tag("Struct", 20, "e", F{"access": "public", "ctype": "TestEmbed", "type": "Struct"}),
-		tag("*io.Writer", 21, "e", F{"access": "public", "ctype": "TestEmbed", "type": "*io.Writer"}),
-		tag("TestEmbed", 19, "t", F{"access": "public", "type": "struct"}),
-		tag("Struct2", 27, "t", F{"access": "public", "type": "struct"}),
-		tag("Connection", 36, "t", F{"access": "public", "type": "struct"}),
-		tag("NewStruct", 9, "f", F{"access": "public", "ctype": "Struct", "signature": "()", "type": "*Struct"}),
-		tag("F1", 13, "m", F{"access": "public", "ctype": "Struct", "signature": "()", "type": "[]bool, [2]*string"}),
-		tag("F2", 16, "m", F{"access": "public", "ctype": "Struct", "signature": "()", "type": "bool"}),
-		tag("NewTestEmbed", 24, "f", F{"access": "public", "ctype": "TestEmbed", "signature": "()", "type": "TestEmbed"}),
-		tag("NewStruct2", 30, "f", F{"access": "public", "ctype": "Struct2", "signature": "()", "type": "*Struct2, error"}),
-		tag("Dial", 33, "f", F{"access": "public", "ctype": "Connection", "signature": "()", "type": "*Connection, error"}),
-		tag("Dial2", 39, "f", F{"access": "public", "ctype": "Connection", "signature": "()", "type": "*Connection, *Struct2"}),
-		tag("Dial3", 42, "f", F{"access": "public", "signature": "()", "type": "*Connection, *Connection"}),
+		tag("Field1", 4, "w", F{"access": "public", "ctype": "Struct", "struct": "Struct", "type": "int"}),
+		tag("Field2", 4, "w", F{"access": "public", "ctype": "Struct", "struct": "Struct", "type": "int"}),
+		tag("field3", 5, "w", F{"access": "private", "ctype": "Struct", "struct": "Struct", "type": "string"}),
+		tag("field4", 6, "w", F{"access": "private", "ctype": "Struct", "struct": "Struct", "type": "*bool"}),
+		tag("Struct", 3, "t", F{"access": "public", "end": "7", "type": "struct"}),
+		tag("Struct", 20, "e", F{"access": "public", "ctype": "TestEmbed", "struct": "TestEmbed", "type": "Struct"}),
+		tag("*io.Writer", 21, "e", F{"access": "public", "ctype": "TestEmbed", "struct": "TestEmbed", "type": "*io.Writer"}),
+		tag("TestEmbed", 19, "t", F{"access": "public", "end": "22", "type": "struct"}),
+		tag("Struct2", 27, "t", F{"access": "public", "end": "28", "type": "struct"}),
+		tag("Connection", 36, "t", F{"access": "public", "end": "37", "type": "struct"}),
+		tag("NewStruct", 9, "f", F{"access": "public", "ctype": "Struct", "end": "11", "signature": "()", "type": "*Struct"}),
+		tag("F1", 13, "m", F{"access": "public", "ctype": "Struct", "end": "14", "signature": "()", "struct": "Struct", "type": "[]bool, [2]*string"}),
+		tag("F2", 16, "m", F{"access": "public", "ctype": "Struct", "end": "17", "signature": "()", "struct": "Struct", "type": "bool"}),
+		tag("NewTestEmbed", 24, "f", F{"access": "public", "ctype": "TestEmbed", "end": "25", "signature": "()", "type": "TestEmbed"}),
+		tag("NewStruct2", 30, "f", F{"access": "public", "ctype": "Struct2", "end": "31", "signature": "()", "type": "*Struct2, error"}),
+		tag("Dial", 33, "f", F{"access": "public", "ctype": "Connection", "end": "34", "signature": "()", "type": "*Connection, error"}),
+		tag("Dial2", 39, "f", F{"access": "public", "ctype": "Connection", "end": "40", "signature": "()", "type": "*Connection, *Struct2"}),
+		tag("Dial3", 42, "f", F{"access": "public", "end": "43", "signature": "()", "type": "*Connection, *Connection"}),
 	}},
 	{filename: "tests/type.go-src", tags: []Tag{
 		tag("Test", 1, "p", F{}),
-		tag("testType", 3, "t", F{"access": "private", "type": "int"}),
-		tag("testArrayType", 4, "t", F{"access": "private", "type": "[4]int"}),
-		tag("testSliceType", 5, "t", F{"access": "private", "type": "[]int"}),
-		tag("testPointerType", 6, "t", F{"access": "private", "type": "*string"}),
-		tag("testFuncType1", 7, "t", F{"access": "private", "type": "func()"}),
-		tag("testFuncType2", 8, "t", F{"access": "private", "type": "func(int) string"}),
-		tag("testMapType", 9, "t", F{"access": "private", "type": "map[string]bool"}),
-		tag("testChanType", 10, "t", F{"access": "private", "type": "chan bool"}),
+		tag("testType", 3, "t", F{"access": "private", "end": "3", "type": "int"}),
+		tag("testArrayType", 4, "t", F{"access": "private", "end": "4", "type": "[4]int"}),
+		tag("testSliceType", 5, "t", F{"access": "private", "end": "5", "type": "[]int"}),
+		tag("testPointerType", 6, "t", F{"access": "private", "end": "6", "type": "*string"}),
+		tag("testFuncType1", 7, "t", F{"access": "private", "end": "7", "type": "func()"}),
+		tag("testFuncType2", 8, "t", F{"access": "private", "end": "8", "type": "func(int) string"}),
+		tag("testMapType", 9, "t", F{"access": "private", "end": "9", "type": "map[string]bool"}),
+		tag("testChanType", 10, "t", F{"access": "private", "end": "10", "type": "chan bool"}),
 	}},
 	{filename: "tests/var.go-src", tags: []Tag{
 		tag("Test", 1, "p", F{}),
@@ -85,6 +85,17 @@ var testCases = []struct {
 		tag("C", 8, "v", F{"access": "public"}),
 		tag("D", 9, "v", F{"access": "public"}),
 	}},
+	{filename: "tests/generic.go-src", tags: []Tag{
+		tag("Test", 1, "p", F{}),
+		tag("head", 4, "w", F{"access": "private", "ctype": "List", "struct": "List", "type": "*node[T]"}),
+		tag("List", 3, "t", F{"access": "public", "end": "5", "signature": "[T any]", "type": "struct"}),
+		tag("Key", 8, "w", F{"access": "public", "ctype": "Pair", "struct": "Pair", "type": "K"}),
+		tag("Pair", 7, "t", F{"access": "public", "end": "9", "signature": "[K comparable, V any]", "type": "struct"}),
+		tag("Number", 20, "n", F{"access": "public", "end": "22", "type": "interface"}),
+		tag("Push", 11, "m", F{"access": "public", "ctype": "List", "end": "12", "signature": "(v T)", "struct": "List"}),
+		tag("Get", 14, "m", F{"access": "public", "ctype": "Pair", "end": "15", "signature": "()", "struct": "Pair", "type": "V"}),
+		tag("Map", 17, "f", F{"access": "public", "end": "18", "signature": "[S ~[]E, E any, R any](s S, f func(E) R)", "type": "[]R"}),
+	}},
 	{filename: "tests/simple.go-src", relative: true, basepath: "dir", tags: []Tag{
 		Tag{Name: "main", File: "../tests/simple.go-src", Address: "1", Type: "p", Fields: F{"line": "1"}},
 	}},
@@ -15,6 +15,11 @@ type Tag struct {
 	Address string
 	Type    TagType
 	Fields  map[TagField]string
+
+	Scope     string // name of the enclosing type, if any
+	ScopeKind string // kind of the enclosing type: struct, interface or type
+	Pattern   string // source line the tag was found on
+	Offset    int    // byte offset of the start of that line
 }
 
 // TagField represents a single field in a tag line.
@@ -28,6 +33,7 @@ const (
 	ReceiverType  TagField = "ctype"
 	Line          TagField = "line"
 	InterfaceType TagField = "ntype"
+	End           TagField = "end"
 )
 
 // TagType represents the type of a tag in a tag line.
@@ -48,6 +54,21 @@ const (
 	Function    TagType = "f"
 )
 
+// kindNames maps tag types to the long kind names used in the JSON output.
+var kindNames = map[TagType]string{
+	Package:     "package",
+	Import:      "import",
+	Constant:    "constant",
+	Variable:    "variable",
+	Type:        "type",
+	Interface:   "interface",
+	Field:       "field",
+	Embedded:    "embedded",
+	Method:      "method",
+	Constructor: "constructor",
+	Function:    "function",
+}
+
 // NewTag creates a new Tag.
 func NewTag(name, file string, line int, tagType TagType) Tag {
 	l := strconv.Itoa(line)
@@ -82,6 +103,9 @@ func (t Tag) String() string {
 		fields = append(fields, fmt.Sprintf("%s:%s", k, v))
 		i++
 	}
+	if len(t.Scope) > 0 {
+		fields = append(fields, fmt.Sprintf("%s:%s", t.ScopeKind, t.Scope))
+	}
 
 	sort.Sort(sort.StringSlice(fields))
 	b.WriteString(strings.Join(fields, "\t"))
@@ -0,0 +1,168 @@
+// Copyright (c) 2013 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package lint
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"path"
+	"sort"
+	"strconv"
+)
+
+// Fix applies the edits suggested for the problems to files, which maps file
+// names to their contents, as passed to LintFiles. The edits of a problem are
+// applied all together or not at all: a problem is left unfixed if any of its
+// edits overlaps an edit of a problem earlier in ps.
+//
+// Fix returns the new contents of the changed files, formatted with gofmt and
+// without the imports the edits made unused, and the problems left unfixed.
+func Fix(files map[string][]byte, ps []Problem) (map[string][]byte, []Problem, error) {
+	edits := make(map[string][]Edit)
+	var unfixed []Problem
+	for _, p := range ps {
+		if len(p.Edits) == 0 || !canApply(files, edits, p.Edits) {
+			unfixed = append(unfixed, p)
+			continue
+		}
+		for _, e := range p.Edits {
+			edits[e.Filename] = append(edits[e.Filename], e)
+		}
+	}
+
+	out := make(map[string][]byte)
+	for filename, es := range edits {
+		src := applyEdits(files[filename], es)
+		src, err := removeUnusedImports(filename, files[filename], src)
+		if err == nil {
+			src, err = format.Source(src)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: fixes produced invalid code: %v", filename, err)
+		}
+		out[filename] = src
+	}
+	return out, unfixed, nil
+}
+
+// canApply reports whether the edits are within their files and don't overlap
+// the edits already accepted.
+func canApply(files map[string][]byte, accepted map[string][]Edit, edits []Edit) bool {
+	for i, e := range edits {
+		src, ok := files[e.Filename]
+		if !ok || e.Offset < 0 || e.Offset > e.End || e.End > len(src) {
+			return false
+		}
+		for _, a := range append(accepted[e.Filename], edits[:i]...) {
+			if a.Filename == e.Filename && overlap(a, e) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// overlap reports whether a and b change the same bytes,
+// or insert text at the same place.
+func overlap(a, b Edit) bool {
+	if a.Offset == b.Offset {
+		return true
+	}
+	return a.Offset < b.End && b.Offset < a.End
+}
+
+// applyEdits returns src with the non-overlapping edits applied.
+func applyEdits(src []byte, edits []Edit) []byte {
+	edits = append([]Edit(nil), edits...)
+	sort.Sort(byOffset(edits))
+
+	var buf bytes.Buffer
+	last := 0
+	for _, e := range edits {
+		buf.Write(src[last:e.Offset])
+		buf.WriteString(e.New)
+		last = e.End
+	}
+	buf.Write(src[last:])
+	return buf.Bytes()
+}
+
+// removeUnusedImports removes from src the imports which were used in orig
+// but aren't any longer, such as "errors" once errors.New(fmt.Sprintf(...))
+// is replaced with fmt.Errorf(...).
+func removeUnusedImports(filename string, orig, src []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	of, err := parser.ParseFile(fset, filename, orig, 0)
+	if err != nil {
+		return nil, err
+	}
+	f, err := parser.ParseFile(fset, filename, src, 0)
+	if err != nil {
+		return nil, err
+	}
+	usedBefore, used := unresolved(of), unresolved(f)
+
+	var edits []Edit
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.IMPORT {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			name := importName(spec.(*ast.ImportSpec))
+			if !usedBefore[name] || used[name] {
+				continue
+			}
+			// Delete the whole declaration if this is its only spec,
+			// or else the lines of the spec.
+			var n ast.Node = spec
+			if len(gd.Specs) == 1 {
+				n = gd
+			}
+			start, end := fset.Position(n.Pos()).Offset, fset.Position(n.End()).Offset
+			for start > 0 && (src[start-1] == ' ' || src[start-1] == '\t') {
+				start--
+			}
+			if i := bytes.IndexByte(src[end:], '\n'); i >= 0 {
+				end += i + 1
+			} else {
+				end = len(src)
+			}
+			edits = append(edits, Edit{Filename: filename, Offset: start, End: end})
+		}
+	}
+	return applyEdits(src, edits), nil
+}
+
+// unresolved returns the identifiers the parser couldn't resolve in f,
+// which include the names of the imported packages in use.
+func unresolved(f *ast.File) map[string]bool {
+	m := make(map[string]bool)
+	for _, id := range f.Unresolved {
+		m[id.Name] = true
+	}
+	return m
+}
+
+// importName returns the name an import is referred to by. Without a type
+// checker it has to guess that a package is named after the last element of
+// its import path, which is only used to compare uses before and after the
+// edits, so a wrong guess leaves the import alone.
+func importName(spec *ast.ImportSpec) string {
+	if spec.Name != nil {
+		return spec.Name.Name
+	}
+	p, err := strconv.Unquote(spec.Path.Value)
+	if err != nil {
+		return ""
+	}
+	return path.Base(p)
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2013 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/golang/lint"
+)
+
+// configName is the name of the configuration file looked for
+// in the current directory and its parents.
+const configName = ".golint.json"
+
+// config is the contents of a configuration file, such as
+//
+//	{
+//		"min_confidence": 0.5,
+//		"rules": {"exported": false, "names": false}
+//	}
+type config struct {
+	// MinConfidence, if set, is the default of the -min_confidence flag.
+	MinConfidence *float64 `json:"min_confidence"`
+	// Rules maps rule IDs to whether they are checked.
+	// Rules not listed are checked.
+	Rules map[string]bool `json:"rules"`
+}
+
+// findConfig returns the path of the configuration file in dir
+// or its closest parent, or "" if there is none.
+func findConfig(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		filename := filepath.Join(dir, configName)
+		if exists(filename) {
+			return filename
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+func readConfig(filename string) (*config, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	c := new(config)
+	if err := dec.Decode(c); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+
+	known := make(map[string]bool)
+	for _, r := range lint.Rules() {
+		known[r.ID] = true
+	}
+	for id := range c.Rules {
+		if !known[id] {
+			return nil, fmt.Errorf("%s: unknown rule %q", filename, id)
+		}
+	}
+	return c, nil
+}
+
+// disabled returns the set of rules the configuration turns off.
+func (c *config) disabled() map[string]bool {
+	m := make(map[string]bool)
+	for id, on := range c.Rules {
+		if !on {
+			m[id] = true
+		}
+	}
+	return m
+}
+
+// loadConfig reads the configuration file named by the -config flag,
+// or the one found for the current directory, and applies it.
+func loadConfig() {
+	filename := *configFile
+	if filename == "" {
+		if filename = findConfig("."); filename == "" {
+			return
+		}
+	}
+	c, err := readConfig(filename)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	linter.Disabled = c.disabled()
+	confidenceSet := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "min_confidence" {
+			confidenceSet = true
+		}
+	})
+	if c.MinConfidence != nil && !confidenceSet {
+		*minConfidence = *c.MinConfidence
+	}
+}
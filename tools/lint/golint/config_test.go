@@ -0,0 +1,179 @@
+// Copyright (c) 2013 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/golang/lint"
+)
+
+// tempTree creates a temporary directory holding files, whose names
+// are slash-separated, and returns it.
+func tempTree(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "golint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Resolve symlinks so that paths compare equal to those of filepath.Abs
+	// after a chdir.
+	if dir, err = filepath.EvalSymlinks(dir); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestFindConfig(t *testing.T) {
+	dir := tempTree(t, map[string]string{
+		"a/.golint.json":   "{}",
+		"a/b/c/x.go":       "package c",
+		"a/d/.golint.json": "{}",
+		"e/y.go":           "package e",
+	})
+	defer os.RemoveAll(dir)
+
+	for _, test := range []struct {
+		dir, want string // want is "" if there is no configuration
+	}{
+		{"a", "a/.golint.json"},
+		{"a/b/c", "a/.golint.json"},
+		{"a/d", "a/d/.golint.json"}, // the closest one wins
+		{"e", ""},
+	} {
+		got := findConfig(filepath.Join(dir, test.dir))
+		if test.want == "" {
+			// A configuration above the temporary directory may be found.
+			if strings.HasPrefix(got, dir) {
+				t.Errorf("findConfig(%q) = %q, want none", test.dir, got)
+			}
+		} else if want := filepath.Join(dir, filepath.FromSlash(test.want)); got != want {
+			t.Errorf("findConfig(%q) = %q, want %q", test.dir, got, want)
+		}
+	}
+}
+
+func TestReadConfig(t *testing.T) {
+	dir := tempTree(t, map[string]string{
+		"ok.json":     `{"min_confidence": 0.5, "rules": {"exported": false, "names": true}}`,
+		"empty.json":  `{}`,
+		"field.json":  `{"min_confidence": 0.5, "rulez": {}}`,
+		"rule.json":   `{"rules": {"exported": false, "no-such-rule": false}}`,
+		"syntax.json": `{"rules": `,
+		"type.json":   `{"min_confidence": "high"}`,
+	})
+	defer os.RemoveAll(dir)
+
+	c, err := readConfig(filepath.Join(dir, "ok.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.MinConfidence == nil || *c.MinConfidence != 0.5 {
+		t.Errorf("min_confidence = %v, want 0.5", c.MinConfidence)
+	}
+	if got, want := c.disabled(), map[string]bool{"exported": true}; !reflect.DeepEqual(got, want) {
+		t.Errorf("disabled() = %v, want %v", got, want)
+	}
+
+	c, err = readConfig(filepath.Join(dir, "empty.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.MinConfidence != nil || len(c.disabled()) != 0 {
+		t.Errorf("empty configuration = %+v, disables %v", c, c.disabled())
+	}
+
+	for _, test := range []struct {
+		file, err string
+	}{
+		{"field.json", `unknown field "rulez"`},
+		{"rule.json", `unknown rule "no-such-rule"`},
+		{"syntax.json", "unexpected EOF"},
+		{"type.json", "cannot unmarshal string"},
+		{"missing.json", "no such file"},
+	} {
+		_, err := readConfig(filepath.Join(dir, test.file))
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("readConfig(%s) = %v, want error containing %q", test.file, err, test.err)
+		}
+	}
+}
+
+// withFlags runs f with the command line flags parsed from args,
+// and restores the flags and the linter afterwards.
+func withFlags(t *testing.T, args []string, f func()) {
+	oldCommandLine, oldLinter := flag.CommandLine, linter
+	oldConfidence, oldConfig := *minConfidence, *configFile
+	defer func() {
+		flag.CommandLine, linter = oldCommandLine, oldLinter
+		*minConfidence, *configFile = oldConfidence, oldConfig
+	}()
+
+	fs := flag.NewFlagSet("golint", flag.ContinueOnError)
+	fs.Float64Var(minConfidence, "min_confidence", 0.8, "")
+	fs.StringVar(configFile, "config", "", "")
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	flag.CommandLine, linter = fs, new(lint.Linter)
+	f()
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := tempTree(t, map[string]string{
+		".golint.json":    `{"min_confidence": 0.3, "rules": {"exported": false}}`,
+		"sub/x.go":        "package sub",
+		"other/conf.json": `{"rules": {"names": false, "exported": true}}`,
+	})
+	defer os.RemoveAll(dir)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(filepath.Join(dir, "sub")); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	for _, test := range []struct {
+		args       []string
+		confidence float64
+		disabled   map[string]bool
+	}{
+		// The configuration of the parent directory sets the default confidence.
+		{nil, 0.3, map[string]bool{"exported": true}},
+		// An explicit flag wins over the configuration.
+		{[]string{"-min_confidence", "0.9"}, 0.9, map[string]bool{"exported": true}},
+		{[]string{"-min_confidence", "0.8"}, 0.8, map[string]bool{"exported": true}},
+		// -config replaces the configuration found, with no default confidence.
+		{[]string{"-config", filepath.Join(dir, "other", "conf.json")}, 0.8, map[string]bool{"names": true}},
+	} {
+		withFlags(t, test.args, func() {
+			loadConfig()
+			if *minConfidence != test.confidence {
+				t.Errorf("%q: min_confidence = %v, want %v", test.args, *minConfidence, test.confidence)
+			}
+			if !reflect.DeepEqual(linter.Disabled, test.disabled) {
+				t.Errorf("%q: disabled rules = %v, want %v", test.args, linter.Disabled, test.disabled)
+			}
+		})
+	}
+}
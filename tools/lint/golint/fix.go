@@ -0,0 +1,99 @@
+// Copyright (c) 2013 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"sort"
+
+	"github.com/golang/lint"
+)
+
+// fixFiles applies the fixes of the problems, writing the changed files
+// with -fix and printing their differences with -diff.
+// It returns the problems left unfixed.
+func fixFiles(files map[string][]byte, ps []lint.Problem) []lint.Problem {
+	out, unfixed, err := lint.Fix(files, ps)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return ps
+	}
+
+	var filenames []string
+	for filename := range out {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+	for _, filename := range filenames {
+		if *diff {
+			d, err := diffFile(filename, files[filename], out[filename])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "computing diff: %v\n", err)
+				continue
+			}
+			os.Stdout.Write(d)
+		}
+		if *fix {
+			if err := writeFile(filename, out[filename]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}
+	}
+	return unfixed
+}
+
+// writeFile replaces the contents of the file, keeping its permissions.
+func writeFile(filename string, src []byte) error {
+	fi, err := os.Stat(filename)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, src, fi.Mode().Perm())
+}
+
+// diffFile returns the unified diff of the old and new contents of filename,
+// as computed by diff(1), in the way gofmt -d does.
+func diffFile(filename string, b1, b2 []byte) ([]byte, error) {
+	f1, err := writeTempFile("golint", b1)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f1)
+
+	f2, err := writeTempFile("golint", b2)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f2)
+
+	data, err := exec.Command("diff", "-u", "-L", filename+".orig", "-L", filename, f1, f2).CombinedOutput()
+	if len(data) > 0 {
+		// diff exits with a non-zero status when the files don't match.
+		// Ignore that failure as long as we get output.
+		err = nil
+	}
+	return data, err
+}
+
+func writeTempFile(prefix string, data []byte) (string, error) {
+	file, err := ioutil.TempFile("", prefix)
+	if err != nil {
+		return "", err
+	}
+	_, err = file.Write(data)
+	if err1 := file.Close(); err == nil {
+		err = err1
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
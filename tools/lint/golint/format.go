@@ -0,0 +1,203 @@
+// Copyright (c) 2013 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+
+	"github.com/golang/lint"
+)
+
+// printProblem prints p in the default format, as understood by
+// Vim's quickfix mode and Emacs' compilation mode.
+func printProblem(w io.Writer, p lint.Problem) {
+	fmt.Fprintf(w, "%v: %s\n", p.Position, p.Text)
+}
+
+// jsonProblem is the JSON form of a lint.Problem.
+type jsonProblem struct {
+	Rule       string     `json:"rule"`
+	File       string     `json:"file"`
+	Line       int        `json:"line"`
+	Column     int        `json:"column"`
+	Text       string     `json:"text"`
+	Link       string     `json:"link,omitempty"`
+	Category   string     `json:"category,omitempty"`
+	Confidence float64    `json:"confidence"`
+	Edits      []jsonEdit `json:"edits,omitempty"`
+}
+
+// jsonEdit is the JSON form of a lint.Edit.
+type jsonEdit struct {
+	File   string `json:"file"`
+	Offset int    `json:"offset"`
+	End    int    `json:"end"`
+	New    string `json:"new"`
+}
+
+// writeJSON writes the problems as a JSON array.
+func writeJSON(w io.Writer, ps []lint.Problem) error {
+	out := make([]jsonProblem, 0, len(ps))
+	for _, p := range ps {
+		var edits []jsonEdit
+		for _, e := range p.Edits {
+			edits = append(edits, jsonEdit{e.Filename, e.Offset, e.End, e.New})
+		}
+		out = append(out, jsonProblem{
+			Rule:       p.Rule,
+			File:       p.Position.Filename,
+			Line:       p.Position.Line,
+			Column:     p.Position.Column,
+			Text:       p.Text,
+			Link:       p.Link,
+			Category:   p.Category,
+			Confidence: p.Confidence,
+			Edits:      edits,
+		})
+	}
+	return writeIndented(w, out)
+}
+
+// The subset of SARIF 2.1.0 golint produces, see
+// https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html.
+
+const (
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion = "2.1.0"
+)
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+	HelpURI          string       `json:"helpUri,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID     string          `json:"ruleId"`
+	RuleIndex  int             `json:"ruleIndex"`
+	Level      string          `json:"level"`
+	Message    sarifMessage    `json:"message"`
+	Locations  []sarifLocation `json:"locations"`
+	Properties sarifProperties `json:"properties"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+type sarifProperties struct {
+	Confidence float64 `json:"confidence"`
+	Category   string  `json:"category,omitempty"`
+}
+
+// writeSARIF writes the problems as a SARIF log with a single run.
+func writeSARIF(w io.Writer, ps []lint.Problem) error {
+	driver := sarifDriver{
+		Name:           "golint",
+		InformationURI: "https://github.com/golang/lint",
+	}
+	index := make(map[string]int)
+	for i, r := range lint.Rules() {
+		index[r.ID] = i
+		driver.Rules = append(driver.Rules, sarifRule{
+			ID:               r.ID,
+			ShortDescription: sarifMessage{r.Doc},
+			HelpURI:          r.Link,
+		})
+	}
+
+	results := make([]sarifResult, 0, len(ps))
+	for _, p := range ps {
+		text := p.Text
+		if p.Link != "" {
+			text += " (see " + p.Link + ")"
+		}
+		results = append(results, sarifResult{
+			RuleID:    p.Rule,
+			RuleIndex: index[p.Rule],
+			Level:     "warning",
+			Message:   sarifMessage{text},
+			Locations: []sarifLocation{{sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{fileURI(p.Position.Filename)},
+				Region:           sarifRegion{p.Position.Line, p.Position.Column},
+			}}},
+			Properties: sarifProperties{p.Confidence, p.Category},
+		})
+	}
+
+	return writeIndented(w, sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs:    []sarifRun{{Tool: sarifTool{driver}, Results: results}},
+	})
+}
+
+// fileURI returns the URI reference of filename: relative paths stay
+// relative, so that they resolve against the repository root.
+func fileURI(filename string) string {
+	u := url.URL{Path: filepath.ToSlash(filename)}
+	if filepath.IsAbs(filename) {
+		u.Scheme = "file"
+		if u.Path[0] != '/' {
+			u.Path = "/" + u.Path // Windows drive letter
+		}
+	}
+	return u.String()
+}
+
+func writeIndented(w io.Writer, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	_, err = w.Write(b)
+	return err
+}
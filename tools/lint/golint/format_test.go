@@ -0,0 +1,96 @@
+// Copyright (c) 2013 The Go Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"bytes"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"runtime"
+	"testing"
+
+	"github.com/golang/lint"
+)
+
+// testProblems are the problems written to the golden files.
+var testProblems = []lint.Problem{
+	{
+		Position:   token.Position{Filename: "a/a.go", Line: 3, Column: 5},
+		Text:       "should replace x += 1 with x++",
+		Confidence: 0.8,
+		Category:   "unary-op",
+		Rule:       "increment-decrement",
+		Edits:      []lint.Edit{{Filename: "a/a.go", Offset: 20, End: 26, New: "x++"}},
+	},
+	{
+		Position:   token.Position{Filename: "a/b c.go", Line: 10, Column: 1},
+		Text:       "exported function F should have comment or be unexported",
+		Link:       "https://golang.org/wiki/CodeReviewComments#doc-comments",
+		Confidence: 1,
+		Category:   "comments",
+		Rule:       "exported",
+	},
+}
+
+func TestWriteFormats(t *testing.T) {
+	for _, test := range []struct {
+		golden string
+		write  func(io.Writer, []lint.Problem) error
+	}{
+		{"testdata/problems.json.golden", writeJSON},
+		{"testdata/problems.sarif.golden", writeSARIF},
+	} {
+		want, err := ioutil.ReadFile(test.golden)
+		if err != nil {
+			t.Fatalf("Failed reading golden file: %v", err)
+		}
+		var buf bytes.Buffer
+		if err := test.write(&buf, testProblems); err != nil {
+			t.Errorf("Writing %s: %v", test.golden, err)
+			continue
+		}
+		if got := buf.Bytes(); !bytes.Equal(got, want) {
+			t.Errorf("Writing %s:\n got:\n%s\nwant:\n%s", test.golden, got, want)
+		}
+	}
+}
+
+func TestWriteNoProblems(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "[]\n"; got != want {
+		t.Errorf("writeJSON(nil) = %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	if err := writeSARIF(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte(`"results": []`)) {
+		t.Errorf("writeSARIF(nil) lacks an empty results array:\n%s", buf.Bytes())
+	}
+}
+
+func TestFileURI(t *testing.T) {
+	tests := []struct {
+		filename, want string
+	}{
+		{"a.go", "a.go"},
+		{"a/b c.go", "a/b%20c.go"},
+	}
+	if runtime.GOOS != "windows" {
+		tests = append(tests, struct{ filename, want string }{"/src/a.go", "file:///src/a.go"})
+	}
+	for _, test := range tests {
+		if got := fileURI(test.filename); got != test.want {
+			t.Errorf("fileURI(%q) = %q, want %q", test.filename, got, test.want)
+		}
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"flag"
 	"fmt"
 	"go/build"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -19,7 +20,20 @@ import (
 	"github.com/golang/lint"
 )
 
-var minConfidence = flag.Float64("min_confidence", 0.8, "minimum confidence of a problem to print it")
+var (
+	minConfidence = flag.Float64("min_confidence", 0.8, "minimum confidence of a problem to print it")
+	configFile    = flag.String("config", "", "configuration `file`; defaults to "+configName+" in the current directory or its closest parent")
+	format        = flag.String("format", "text", "output `format`: text, json or sarif")
+	fix           = flag.Bool("fix", false, "apply the suggested fixes to the source files and print the remaining problems")
+	diff          = flag.Bool("diff", false, "print the suggested fixes as a unified diff instead of the problems; requires the text format")
+)
+
+var (
+	linter = new(lint.Linter)
+	// problems collects the problems for the json and sarif formats,
+	// which are written once all packages are linted.
+	problems []lint.Problem
+)
 
 func usage() {
 	fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
@@ -35,6 +49,23 @@ func main() {
 	flag.Usage = usage
 	flag.Parse()
 
+	var write func(io.Writer, []lint.Problem) error
+	switch *format {
+	case "text":
+	case "json":
+		write = writeJSON
+	case "sarif":
+		write = writeSARIF
+	default:
+		fmt.Fprintf(os.Stderr, "unknown output format %q\n", *format)
+		os.Exit(2)
+	}
+	if *diff && write != nil {
+		fmt.Fprintf(os.Stderr, "-diff cannot be combined with -format %s\n", *format)
+		os.Exit(2)
+	}
+	loadConfig()
+
 	switch flag.NArg() {
 	case 0:
 		lintDir(".")
@@ -56,6 +87,13 @@ func main() {
 	default:
 		lintFiles(flag.Args()...)
 	}
+
+	if write != nil {
+		if err := write(os.Stdout, problems); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
 }
 
 func isDir(filename string) bool {
@@ -79,15 +117,29 @@ func lintFiles(filenames ...string) {
 		files[filename] = src
 	}
 
-	l := new(lint.Linter)
-	ps, err := l.LintFiles(files)
+	ps, err := linter.LintFiles(files)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "%v\n", err)
 		return
 	}
+	confident := ps[:0]
 	for _, p := range ps {
 		if p.Confidence >= *minConfidence {
-			fmt.Printf("%v: %s\n", p.Position, p.Text)
+			confident = append(confident, p)
+		}
+	}
+	ps = confident
+	if *fix || *diff {
+		ps = fixFiles(files, ps)
+	}
+	if *diff {
+		return
+	}
+	for _, p := range ps {
+		if *format == "text" {
+			printProblem(os.Stdout, p)
+		} else {
+			problems = append(problems, p)
 		}
 	}
 }
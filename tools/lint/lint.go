@@ -29,6 +29,8 @@ const styleGuideBase = "http://golang.org/s/comments"
 
 // A Linter lints Go source code.
 type Linter struct {
+	// Disabled is the set of IDs of the rules that are not checked.
+	Disabled map[string]bool
 }
 
 // Problem represents a problem in some source code.
@@ -39,6 +41,16 @@ type Problem struct {
 	Confidence float64        // a value in (0,1] estimating the confidence in this problem's correctness
 	LineText   string         // the source line
 	Category   string         // a short name for the general category of the problem
+	Rule       string         // the ID of the rule that found the problem, see Rules
+	Edits      []Edit         // (optional) the edits that fix the problem, see Fix
+}
+
+// An Edit is a change of a source file suggested to fix a problem.
+// It replaces the bytes between Offset and End with New.
+type Edit struct {
+	Filename    string
+	Offset, End int
+	New         string
 }
 
 func (p *Problem) String() string {
@@ -80,9 +92,15 @@ func (l *Linter) LintFiles(files map[string][]byte) ([]Problem, error) {
 	if len(files) == 0 {
 		return nil, nil
 	}
+	for id := range l.Disabled {
+		if _, ok := ruleIndex[id]; !ok {
+			return nil, fmt.Errorf("unknown rule %q", id)
+		}
+	}
 	pkg := &pkg{
-		fset:  token.NewFileSet(),
-		files: make(map[string]*file),
+		fset:     token.NewFileSet(),
+		files:    make(map[string]*file),
+		disabled: l.Disabled,
 	}
 	var pkgName string
 	for filename, src := range files {
@@ -118,12 +136,22 @@ type pkg struct {
 	sortable map[string]bool
 	// main is whether this is a "main" package.
 	main bool
+	// typesOK is whether type checking succeeded,
+	// so that typesInfo records all uses of all objects.
+	typesOK bool
+
+	// disabled is the set of rules not to check.
+	disabled map[string]bool
+	// rule is the ID of the rule being checked.
+	rule string
 
 	problems []Problem
 }
 
 func (p *pkg) lint() []Problem {
-	if err := p.typeCheck(); err != nil {
+	err := p.typeCheck()
+	p.typesOK = err == nil
+	if err != nil {
 		/* TODO(dsymonds): Consider reporting these errors when golint operates on entire packages.
 		if e, ok := err.(types.Error); ok {
 			pos := p.fset.Position(e.Pos)
@@ -148,6 +176,7 @@ func (p *pkg) lint() []Problem {
 	for _, f := range p.files {
 		f.lint()
 	}
+	p.suppress()
 
 	sort.Sort(byPosition(p.problems))
 
@@ -161,33 +190,79 @@ type file struct {
 	fset     *token.FileSet
 	src      []byte
 	filename string
+
+	// ignores are the suppressions of the file's lint directives.
+	ignores []ignore
 }
 
 func (f *file) isTest() bool { return strings.HasSuffix(f.filename, "_test.go") }
 
 func (f *file) lint() {
-	f.lintPackageComment()
-	f.lintImports()
-	f.lintBlankImports()
-	f.lintExported()
-	f.lintNames()
-	f.lintVarDecls()
-	f.lintElses()
-	f.lintRanges()
-	f.lintErrorf()
-	f.lintErrors()
-	f.lintErrorStrings()
-	f.lintReceiverNames()
-	f.lintIncDec()
-	f.lintMake()
-	f.lintErrorReturn()
-	f.lintUnexportedReturn()
+	for _, c := range checks {
+		// The directives are needed even if their problems aren't reported.
+		if f.pkg.disabled[c.ID] && c.ID != "lint-directive" {
+			continue
+		}
+		f.pkg.rule = c.ID
+		c.check(f)
+	}
+}
+
+// A Rule describes one of the checks made by the linter.
+type Rule struct {
+	ID   string // a stable, short name of the rule
+	Doc  string // a one line description of the rule
+	Link string // (optional) the link to the style guide for the rule
+}
+
+type check struct {
+	Rule
+	check func(*file)
+}
+
+// checks lists the rules in the order they are checked.
+var checks = []check{
+	{Rule{"lint-directive", "lint directives should be well-formed and give a reason", ""}, (*file).lintDirectives},
+	{Rule{"package-comments", "packages should have a package comment of the form \"Package x ...\"", styleGuideBase + "#Package_Comments"}, (*file).lintPackageComment},
+	{Rule{"dot-imports", "dot imports should not be used outside of tests", styleGuideBase + "#Import_Dot"}, (*file).lintImports},
+	{Rule{"blank-imports", "blank imports should be in a main or test package, or have a comment justifying them", ""}, (*file).lintBlankImports},
+	{Rule{"exported", "exported names should have doc comments of the right form and should not stutter", docCommentsLink}, (*file).lintExported},
+	{Rule{"names", "names should use MixedCaps and the proper case for initialisms", styleGuideBase + "#Mixed_Caps"}, (*file).lintNames},
+	{Rule{"var-declaration", "var declarations should not have redundant types or zero values", ""}, (*file).lintVarDecls},
+	{Rule{"indent-error-flow", "if blocks ending with a return should not be followed by an else", styleGuideBase + "#Indent_Error_Flow"}, (*file).lintElses},
+	{Rule{"range", "range loops should omit unused second values", ""}, (*file).lintRanges},
+	{Rule{"errorf", "errors.New(fmt.Sprintf(...)) should be fmt.Errorf(...)", ""}, (*file).lintErrorf},
+	{Rule{"error-naming", "error variables should be named errFoo or ErrFoo", ""}, (*file).lintErrors},
+	{Rule{"error-strings", "error strings should not be capitalized or end with punctuation", styleGuideBase + "#Error_Strings"}, (*file).lintErrorStrings},
+	{Rule{"receiver-naming", "receiver names should be short, consistent and not generic", styleGuideBase + "#Receiver_Names"}, (*file).lintReceiverNames},
+	{Rule{"increment-decrement", "x += 1 and x -= 1 should be x++ and x--", ""}, (*file).lintIncDec},
+	{Rule{"make", "empty slices should be declared with var instead of make", ""}, (*file).lintMake},
+	{Rule{"error-return", "error should be the last of multiple return values", ""}, (*file).lintErrorReturn},
+	{Rule{"unexported-return", "exported functions should not return unexported types", ""}, (*file).lintUnexportedReturn},
+}
+
+// ruleIndex maps rule IDs to their index in checks.
+var ruleIndex = make(map[string]int)
+
+func init() {
+	for i, c := range checks {
+		ruleIndex[c.ID] = i
+	}
+}
+
+// Rules returns the rules the linter checks, in a fixed order.
+func Rules() []Rule {
+	rules := make([]Rule, len(checks))
+	for i, c := range checks {
+		rules[i] = c.Rule
+	}
+	return rules
 }
 
 type link string
 type category string
 
-// The variadic arguments may start with link and category types,
+// The variadic arguments may start with link, category and []Edit types,
 // and must end with a format string and any arguments.
 func (f *file) errorf(n ast.Node, confidence float64, args ...interface{}) {
 	pos := f.fset.Position(n.Pos())
@@ -198,9 +273,13 @@ func (f *file) errorf(n ast.Node, confidence float64, args ...interface{}) {
 }
 
 func (p *pkg) errorfAt(pos token.Position, confidence float64, args ...interface{}) {
+	if p.disabled[p.rule] {
+		return
+	}
 	problem := Problem{
 		Position:   pos,
 		Confidence: confidence,
+		Rule:       p.rule,
 	}
 	if pos.Filename != "" {
 		// The file might not exist in our mapping if a //line directive was encountered.
@@ -216,6 +295,8 @@ argLoop:
 			problem.Link = string(v)
 		case category:
 			problem.Category = string(v)
+		case []Edit:
+			problem.Edits = v
 		default:
 			break argLoop
 		}
@@ -330,6 +411,79 @@ func (f *file) isMain() bool {
 	return false
 }
 
+// An ignore suppresses the problems found by some rules,
+// either on a single line or in the whole file.
+type ignore struct {
+	rules map[string]bool
+	line  int // 0 for the whole file
+}
+
+// lintDirectives parses the lint directives in the file's comments:
+//
+//	//lint:ignore rule1[,rule2...] reason
+//	//lint:file-ignore rule1[,rule2...] reason
+//
+// The first suppresses the named rules on the line of the comment, or on the
+// following line if the comment is on a line of its own. The second suppresses
+// them in the whole file. It complains about malformed directives, such as
+// those naming unknown rules or giving no reason.
+func (f *file) lintDirectives() {
+	const prefix = "//lint:"
+	for _, cg := range f.f.Comments {
+		for _, c := range cg.List {
+			if !strings.HasPrefix(c.Text, prefix) {
+				continue
+			}
+			fields := strings.Fields(c.Text[len(prefix):])
+			if len(fields) == 0 || (fields[0] != "ignore" && fields[0] != "file-ignore") {
+				f.errorf(c, 1, category("comments"), "unknown lint directive %s", c.Text)
+				continue
+			}
+			if len(fields) < 3 {
+				f.errorf(c, 1, category("comments"), "lint directive should be of the form \"%s%s rule reason\"; a reason is required", prefix, fields[0])
+				continue
+			}
+
+			ig := ignore{rules: make(map[string]bool)}
+			for _, id := range strings.Split(fields[1], ",") {
+				if _, ok := ruleIndex[id]; !ok {
+					f.errorf(c, 1, category("comments"), "unknown rule %q in lint directive", id)
+					continue
+				}
+				ig.rules[id] = true
+			}
+			if fields[0] == "ignore" {
+				pos := f.fset.Position(c.Pos())
+				ig.line = pos.Line
+				if line := srcLine(f.src, pos); strings.TrimSpace(line[:pos.Column-1]) == "" {
+					ig.line++
+				}
+			}
+			f.ignores = append(f.ignores, ig)
+		}
+	}
+}
+
+// suppress removes the problems suppressed by lint directives.
+func (p *pkg) suppress() {
+	problems := p.problems[:0]
+	for _, prob := range p.problems {
+		if f, ok := p.files[prob.Position.Filename]; !ok || !f.ignored(prob) {
+			problems = append(problems, prob)
+		}
+	}
+	p.problems = problems
+}
+
+func (f *file) ignored(p Problem) bool {
+	for _, ig := range f.ignores {
+		if ig.rules[p.Rule] && (ig.line == 0 || ig.line == p.Position.Line) {
+			return true
+		}
+	}
+	return false
+}
+
 // lintPackageComment checks package comments. It complains if
 // there is no package comment, or if it is not of the right form.
 // This has a notable false positive in that a package comment
@@ -487,11 +641,12 @@ func (f *file) lintNames() {
 		if id.Name == should {
 			return
 		}
+		edits := f.pkg.rename(id, should)
 		if len(id.Name) > 2 && strings.Contains(id.Name[1:], "_") {
-			f.errorf(id, 0.9, link("http://golang.org/doc/effective_go.html#mixed-caps"), category("naming"), "don't use underscores in Go names; %s %s should be %s", thing, id.Name, should)
+			f.errorf(id, 0.9, link("http://golang.org/doc/effective_go.html#mixed-caps"), category("naming"), edits, "don't use underscores in Go names; %s %s should be %s", thing, id.Name, should)
 			return
 		}
-		f.errorf(id, 0.8, link(styleGuideBase+"#Initialisms"), category("naming"), "%s %s should be %s", thing, id.Name, should)
+		f.errorf(id, 0.8, link(styleGuideBase+"#Initialisms"), category("naming"), edits, "%s %s should be %s", thing, id.Name, should)
 	}
 	checkList := func(fl *ast.FieldList, thing string) {
 		if fl == nil {
@@ -878,7 +1033,8 @@ func (f *file) lintVarDecls() {
 				zero = true
 			}
 			if zero {
-				f.errorf(rhs, 0.9, category("zero-value"), "should drop = %s from declaration of var %s; it is the zero value", f.render(rhs), v.Names[0])
+				edits := []Edit{f.edit(v.Type.End(), rhs.End(), "")}
+				f.errorf(rhs, 0.9, category("zero-value"), edits, "should drop = %s from declaration of var %s; it is the zero value", f.render(rhs), v.Names[0])
 				return false
 			}
 			lhsTyp := f.pkg.typeOf(v.Type)
@@ -903,7 +1059,8 @@ func (f *file) lintVarDecls() {
 			if defType, ok := f.isUntypedConst(rhs, scope); ok && !isIdent(v.Type, defType) {
 				return false
 			}
-			f.errorf(v.Type, 0.8, category("type-inference"), "should omit type %s from declaration of var %s; it will be inferred from the right-hand side", f.render(v.Type), v.Names[0])
+			edits := []Edit{f.edit(v.Names[0].End(), v.Type.End(), "")}
+			f.errorf(v.Type, 0.8, category("type-inference"), edits, "should omit type %s from declaration of var %s; it will be inferred from the right-hand side", f.render(v.Type), v.Names[0])
 			return false
 		}
 		return true
@@ -948,7 +1105,13 @@ func (f *file) lintElses() {
 			if shortDecl {
 				extra = " (move short variable declaration to its own line if necessary)"
 			}
-			f.errorf(ifStmt.Else, 1, link(styleGuideBase+"#Indent_Error_Flow"), category("indent"), "if block ends with a return statement, so drop this else and outdent its block"+extra)
+			var edits []Edit
+			if elseBlock := ifStmt.Else.(*ast.BlockStmt); !shortDecl && !declares(elseBlock) {
+				// The statements are reindented by gofmt once the edit is applied.
+				body := f.src[f.offset(elseBlock.Lbrace)+1 : f.offset(elseBlock.Rbrace)]
+				edits = []Edit{f.edit(ifStmt.Body.End(), elseBlock.End(), "\n"+strings.TrimSpace(string(body)))}
+			}
+			f.errorf(ifStmt.Else, 1, link(styleGuideBase+"#Indent_Error_Flow"), category("indent"), edits, "if block ends with a return statement, so drop this else and outdent its block"+extra)
 		}
 		return true
 	})
@@ -970,7 +1133,8 @@ func (f *file) lintRanges() {
 			return true
 		}
 
-		f.errorf(rs.Value, 1, category("range-loop"), "should omit 2nd value from range; this loop is equivalent to `for %s %s range ...`", f.render(rs.Key), rs.Tok)
+		edits := []Edit{f.edit(rs.Key.End(), rs.Value.End(), "")}
+		f.errorf(rs.Value, 1, category("range-loop"), edits, "should omit 2nd value from range; this loop is equivalent to `for %s %s range ...`", f.render(rs.Key), rs.Tok)
 		return true
 	})
 }
@@ -990,7 +1154,9 @@ func (f *file) lintErrorf() {
 		if !ok || !isPkgDot(ce.Fun, "fmt", "Sprintf") {
 			return true
 		}
-		f.errorf(node, 1, category("errors"), "should replace errors.New(fmt.Sprintf(...)) with fmt.Errorf(...)")
+		args := f.src[f.offset(ce.Lparen) : f.offset(ce.Rparen)+1]
+		edits := []Edit{f.edit(node.Pos(), node.End(), "fmt.Errorf"+string(args))}
+		f.errorf(node, 1, category("errors"), edits, "should replace errors.New(fmt.Sprintf(...)) with fmt.Errorf(...)")
 		return true
 	})
 }
@@ -1147,7 +1313,8 @@ func (f *file) lintIncDec() {
 		default:
 			return true
 		}
-		f.errorf(as, 0.8, category("unary-op"), "should replace %s with %s%s", f.render(as), f.render(as.Lhs[0]), suffix)
+		edits := []Edit{f.edit(as.Lhs[0].End(), as.End(), suffix)}
+		f.errorf(as, 0.8, category("unary-op"), edits, "should replace %s with %s%s", f.render(as), f.render(as.Lhs[0]), suffix)
 		return true
 	})
 }
@@ -1260,6 +1427,80 @@ func exportedType(typ types.Type) bool {
 	return true
 }
 
+// declares reports whether any of the statements of block declare a name
+// in the block's scope.
+func declares(block *ast.BlockStmt) bool {
+	for _, stmt := range block.List {
+		switch s := stmt.(type) {
+		case *ast.DeclStmt, *ast.LabeledStmt:
+			return true
+		case *ast.AssignStmt:
+			if s.Tok == token.DEFINE {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// rename returns the edits renaming the object declared by id to name in the
+// whole package, or nil if that might change the meaning of the program or
+// break other packages. It conservatively refuses to use a name already used
+// anywhere in the package, and to rename methods, which may implement
+// interfaces, and exported objects of packages other than main.
+func (p *pkg) rename(id *ast.Ident, name string) []Edit {
+	if !p.typesOK {
+		return nil
+	}
+	obj := p.typesInfo.Defs[id]
+	if obj == nil || (obj.Exported() && !p.main) || types.Universe.Lookup(name) != nil {
+		return nil
+	}
+	switch obj := obj.(type) {
+	case *types.Func:
+		if obj.Type().(*types.Signature).Recv() != nil {
+			return nil
+		}
+	case *types.Var:
+		// Exported fields are seen by reflection, such as encoding/json.
+		if obj.IsField() && obj.Exported() {
+			return nil
+		}
+	}
+
+	var edits []Edit
+	for _, m := range []map[*ast.Ident]types.Object{p.typesInfo.Defs, p.typesInfo.Uses} {
+		for ident, o := range m {
+			if ident.Name == name {
+				return nil
+			}
+			if o != obj {
+				continue
+			}
+			filename := p.fset.File(ident.Pos()).Name()
+			f, ok := p.files[filename]
+			if !ok {
+				return nil
+			}
+			edits = append(edits, f.edit(ident.Pos(), ident.End(), name))
+		}
+	}
+	sort.Sort(byOffset(edits))
+	return edits
+}
+
+type byOffset []Edit
+
+func (e byOffset) Len() int      { return len(e) }
+func (e byOffset) Swap(i, j int) { e[i], e[j] = e[j], e[i] }
+
+func (e byOffset) Less(i, j int) bool {
+	if e[i].Filename != e[j].Filename {
+		return e[i].Filename < e[j].Filename
+	}
+	return e[i].Offset < e[j].Offset
+}
+
 func receiverType(fn *ast.FuncDecl) string {
 	switch e := fn.Recv.List[0].Type.(type) {
 	case *ast.Ident:
@@ -1274,6 +1515,16 @@ func (f *file) walk(fn func(ast.Node) bool) {
 	ast.Walk(walker(fn), f.f)
 }
 
+// offset returns the offset of pos in the file's source.
+func (f *file) offset(pos token.Pos) int {
+	return f.fset.Position(pos).Offset
+}
+
+// edit returns the edit replacing the source between pos and end with text.
+func (f *file) edit(pos, end token.Pos, text string) Edit {
+	return Edit{Filename: f.filename, Offset: f.offset(pos), End: f.offset(end), New: text}
+}
+
 func (f *file) render(x interface{}) string {
 	var buf bytes.Buffer
 	if err := printer.Fprint(&buf, f.fset, x); err != nil {
@@ -15,6 +15,7 @@ import (
 	"go/token"
 	"io/ioutil"
 	"path"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"testing"
@@ -40,7 +41,7 @@ func TestAll(t *testing.T) {
 		t.Fatalf("no files in %v", baseDir)
 	}
 	for _, fi := range fis {
-		if !rx.MatchString(fi.Name()) {
+		if fi.IsDir() || !rx.MatchString(fi.Name()) {
 			continue
 		}
 		//t.Logf("Testing %s", fi.Name())
@@ -61,6 +62,12 @@ func TestAll(t *testing.T) {
 			continue
 		}
 
+		for _, p := range ps {
+			if _, ok := ruleIndex[p.Rule]; !ok {
+				t.Errorf("Problem at %s:%d has unknown rule %q: %v", fi.Name(), p.Position.Line, p.Rule, p.Text)
+			}
+		}
+
 		for _, in := range ins {
 			ok := false
 			for i, p := range ps {
@@ -87,6 +94,118 @@ func TestAll(t *testing.T) {
 	}
 }
 
+func TestDisabled(t *testing.T) {
+	src, err := ioutil.ReadFile("testdata/names.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := &Linter{Disabled: map[string]bool{"names": true, "lint-directive": true}}
+	ps, err := l.Lint("names.go", src)
+	if err != nil {
+		t.Fatalf("Linting names.go: %v", err)
+	}
+	for _, p := range ps {
+		if p.Rule == "names" {
+			t.Errorf("Problem of disabled rule at names.go:%d: %v", p.Position.Line, p.Text)
+		}
+	}
+
+	l.Disabled["bogus"] = true
+	if _, err := l.Lint("names.go", src); err == nil {
+		t.Errorf("Linting with unknown disabled rule succeeded")
+	}
+}
+
+func TestDisabledDirectives(t *testing.T) {
+	src := []byte(`// Package foo ...
+package foo
+
+//lint:ignore names
+var bad_name int //lint:ignore names no reason given above
+`)
+	l := &Linter{Disabled: map[string]bool{"lint-directive": true}}
+	ps, err := l.Lint("foo.go", src)
+	if err != nil {
+		t.Fatalf("Linting: %v", err)
+	}
+	if len(ps) != 0 {
+		t.Errorf("Got problems %v, want none", ps)
+	}
+}
+
+func TestRules(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, r := range Rules() {
+		if r.ID == "" || r.Doc == "" {
+			t.Errorf("Rule %+v lacks an ID or doc", r)
+		}
+		if seen[r.ID] {
+			t.Errorf("Duplicate rule ID %q", r.ID)
+		}
+		seen[r.ID] = true
+	}
+}
+
+func TestFix(t *testing.T) {
+	l := new(Linter)
+	filenames, err := filepath.Glob("testdata/fix/*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filenames) == 0 {
+		t.Fatalf("no files in testdata/fix")
+	}
+	for _, filename := range filenames {
+		src, err := ioutil.ReadFile(filename)
+		if err != nil {
+			t.Fatalf("Failed reading %s: %v", filename, err)
+		}
+		want, err := ioutil.ReadFile(filename + ".golden")
+		if err != nil {
+			t.Fatalf("Failed reading golden file: %v", err)
+		}
+
+		files := map[string][]byte{filename: src}
+		ps, err := l.LintFiles(files)
+		if err != nil {
+			t.Errorf("Linting %s: %v", filename, err)
+			continue
+		}
+		out, _, err := Fix(files, ps)
+		if err != nil {
+			t.Errorf("Fixing %s: %v", filename, err)
+			continue
+		}
+		if got := out[filename]; !bytes.Equal(got, want) {
+			t.Errorf("Fixing %s:\n got:\n%s\nwant:\n%s", filename, got, want)
+		}
+	}
+}
+
+func TestFixOverlap(t *testing.T) {
+	files := map[string][]byte{"a.go": []byte("package a\n\nvar a, b int\n")}
+	ps := []Problem{
+		{Text: "first", Edits: []Edit{{Filename: "a.go", Offset: 15, End: 16, New: "x"}}},
+		{Text: "overlapping", Edits: []Edit{{Filename: "a.go", Offset: 15, End: 19, New: "y"}}},
+		{Text: "partly overlapping", Edits: []Edit{
+			{Filename: "a.go", Offset: 18, End: 19, New: "z"},
+			{Filename: "a.go", Offset: 15, End: 15, New: "w"},
+		}},
+		{Text: "no edits"},
+	}
+	out, unfixed, err := Fix(files, ps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(out["a.go"]), "package a\n\nvar x, b int\n"; got != want {
+		t.Errorf("Fix = %q, want %q", got, want)
+	}
+	if len(unfixed) != 3 {
+		t.Errorf("Got %d unfixed problems, want 3", len(unfixed))
+	}
+}
+
 type instruction struct {
 	Line  int            // the line number this applies to
 	Match *regexp.Regexp // what pattern to match
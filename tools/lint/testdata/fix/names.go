@@ -0,0 +1,32 @@
+// Package foo tests renaming fixes.
+package foo
+
+// Exported names of a library are part of its API, so they aren't renamed.
+var Exported_name int
+
+var package_var = "x"
+
+type my_type struct {
+	field_name int
+	Url        string
+}
+
+func new_type(base_value int) *my_type {
+	t := &my_type{field_name: base_value}
+	t.field_name += package_var_len()
+	return t
+}
+
+func package_var_len() int {
+	for some_index := range package_var {
+		return some_index
+	}
+	return len(package_var)
+}
+
+func (t *my_type) do_it() {}
+
+// taken_name isn't renamed to takenName, which is used already.
+var taken_name = takenName()
+
+func takenName() int { return 0 }
@@ -0,0 +1,32 @@
+// Package foo tests rewriting fixes.
+package foo
+
+import (
+	"errors"
+	"fmt"
+)
+
+var count int = 0
+
+var ratio float64 = 1.5
+
+func f(x int, m map[string]int) error {
+	for k, _ := range m {
+		count += 1
+		x -= 1
+		_ = k
+	}
+	if x > 10 {
+		return errors.New(fmt.Sprintf("too big: %d", x))
+	} else {
+		// Not too big.
+		count++
+	}
+	if x > 5 {
+		return nil
+	} else {
+		y := x * 2
+		count += y
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+// Test for lint directives.
+
+// Package foo ...
+package foo
+
+//lint:file-ignore receiver-naming the receivers follow the C++ sources
+
+type t int
+
+func (this t) m() {}
+
+var first_name int //lint:ignore names kept for compatibility with the C API
+
+//lint:ignore names,var-declaration kept for compatibility with the C API
+var second_name int = 0
+
+var third_name int // MATCH /underscore.*third_name/
+
+/* MATCH /lint directive.*reason is required/ */ //lint:ignore names
+var fourth_name int                              // MATCH /underscore.*fourth_name/
+
+var fifth_name int /* MATCH /unknown rule "bogus"/ */ //lint:ignore bogus,names old name
+
+//lint:ignore error-strings not the rule that applies
+var sixth_name int // MATCH /underscore.*sixth_name/
+
+/* MATCH /unknown lint directive/ */ //lint:disable names
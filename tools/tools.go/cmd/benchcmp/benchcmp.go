@@ -5,8 +5,11 @@
 package main
 
 import (
+	"encoding/csv"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"math"
 	"os"
 	"sort"
 	"strconv"
@@ -17,6 +20,9 @@ var (
 	changedOnly = flag.Bool("changed", false, "show only benchmarks that have changed")
 	magSort     = flag.Bool("mag", false, "sort benchmarks by magnitude of change")
 	best        = flag.Bool("best", false, "compare best times from old and new")
+	stats       = flag.Bool("stats", false, "compare repeated runs statistically")
+	alpha       = flag.Float64("alpha", 0.05, "significance level of changes, with -stats")
+	format      = flag.String("format", "text", "output format: text, json or csv; json and csv imply -stats")
 )
 
 const usageFooter = `
@@ -27,6 +33,9 @@ Benchcmp compares old and new for each benchmark.
 
 If -test.benchmem=true is added to the "go test" command
 benchcmp will also compare memory allocations.
+
+To compare runs repeated with "go test -count=N" statistically,
+use -stats.
 `
 
 func main() {
@@ -40,10 +49,28 @@ func main() {
 	if flag.NArg() != 2 {
 		flag.Usage()
 	}
+	switch *format {
+	case "text":
+	case "json", "csv":
+		*stats = true
+	default:
+		fatal(fmt.Sprintf("benchcmp: invalid -format %q", *format))
+	}
+	if *stats && *best {
+		fatal("benchcmp: -best cannot be used with -stats")
+	}
+	if *alpha <= 0 || *alpha >= 1 {
+		fatal("benchcmp: -alpha must be between 0 and 1")
+	}
 
 	before := parseFile(flag.Arg(0))
 	after := parseFile(flag.Arg(1))
 
+	if *stats {
+		compareStats(before, after)
+		return
+	}
+
 	cmps, warnings := Correlate(before, after)
 
 	for _, warn := range warnings {
@@ -159,3 +186,170 @@ func formatNs(ns float64) string {
 	}
 	return strconv.FormatFloat(ns, 'f', prec, 64)
 }
+
+// compareStats compares the repeated runs of each benchmark in before
+// and after statistically, and prints the results in the -format.
+func compareStats(before, after BenchSet) {
+	cmps, warnings := CorrelateStats(before, after, *alpha)
+
+	for _, warn := range warnings {
+		fmt.Fprintln(os.Stderr, warn)
+	}
+
+	if len(cmps) == 0 {
+		fatal("benchcmp: no repeated benchmarks")
+	}
+
+	if *magSort {
+		sort.Sort(StatsByDelta(cmps))
+	} else {
+		sort.Sort(StatsByParseOrder(cmps))
+	}
+	if *changedOnly {
+		var changed []StatCmp
+		for _, cmp := range cmps {
+			if cmp.Significant(*alpha) {
+				changed = append(changed, cmp)
+			}
+		}
+		cmps = changed
+	}
+
+	var err error
+	switch *format {
+	case "json":
+		err = writeJSON(cmps)
+	case "csv":
+		err = writeCSV(cmps)
+	default:
+		printStats(cmps)
+	}
+	if err != nil {
+		fatal(err)
+	}
+}
+
+// printStats prints a table of cmps for each unit, such as
+//
+//	benchmark           old ns/op     new ns/op     delta
+//	BenchmarkConcat     523 ± 2%      68.6 ± 1%     -86.88%     (p=0.008 n=5+5)
+//	BenchmarkSplit      98.2 ± 3%     99.0 ± 2%     ~           (p=0.690 n=5+5)
+//
+// where ± gives the confidence interval of the mean, and ~ denotes a
+// change that is not statistically significant.
+func printStats(cmps []StatCmp) {
+	w := new(tabwriter.Writer)
+	w.Init(os.Stdout, 0, 0, 5, ' ', 0)
+	defer w.Flush()
+
+	for i, cmp := range cmps {
+		if i == 0 || cmp.Unit != cmps[i-1].Unit {
+			if i > 0 {
+				fmt.Fprint(w, "\n")
+			}
+			deltaName := "delta"
+			if cmp.Unit == "MB/s" {
+				deltaName = "speedup"
+			}
+			fmt.Fprintf(w, "benchmark\told %s\tnew %s\t%s\t\n", cmp.Unit, cmp.Unit, deltaName)
+		}
+		delta := "~"
+		if cmp.Significant(*alpha) {
+			if cmp.Unit == "MB/s" {
+				delta = cmp.Delta().Multiple()
+			} else {
+				delta = cmp.Delta().Percent()
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t(p=%.3f n=%d+%d)\n", cmp.Name, formatSample(cmp.Before), formatSample(cmp.After),
+			delta, cmp.P, len(cmp.Before.Values), len(cmp.After.Values))
+	}
+}
+
+// formatSample formats the mean of s and its confidence interval,
+// as a percentage of the mean.
+func formatSample(s Sample) string {
+	mean := formatNs(s.Mean) // (its choice of precision suits any unit)
+	if len(s.Values) < 2 || s.Mean == 0 {
+		return mean
+	}
+	return fmt.Sprintf("%s ± %.0f%%", mean, 100*s.CI/math.Abs(s.Mean))
+}
+
+// jsonSample is the JSON form of a Sample.
+type jsonSample struct {
+	N      int       `json:"n"`
+	Mean   float64   `json:"mean"`
+	CI     float64   `json:"ci"`
+	Values []float64 `json:"values"`
+}
+
+// jsonCmp is the JSON form of a StatCmp.
+type jsonCmp struct {
+	Name        string     `json:"name"`
+	Unit        string     `json:"unit"`
+	Old         jsonSample `json:"old"`
+	New         jsonSample `json:"new"`
+	Delta       *float64   `json:"delta"` // percent change of the mean; null if infinite
+	P           float64    `json:"p"`
+	Significant bool       `json:"significant"`
+}
+
+// writeJSON writes cmps to standard output as a JSON array.
+func writeJSON(cmps []StatCmp) error {
+	out := make([]jsonCmp, 0, len(cmps))
+	for _, cmp := range cmps {
+		var delta *float64
+		if pct, ok := percent(cmp.Delta()); ok {
+			delta = &pct
+		}
+		out = append(out, jsonCmp{
+			Name:        cmp.Name,
+			Unit:        cmp.Unit,
+			Old:         jsonSample{len(cmp.Before.Values), cmp.Before.Mean, cmp.Before.CI, cmp.Before.Values},
+			New:         jsonSample{len(cmp.After.Values), cmp.After.Mean, cmp.After.CI, cmp.After.Values},
+			Delta:       delta,
+			P:           cmp.P,
+			Significant: cmp.Significant(*alpha),
+		})
+	}
+	b, err := json.MarshalIndent(out, "", "\t")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	_, err = os.Stdout.Write(b)
+	return err
+}
+
+// writeCSV writes cmps to standard output as CSV, with a header row.
+// The delta column is empty if the change is infinite.
+func writeCSV(cmps []StatCmp) error {
+	w := csv.NewWriter(os.Stdout)
+	w.Write([]string{"name", "unit", "old n", "old mean", "old ci", "new n", "new mean", "new ci", "delta %", "p", "significant"})
+	f := func(x float64) string { return strconv.FormatFloat(x, 'g', -1, 64) }
+	for _, cmp := range cmps {
+		var delta string
+		if pct, ok := percent(cmp.Delta()); ok {
+			delta = strconv.FormatFloat(pct, 'f', 2, 64)
+		}
+		w.Write([]string{
+			cmp.Name,
+			cmp.Unit,
+			strconv.Itoa(len(cmp.Before.Values)), f(cmp.Before.Mean), f(cmp.Before.CI),
+			strconv.Itoa(len(cmp.After.Values)), f(cmp.After.Mean), f(cmp.After.CI),
+			delta,
+			f(cmp.P),
+			strconv.FormatBool(cmp.Significant(*alpha)),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// percent returns the percent change of d,
+// and reports whether it is finite.
+func percent(d Delta) (float64, bool) {
+	pct := 100*d.Float64() - 100
+	return pct, !math.IsInf(pct, 0)
+}
@@ -7,6 +7,7 @@ package main
 import (
 	"fmt"
 	"math"
+	"sort"
 )
 
 // BenchCmp is a pair of benchmarks.
@@ -146,3 +147,137 @@ type ByDeltaAllocsOp []BenchCmp
 func (x ByDeltaAllocsOp) Len() int           { return len(x) }
 func (x ByDeltaAllocsOp) Swap(i, j int)      { x[i], x[j] = x[j], x[i] }
 func (x ByDeltaAllocsOp) Less(i, j int) bool { return lessByDelta(x[i], x[j], BenchCmp.DeltaAllocsOp) }
+
+// StatCmp compares one measurement of a benchmark
+// over repeated runs before and after.
+type StatCmp struct {
+	Name   string  // benchmark name
+	Unit   string  // unit of the measurement, e.g. "ns/op"
+	Before Sample  // values before
+	After  Sample  // values after
+	P      float64 // p-value of the Mann-Whitney U test of the values
+	ord    int     // ordinal position of the first run before, used for sorting
+}
+
+// CorrelateStats correlates the repeated runs of each benchmark from
+// two BenchSets, as produced by 'go test -count=N'.  It returns a
+// StatCmp for each unit measured both before and after, whose
+// confidence intervals have confidence level 1-alpha.
+func CorrelateStats(before, after BenchSet, alpha float64) (cmps []StatCmp, warnings []string) {
+	for name, beforebb := range before {
+		afterbb := after[name]
+		if len(afterbb) == 0 {
+			warnings = append(warnings, fmt.Sprintf("ignoring %s: before has %d instances, after has 0", name, len(beforebb)))
+			continue
+		}
+		for _, unit := range units(beforebb) {
+			x, y := values(beforebb, unit), values(afterbb, unit)
+			if len(y) == 0 {
+				continue
+			}
+			cmps = append(cmps, StatCmp{
+				Name:   name,
+				Unit:   unit,
+				Before: NewSample(x, alpha),
+				After:  NewSample(y, alpha),
+				P:      MannWhitneyU(x, y),
+				ord:    beforebb[0].ord,
+			})
+		}
+	}
+	for name, afterbb := range after {
+		if _, ok := before[name]; !ok {
+			warnings = append(warnings, fmt.Sprintf("ignoring %s: before has 0 instances, after has %d", name, len(afterbb)))
+		}
+	}
+	return
+}
+
+// units returns the units measured by any of bb: the standard
+// units first, then the others in alphabetical order.
+func units(bb []*Bench) []string {
+	var units []string
+	for _, u := range standardUnits {
+		for _, b := range bb {
+			if b.Measured&u.flag != 0 {
+				units = append(units, u.unit)
+				break
+			}
+		}
+	}
+	var others []string
+	seen := make(map[string]bool)
+	for _, b := range bb {
+		for unit := range b.Metrics {
+			if !seen[unit] {
+				seen[unit] = true
+				others = append(others, unit)
+			}
+		}
+	}
+	sort.Strings(others)
+	return append(units, others...)
+}
+
+// values returns the measurements in unit of those of bb that have one.
+func values(bb []*Bench, unit string) []float64 {
+	var vs []float64
+	for _, b := range bb {
+		if v, ok := b.Value(unit); ok {
+			vs = append(vs, v)
+		}
+	}
+	return vs
+}
+
+func (c StatCmp) Delta() Delta { return Delta{c.Before.Mean, c.After.Mean} }
+
+// Significant reports whether the change is statistically significant
+// at level alpha.
+func (c StatCmp) Significant(alpha float64) bool { return c.P < alpha }
+
+// unitRank orders units as CorrelateStats's units does.
+func unitRank(unit string) int {
+	for i, u := range standardUnits {
+		if u.unit == unit {
+			return i
+		}
+	}
+	return len(standardUnits)
+}
+
+// lessByUnit orders StatCmps by unit, then by less.
+func lessByUnit(i, j StatCmp, less func(i, j StatCmp) bool) bool {
+	if ri, rj := unitRank(i.Unit), unitRank(j.Unit); ri != rj {
+		return ri < rj
+	}
+	if i.Unit != j.Unit {
+		return i.Unit < j.Unit
+	}
+	return less(i, j)
+}
+
+// StatsByParseOrder sorts StatCmps by unit, then to match the
+// order in which the Before benchmarks were presented to Parse.
+type StatsByParseOrder []StatCmp
+
+func (x StatsByParseOrder) Len() int      { return len(x) }
+func (x StatsByParseOrder) Swap(i, j int) { x[i], x[j] = x[j], x[i] }
+func (x StatsByParseOrder) Less(i, j int) bool {
+	return lessByUnit(x[i], x[j], func(i, j StatCmp) bool { return i.ord < j.ord })
+}
+
+// StatsByDelta sorts StatCmps by unit, then lexicographically
+// by change in mean, descending, then by benchmark name.
+type StatsByDelta []StatCmp
+
+func (x StatsByDelta) Len() int      { return len(x) }
+func (x StatsByDelta) Swap(i, j int) { x[i], x[j] = x[j], x[i] }
+func (x StatsByDelta) Less(i, j int) bool {
+	return lessByUnit(x[i], x[j], func(i, j StatCmp) bool {
+		if iDelta, jDelta := i.Delta().mag(), j.Delta().mag(); iDelta != jDelta {
+			return iDelta < jDelta
+		}
+		return i.Name < j.Name
+	})
+}
@@ -33,5 +33,26 @@ in a format like this:
 	benchmark           old bytes     new bytes     delta
 	BenchmarkConcat     80            48            -40.00%
 
+Benchmark timings are noisy, so a single run of each benchmark may
+show changes that are not real.  To compare repeated runs instead,
+run each benchmark several times, for example with 'go test -count=10',
+and use the -stats flag:
+
+	$ benchcmp -stats old.txt new.txt
+	benchmark           old ns/op     new ns/op     delta
+	BenchmarkConcat     202 ± 1%      151 ± 1%      -25.25%     (p=0.008 n=5+5)
+	BenchmarkParse      3413 ± 1%     3411 ± 0%     ~           (p=1.000 n=5+5)
+
+For each benchmark and unit, including custom units reported with
+testing.B.ReportMetric, benchcmp -stats shows the mean of the runs
+with the half-width of its confidence interval, and the change in
+the mean.  Changes are tested for significance with the Mann-Whitney
+U test; a change whose p-value is not below -alpha (default 0.05) is
+shown as "~".  With -changed, only significant changes are shown.
+
+The -format flag selects the output format: text (the default), json
+or csv.  The json and csv formats imply -stats, and include the
+individual values of the runs (json) and the p-values in full.
+
 */
 package main
@@ -9,6 +9,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -32,6 +33,11 @@ type Bench struct {
 	AllocsOp uint64  // allocs per iteration
 	Measured int     // which measurements were recorded
 	ord      int     // ordinal position within a benchmark run, used for sorting
+
+	// Metrics holds the measurements in other units, such as
+	// those reported by testing.B.ReportMetric, keyed by unit.
+	// It is nil if there are none.
+	Metrics map[string]float64
 }
 
 // ParseLine extracts a Bench from a single line of testing.B output.
@@ -80,7 +86,43 @@ func (b *Bench) parseMeasurement(quant string, unit string) {
 			b.AllocsOp = i
 			b.Measured |= AllocsOp
 		}
+	default:
+		if f, err := strconv.ParseFloat(quant, 64); err == nil {
+			if b.Metrics == nil {
+				b.Metrics = make(map[string]float64)
+			}
+			b.Metrics[unit] = f
+		}
+	}
+}
+
+// standardUnits lists the units of the measurements stored in Bench
+// fields, with their Measured flags, in the order benchcmp shows them.
+var standardUnits = []struct {
+	unit string
+	flag int
+}{
+	{"ns/op", NsOp},
+	{"MB/s", MbS},
+	{"B/op", BOp},
+	{"allocs/op", AllocsOp},
+}
+
+// Value returns the measurement of b in the given unit,
+// and reports whether b has one.
+func (b *Bench) Value(unit string) (float64, bool) {
+	switch unit {
+	case "ns/op":
+		return b.NsOp, b.Measured&NsOp != 0
+	case "MB/s":
+		return b.MbS, b.Measured&MbS != 0
+	case "B/op":
+		return float64(b.BOp), b.Measured&BOp != 0
+	case "allocs/op":
+		return float64(b.AllocsOp), b.Measured&AllocsOp != 0
 	}
+	v, ok := b.Metrics[unit]
+	return v, ok
 }
 
 func (b *Bench) String() string {
@@ -98,6 +140,14 @@ func (b *Bench) String() string {
 	if b.Measured&AllocsOp != 0 {
 		fmt.Fprintf(buf, " %d allocs/op", b.AllocsOp)
 	}
+	units := make([]string, 0, len(b.Metrics))
+	for unit := range b.Metrics {
+		units = append(units, unit)
+	}
+	sort.Strings(units)
+	for _, unit := range units {
+		fmt.Fprintf(buf, " %.2f %s", b.Metrics[unit], unit)
+	}
 	return buf.String()
 }
 
@@ -66,10 +66,20 @@ func TestParseLine(t *testing.T) {
 			err: true,
 		},
 		{
-			line: "BenchmarkBridge	100000000	        19.6 smoots", // unknown unit
+			line: "BenchmarkBridge	100000000	        19.6 smoots", // custom metric
+			want: &Bench{
+				Name:    "BenchmarkBridge",
+				N:       100000000,
+				Metrics: map[string]float64{"smoots": 19.6},
+			},
+		},
+		{
+			line: "BenchmarkBridge	100000000	        19.6 ns/op	  3 smoots	 lots furlongs", // custom metrics
 			want: &Bench{
 				Name: "BenchmarkBridge",
-				N:    100000000,
+				N:    100000000, NsOp: 19.6,
+				Measured: NsOp,
+				Metrics:  map[string]float64{"smoots": 3},
 			},
 		},
 		{
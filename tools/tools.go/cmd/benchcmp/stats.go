@@ -0,0 +1,247 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+// Sample summarizes the values of one measurement
+// over repeated runs of a benchmark.
+type Sample struct {
+	Values []float64 // values, in the order of the runs
+	Mean   float64   // arithmetic mean of Values
+	CI     float64   // half-width of the confidence interval of Mean; 0 if len(Values) < 2
+}
+
+// NewSample returns the Sample of values, whose confidence interval
+// has confidence level 1-alpha.
+func NewSample(values []float64, alpha float64) Sample {
+	s := Sample{Values: values}
+	n := float64(len(values))
+	if n == 0 {
+		return s
+	}
+	for _, v := range values {
+		s.Mean += v
+	}
+	s.Mean /= n
+	if n < 2 {
+		return s
+	}
+
+	var ss float64
+	for _, v := range values {
+		ss += (v - s.Mean) * (v - s.Mean)
+	}
+	stddev := math.Sqrt(ss / (n - 1))
+	s.CI = tQuantile(1-alpha/2, n-1) * stddev / math.Sqrt(n)
+	return s
+}
+
+// MannWhitneyU returns the two-sided p-value of the Mann-Whitney U
+// test (also known as the Wilcoxon rank-sum test) of the hypothesis
+// that x and y are samples of the same distribution.  Unlike a t-test,
+// it doesn't assume that the distributions are normal, which benchmark
+// timings seldom are.
+//
+// The p-value is exact for small samples without ties, and otherwise
+// uses the normal approximation with a correction for ties.
+// It is 1 if either sample is empty.
+func MannWhitneyU(x, y []float64) float64 {
+	n1, n2 := len(x), len(y)
+	if n1 == 0 || n2 == 0 {
+		return 1
+	}
+
+	// Rank the pooled values, giving tied values their mean rank.
+	pooled := make(byValue, 0, n1+n2)
+	for _, v := range x {
+		pooled = append(pooled, pooledValue{v, true})
+	}
+	for _, v := range y {
+		pooled = append(pooled, pooledValue{v, false})
+	}
+	sort.Sort(pooled)
+
+	var r1 float64     // rank sum of x
+	var tieSum float64 // sum of t³-t over groups of t tied values
+	for i := 0; i < len(pooled); {
+		j := i + 1
+		for j < len(pooled) && pooled[j].v == pooled[i].v {
+			j++
+		}
+		rank := float64(i+j+1) / 2 // mean of ranks i+1..j
+		for k := i; k < j; k++ {
+			if pooled[k].inX {
+				r1 += rank
+			}
+		}
+		if t := float64(j - i); t > 1 {
+			tieSum += t*t*t - t
+		}
+		i = j
+	}
+	u1 := r1 - float64(n1*(n1+1))/2
+	mu := float64(n1*n2) / 2
+
+	if tieSum == 0 && n1+n2 <= 50 {
+		// The distribution of U is small enough to compute exactly.
+		u := math.Min(u1, float64(n1*n2)-u1)
+		dist := uDistribution(n1, n2)
+		var total, tail float64
+		for k, c := range dist {
+			total += c
+			if float64(k) <= u {
+				tail += c
+			}
+		}
+		return math.Min(1, 2*tail/total)
+	}
+
+	n := float64(n1 + n2)
+	sigma := math.Sqrt(float64(n1*n2) / 12 * ((n + 1) - tieSum/(n*(n-1))))
+	if sigma == 0 {
+		return 1 // all values are equal
+	}
+	z := (math.Abs(u1-mu) - 0.5) / sigma // with continuity correction
+	if z <= 0 {
+		return 1
+	}
+	return math.Min(1, math.Erfc(z/math.Sqrt2))
+}
+
+// A pooledValue is a value of either sample of MannWhitneyU.
+type pooledValue struct {
+	v   float64
+	inX bool // the value is from the first sample
+}
+
+type byValue []pooledValue
+
+func (x byValue) Len() int           { return len(x) }
+func (x byValue) Swap(i, j int)      { x[i], x[j] = x[j], x[i] }
+func (x byValue) Less(i, j int) bool { return x[i].v < x[j].v }
+
+// uDistribution returns the number of arrangements of samples of
+// sizes n1 and n2 without ties for which U = k, for k in 0..n1*n2.
+// These are the coefficients of the Gaussian binomial coefficient
+//
+//	[n1+n2, n1](q) = ∏_{i=1..n1} (1 - q^(n2+i)) / (1 - q^i).
+func uDistribution(n1, n2 int) []float64 {
+	// Multiplication temporarily raises the degree by up to n1.
+	c := make([]float64, n1*n2+n1+1)
+	c[0] = 1
+	for i, deg := 1, 0; i <= n1; i++ {
+		// Multiply by 1 - q^(n2+i).
+		deg += n2 + i
+		for k := deg; k >= n2+i; k-- {
+			c[k] -= c[k-(n2+i)]
+		}
+		// Divide by 1 - q^i; the quotient is exact.
+		deg -= i
+		for k := i; k <= deg; k++ {
+			c[k] += c[k-i]
+		}
+		for k := deg + 1; k <= deg+i; k++ {
+			c[k] = 0
+		}
+	}
+	return c[:n1*n2+1]
+}
+
+// tQuantile returns the p-quantile of Student's t-distribution
+// with df degrees of freedom, for 0.5 <= p < 1.
+func tQuantile(p, df float64) float64 {
+	// Find t such that tCDF(t) = p by bisection.
+	lo, hi := 0.0, 1.0
+	for tCDF(hi, df) < p {
+		lo, hi = hi, 2*hi
+	}
+	for i := 0; i < 100; i++ {
+		mid := (lo + hi) / 2
+		if tCDF(mid, df) < p {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return (lo + hi) / 2
+}
+
+// tCDF returns the cumulative distribution function at t >= 0 of
+// Student's t-distribution with df degrees of freedom.
+func tCDF(t, df float64) float64 {
+	return 1 - 0.5*betaInc(df/(df+t*t), df/2, 0.5)
+}
+
+// betaInc returns the regularized incomplete beta function I_x(a, b).
+func betaInc(x, a, b float64) float64 {
+	switch {
+	case x <= 0:
+		return 0
+	case x >= 1:
+		return 1
+	}
+	lga, _ := math.Lgamma(a)
+	lgb, _ := math.Lgamma(b)
+	lgab, _ := math.Lgamma(a + b)
+	front := math.Exp(lgab - lga - lgb + a*math.Log(x) + b*math.Log(1-x))
+
+	// The continued fraction converges rapidly for x < (a+1)/(a+b+2);
+	// otherwise use the symmetry I_x(a, b) = 1 - I_(1-x)(b, a).
+	if x < (a+1)/(a+b+2) {
+		return front * betaCF(x, a, b) / a
+	}
+	return 1 - front*betaCF(1-x, b, a)/b
+}
+
+// betaCF evaluates the continued fraction of the incomplete beta
+// function using the modified Lentz method.
+func betaCF(x, a, b float64) float64 {
+	const (
+		eps  = 1e-15
+		tiny = 1e-300
+	)
+	c, d := 1.0, 1-(a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	f := d
+	for m := 1.0; m <= 300; m++ {
+		// Even step.
+		num := m * (b - m) * x / ((a + 2*m - 1) * (a + 2*m))
+		d = 1 + num*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + num/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		f *= d * c
+
+		// Odd step.
+		num = -(a + m) * (a + b + m) * x / ((a + 2*m) * (a + 2*m + 1))
+		d = 1 + num*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + num/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		f *= delta
+		if math.Abs(delta-1) < eps {
+			break
+		}
+	}
+	return f
+}
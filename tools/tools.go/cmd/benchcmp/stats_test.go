@@ -0,0 +1,159 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestTQuantile(t *testing.T) {
+	cases := []struct {
+		p, df float64
+		want  float64
+	}{
+		{0.975, 1, 12.7062},
+		{0.975, 4, 2.7764},
+		{0.975, 30, 2.0423},
+		{0.975, 1e6, 1.9600},
+		{0.995, 9, 3.2498},
+		{0.5, 3, 0},
+	}
+	for _, tt := range cases {
+		if have := tQuantile(tt.p, tt.df); math.Abs(have-tt.want) > 1e-4 {
+			t.Errorf("tQuantile(%v, %v): want %.4f have %.4f", tt.p, tt.df, tt.want, have)
+		}
+	}
+}
+
+func TestNewSample(t *testing.T) {
+	s := NewSample([]float64{10, 12, 14}, 0.05)
+	if s.Mean != 12 {
+		t.Errorf("Mean: want 12 have %f", s.Mean)
+	}
+	// stddev 2, t(0.975, 2) = 4.3027
+	if want := 4.3027 * 2 / math.Sqrt(3); math.Abs(s.CI-want) > 1e-3 {
+		t.Errorf("CI: want %f have %f", want, s.CI)
+	}
+
+	if s := NewSample([]float64{7}, 0.05); s.Mean != 7 || s.CI != 0 {
+		t.Errorf("single value: want mean 7 ci 0, have %+v", s)
+	}
+}
+
+func TestUDistribution(t *testing.T) {
+	// The number of arrangements of 2+3 values by U.
+	want := []float64{1, 1, 2, 2, 2, 1, 1}
+	if have := uDistribution(2, 3); !reflect.DeepEqual(have, want) {
+		t.Errorf("uDistribution(2, 3): want %v have %v", want, have)
+	}
+	// Summing to the binomial coefficient C(n1+n2, n1).
+	var sum float64
+	for _, c := range uDistribution(10, 12) {
+		if c < 0 {
+			t.Fatalf("uDistribution(10, 12) has negative coefficient %v", c)
+		}
+		sum += c
+	}
+	if sum != 646646 {
+		t.Errorf("uDistribution(10, 12) sums to %v, want 646646", sum)
+	}
+}
+
+func TestMannWhitneyU(t *testing.T) {
+	cases := []struct {
+		x, y []float64
+		want float64
+	}{
+		// Exact: the samples are completely separated.
+		{[]float64{1, 2, 3, 4, 5}, []float64{6, 7, 8, 9, 10}, 2.0 / 252},
+		{[]float64{4, 5, 6}, []float64{1, 2, 3}, 2.0 / 20},
+		// Exact: R's wilcox.test example (W = 35, one-sided p = 0.1272).
+		{
+			[]float64{0.80, 0.83, 1.89, 1.04, 1.45, 1.38, 1.91, 1.64, 0.73, 1.46},
+			[]float64{1.15, 0.88, 0.90, 0.74, 1.21},
+			0.2544,
+		},
+		// Identical samples.
+		{[]float64{1, 2, 3}, []float64{1, 2, 3}, 1},
+		{[]float64{5, 5, 5}, []float64{5, 5, 5, 5}, 1},
+		// Too few values to be significant.
+		{[]float64{1}, []float64{2}, 1},
+		{nil, []float64{2}, 1},
+	}
+	for _, tt := range cases {
+		if have := MannWhitneyU(tt.x, tt.y); math.Abs(have-tt.want) > 1e-4 {
+			t.Errorf("MannWhitneyU(%v, %v): want %.4f have %.4f", tt.x, tt.y, tt.want, have)
+		}
+	}
+
+	// Normal approximation, with ties.
+	x := []float64{10, 11, 11, 12, 12, 12, 13, 13, 14, 15}
+	y := []float64{14, 15, 15, 16, 16, 16, 17, 17, 18, 19}
+	if p := MannWhitneyU(x, y); p > 0.001 {
+		t.Errorf("MannWhitneyU of shifted samples with ties: have p=%f, want < 0.001", p)
+	}
+	if p := MannWhitneyU(x, x); p != 1 {
+		t.Errorf("MannWhitneyU of identical samples with ties: have p=%f, want 1", p)
+	}
+}
+
+func TestCorrelateStats(t *testing.T) {
+	before, err := ParseBenchSet(strings.NewReader(`
+		BenchmarkA 10 100 ns/op 3 allocs/op
+		BenchmarkB 10 50 ns/op 2 widgets/op
+		BenchmarkA 10 101 ns/op 3 allocs/op
+		BenchmarkB 10 51 ns/op 2 widgets/op
+		BenchmarkA 10 102 ns/op 3 allocs/op
+		BenchmarkB 10 52 ns/op 2 widgets/op
+		BenchmarkA 10 103 ns/op 3 allocs/op
+		BenchmarkOld 10 1 ns/op
+	`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := ParseBenchSet(strings.NewReader(`
+		BenchmarkA 10 200 ns/op 3 allocs/op
+		BenchmarkA 10 201 ns/op 3 allocs/op
+		BenchmarkA 10 202 ns/op 3 allocs/op
+		BenchmarkA 10 203 ns/op 3 allocs/op
+		BenchmarkB 10 51 ns/op 4 widgets/op
+		BenchmarkB 10 50 ns/op 4 widgets/op
+		BenchmarkB 10 52 ns/op 4 widgets/op
+		BenchmarkNew 10 1 ns/op
+	`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmps, warnings := CorrelateStats(before, after, 0.05)
+	sort.Strings(warnings)
+	wantWarnings := []string{
+		"ignoring BenchmarkNew: before has 0 instances, after has 1",
+		"ignoring BenchmarkOld: before has 1 instances, after has 0",
+	}
+	if !reflect.DeepEqual(warnings, wantWarnings) {
+		t.Errorf("warnings: want %q have %q", wantWarnings, warnings)
+	}
+
+	sort.Sort(StatsByParseOrder(cmps))
+	var have []string
+	for _, cmp := range cmps {
+		have = append(have, cmp.Name+" "+cmp.Unit+" "+cmp.Delta().Percent()+" "+
+			map[bool]string{true: "significant", false: "~"}[cmp.Significant(0.05)])
+	}
+	want := []string{
+		"BenchmarkA ns/op +98.52% significant",
+		"BenchmarkB ns/op +0.00% ~",
+		"BenchmarkA allocs/op +0.00% ~",
+		"BenchmarkB widgets/op +100.00% significant",
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("CorrelateStats: want\n%s\nhave\n%s", strings.Join(want, "\n"), strings.Join(have, "\n"))
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file implements the checking of per-package coverage against minimums.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"code.google.com/p/go.tools/cover"
+)
+
+// A threshold is the minimum coverage required of the packages
+// matching a pattern.  The pattern is an import path, which may end
+// in "/..." to match the package and all packages beneath it, or is
+// "..." to match all packages.
+type threshold struct {
+	pattern string
+	min     float64 // percentage of statements
+}
+
+// match reports whether the threshold applies to the package and,
+// if so, how specific the match is: exact matches are more specific
+// than any wildcard, and longer wildcards more specific than shorter.
+func (t threshold) match(pkg string) (specificity int, ok bool) {
+	if t.pattern == "..." {
+		return 0, true
+	}
+	if prefix := strings.TrimSuffix(t.pattern, "/..."); prefix != t.pattern {
+		if pkg == prefix || strings.HasPrefix(pkg, prefix+"/") {
+			return 1 + len(prefix), true
+		}
+		return 0, false
+	}
+	if pkg == t.pattern {
+		return int(^uint(0) >> 1), true
+	}
+	return 0, false
+}
+
+// parseThresholds parses a threshold configuration, which has one
+// threshold per line: a package pattern and a percentage, like this:
+//
+//	# Require 80% everywhere, but 95% in the parser.
+//	...			80
+//	example.com/x/parser	95%
+//
+// Blank lines and lines beginning with '#' are ignored.
+func parseThresholds(r io.Reader, name string) ([]threshold, error) {
+	var thresholds []threshold
+	s := bufio.NewScanner(r)
+	for lineno := 1; s.Scan(); lineno++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: want package pattern and minimum coverage, have %q", name, lineno, line)
+		}
+		min, err := strconv.ParseFloat(strings.TrimSuffix(fields[1], "%"), 64)
+		if err != nil || min < 0 || min > 100 {
+			return nil, fmt.Errorf("%s:%d: invalid minimum coverage %q", name, lineno, fields[1])
+		}
+		thresholds = append(thresholds, threshold{fields[0], min})
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return thresholds, nil
+}
+
+// checkOutput reads the threshold configuration in configFile and
+// the named coverage profiles, which it merges, and reports whether
+// each package meets the minimum coverage of the most specific
+// threshold that applies to it, like this:
+//
+//	ok  	example.com/x		85.2%	(minimum 80.0%)
+//	FAIL	example.com/x/parser	90.1%	(minimum 95.0%)
+//
+// Packages to which no threshold applies are not reported.  A package
+// named exactly by a threshold but absent from the profiles fails.
+// The report is written to outputFile ("" means to write to standard
+// output), and checkOutput returns the number of failing packages.
+func checkOutput(configFile string, profiles []string, outputFile string) (failed int, err error) {
+	f, err := os.Open(configFile)
+	if err != nil {
+		return 0, err
+	}
+	thresholds, err := parseThresholds(f, configFile)
+	f.Close()
+	if err != nil {
+		return 0, err
+	}
+	merged, err := readProfiles(profiles)
+	if err != nil {
+		return 0, err
+	}
+	err = writeOutput(outputFile, func(w io.Writer) error {
+		failed = checkCoverage(w, thresholds, merged)
+		return nil
+	})
+	return failed, err
+}
+
+// checkCoverage writes the report described at checkOutput and
+// returns the number of failing packages.
+func checkCoverage(w io.Writer, thresholds []threshold, profiles []*cover.Profile) (failed int) {
+	type counts struct{ covered, total int64 }
+	pkgs := make(map[string]*counts)
+	for _, p := range profiles {
+		pkg := path.Dir(p.FileName)
+		c := pkgs[pkg]
+		if c == nil {
+			c = new(counts)
+			pkgs[pkg] = c
+		}
+		covered, total := p.Coverage()
+		c.covered += covered
+		c.total += total
+	}
+	var names []string
+	for pkg := range pkgs {
+		names = append(names, pkg)
+	}
+	sort.Strings(names)
+
+	tabber := tabwriter.NewWriter(w, 1, 8, 1, '\t', 0)
+	defer tabber.Flush()
+
+	for _, pkg := range names {
+		best, found := -1, threshold{}
+		for _, t := range thresholds {
+			if s, ok := t.match(pkg); ok && s > best {
+				best, found = s, t
+			}
+		}
+		if best < 0 {
+			continue
+		}
+		c := pkgs[pkg]
+		pct := percent(c.covered, c.total)
+		status := "ok  "
+		if pct < found.min {
+			status = "FAIL"
+			failed++
+		}
+		fmt.Fprintf(tabber, "%s\t%s\t%.1f%%\t(minimum %.1f%%)\n", status, pkg, pct, found.min)
+	}
+	for _, t := range thresholds {
+		if strings.HasSuffix(t.pattern, "...") || pkgs[t.pattern] != nil {
+			continue
+		}
+		fmt.Fprintf(tabber, "FAIL\t%s\tno coverage data\t(minimum %.1f%%)\n", t.pattern, t.min)
+		failed++
+	}
+	return failed
+}
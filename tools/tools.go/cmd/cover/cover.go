@@ -5,6 +5,7 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
 	"flag"
 	"fmt"
@@ -35,16 +36,31 @@ Write out an HTML file instead of launching a web browser:
 Display coverage percentages to stdout for each function:
 	go tool cover -func=c.out
 
+Write out the coverage in Cobertura XML or LCOV format:
+	go tool cover -cobertura=c.out -o coverage.xml
+	go tool cover -lcov=c.out -o coverage.info
+
+Merge several coverage profiles, such as those of parallel test runs:
+	go tool cover -merge -o c.out c1.out c2.out c3.out
+
+Show the blocks that are uncovered in a new profile but not in an old one,
+optionally only in the files listed, one per line, in changed.txt:
+	go tool cover -diff [-changed=changed.txt] old.out new.out
+
+Check the coverage of each package against the minimums in a config file,
+exiting with status 1 if any package is below its minimum:
+	go tool cover -check=coverage.cfg c1.out c2.out
+
 Finally, to generate modified source code with coverage annotations
 (what go test -cover does):
 	go tool cover -mode=set -var=CoverageVariableName program.go
 `
 
 func usage() {
-	fmt.Fprintln(os.Stderr, usageMessage)
+	fmt.Fprintf(os.Stderr, "%s\n", usageMessage)
 	fmt.Fprintln(os.Stderr, "Flags:")
 	flag.PrintDefaults()
-	fmt.Fprintln(os.Stderr, "\n  Only one of -html, -func, or -mode may be set.")
+	fmt.Fprintln(os.Stderr, "\n  Only one of -html, -func, -cobertura, -lcov, -merge, -diff, -check, or -mode may be set.")
 	os.Exit(2)
 }
 
@@ -54,9 +70,16 @@ var (
 	output  = flag.String("o", "", "file for output; default: stdout")
 	htmlOut = flag.String("html", "", "generate HTML representation of coverage profile")
 	funcOut = flag.String("func", "", "output coverage profile information for each function")
+
+	coberturaOut = flag.String("cobertura", "", "output coverage profile in Cobertura XML format")
+	lcovOut      = flag.String("lcov", "", "output coverage profile in LCOV format")
+	mergeOut     = flag.Bool("merge", false, "merge the coverage profiles named by the arguments")
+	diffOut      = flag.Bool("diff", false, "show blocks newly uncovered between the two coverage profiles named by the arguments")
+	changed      = flag.String("changed", "", "with -diff, file listing the changed source files to consider, one per line")
+	checkOut     = flag.String("check", "", "check the coverage profiles named by the arguments against the per-package minimums in this file")
 )
 
-var profile string // The profile to read; the value of -html or -func
+var profile string // The profile to read; the value of -html, -func, -cobertura or -lcov
 
 var counterStmt func(*File, ast.Expr) ast.Stmt
 
@@ -87,11 +110,33 @@ func main() {
 		return
 	}
 
-	// Output HTML or function coverage information.
-	if *htmlOut != "" {
+	// Check per-package coverage; failing packages exit with status 1.
+	if *checkOut != "" {
+		failed, err := checkOutput(*checkOut, flag.Args(), *output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cover: %v\n", err)
+			os.Exit(2)
+		}
+		if failed > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Output HTML, function coverage information, or another format.
+	switch {
+	case *htmlOut != "":
 		err = htmlOutput(profile, *output)
-	} else {
+	case *funcOut != "":
 		err = funcOutput(profile, *output)
+	case *coberturaOut != "":
+		err = coberturaOutput(profile, *output)
+	case *lcovOut != "":
+		err = lcovOutput(profile, *output)
+	case *mergeOut:
+		err = mergeOutput(flag.Args(), *output)
+	case *diffOut:
+		err = diffOutput(flag.Arg(0), flag.Arg(1), *changed, *output)
 	}
 
 	if err != nil {
@@ -102,18 +147,41 @@ func main() {
 
 // parseFlags sets the profile and counterStmt globals and performs validations.
 func parseFlags() error {
-	profile = *htmlOut
-	if *funcOut != "" {
-		if profile != "" {
-			return fmt.Errorf("too many options")
+	// Exactly one operation must be requested: display or convert a
+	// profile, combine profiles, or rewrite Go source.
+	n := 0
+	for _, set := range []bool{
+		*htmlOut != "", *funcOut != "", *coberturaOut != "", *lcovOut != "",
+		*mergeOut, *diffOut, *checkOut != "", *mode != "",
+	} {
+		if set {
+			n++
 		}
-		profile = *funcOut
 	}
-
-	// Must either display a profile or rewrite Go source.
-	if (profile == "") == (*mode == "") {
+	if n != 1 {
 		return fmt.Errorf("too many options")
 	}
+	for _, p := range []string{*htmlOut, *funcOut, *coberturaOut, *lcovOut} {
+		if p != "" {
+			profile = p
+		}
+	}
+
+	if *changed != "" && !*diffOut {
+		return fmt.Errorf("-changed requires -diff")
+	}
+	switch {
+	case *mergeOut, *checkOut != "":
+		if flag.NArg() == 0 {
+			return fmt.Errorf("missing profile")
+		}
+		return nil
+	case *diffOut:
+		if flag.NArg() != 2 {
+			return fmt.Errorf("-diff requires two profiles: old and new")
+		}
+		return nil
+	}
 
 	if *mode != "" {
 		switch *mode {
@@ -138,6 +206,30 @@ func parseFlags() error {
 	return fmt.Errorf("too many arguments")
 }
 
+// writeOutput calls write with a buffered writer for outputFile
+// ("" means standard output), and flushes and closes it afterwards.
+func writeOutput(outputFile string, write func(w io.Writer) error) error {
+	fd := os.Stdout
+	if outputFile != "" {
+		var err error
+		fd, err = os.Create(outputFile)
+		if err != nil {
+			return err
+		}
+	}
+	out := bufio.NewWriter(fd)
+	err := write(out)
+	if ferr := out.Flush(); err == nil {
+		err = ferr
+	}
+	if outputFile != "" {
+		if cerr := fd.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
 // Block represents the information about a basic block to be recorded in the analysis.
 // Note: Our definition of basic block is based on control structures; we don't break
 // apart && and ||. We could but it doesn't seem important enough to bother.
@@ -14,6 +14,16 @@ than binary-rewriting coverage tools, but also a little less capable.
 For instance, it does not probe inside && and || expressions, and can
 be mildly confused by single statements with multiple function literals.
 
+Cover can also combine and convert profiles, for use in continuous
+integration.  With -merge it merges the profiles of several test runs,
+such as parallel shards, into one; with -diff it reports the blocks
+that a new profile leaves uncovered but an old one did not, optionally
+restricted by -changed to the files that a change touches; and with
+-check it compares the coverage of each package with a minimum given
+in a configuration file and exits with status 1 if any falls short.
+The -cobertura and -lcov flags write a profile in the Cobertura XML
+and LCOV formats read by coverage dashboards.
+
 For usage information, please see:
 	go help testflag
 	go tool cover -help
@@ -0,0 +1,195 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file implements the export of coverage profiles in the Cobertura XML and LCOV formats.
+
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"code.google.com/p/go.tools/cover"
+)
+
+// now returns the time recorded in Cobertura reports; tests replace it.
+var now = time.Now
+
+// lineHits returns the lines of the file that contain statements,
+// in increasing order, and the execution count of each: the smallest
+// count of the blocks that touch the line, so that a line is reported
+// as covered only if all of its statements are.
+func lineHits(p *cover.Profile) (lines []int, hits map[int]int) {
+	hits = make(map[int]int)
+	for _, b := range p.Blocks {
+		if b.NumStmt == 0 {
+			continue
+		}
+		for line := b.StartLine; line <= b.EndLine; line++ {
+			if count, ok := hits[line]; !ok || b.Count < count {
+				if !ok {
+					lines = append(lines, line)
+				}
+				hits[line] = b.Count
+			}
+		}
+	}
+	sort.Ints(lines)
+	return lines, hits
+}
+
+// lcovOutput converts the profile to the LCOV tracefile format read
+// by genhtml and many coverage services, and writes it to outputFile
+// ("" means to write to standard output).
+func lcovOutput(profile, outputFile string) error {
+	profiles, err := cover.ParseProfiles(profile)
+	if err != nil {
+		return err
+	}
+	return writeOutput(outputFile, func(w io.Writer) error {
+		writeLCOV(w, profiles)
+		return nil
+	})
+}
+
+// writeLCOV writes a record for each file of the profiles, listing the
+// execution count of each line (DA), the number of lines found (LF)
+// and the number of them hit (LH).
+func writeLCOV(w io.Writer, profiles []*cover.Profile) {
+	fmt.Fprintln(w, "TN:")
+	for _, p := range profiles {
+		lines, hits := lineHits(p)
+		fmt.Fprintf(w, "SF:%s\n", p.FileName)
+		lh := 0
+		for _, line := range lines {
+			fmt.Fprintf(w, "DA:%d,%d\n", line, hits[line])
+			if hits[line] > 0 {
+				lh++
+			}
+		}
+		fmt.Fprintf(w, "LF:%d\nLH:%d\nend_of_record\n", len(lines), lh)
+	}
+}
+
+// The Cobertura report, in the format described by
+// http://cobertura.sourceforge.net/xml/coverage-04.dtd.
+// Branch coverage is not recorded by 'go test', so it is always zero.
+type coberturaCoverage struct {
+	XMLName         xml.Name           `xml:"coverage"`
+	LineRate        float64            `xml:"line-rate,attr"`
+	BranchRate      float64            `xml:"branch-rate,attr"`
+	LinesCovered    int                `xml:"lines-covered,attr"`
+	LinesValid      int                `xml:"lines-valid,attr"`
+	BranchesCovered int                `xml:"branches-covered,attr"`
+	BranchesValid   int                `xml:"branches-valid,attr"`
+	Complexity      float64            `xml:"complexity,attr"`
+	Version         string             `xml:"version,attr"`
+	Timestamp       int64              `xml:"timestamp,attr"`
+	Packages        []coberturaPackage `xml:"packages>package"`
+}
+
+type coberturaPackage struct {
+	Name       string           `xml:"name,attr"`
+	LineRate   float64          `xml:"line-rate,attr"`
+	BranchRate float64          `xml:"branch-rate,attr"`
+	Complexity float64          `xml:"complexity,attr"`
+	Classes    []coberturaClass `xml:"classes>class"`
+}
+
+// A coberturaClass describes one source file.
+type coberturaClass struct {
+	Name       string          `xml:"name,attr"`
+	Filename   string          `xml:"filename,attr"`
+	LineRate   float64         `xml:"line-rate,attr"`
+	BranchRate float64         `xml:"branch-rate,attr"`
+	Complexity float64         `xml:"complexity,attr"`
+	Methods    struct{}        `xml:"methods"`
+	Lines      []coberturaLine `xml:"lines>line"`
+}
+
+type coberturaLine struct {
+	Number int `xml:"number,attr"`
+	Hits   int `xml:"hits,attr"`
+}
+
+const coberturaDoctype = `<!DOCTYPE coverage SYSTEM "http://cobertura.sourceforge.net/xml/coverage-04.dtd">`
+
+// coberturaOutput converts the profile to a Cobertura XML report,
+// with a package for each directory and a class for each file, and
+// writes it to outputFile ("" means to write to standard output).
+func coberturaOutput(profile, outputFile string) error {
+	profiles, err := cover.ParseProfiles(profile)
+	if err != nil {
+		return err
+	}
+	return writeOutput(outputFile, func(w io.Writer) error {
+		return writeCobertura(w, profiles)
+	})
+}
+
+func writeCobertura(w io.Writer, profiles []*cover.Profile) error {
+	report := &coberturaCoverage{Timestamp: now().UnixNano() / int64(time.Millisecond)}
+	var pkg *coberturaPackage
+	var pkgCovered, pkgValid int
+	endPackage := func() {
+		if pkg != nil {
+			pkg.LineRate = rate(pkgCovered, pkgValid)
+			report.Packages = append(report.Packages, *pkg)
+		}
+	}
+	// The profiles are sorted by file name, so the files of each
+	// package are adjacent.
+	for _, p := range profiles {
+		dir := path.Dir(p.FileName)
+		if pkg == nil || pkg.Name != dir {
+			endPackage()
+			pkg = &coberturaPackage{Name: dir}
+			pkgCovered, pkgValid = 0, 0
+		}
+		lines, hits := lineHits(p)
+		class := coberturaClass{
+			Name:     strings.TrimSuffix(path.Base(p.FileName), ".go"),
+			Filename: p.FileName,
+		}
+		covered := 0
+		for _, line := range lines {
+			class.Lines = append(class.Lines, coberturaLine{line, hits[line]})
+			if hits[line] > 0 {
+				covered++
+			}
+		}
+		class.LineRate = rate(covered, len(lines))
+		pkg.Classes = append(pkg.Classes, class)
+		pkgCovered += covered
+		pkgValid += len(lines)
+		report.LinesCovered += covered
+		report.LinesValid += len(lines)
+	}
+	endPackage()
+	report.LineRate = rate(report.LinesCovered, report.LinesValid)
+
+	if _, err := io.WriteString(w, xml.Header+coberturaDoctype+"\n"); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "\t")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// rate returns the fraction of lines covered, or 1 if there are none.
+func rate(covered, valid int) float64 {
+	if valid == 0 {
+		return 1
+	}
+	return float64(covered) / float64(valid)
+}
@@ -0,0 +1,186 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file implements the merging and comparison of coverage profiles.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"code.google.com/p/go.tools/cover"
+)
+
+// readProfiles reads the named coverage profiles and merges them.
+func readProfiles(names []string) ([]*cover.Profile, error) {
+	var all []*cover.Profile
+	for _, name := range names {
+		profiles, err := cover.ParseProfiles(name)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, profiles...)
+	}
+	merged, err := cover.MergeProfiles(all)
+	if err != nil {
+		return nil, err
+	}
+	if len(merged) == 0 {
+		return nil, fmt.Errorf("no coverage data in %s", strings.Join(names, ", "))
+	}
+	return merged, nil
+}
+
+// mergeOutput merges the named profiles and writes the result, in the
+// format of 'go test -coverprofile', to outputFile ("" means to write
+// to standard output).
+func mergeOutput(profiles []string, outputFile string) error {
+	merged, err := readProfiles(profiles)
+	if err != nil {
+		return err
+	}
+	return writeOutput(outputFile, func(w io.Writer) error {
+		return cover.WriteProfiles(w, merged)
+	})
+}
+
+// diffOutput compares the coverage in the profiles oldProfile and
+// newProfile and reports, for each file whose coverage changed, the
+// change in coverage and the blocks that are not covered in the new
+// profile but were covered, or did not exist, in the old one, like this:
+//
+//	fmt/format.go: 92.1% -> 89.5% (-2.6%)
+//	fmt/format.go:131.22,134.3: 2 statements newly uncovered
+//	fmt/scan.go: new file, 75.0%
+//	fmt/scan.go:40.2,42.16: 1 statement newly uncovered
+//	total: 3 statements newly uncovered in 2 files
+//
+// If changedFile is not empty, it names a file listing the source
+// files to consider, one per line; the others are ignored.  A listed
+// file matches a profile's file name if it is equal to it or is a
+// suffix of it following a slash, so that paths relative to the root
+// of a repository match import paths.
+func diffOutput(oldProfile, newProfile, changedFile, outputFile string) error {
+	oldProfiles, err := readProfiles([]string{oldProfile})
+	if err != nil {
+		return err
+	}
+	newProfiles, err := readProfiles([]string{newProfile})
+	if err != nil {
+		return err
+	}
+	var changed []string
+	if changedFile != "" {
+		changed, err = readFileList(changedFile)
+		if err != nil {
+			return err
+		}
+	}
+	return writeOutput(outputFile, func(w io.Writer) error {
+		diffProfiles(w, oldProfiles, newProfiles, changed)
+		return nil
+	})
+}
+
+// readFileList returns the non-blank lines of the named file,
+// other than comments beginning with '#'.  The list is not nil even
+// if it is empty, so that no file matches it.
+func readFileList(name string) ([]string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	files := []string{}
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		files = append(files, line)
+	}
+	return files, s.Err()
+}
+
+// fileMatches reports whether the profile's file name matches one of
+// the files in the list.
+func fileMatches(fileName string, list []string) bool {
+	for _, f := range list {
+		f = strings.TrimPrefix(f, "./")
+		if fileName == f || strings.HasSuffix(fileName, "/"+f) {
+			return true
+		}
+	}
+	return false
+}
+
+// diffProfiles writes the comparison described at diffOutput.  If
+// changed is nil, all files are considered.
+func diffProfiles(w io.Writer, oldProfiles, newProfiles []*cover.Profile, changed []string) {
+	type extent struct{ startLine, startCol, endLine, endCol int }
+	old := make(map[string]*cover.Profile)
+	for _, p := range oldProfiles {
+		old[p.FileName] = p
+	}
+
+	var stmts, files int
+	for _, p := range newProfiles {
+		if changed != nil && !fileMatches(p.FileName, changed) {
+			continue
+		}
+		op := old[p.FileName]
+		oldCount := make(map[extent]int)
+		if op != nil {
+			for _, b := range op.Blocks {
+				oldCount[extent{b.StartLine, b.StartCol, b.EndLine, b.EndCol}] = b.Count
+			}
+		}
+		var uncovered []cover.ProfileBlock
+		for _, b := range p.Blocks {
+			if b.Count > 0 || b.NumStmt == 0 {
+				continue
+			}
+			if count, ok := oldCount[extent{b.StartLine, b.StartCol, b.EndLine, b.EndCol}]; ok && count == 0 {
+				continue // uncovered before too
+			}
+			uncovered = append(uncovered, b)
+		}
+
+		newPercent := percent(p.Coverage())
+		switch {
+		case op == nil:
+			fmt.Fprintf(w, "%s: new file, %.1f%%\n", p.FileName, newPercent)
+		case len(uncovered) > 0 || percent(op.Coverage()) != newPercent:
+			oldPercent := percent(op.Coverage())
+			fmt.Fprintf(w, "%s: %.1f%% -> %.1f%% (%+.1f%%)\n", p.FileName, oldPercent, newPercent, newPercent-oldPercent)
+		}
+		for _, b := range uncovered {
+			s := "s"
+			if b.NumStmt == 1 {
+				s = ""
+			}
+			fmt.Fprintf(w, "%s:%d.%d,%d.%d: %d statement%s newly uncovered\n",
+				p.FileName, b.StartLine, b.StartCol, b.EndLine, b.EndCol, b.NumStmt, s)
+			stmts += b.NumStmt
+		}
+		if len(uncovered) > 0 {
+			files++
+		}
+	}
+	fmt.Fprintf(w, "total: %d statements newly uncovered in %d files\n", stmts, files)
+}
+
+// percent returns the percentage of statements covered, or 100 if
+// there are no statements.
+func percent(covered, total int64) float64 {
+	if total == 0 {
+		return 100
+	}
+	return 100 * float64(covered) / float64(total)
+}
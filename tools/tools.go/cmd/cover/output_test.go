@@ -0,0 +1,212 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"code.google.com/p/go.tools/cover"
+)
+
+func parseProfiles(t *testing.T, data string) []*cover.Profile {
+	profiles, err := cover.ParseProfilesFromReader(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return profiles
+}
+
+const (
+	oldProfile = `mode: set
+x/a.go:1.10,3.2 2 1
+x/a.go:3.2,5.3 1 0
+x/a.go:6.1,8.2 3 1
+x/b.go:1.1,2.2 1 1
+`
+	newProfile = `mode: set
+x/a.go:1.10,3.2 2 0
+x/a.go:3.2,5.3 1 0
+x/a.go:6.1,8.2 3 1
+x/a.go:9.1,9.20 1 0
+x/b.go:1.1,2.2 1 1
+x/y/c.go:1.1,4.2 4 1
+x/y/c.go:5.1,6.2 1 0
+`
+)
+
+func TestDiff(t *testing.T) {
+	var buf bytes.Buffer
+	diffProfiles(&buf, parseProfiles(t, oldProfile), parseProfiles(t, newProfile), nil)
+	want := `x/a.go: 83.3% -> 42.9% (-40.5%)
+x/a.go:1.10,3.2: 2 statements newly uncovered
+x/a.go:9.1,9.20: 1 statement newly uncovered
+x/y/c.go: new file, 80.0%
+x/y/c.go:5.1,6.2: 1 statement newly uncovered
+total: 4 statements newly uncovered in 2 files
+`
+	if buf.String() != want {
+		t.Errorf("diff: want\n%s\nhave\n%s", want, buf.String())
+	}
+
+	buf.Reset()
+	diffProfiles(&buf, parseProfiles(t, oldProfile), parseProfiles(t, newProfile), []string{"./y/c.go", "b.go"})
+	want = `x/y/c.go: new file, 80.0%
+x/y/c.go:5.1,6.2: 1 statement newly uncovered
+total: 1 statements newly uncovered in 1 files
+`
+	if buf.String() != want {
+		t.Errorf("diff of changed files: want\n%s\nhave\n%s", want, buf.String())
+	}
+}
+
+func TestDiffNoChangedFiles(t *testing.T) {
+	f, err := ioutil.TempFile("", "changed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	fmt.Fprintf(f, "# No files changed.\n\n")
+	f.Close()
+
+	changed, err := readFileList(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed == nil || len(changed) != 0 {
+		t.Fatalf("readFileList: want empty non-nil list, have %#v", changed)
+	}
+	var buf bytes.Buffer
+	diffProfiles(&buf, parseProfiles(t, oldProfile), parseProfiles(t, newProfile), changed)
+	if want := "total: 0 statements newly uncovered in 0 files\n"; buf.String() != want {
+		t.Errorf("diff of no changed files: want\n%s\nhave\n%s", want, buf.String())
+	}
+}
+
+func TestThresholdMatch(t *testing.T) {
+	cases := []struct {
+		pattern, pkg string
+		ok           bool
+	}{
+		{"...", "x", true},
+		{"x", "x", true},
+		{"x", "x/y", false},
+		{"x/...", "x", true},
+		{"x/...", "x/y/z", true},
+		{"x/...", "xy", false},
+	}
+	for _, tt := range cases {
+		if _, ok := (threshold{tt.pattern, 0}).match(tt.pkg); ok != tt.ok {
+			t.Errorf("%q matches %q: want %v have %v", tt.pattern, tt.pkg, tt.ok, ok)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	thresholds, err := parseThresholds(strings.NewReader(`
+# Defaults.
+...	50
+x/...	40%
+x/y	90
+x/z	10
+`), "test.cfg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	failed := checkCoverage(&buf, thresholds, parseProfiles(t, newProfile))
+	want := "ok  \tx\t50.0%\t\t\t(minimum 40.0%)\n" +
+		"FAIL\tx/y\t80.0%\t\t\t(minimum 90.0%)\n" +
+		"FAIL\tx/z\tno coverage data\t(minimum 10.0%)\n"
+	if failed != 2 || buf.String() != want {
+		t.Errorf("check: want 2 failures and\n%s\nhave %d and\n%s", want, failed, buf.String())
+	}
+
+	for _, config := range []string{"x", "x 50 60", "x fifty", "x 101%"} {
+		if _, err := parseThresholds(strings.NewReader(config), "test.cfg"); err == nil || !strings.HasPrefix(err.Error(), "test.cfg:1: ") {
+			t.Errorf("parseThresholds(%q): want error at test.cfg:1, have %v", config, err)
+		}
+	}
+}
+
+const countProfile = `mode: count
+x/a.go:1.10,3.2 2 5
+x/a.go:3.2,5.3 1 0
+x/a.go:5.3,5.10 0 0
+x/y/c.go:2.1,2.9 1 0
+`
+
+func TestLCOV(t *testing.T) {
+	var buf bytes.Buffer
+	writeLCOV(&buf, parseProfiles(t, countProfile))
+	want := `TN:
+SF:x/a.go
+DA:1,5
+DA:2,5
+DA:3,0
+DA:4,0
+DA:5,0
+LF:5
+LH:2
+end_of_record
+SF:x/y/c.go
+DA:2,0
+LF:1
+LH:0
+end_of_record
+`
+	if buf.String() != want {
+		t.Errorf("lcov: want\n%s\nhave\n%s", want, buf.String())
+	}
+}
+
+func TestCobertura(t *testing.T) {
+	defer func(f func() time.Time) { now = f }(now)
+	now = func() time.Time { return time.Unix(1400000000, 0) }
+
+	var buf bytes.Buffer
+	if err := writeCobertura(&buf, parseProfiles(t, countProfile)); err != nil {
+		t.Fatal(err)
+	}
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE coverage SYSTEM "http://cobertura.sourceforge.net/xml/coverage-04.dtd">
+<coverage line-rate="0.3333333333333333" branch-rate="0" lines-covered="2" lines-valid="6" branches-covered="0" branches-valid="0" complexity="0" version="" timestamp="1400000000000">
+	<packages>
+		<package name="x" line-rate="0.4" branch-rate="0" complexity="0">
+			<classes>
+				<class name="a" filename="x/a.go" line-rate="0.4" branch-rate="0" complexity="0">
+					<methods></methods>
+					<lines>
+						<line number="1" hits="5"></line>
+						<line number="2" hits="5"></line>
+						<line number="3" hits="0"></line>
+						<line number="4" hits="0"></line>
+						<line number="5" hits="0"></line>
+					</lines>
+				</class>
+			</classes>
+		</package>
+		<package name="x/y" line-rate="0" branch-rate="0" complexity="0">
+			<classes>
+				<class name="c" filename="x/y/c.go" line-rate="0" branch-rate="0" complexity="0">
+					<methods></methods>
+					<lines>
+						<line number="2" hits="0"></line>
+					</lines>
+				</class>
+			</classes>
+		</package>
+	</packages>
+</coverage>
+`
+	if buf.String() != want {
+		t.Errorf("cobertura: want\n%s\nhave\n%s", want, buf.String())
+	}
+}
@@ -6,32 +6,34 @@
 // etc) often arise during software tool development and debugging, this
 // command is included in the go.tools repository.
 //
-// TODO(adonovan):
-// - support input files other than stdin
-// - suport alternative formats (AT&T GraphViz, CSV, etc),
-//   a comment syntax, etc.
-// - allow queries to nest, like Blaze query language.
-//
 package main
 
 import (
 	"bufio"
+	"encoding/csv"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 )
 
 const Usage = `digraph: queries over directed graphs in text form.
 
+Usage: digraph [flags] <query> <label> ...
+   or: digraph [flags] <expression>
+
 Graph format:
 
   Each line contains zero or more whitespace-separated fields.
   Each field declares a node, and if there are more than one,
   an edge from the first to each subsequent one.
-  The graph is provided on the standard input.
+  A field beginning with '#' starts a comment, which extends to
+  the end of the line.
+  The graph is provided on the standard input, or in the files
+  named by -in flags.
 
   For instance, the following (acyclic) graph specifies a partial order
   among the subtasks of getting dressed:
@@ -47,6 +49,23 @@ Graph format:
   The line "shirt tie sweater" indicates the two edges shirt -> tie and
   shirt -> sweater, not shirt -> tie -> sweater.
 
+  The graph may also be given in CSV format, in which each record is
+  interpreted like a line of the text format, or in the DOT format of
+  AT&T GraphViz, whose node attributes are preserved in DOT output.
+
+Flags:
+
+  -in file
+	read the graph from the named file ("-" for the standard input).
+	The flag may be repeated to read the union of several graphs.
+  -informat text|csv|dot
+	the format of the input.  By default it is determined by the
+	file name extension (.csv; .dot or .gv), and is text otherwise.
+  -format text|csv|dot
+	the format of the output (default text).  The csv format prints
+	the same records as the text format.  The dot format prints the
+	subgraph of the nodes in the result.
+
 Supported queries:
 
   nodes
@@ -62,13 +81,39 @@ Supported queries:
   reverse <label> ...
 	the set of nodes that transitively reach the specified nodes
   somepath <label> <label>
-	the list of nodes on some arbitrary path from the first node to the second
+	the list of nodes on some shortest path from the first node to the second
   allpaths <label> <label>
 	the set of nodes on all paths from the first node to the second
   sccs
 	all strongly connected components (one per line)
   scc <label>
 	the set of nodes nodes strongly connected to the specified one
+  toposort
+	the list of all nodes in topological order (the graph must be acyclic)
+  transitive-reduction
+	the graph with the fewest edges having the same reachability
+	as the (acyclic) input graph, printed in the input format
+
+Query expressions:
+
+  Queries may be combined into expressions, which must be passed
+  as a single argument.  The arguments of a query in an expression
+  are themselves expressions, which denote the sets of nodes in their
+  results: for example, forward(a, b) is the set of nodes reachable
+  from a or b.  The queries degree, sccs, toposort and
+  transitive-reduction accept an optional argument, restricting them
+  to a subgraph; for example, sccs(forward(a)).
+
+  The results of expressions may be combined by the operators union,
+  intersect and minus, which are left-associative and have equal
+  precedence.  Parentheses group subexpressions.  A node whose label
+  is the name of a query or an operator, or contains spaces,
+  parentheses, commas or quotes, must be written as a Go string
+  literal.
+
+  As an operand, sccs denotes the set of nodes that lie on a cycle.
+  The result of an expression is printed like that of its outermost
+  query; the result of an operator is printed as a set.
 
 Example usage:
 
@@ -79,14 +124,41 @@ Example usage:
    Show which clothes (see above) must be donned before a jacket:
    %  digraph reverse jacket <clothes.txt
 
+   Show the packages on import cycles that the oracle depends on:
+   % digraph -in imports.txt 'forward(code.google.com/p/go.tools/oracle) intersect sccs'
+
+   Draw the direct dependencies of a graph given in DOT format:
+   % digraph -in deps.dot -format dot transitive-reduction | dot -Tpng >deps.png
+
 `
 
+// fileList is a flag.Value for a repeated flag naming files.
+type fileList []string
+
+func (l *fileList) String() string { return strings.Join(*l, ",") }
+
+func (l *fileList) Set(name string) error {
+	*l = append(*l, name)
+	return nil
+}
+
+var (
+	inputs    fileList
+	inFormat  = flag.String("informat", "", "input format: text, csv or dot (default: by file name extension)")
+	outFormat = flag.String("format", "text", "output format: text, csv or dot")
+)
+
+func init() {
+	flag.Var(&inputs, "in", "read the graph from the named file (may be repeated)")
+}
+
 func main() {
+	flag.Usage = func() { fmt.Fprint(os.Stderr, Usage) }
 	flag.Parse()
 
 	args := flag.Args()
 	if len(args) == 0 {
-		fmt.Println(Usage)
+		fmt.Print(Usage)
 		return
 	}
 
@@ -98,16 +170,6 @@ func main() {
 
 type nodelist []string
 
-func (l nodelist) println(sep string) {
-	for i, label := range l {
-		if i > 0 {
-			fmt.Fprint(stdout, sep)
-		}
-		fmt.Fprint(stdout, label)
-	}
-	fmt.Fprintln(stdout)
-}
-
 type nodeset map[string]bool
 
 func (s nodeset) sort() nodelist {
@@ -147,6 +209,29 @@ func (g graph) addEdges(from string, to ...string) {
 	}
 }
 
+// nodes returns the set of nodes of g.
+func (g graph) nodes() nodeset {
+	nodes := make(nodeset)
+	for label := range g {
+		nodes[label] = true
+	}
+	return nodes
+}
+
+// subgraph returns the subgraph of g induced by the specified nodes.
+func (g graph) subgraph(nodes nodeset) graph {
+	sub := make(graph)
+	for label := range nodes {
+		edges := sub.addNode(label)
+		for succ := range g[label] {
+			if nodes[succ] {
+				edges[succ] = true
+			}
+		}
+	}
+	return sub
+}
+
 func (g graph) reachableFrom(roots nodeset) nodeset {
 	seen := make(nodeset)
 	var visit func(label string)
@@ -175,6 +260,8 @@ func (g graph) transpose() graph {
 	return rev
 }
 
+// sccs returns the strongly connected components of g, in
+// topological order.  The order is deterministic.
 func (g graph) sccs() []nodeset {
 	// Kosaraju's algorithm---Tarjan is overkill here.
 
@@ -185,13 +272,13 @@ func (g graph) sccs() []nodeset {
 	visit = func(label string) {
 		if !seen[label] {
 			seen[label] = true
-			for e := range g[label] {
+			for _, e := range g[label].sort() {
 				visit(e)
 			}
 			S = append(S, label)
 		}
 	}
-	for label := range g {
+	for _, label := range g.nodes().sort() {
 		visit(label)
 	}
 
@@ -222,190 +309,556 @@ func (g graph) sccs() []nodeset {
 	return sccs
 }
 
-func parse(rd io.Reader) (graph, error) {
-	g := make(graph)
+// cyclic reports whether the strongly connected component scc of g
+// contains a cycle, i.e. has more than one node or a self-loop.
+func (g graph) cyclic(scc nodeset) bool {
+	for label := range scc {
+		return len(scc) > 1 || g[label][label]
+	}
+	return false
+}
+
+// toposort returns the nodes of g in topological order, choosing the
+// lexically least node among those ready.  It fails if g is cyclic.
+func (g graph) toposort() (nodelist, error) {
+	indegree := make(map[string]int)
+	for _, edges := range g {
+		for succ := range edges {
+			indegree[succ]++
+		}
+	}
+	var ready nodelist
+	for label := range g {
+		if indegree[label] == 0 {
+			ready = append(ready, label)
+		}
+	}
+	order := make(nodelist, 0, len(g))
+	for len(ready) > 0 {
+		sort.Sort(sort.Reverse(sort.StringSlice(ready)))
+		label := ready[len(ready)-1]
+		ready = ready[:len(ready)-1]
+		order = append(order, label)
+		for succ := range g[label] {
+			indegree[succ]--
+			if indegree[succ] == 0 {
+				ready = append(ready, succ)
+			}
+		}
+	}
+	if len(order) < len(g) {
+		for _, scc := range g.sccs() {
+			if g.cyclic(scc) {
+				return nil, fmt.Errorf("graph contains a cycle: %s", strings.Join(scc.sort(), " "))
+			}
+		}
+	}
+	return order, nil
+}
 
+// transitiveReduction returns the transitive reduction of g: the
+// graph with the fewest edges and the same reachability.
+// It fails if g is cyclic.
+func (g graph) transitiveReduction() (graph, error) {
+	order, err := g.toposort()
+	if err != nil {
+		return nil, err
+	}
+	// reach[x] is the set of nodes reachable from x by a non-empty path.
+	reach := make(map[string]nodeset)
+	red := make(graph)
+	for i := len(order) - 1; i >= 0; i-- {
+		label := order[i]
+		edges := red.addNode(label)
+		r := make(nodeset)
+		for succ := range g[label] {
+			r[succ] = true
+			r.addAll(reach[succ])
+		}
+		reach[label] = r
+		// The edge to succ is redundant if succ is reachable
+		// through another successor.
+	edges:
+		for succ := range g[label] {
+			for other := range g[label] {
+				if other != succ && reach[other][succ] {
+					continue edges
+				}
+			}
+			edges[succ] = true
+		}
+	}
+	return red, nil
+}
+
+// ---------- Input ----------
+
+// parse parses a graph in text format, adding its nodes and edges to g.
+func parse(rd io.Reader, g graph) error {
 	in := bufio.NewScanner(rd)
 	for in.Scan() {
 		words := strings.Fields(in.Text())
+		for i, word := range words {
+			if strings.HasPrefix(word, "#") {
+				words = words[:i] // comment
+				break
+			}
+		}
 		if len(words) > 0 {
 			g.addEdges(words[0], words[1:]...)
 		}
 	}
-	if err := in.Err(); err != nil {
-		return nil, err
+	return in.Err()
+}
+
+// parseCSV parses a graph in CSV format, adding its nodes and edges to g.
+// Each record is interpreted like a line of the text format; empty
+// fields are ignored.
+func parseCSV(rd io.Reader, g graph) error {
+	r := csv.NewReader(rd)
+	r.FieldsPerRecord = -1
+	r.Comment = '#'
+	r.TrimLeadingSpace = true
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var words []string
+		for _, field := range record {
+			if field != "" {
+				words = append(words, field)
+			}
+		}
+		if len(words) > 0 {
+			g.addEdges(words[0], words[1:]...)
+		}
 	}
-	return g, nil
 }
 
 var stdin io.Reader = os.Stdin
 var stdout io.Writer = os.Stdout
 
-func digraph(cmd string, args []string) error {
-	// Parse the input graph.
-	g, err := parse(stdin)
-	if err != nil {
-		return err
+// readInput reads the graph from the files named by -in flags, or
+// from the standard input if there are none.  It returns the graph
+// and the attributes of its nodes.
+func readInput() (graph, map[string]attrList, error) {
+	g := make(graph)
+	attrs := make(map[string]attrList)
+	read := func(rd io.Reader, format string) error {
+		switch format {
+		case "text":
+			return parse(rd, g)
+		case "csv":
+			return parseCSV(rd, g)
+		case "dot":
+			return parseDOT(rd, g, attrs)
+		}
+		return fmt.Errorf("invalid input format %q", format)
 	}
 
-	// Parse the command line.
-	switch cmd {
-	case "nodes":
-		if len(args) != 0 {
-			return fmt.Errorf("usage: nodes")
-		}
-		nodes := make(nodeset)
-		for label := range g {
-			nodes[label] = true
+	if len(inputs) == 0 {
+		format := *inFormat
+		if format == "" {
+			format = "text"
 		}
-		nodes.sort().println("\n")
-
-	case "degree":
-		if len(args) != 0 {
-			return fmt.Errorf("usage: degree")
+		return g, attrs, read(stdin, format)
+	}
+	for _, name := range inputs {
+		format := *inFormat
+		if format == "" {
+			switch filepath.Ext(name) {
+			case ".csv":
+				format = "csv"
+			case ".dot", ".gv":
+				format = "dot"
+			default:
+				format = "text"
+			}
 		}
-		nodes := make(nodeset)
-		for label := range g {
-			nodes[label] = true
+		var err error
+		if name == "-" {
+			err = read(stdin, format)
+		} else {
+			var f *os.File
+			f, err = os.Open(name)
+			if err != nil {
+				return nil, nil, err
+			}
+			err = read(f, format)
+			f.Close()
 		}
-		rev := g.transpose()
-		for _, label := range nodes.sort() {
-			fmt.Fprintf(stdout, "%d\t%d\t%s\n", len(rev[label]), len(g[label]), label)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %s", name, err)
 		}
+	}
+	return g, attrs, nil
+}
 
-	case "succs", "preds":
-		if len(args) == 0 {
-			return fmt.Errorf("usage: %s <label> ...", cmd)
+// ---------- Queries ----------
+
+// A value is the result of a query: a subgraph of the input graph.
+// Some queries also order or group its nodes, which affects only how
+// the value is printed.
+type value struct {
+	g      graph
+	order  nodelist  // somepath, toposort: the nodes, in order
+	sccs   []nodeset // sccs: the components
+	degree bool      // degree: print the degrees of the nodes
+	edges  bool      // transitive-reduction: print the edges
+}
+
+// A queryInfo describes a query.
+type queryInfo struct {
+	minArgs, maxArgs int    // maxArgs < 0 means no limit
+	usage            string // query and its arguments, for errors
+	// eval computes the result of the query from those of its
+	// arguments, in input graph g.
+	eval func(g graph, args []value) (value, error)
+}
+
+var queries = map[string]*queryInfo{
+	"nodes":                {0, 0, "nodes", evalNodes},
+	"degree":               {0, 1, "degree", evalDegree},
+	"succs":                {1, -1, "succs <label> ...", evalSuccs},
+	"preds":                {1, -1, "preds <label> ...", evalPreds},
+	"forward":              {1, -1, "forward <label> ...", evalForward},
+	"reverse":              {1, -1, "reverse <label> ...", evalReverse},
+	"somepath":             {2, 2, "somepath <from> <to>", evalSomepath},
+	"allpaths":             {2, 2, "allpaths <from> <to>", evalAllpaths},
+	"sccs":                 {0, 1, "sccs", evalSCCs},
+	"scc":                  {1, 1, "scc <label>", evalSCC},
+	"toposort":             {0, 1, "toposort", evalToposort},
+	"transitive-reduction": {0, 1, "transitive-reduction", evalTransitiveReduction},
+}
+
+// union returns the set of nodes of all the values.
+func union(values []value) nodeset {
+	nodes := make(nodeset)
+	for _, v := range values {
+		for label := range v.g {
+			nodes[label] = true
 		}
-		g := g
-		if cmd == "preds" {
-			g = g.transpose()
+	}
+	return nodes
+}
+
+// describe returns a description of a set of nodes for an error message.
+func describe(nodes nodeset) string {
+	var quoted []string
+	for _, label := range nodes.sort() {
+		quoted = append(quoted, fmt.Sprintf("%q", label))
+	}
+	if len(quoted) == 1 {
+		return quoted[0]
+	}
+	return "{" + strings.Join(quoted, ", ") + "}"
+}
+
+// restrict returns the subgraph of g denoted by the optional argument.
+func restrict(g graph, args []value) graph {
+	if len(args) == 0 {
+		return g
+	}
+	return args[0].g
+}
+
+func evalNodes(g graph, args []value) (value, error) {
+	return value{g: g}, nil
+}
+
+func evalDegree(g graph, args []value) (value, error) {
+	return value{g: restrict(g, args), degree: true}, nil
+}
+
+func evalSuccs(g graph, args []value) (value, error) {
+	result := make(nodeset)
+	for root := range union(args) {
+		result.addAll(g[root])
+	}
+	return value{g: g.subgraph(result)}, nil
+}
+
+func evalPreds(g graph, args []value) (value, error) {
+	return evalSuccs(g.transpose(), args)
+}
+
+func evalForward(g graph, args []value) (value, error) {
+	return value{g: g.subgraph(g.reachableFrom(union(args)))}, nil
+}
+
+func evalReverse(g graph, args []value) (value, error) {
+	return value{g: g.subgraph(g.transpose().reachableFrom(union(args)))}, nil
+}
+
+func evalSomepath(g graph, args []value) (value, error) {
+	from, to := union(args[:1]), union(args[1:])
+
+	// Breadth-first search, visiting successors in order
+	// so that the result is deterministic.
+	pred := make(map[string]string)
+	queue := from.sort()
+	for _, label := range queue {
+		pred[label] = ""
+	}
+	for len(queue) > 0 {
+		label := queue[0]
+		queue = queue[1:]
+		if to[label] {
+			var path nodelist
+			for ; label != ""; label = pred[label] {
+				path = append(path, label)
+			}
+			for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+				path[i], path[j] = path[j], path[i]
+			}
+			nodes := make(nodeset)
+			for _, label := range path {
+				nodes[label] = true
+			}
+			return value{g: g.subgraph(nodes), order: path}, nil
 		}
-		result := make(nodeset)
-		for _, root := range args {
-			edges := g[root]
-			if edges == nil {
-				return fmt.Errorf("no such node %q", root)
+		for _, succ := range g[label].sort() {
+			if _, ok := pred[succ]; !ok {
+				pred[succ] = label
+				queue = append(queue, succ)
 			}
-			result.addAll(edges)
 		}
-		result.sort().println("\n")
+	}
+	return value{}, fmt.Errorf("no path from %s to %s", describe(from), describe(to))
+}
 
-	case "forward", "reverse":
-		if len(args) == 0 {
-			return fmt.Errorf("usage: %s <label> ...", cmd)
+func evalAllpaths(g graph, args []value) (value, error) {
+	from, to := union(args[:1]), union(args[1:])
+	// A node is on a path from 'from' to 'to' if it is reachable
+	// from 'from' and reaches 'to'.
+	fwd := g.reachableFrom(from)
+	nodes := make(nodeset)
+	for label := range g.transpose().reachableFrom(to) {
+		if fwd[label] {
+			nodes[label] = true
 		}
-		roots := make(nodeset)
-		for _, root := range args {
-			if g[root] == nil {
-				return fmt.Errorf("no such node %q", root)
-			}
-			roots[root] = true
+	}
+	if len(nodes) == 0 {
+		return value{}, fmt.Errorf("no path from %s to %s", describe(from), describe(to))
+	}
+	return value{g: g.subgraph(nodes)}, nil
+}
+
+func evalSCCs(g graph, args []value) (value, error) {
+	sub := restrict(g, args)
+	sccs := sub.sccs()
+	cyclic := make(nodeset)
+	for _, scc := range sccs {
+		if sub.cyclic(scc) {
+			cyclic.addAll(scc)
 		}
-		g := g
-		if cmd == "reverse" {
-			g = g.transpose()
+	}
+	return value{g: sub.subgraph(cyclic), sccs: sccs}, nil
+}
+
+func evalSCC(g graph, args []value) (value, error) {
+	labels := union(args)
+	result := make(nodeset)
+	for _, scc := range g.sccs() {
+		for label := range labels {
+			if scc[label] {
+				result.addAll(scc)
+				break
+			}
 		}
-		g.reachableFrom(roots).sort().println("\n")
+	}
+	return value{g: g.subgraph(result)}, nil
+}
+
+func evalToposort(g graph, args []value) (value, error) {
+	sub := restrict(g, args)
+	order, err := sub.toposort()
+	if err != nil {
+		return value{}, err
+	}
+	return value{g: sub, order: order}, nil
+}
+
+func evalTransitiveReduction(g graph, args []value) (value, error) {
+	red, err := restrict(g, args).transitiveReduction()
+	if err != nil {
+		return value{}, err
+	}
+	return value{g: red, edges: true}, nil
+}
 
-	case "somepath":
-		if len(args) != 2 {
-			return fmt.Errorf("usage: somepath <from> <to>")
+// eval returns the result of the query expression e in graph g.
+func (g graph) eval(e expr) (value, error) {
+	switch e := e.(type) {
+	case label:
+		if g[string(e)] == nil {
+			return value{}, fmt.Errorf("no such node %q", string(e))
+		}
+		return value{g: g.subgraph(nodeset{string(e): true})}, nil
+
+	case call:
+		info := queries[e.name]
+		if len(e.args) < info.minArgs || info.maxArgs >= 0 && len(e.args) > info.maxArgs {
+			return value{}, fmt.Errorf("usage: %s", info.usage)
+		}
+		var args []value
+		for _, arg := range e.args {
+			v, err := g.eval(arg)
+			if err != nil {
+				return value{}, err
+			}
+			args = append(args, v)
 		}
-		from, to := args[0], args[1]
-		if g[from] == nil {
-			return fmt.Errorf("no such 'from' node %q", from)
+		return info.eval(g, args)
+
+	case binary:
+		x, err := g.eval(e.x)
+		if err != nil {
+			return value{}, err
 		}
-		if g[to] == nil {
-			return fmt.Errorf("no such 'to' node %q", to)
+		y, err := g.eval(e.y)
+		if err != nil {
+			return value{}, err
 		}
-
-		seen := make(nodeset)
-		var visit func(path nodelist, label string) bool
-		visit = func(path nodelist, label string) bool {
-			if !seen[label] {
-				seen[label] = true
-				if label == to {
-					append(path, label).println("\n")
-					return true // unwind
+		result := make(graph)
+		switch e.op {
+		case "union":
+			for _, v := range []value{x, y} {
+				for label, edges := range v.g {
+					result.addEdges(label, edges.sort()...)
 				}
-				for e := range g[label] {
-					if visit(append(path, label), e) {
-						return true
+			}
+		case "intersect":
+			for label, edges := range x.g {
+				if yedges := y.g[label]; yedges != nil {
+					redges := result.addNode(label)
+					for succ := range edges {
+						if yedges[succ] {
+							redges[succ] = true
+						}
 					}
 				}
 			}
-			return false
-		}
-		if !visit(make(nodelist, 0, 100), from) {
-			return fmt.Errorf("no path from %q to %q", args[0], args[1])
+		case "minus":
+			nodes := make(nodeset)
+			for label := range x.g {
+				if y.g[label] == nil {
+					nodes[label] = true
+				}
+			}
+			result = x.g.subgraph(nodes)
 		}
+		return value{g: result}, nil
+	}
+	panic(fmt.Sprintf("unexpected expr %T", e))
+}
 
-	case "allpaths":
-		if len(args) != 2 {
-			return fmt.Errorf("usage: allpaths <from> <to>")
-		}
-		from, to := args[0], args[1]
-		if g[from] == nil {
-			return fmt.Errorf("no such 'from' node %q", from)
-		}
-		if g[to] == nil {
-			return fmt.Errorf("no such 'to' node %q", to)
-		}
+// ---------- Output ----------
 
-		seen := make(nodeset) // value of seen[x] indicates whether x is on some path to 'to'
-		var visit func(label string) bool
-		visit = func(label string) bool {
-			reachesTo, ok := seen[label]
-			if !ok {
-				reachesTo = label == to
+// A printer prints the records of a result in text or CSV format.
+type printer struct {
+	w   io.Writer
+	csv *csv.Writer // non-nil for CSV format
+}
 
-				seen[label] = reachesTo
-				for e := range g[label] {
-					if visit(e) {
-						reachesTo = true
-					}
-				}
-				seen[label] = reachesTo
-			}
-			return reachesTo
-		}
-		if !visit(from) {
-			return fmt.Errorf("no path from %q to %q", from, to)
-		}
-		for label, reachesTo := range seen {
-			if !reachesTo {
-				delete(seen, label)
-			}
+// record prints a record, separating the fields by sep in text format.
+func (p *printer) record(sep string, fields ...string) {
+	if p.csv != nil {
+		p.csv.Write(fields)
+	} else {
+		fmt.Fprintln(p.w, strings.Join(fields, sep))
+	}
+}
+
+// print prints the result v of a query over input graph g, whose
+// nodes have the specified attributes, in the specified format.
+func (v value) print(g graph, attrs map[string]attrList, format string) error {
+	if format == "dot" {
+		if v.degree {
+			return fmt.Errorf("the degree query cannot be printed in DOT format")
 		}
-		seen.sort().println("\n")
+		return writeDOT(stdout, v.g, attrs, v.sccs)
+	}
 
-	case "sccs":
-		if len(args) != 0 {
-			return fmt.Errorf("usage: sccs")
+	out := bufio.NewWriter(stdout)
+	p := &printer{w: out}
+	if format == "csv" {
+		p.csv = csv.NewWriter(out)
+	}
+	switch {
+	case v.degree:
+		rev := g.transpose()
+		for _, label := range v.g.nodes().sort() {
+			in, out := fmt.Sprint(len(rev[label])), fmt.Sprint(len(g[label]))
+			p.record("\t", in, out, label)
 		}
-		for _, scc := range g.sccs() {
-			scc.sort().println(" ")
+	case v.sccs != nil:
+		for _, scc := range v.sccs {
+			p.record(" ", scc.sort()...)
 		}
-
-	case "scc":
-		if len(args) != 1 {
-			return fmt.Errorf("usage: scc <label>")
+	case v.order != nil:
+		for _, label := range v.order {
+			p.record("", label)
 		}
-		label := args[0]
-		if g[label] == nil {
-			return fmt.Errorf("no such node %q", label)
+	case v.edges:
+		for _, label := range v.g.nodes().sort() {
+			p.record(" ", append(nodelist{label}, v.g[label].sort()...)...)
 		}
-		for _, scc := range g.sccs() {
-			if scc[label] {
-				scc.sort().println("\n")
-				break
-			}
+	default:
+		for _, label := range v.g.nodes().sort() {
+			p.record("", label)
 		}
+	}
+	if p.csv != nil {
+		p.csv.Flush()
+		if err := p.csv.Error(); err != nil {
+			return err
+		}
+	}
+	return out.Flush()
+}
 
+// digraph performs the query cmd with the specified arguments.
+// If cmd is a query and there are arguments, they are node labels.
+// Otherwise, cmd and the arguments form a query expression.
+func digraph(cmd string, args []string) error {
+	// Parse the command line.
+	var q expr
+	if queries[cmd] != nil && len(args) > 0 {
+		c := call{name: cmd}
+		for _, arg := range args {
+			c.args = append(c.args, label(arg))
+		}
+		q = c
+	} else {
+		var err error
+		q, err = parseQuery(strings.Join(append([]string{cmd}, args...), " "))
+		if err != nil {
+			return err
+		}
+	}
+	switch *outFormat {
+	case "text", "csv", "dot":
 	default:
+		return fmt.Errorf("invalid output format %q", *outFormat)
+	}
+
+	// Parse the input graph.
+	g, attrs, err := readInput()
+	if err != nil {
+		return err
+	}
+	if l, ok := q.(label); ok && string(l) == cmd && g[cmd] == nil {
 		return fmt.Errorf("no such command %q", cmd)
 	}
 
-	return nil
+	v, err := g.eval(q)
+	if err != nil {
+		return err
+	}
+	return v.print(g, attrs, *outFormat)
 }
@@ -3,6 +3,9 @@ package main
 import (
 	"bytes"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -22,6 +25,11 @@ a b c
 b d
 c d
 d c
+`
+
+	const g3 = `
+a b c
+b c
 `
 
 	for _, test := range []struct {
@@ -42,6 +50,32 @@ d c
 		{g2, "succs", []string{"a"}, "b\nc\n"},
 		{g2, "preds", []string{"c"}, "a\nd\n"},
 		{g2, "preds", []string{"c", "d"}, "a\nb\nc\nd\n"},
+
+		{g1, "somepath", []string{"shirt", "jacket"}, "shirt\nsweater\njacket\n"},
+		{g2, "somepath", []string{"a", "d"}, "a\nb\nd\n"},
+		{g1, "toposort", nil, "hat\nshirt\nshorts\npants\nbelt\nsocks\nshoes\nsweater\njacket\ntie\n"},
+		{g3, "transitive-reduction", nil, "a b\nb c\nc\n"},
+		{g3, "degree", nil, "0\t2\ta\n1\t1\tb\n2\t0\tc\n"},
+
+		// Comments.
+		{"a b # c\n# d e\n", "nodes", nil, "a\nb\n"},
+
+		// Expressions.
+		{g1, "reverse(jacket) minus shirt", nil, "jacket\nsweater\n"},
+		{g1, "forward(socks, shorts)", nil, "belt\npants\nshoes\nshorts\nsocks\n"},
+		{g1, "forward(socks) union forward(shorts) intersect reverse(shoes)", nil, "pants\nshoes\nshorts\nsocks\n"},
+		{g1, "forward(socks) union (forward(shorts) intersect reverse(shoes))", nil, "pants\nshoes\nshorts\nsocks\n"},
+		{g1, `succs("pants")`, nil, "belt\nshoes\n"},
+		{g2, "sccs", nil, "a\nb\nc d\n"},
+		{g2, "nodes minus sccs", nil, "a\nb\n"},
+		{g2, "reverse(forward(b) intersect sccs)", nil, "a\nb\nc\nd\n"},
+		{g2, "sccs(nodes minus d)", nil, "a\nc\nb\n"},
+		{g2, "scc(c)", nil, "c\nd\n"},
+		{g2, "toposort(a union b union c)", nil, "a\nb\nc\n"},
+		{g3, "transitive-reduction(nodes)", nil, "a b\nb c\nc\n"},
+		{g3, "degree(b)", nil, "1\t1\tb\n"},
+		{g3, "somepath(a, c)", nil, "a\nc\n"},
+		{g3, "allpaths(b, c)", nil, "b\nc\n"},
 	} {
 		stdin = strings.NewReader(test.input)
 		stdout = new(bytes.Buffer)
@@ -55,8 +89,181 @@ d c
 			t.Errorf("digraph(%s, %s) = %q, want %q", test.cmd, test.args, got, test.want)
 		}
 	}
+}
+
+func TestErrors(t *testing.T) {
+	const g = `
+a b
+b c
+c b
+`
+	for _, test := range []struct {
+		cmd  string
+		args []string
+		want string
+	}{
+		{"frobnicate", nil, `no such command "frobnicate"`},
+		{"nodes", []string{"a"}, "usage: nodes"},
+		{"forward", nil, "usage: forward <label> ..."},
+		{"forward", []string{"x"}, `no such node "x"`},
+		{"forward", []string{"a", "union", "b"}, `no such node "union"`},
+		{"somepath", []string{"c", "a"}, `no path from "c" to "a"`},
+		{"allpaths(b, a union x)", nil, `no such node "x"`},
+		{"allpaths(b minus b, a)", nil, `no path from {} to "a"`},
+		{"toposort", nil, "graph contains a cycle: b c"},
+		{"transitive-reduction", nil, "graph contains a cycle: b c"},
+		{"forward(a", nil, `invalid query "forward(a": got end of query, want ")"`},
+		{"frob(a)", nil, `invalid query "frob(a)": no such query "frob"`},
+		{"a union", nil, `invalid query "a union": got end of query, want node or query`},
+		{"union a", nil, `invalid query "union a": missing operand before "union"`},
+		{"a b", nil, `invalid query "a b": unexpected "b"`},
+		{`"a`, nil, `invalid query "\"a": unterminated string`},
+	} {
+		stdin = strings.NewReader(g)
+		stdout = new(bytes.Buffer)
+		err := digraph(test.cmd, test.args)
+		if err == nil {
+			t.Errorf("digraph(%s, %s) succeeded, want error %q", test.cmd, test.args, test.want)
+		} else if err.Error() != test.want {
+			t.Errorf("digraph(%s, %s) failed with %q, want %q", test.cmd, test.args, err, test.want)
+		}
+	}
+}
+
+func TestFormats(t *testing.T) {
+	const dot = `
+/* The clothes graph, in DOT format. */
+digraph clothes {
+	node [shape=box];
+	socks -> shoes;
+	shorts -> pants -> { belt shoes };
+	shirt -> tie [color=red];
+	shirt -> sweater -> jacket
+# preprocessor line
+	hat [label="a \"hat\"", shape=<<b>hat</b>>]; // comment
+	"sweater":n [style=filled]
+}
+`
+	defer func() {
+		*inFormat, *outFormat = "", "text"
+	}()
+	for _, test := range []struct {
+		input               string
+		inFormat, outFormat string
+		cmd                 string
+		args                []string
+		want                string
+	}{
+		{dot, "dot", "text", "reverse", []string{"jacket"}, "jacket\nshirt\nsweater\n"},
+		{dot, "dot", "text", "forward", []string{"shorts"}, "belt\npants\nshoes\nshorts\n"},
+		{dot, "dot", "dot", "forward(shirt) minus tie", nil, `digraph {
+	"jacket";
+	"shirt";
+	"sweater" [style="filled"];
+	"shirt" -> "sweater";
+	"sweater" -> "jacket";
+}
+`},
+		{dot, "dot", "dot", "hat", nil, `digraph {
+	"hat" [label="a \"hat\"", shape=<<b>hat</b>>];
+}
+`},
+		{"graph { a -- b -- c }", "dot", "text", "forward", []string{"c"}, "a\nb\nc\n"},
+		{"digraph { subgraph s { a b } -> c; d:p:n -> e }", "dot", "text", "preds", []string{"c", "e"}, "a\nb\nd\n"},
+		{"digraph { a -> b }\ndigraph { b -> c }", "dot", "text", "forward", []string{"a"}, "a\nb\nc\n"},
+
+		{"a,b,c\n\"x y\",a\n# comment\nd,,\n", "csv", "text", "nodes", nil, "a\nb\nc\nd\nx y\n"},
+		{"a,b,c\n\"x y\",a\n", "csv", "csv", "reverse", []string{"b"}, "a\nb\nx y\n"},
+		{"a b c\nb c\n", "text", "csv", "degree", nil, "0,2,a\n1,1,b\n2,0,c\n"},
+		{"a b c\nb c\nd\n", "text", "csv", "transitive-reduction", nil, "a,b\nb,c\nc\nd\n"},
+		{"a b\nb a c\n", "text", "csv", "sccs", nil, "a,b\nc\n"},
+		{"a b\nb a c\n", "text", "dot", "sccs", nil, `digraph {
+	subgraph cluster_0 {
+		"a";
+		"b";
+	}
+	"a";
+	"b";
+	"a" -> "b";
+	"b" -> "a";
+}
+`},
+		{"a b c\nb c\n", "text", "dot", "transitive-reduction", nil, `digraph {
+	"a";
+	"b";
+	"c";
+	"a" -> "b";
+	"b" -> "c";
+}
+`},
+	} {
+		stdin = strings.NewReader(test.input)
+		stdout = new(bytes.Buffer)
+		*inFormat, *outFormat = test.inFormat, test.outFormat
+		if err := digraph(test.cmd, test.args); err != nil {
+			t.Errorf("digraph(%s, %s) with %s input: %s", test.cmd, test.args, test.inFormat, err)
+			continue
+		}
+
+		got := stdout.(fmt.Stringer).String()
+		if got != test.want {
+			t.Errorf("digraph(%s, %s) with %s input, %s output = %q, want %q",
+				test.cmd, test.args, test.inFormat, test.outFormat, got, test.want)
+		}
+	}
+
+	// Errors.
+	for _, test := range []struct {
+		input, inFormat, outFormat, want string
+	}{
+		{"a b", "xml", "text", `invalid input format "xml"`},
+		{"a b", "text", "xml", `invalid output format "xml"`},
+		{"a b", "text", "dot", "the degree query cannot be printed in DOT format"},
+		{"digraph { a -- b }", "dot", "text", "DOT input, line 1: undirected edge in digraph"},
+		{"digraph {\n a -> \n}", "dot", "text", "DOT input, line 3: got '}', want ID"},
+		{"digraph { a [x=\"y] }", "dot", "text", "DOT input, line 1: unterminated string"},
+		{"\"a\nb", "csv", "text", `extraneous or missing " in quoted-field`},
+	} {
+		stdin = strings.NewReader(test.input)
+		stdout = new(bytes.Buffer)
+		*inFormat, *outFormat = test.inFormat, test.outFormat
+		err := digraph("degree", nil)
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("digraph(degree) with %s input %q and %s output: got error %v, want %q",
+				test.inFormat, test.input, test.outFormat, err, test.want)
+		}
+	}
+}
+
+func TestInputFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "digraph")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"a.txt": "a b # text\n",
+		"b.dot": "digraph { b -> c }",
+		"c.csv": "c,\"d e\"\n",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer func() { inputs = nil }()
+	inputs = fileList{filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.dot"), filepath.Join(dir, "c.csv"), "-"}
+	stdin = strings.NewReader("x a\n")
+	stdout = new(bytes.Buffer)
+	if err := digraph("forward", []string{"x"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := stdout.(fmt.Stringer).String(), "a\nb\nc\nd e\nx\n"; got != want {
+		t.Errorf("forward x = %q, want %q", got, want)
+	}
 
-	// TODO(adonovan):
-	// - test somepath (it's nondeterministic).
-	// - test errors
+	inputs = fileList{filepath.Join(dir, "nonesuch.txt")}
+	if err := digraph("nodes", nil); err == nil || !os.IsNotExist(err) {
+		t.Errorf("nodes with missing input: got %v, want not-exist error", err)
+	}
 }
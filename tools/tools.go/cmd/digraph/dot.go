@@ -0,0 +1,492 @@
+package main
+
+// This file defines the reader and writer for the AT&T GraphViz DOT
+// format (http://www.graphviz.org/doc/info/lang.html).
+//
+// The reader accepts the whole language, but retains only the nodes,
+// the edges and the attributes of node statements.  Ports, edge
+// attributes, attribute statements ("node [shape=box]") and graph
+// attributes are discarded.  The edges of an undirected graph are
+// treated as pairs of directed edges.
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// An attr is an attribute of a node.
+type attr struct {
+	key, value string
+	html       bool // value is an HTML string, <...>
+}
+
+// An attrList is a list of attributes, in order of first definition.
+type attrList []attr
+
+// set sets the attribute a, replacing any previous value.
+func (l attrList) set(a attr) attrList {
+	for i := range l {
+		if l[i].key == a.key {
+			l[i] = a
+			return l
+		}
+	}
+	return append(l, a)
+}
+
+// parseDOT parses a graph in DOT format, adding its nodes, their
+// attributes and its edges to g and attrs.
+func parseDOT(rd io.Reader, g graph, attrs map[string]attrList) (err error) {
+	data, err := ioutil.ReadAll(rd)
+	if err != nil {
+		return err
+	}
+	p := &dotParser{src: string(data), line: 1, bol: true, g: g, attrs: attrs}
+	defer func() {
+		switch e := recover().(type) {
+		case nil:
+		case dotError:
+			err = e
+		default:
+			panic(e)
+		}
+	}()
+	p.next()
+	for p.tok != dotEOF {
+		p.graph()
+	}
+	return nil
+}
+
+// A dotError is an error in the DOT input.  The parser panics with
+// a dotError, recovered by parseDOT.
+type dotError struct {
+	line int
+	msg  string
+}
+
+func (e dotError) Error() string { return fmt.Sprintf("DOT input, line %d: %s", e.line, e.msg) }
+
+// DOT tokens.  Punctuation is its own token.
+const (
+	dotEOF    = "EOF"
+	dotID     = "ID"     // identifier, numeral or string
+	dotEdgeOp = "EDGEOP" // -> or --
+)
+
+type dotParser struct {
+	src   string
+	line  int    // line number of src[0]
+	bol   bool   // src[0] is at the beginning of a line
+	tok   string // current token
+	lit   string // value of current ID or edge operator
+	kw    string // lower-cased keyword if the current ID is an unquoted keyword
+	html  bool   // current ID is an HTML string
+	g     graph
+	attrs map[string]attrList
+
+	directed bool // the current graph is a digraph
+}
+
+func (p *dotParser) errorf(format string, args ...interface{}) {
+	panic(dotError{p.line, fmt.Sprintf(format, args...)})
+}
+
+// advance consumes n bytes of input.
+func (p *dotParser) advance(n int) string {
+	s := p.src[:n]
+	p.line += strings.Count(s, "\n")
+	p.src = p.src[n:]
+	if n > 0 {
+		p.bol = s[n-1] == '\n'
+	}
+	return s
+}
+
+// skipSpace skips white space and comments.
+func (p *dotParser) skipSpace() {
+	for p.src != "" {
+		switch {
+		case p.src[0] == ' ' || p.src[0] == '\t' || p.src[0] == '\r' || p.src[0] == '\n':
+			p.advance(1)
+			continue
+		case p.src[0] == '#' && p.bol:
+			// C preprocessor output line.
+			p.skipLine()
+			continue
+		case strings.HasPrefix(p.src, "//"):
+			p.skipLine()
+			continue
+		case strings.HasPrefix(p.src, "/*"):
+			i := strings.Index(p.src, "*/")
+			if i < 0 {
+				p.errorf("unterminated comment")
+			}
+			p.advance(i + len("*/"))
+			continue
+		}
+		return
+	}
+}
+
+func (p *dotParser) skipLine() {
+	if i := strings.IndexByte(p.src, '\n'); i >= 0 {
+		p.advance(i)
+	} else {
+		p.advance(len(p.src))
+	}
+}
+
+// next reads the next token.
+func (p *dotParser) next() {
+	p.skipSpace()
+	p.lit, p.kw, p.html = "", "", false
+	if p.src == "" {
+		p.tok = dotEOF
+		return
+	}
+	switch c := p.src[0]; {
+	case c == '{' || c == '}' || c == '[' || c == ']' || c == ';' || c == ',' || c == '=' || c == ':':
+		p.tok = p.advance(1)
+	case strings.HasPrefix(p.src, "->") || strings.HasPrefix(p.src, "--"):
+		p.tok, p.lit = dotEdgeOp, p.advance(2)
+	case c == '"':
+		p.tok, p.lit = dotID, p.quoted()
+		// Concatenation: "a" + "b".
+		for {
+			save, line, bol := p.src, p.line, p.bol
+			p.skipSpace()
+			if !strings.HasPrefix(p.src, "+") {
+				p.src, p.line, p.bol = save, line, bol
+				break
+			}
+			p.advance(1)
+			p.skipSpace()
+			if !strings.HasPrefix(p.src, `"`) {
+				p.errorf("'+' not followed by string")
+			}
+			p.lit += p.quoted()
+		}
+	case c == '<':
+		p.tok, p.lit, p.html = dotID, p.htmlString(), true
+	case c == '-' || c == '.' || '0' <= c && c <= '9':
+		n := 0
+		if c == '-' {
+			n++
+		}
+		for n < len(p.src) && (p.src[n] == '.' || '0' <= p.src[n] && p.src[n] <= '9') {
+			n++
+		}
+		if n == 1 && c == '-' || p.src[:n] == "." {
+			p.errorf("invalid numeral %q", p.src[:n])
+		}
+		p.tok, p.lit = dotID, p.advance(n)
+	default:
+		n := 0
+		for n < len(p.src) {
+			r, size := utf8.DecodeRuneInString(p.src[n:])
+			if !(r == '_' || unicode.IsLetter(r) || r >= 0x80 || n > 0 && unicode.IsDigit(r)) {
+				break
+			}
+			n += size
+		}
+		if n == 0 {
+			p.errorf("unexpected character %q", c)
+		}
+		p.tok, p.lit = dotID, p.advance(n)
+		switch kw := strings.ToLower(p.lit); kw {
+		case "strict", "graph", "digraph", "subgraph", "node", "edge":
+			p.kw = kw
+		}
+	}
+}
+
+// quoted consumes a double-quoted string and returns its value.
+// Only \" is an escape sequence; a backslash-newline is elided.
+func (p *dotParser) quoted() string {
+	var buf []byte
+	for i := 1; i < len(p.src); i++ {
+		switch c := p.src[i]; {
+		case c == '"':
+			p.advance(i + 1)
+			return string(buf)
+		case c == '\\' && i+1 < len(p.src) && p.src[i+1] == '"':
+			buf = append(buf, '"')
+			i++
+		case c == '\\' && i+1 < len(p.src) && p.src[i+1] == '\n':
+			i++
+		case c == '\\' && strings.HasPrefix(p.src[i+1:], "\r\n"):
+			i += 2
+		default:
+			buf = append(buf, c)
+		}
+	}
+	p.errorf("unterminated string")
+	panic("unreachable")
+}
+
+// htmlString consumes an HTML string, <...> with balanced angle
+// brackets, and returns it including the outermost brackets.
+func (p *dotParser) htmlString() string {
+	depth := 0
+	for i := 0; i < len(p.src); i++ {
+		switch p.src[i] {
+		case '<':
+			depth++
+		case '>':
+			depth--
+			if depth == 0 {
+				return p.advance(i + 1)
+			}
+		}
+	}
+	p.errorf("unterminated HTML string")
+	panic("unreachable")
+}
+
+func (p *dotParser) want(tok string) {
+	if p.tok != tok {
+		p.errorf("got %s, want %s", p.describe(), tok)
+	}
+	p.next()
+}
+
+func (p *dotParser) describe() string {
+	switch p.tok {
+	case dotID, dotEdgeOp:
+		return fmt.Sprintf("%q", p.lit)
+	case dotEOF:
+		return "end of input"
+	}
+	return fmt.Sprintf("'%s'", p.tok)
+}
+
+// id consumes an ID and returns its value.
+func (p *dotParser) id() string {
+	if p.tok != dotID {
+		p.errorf("got %s, want ID", p.describe())
+	}
+	lit := p.lit
+	p.next()
+	return lit
+}
+
+// graph parses: [strict] (graph | digraph) [ID] '{' stmt_list '}'
+func (p *dotParser) graph() {
+	if p.kw == "strict" {
+		p.next()
+	}
+	switch p.kw {
+	case "graph":
+		p.directed = false
+	case "digraph":
+		p.directed = true
+	default:
+		p.errorf("got %s, want graph or digraph", p.describe())
+	}
+	p.next()
+	if p.tok == dotID && p.kw == "" {
+		p.next()
+	}
+	p.want("{")
+	p.stmtList()
+	p.want("}")
+}
+
+// stmtList parses: { stmt [';'] }
+func (p *dotParser) stmtList() {
+	for p.tok != "}" && p.tok != dotEOF {
+		p.stmt()
+		if p.tok == ";" {
+			p.next()
+		}
+	}
+}
+
+// stmt parses a statement.
+func (p *dotParser) stmt() {
+	switch p.kw {
+	case "graph", "node", "edge":
+		// attr_stmt: discard the attributes.
+		p.next()
+		p.attrList()
+		return
+	}
+
+	// A subgraph or node may start an edge statement.
+	var nodes []string
+	if p.tok == "{" || p.kw == "subgraph" {
+		nodes = p.subgraph()
+	} else {
+		name := p.id()
+		if p.tok == "=" {
+			// ID '=' ID: a graph attribute; discard it.
+			p.next()
+			p.id()
+			return
+		}
+		p.port()
+		p.g.addNode(name)
+		if p.tok != dotEdgeOp {
+			// node_stmt
+			for _, a := range p.attrList() {
+				p.attrs[name] = p.attrs[name].set(a)
+			}
+			return
+		}
+		nodes = []string{name}
+	}
+
+	// edge_stmt: the attributes of edges are discarded.
+	for p.tok == dotEdgeOp {
+		if p.lit == "--" && p.directed {
+			p.errorf("undirected edge in digraph")
+		} else if p.lit == "->" && !p.directed {
+			p.errorf("directed edge in graph")
+		}
+		p.next()
+		var to []string
+		if p.tok == "{" || p.kw == "subgraph" {
+			to = p.subgraph()
+		} else {
+			name := p.id()
+			p.port()
+			to = []string{name}
+		}
+		for _, from := range nodes {
+			p.g.addEdges(from, to...)
+			if !p.directed {
+				for _, to := range to {
+					p.g.addEdges(to, from)
+				}
+			}
+		}
+		nodes = to
+	}
+	p.attrList()
+}
+
+// port parses an optional port: [':' ID [':' ID]]
+func (p *dotParser) port() {
+	for i := 0; i < 2 && p.tok == ":"; i++ {
+		p.next()
+		p.id()
+	}
+}
+
+// subgraph parses: [subgraph [ID]] '{' stmt_list '}'
+// and returns the nodes it declares.
+func (p *dotParser) subgraph() []string {
+	if p.kw == "subgraph" {
+		p.next()
+		if p.tok == dotID && p.kw == "" {
+			p.next()
+		}
+	}
+	// Collect the nodes by parsing into a fresh graph,
+	// then merge it into the enclosing one.
+	g := p.g
+	p.g = make(graph)
+	p.want("{")
+	p.stmtList()
+	p.want("}")
+	sub := p.g
+	p.g = g
+	var nodes []string
+	for label, edges := range sub {
+		nodes = append(nodes, label)
+		p.g.addEdges(label, edges.sort()...)
+	}
+	return nodes
+}
+
+// attrList parses: { '[' [ a_list ] ']' }
+// and returns the attributes.
+func (p *dotParser) attrList() attrList {
+	var list attrList
+	for p.tok == "[" {
+		p.next()
+		for p.tok != "]" {
+			key := p.id()
+			p.want("=")
+			html := p.html
+			value := p.id()
+			list = list.set(attr{key, value, html})
+			if p.tok == ";" || p.tok == "," {
+				p.next()
+			}
+		}
+		p.next()
+	}
+	return list
+}
+
+// dotQuote returns s as a DOT double-quoted string.
+func dotQuote(s string) string {
+	return `"` + strings.Replace(s, `"`, `\"`, -1) + `"`
+}
+
+// dotName returns s as a DOT ID: unquoted if it is an identifier
+// other than a keyword, and double-quoted otherwise.
+func dotName(s string) string {
+	switch strings.ToLower(s) {
+	case "strict", "graph", "digraph", "subgraph", "node", "edge":
+		return dotQuote(s)
+	}
+	for i, r := range s {
+		if !(r == '_' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || i > 0 && '0' <= r && r <= '9') {
+			return dotQuote(s)
+		}
+	}
+	if s == "" {
+		return dotQuote(s)
+	}
+	return s
+}
+
+// writeDOT writes the subgraph g in DOT format, with the attributes
+// of its nodes.  If clusters is non-nil, the nodes of each set with
+// more than one element are drawn in a cluster.
+func writeDOT(w io.Writer, g graph, attrs map[string]attrList, clusters []nodeset) error {
+	out := bufio.NewWriter(w)
+	fmt.Fprintln(out, "digraph {")
+	for i, cluster := range clusters {
+		if len(cluster) > 1 {
+			fmt.Fprintf(out, "\tsubgraph cluster_%d {\n", i)
+			for _, label := range cluster.sort() {
+				fmt.Fprintf(out, "\t\t%s;\n", dotQuote(label))
+			}
+			fmt.Fprintf(out, "\t}\n")
+		}
+	}
+	for _, label := range g.nodes().sort() {
+		fmt.Fprintf(out, "\t%s", dotQuote(label))
+		if list := attrs[label]; len(list) > 0 {
+			fmt.Fprint(out, " [")
+			for i, a := range list {
+				if i > 0 {
+					fmt.Fprint(out, ", ")
+				}
+				value := a.value
+				if !a.html {
+					value = dotQuote(value)
+				}
+				fmt.Fprintf(out, "%s=%s", dotName(a.key), value)
+			}
+			fmt.Fprint(out, "]")
+		}
+		fmt.Fprintln(out, ";")
+	}
+	for _, from := range g.nodes().sort() {
+		for _, to := range g[from].sort() {
+			fmt.Fprintf(out, "\t%s -> %s;\n", dotQuote(from), dotQuote(to))
+		}
+	}
+	fmt.Fprintln(out, "}")
+	return out.Flush()
+}
@@ -0,0 +1,193 @@
+package main
+
+// This file defines the syntax of query expressions.
+//
+// The grammar is:
+//
+//	expr    = operand { ("union" | "intersect" | "minus") operand } .
+//	operand = "(" expr ")"
+//	        | label
+//	        | query [ "(" [ expr { "," expr } ] ")" ] .
+//	label   = word | string .
+//	query   = word .
+//
+// A word is a sequence of characters other than spaces, parentheses,
+// commas and double quotes; a string is a Go double-quoted string.
+// A word followed by "(" must name a query; a word that names a query
+// is a query with no arguments; any other word is a node label.
+// The operators are left-associative and have equal precedence.
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// An expr is a query expression: a label, a call or a binary.
+type expr interface{}
+
+// A label denotes the node of that name.
+type label string
+
+// A call applies a query to its (node set) arguments.
+type call struct {
+	name string
+	args []expr
+}
+
+// A binary combines the results of two expressions.
+type binary struct {
+	op   string // "union", "intersect" or "minus"
+	x, y expr
+}
+
+func isOperator(word string) bool {
+	return word == "union" || word == "intersect" || word == "minus"
+}
+
+// parseQuery parses a query expression.
+func parseQuery(input string) (expr, error) {
+	p := &queryParser{input: input}
+	p.next()
+	e := p.expr()
+	if p.err == nil && p.tok != "" {
+		p.errorf("unexpected %s", p.describe())
+	}
+	if p.err != nil {
+		return nil, fmt.Errorf("invalid query %q: %s", input, p.err)
+	}
+	return e, nil
+}
+
+type queryParser struct {
+	input  string
+	tok    string // current token: "(", ")", ",", a word, a string, or "" at EOF
+	quoted bool   // tok is a string, with the quotes removed
+	err    error  // first error
+}
+
+func (p *queryParser) errorf(format string, args ...interface{}) {
+	if p.err == nil {
+		p.err = fmt.Errorf(format, args...)
+	}
+	p.tok = "" // stop
+}
+
+// describe describes the current token for an error message.
+func (p *queryParser) describe() string {
+	if p.tok == "" && !p.quoted {
+		return "end of query"
+	}
+	return strconv.Quote(p.tok)
+}
+
+// next reads the next token.
+func (p *queryParser) next() {
+	p.input = strings.TrimLeftFunc(p.input, unicode.IsSpace)
+	p.quoted = false
+	if p.input == "" {
+		p.tok = ""
+		return
+	}
+	switch c := p.input[0]; c {
+	case '(', ')', ',':
+		p.tok, p.input = p.input[:1], p.input[1:]
+	case '"':
+		// Find the closing quote, skipping escapes.
+		i := 1
+		for i < len(p.input) && p.input[i] != '"' {
+			if p.input[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(p.input) {
+			p.errorf("unterminated string")
+			return
+		}
+		s, err := strconv.Unquote(p.input[:i+1])
+		if err != nil {
+			p.errorf("invalid string %s", p.input[:i+1])
+			return
+		}
+		p.tok, p.quoted, p.input = s, true, p.input[i+1:]
+	default:
+		i := strings.IndexFunc(p.input, func(r rune) bool {
+			return unicode.IsSpace(r) || strings.ContainsRune(`(),"`, r)
+		})
+		if i < 0 {
+			i = len(p.input)
+		}
+		p.tok, p.input = p.input[:i], p.input[i:]
+	}
+}
+
+// isPunct reports whether the current token is the punctuation tok.
+func (p *queryParser) isPunct(tok string) bool {
+	return !p.quoted && p.tok == tok
+}
+
+func (p *queryParser) expect(tok string) {
+	if !p.isPunct(tok) {
+		p.errorf("got %s, want %q", p.describe(), tok)
+		return
+	}
+	p.next()
+}
+
+func (p *queryParser) expr() expr {
+	x := p.operand()
+	for !p.quoted && isOperator(p.tok) {
+		op := p.tok
+		p.next()
+		x = binary{op, x, p.operand()}
+	}
+	return x
+}
+
+func (p *queryParser) operand() expr {
+	if p.isPunct("(") {
+		p.next()
+		x := p.expr()
+		p.expect(")")
+		return x
+	}
+	if p.quoted {
+		l := label(p.tok)
+		p.next()
+		return l
+	}
+	switch {
+	case p.tok == "" || p.tok == ")" || p.tok == ",":
+		p.errorf("got %s, want node or query", p.describe())
+		return nil
+	case isOperator(p.tok):
+		p.errorf("missing operand before %q", p.tok)
+		return nil
+	}
+
+	word := p.tok
+	p.next()
+	if !p.isPunct("(") {
+		if queries[word] != nil {
+			return call{name: word}
+		}
+		return label(word)
+	}
+	if queries[word] == nil {
+		p.errorf("no such query %q", word)
+		return nil
+	}
+	p.next()
+	c := call{name: word}
+	if !p.isPunct(")") {
+		c.args = append(c.args, p.expr())
+		for p.isPunct(",") {
+			p.next()
+			c.args = append(c.args, p.expr())
+		}
+	}
+	p.expect(")")
+	return c
+}
@@ -76,6 +76,8 @@ The flags are:
 		print HTML in command-line mode
 	-goroot=$GOROOT
 		Go root directory
+	-modules=""
+		list of directories searched for Go modules to serve
 	-http=addr
 		HTTP service address (e.g., '127.0.0.1:6060' or just ':6060')
 	-server=addr
@@ -101,6 +103,16 @@ By default, godoc looks at the packages it finds via $GOROOT and $GOPATH (if set
 This behavior can be altered by providing an alternative $GOROOT with the -goroot
 flag.
 
+Go modules are served with the -modules flag, whose directories are searched
+for go.mod files. The packages of each module appear at their module path,
+e.g. /pkg/example.com/m/, with the module version in the page header, and are
+indexed for search like all others. A replace directive naming a local
+directory, such as "replace example.com/greetings => ./greetings", serves that
+directory as the replaced module; modules nested in a module's tree are not
+part of it. For instance:
+
+	godoc -http=:6060 -modules=$HOME/helloworld
+
 When godoc runs as a web server and -index is set, a search index is maintained.
 The index is created at startup.
 
@@ -122,6 +134,14 @@ For instance, http://golang.org/pkg/math/big/?m=all,text shows the documentation
 for all (not just the exported) declarations of package big, in textual form (as
 it would appear when using godoc from the command line: "godoc -src math/big .*").
 
+The same information is available to programs in JSON form below /api/v1/:
+
+	/api/v1/pkg/<path>		documentation of a package (accepts "m" too)
+	/api/v1/cmd/<path>		documentation of a command
+	/api/v1/search?q=<query>	identifier and full text search results
+	/api/v1/analysis/pkg/<path>	call graph and types of a package (see -analysis)
+	/api/v1/analysis/file/<file>	links in a source file, e.g. src/fmt/print.go
+
 By default, godoc serves files from the file system of the underlying OS.
 Instead, a .zip file may be provided via the -zip flag, which contains
 the file system to serve. The file paths stored in the .zip file must use
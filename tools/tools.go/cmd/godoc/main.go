@@ -84,7 +84,8 @@ var (
 
 	// file system roots
 	// TODO(gri) consider the invariant that goroot always end in '/'
-	goroot = flag.String("goroot", runtime.GOROOT(), "Go root directory")
+	goroot  = flag.String("goroot", runtime.GOROOT(), "Go root directory")
+	modules = flag.String("modules", "", "list of directories searched for Go modules to serve, separated by "+string(filepath.ListSeparator))
 
 	// layout control
 	tabWidth       = flag.Int("tabwidth", 4, "tab width")
@@ -195,6 +196,26 @@ func main() {
 		fs.Bind("/src", gatefs.New(vfs.OS(p), fsGate), "/src", vfs.BindAfter)
 	}
 
+	// Bind the Go modules found below the -modules directories
+	// at their module paths.
+	var mods []*godoc.Module
+	if *modules != "" {
+		var roots []string
+		for _, dir := range filepath.SplitList(*modules) {
+			abs, err := filepath.Abs(dir)
+			if err != nil {
+				log.Fatal(err)
+			}
+			roots = append(roots, filepath.ToSlash(abs[len(filepath.VolumeName(abs)):]))
+		}
+		osfs := gatefs.New(vfs.OS(string(filepath.Separator)), fsGate)
+		var err error
+		if mods, err = godoc.FindModules(osfs, roots...); err != nil {
+			log.Fatal(err)
+		}
+		godoc.BindModules(fs, osfs, mods)
+	}
+
 	httpMode := *httpAddr != ""
 
 	var typeAnalysis, pointerAnalysis bool
@@ -213,6 +234,7 @@ func main() {
 
 	corpus := godoc.NewCorpus(fs)
 	corpus.Verbose = *verbose
+	corpus.Modules = mods
 	corpus.MaxResults = *maxResults
 	corpus.IndexEnabled = *indexEnabled && httpMode
 	if *maxResults == 0 {
@@ -285,6 +307,9 @@ func main() {
 			log.Printf("version = %s", runtime.Version())
 			log.Printf("address = %s", *httpAddr)
 			log.Printf("goroot = %s", *goroot)
+			for _, m := range mods {
+				log.Printf("module = %s", m)
+			}
 			log.Printf("tabwidth = %d", *tabWidth)
 			switch {
 			case !*indexEnabled:
@@ -19,6 +19,7 @@ import (
 	"go/build"
 	"io"
 	"log"
+	"net"
 	"os"
 	"runtime"
 	"runtime/pprof"
@@ -36,9 +37,16 @@ var ptalogFlag = flag.String("ptalog", "",
 
 var formatFlag = flag.String("format", "plain", "Output format.  One of {plain,json,xml}.")
 
+var listenFlag = flag.String("listen", "",
+	"Address on which the server listens in 'serve' mode: "+
+		"host:port for TCP, otherwise the path of a Unix domain socket.")
+
 // TODO(adonovan): flip this flag after PTA presolver is implemented.
 var reflectFlag = flag.Bool("reflect", false, "Analyze reflection soundly (slow).")
 
+var summariesFlag = flag.String("summaries", "",
+	"Directory in which to cache points-to analysis summaries, or empty to disable caching.")
+
 const useHelp = "Run 'oracle -help' for more information.\n"
 
 const helpMessage = `Go source code oracle.
@@ -52,6 +60,11 @@ The -format flag controls the output format:
 
 The -pos flag is required in all modes except 'callgraph'.
 
+The -summaries flag names a directory in which the pointer analysis
+caches what it learns about each package, so that later queries about
+the same program, or a slightly changed one, need not analyze the
+unchanged packages again.
+
 The mode argument determines the query to perform:
 
 	callees	  	show possible targets of selected function call
@@ -64,6 +77,13 @@ The mode argument determines the query to perform:
 	peers     	show send/receive corresponding to selected channel op
 	referrers 	show all refs to entity denoted by selected identifier
 
+In 'serve' mode, the oracle loads the program once and answers a
+stream of queries, encoded as JSON, on each connection to the address
+given by the -listen flag.  Requests may also supply the contents of
+modified editor buffers, or report files changed on disk; the next
+query reloads only the affected packages.  See the Request and
+Response types in package code.google.com/p/go.tools/oracle/serial.
+
 The user manual is available here:  http://golang.org/s/oracle-user-manual
 
 Examples:
@@ -74,6 +94,9 @@ Describe the syntax at offset 530 in this file (an import spec):
 
 Print the callgraph of the trivial web-server in JSON format:
 % oracle -format=json $GOROOT/src/net/http/triv.go callgraph
+
+Serve queries about the oracle itself on a Unix domain socket:
+% oracle -listen=/tmp/oracle.sock serve code.google.com/p/go.tools/cmd/oracle
 ` + loader.FromArgsUsage
 
 var cpuprofile = flag.String("cpuprofile", "", "write cpu profile to file")
@@ -152,6 +175,14 @@ func main() {
 		defer pprof.StopCPUProfile()
 	}
 
+	if mode == "serve" {
+		if err := serve(args, ptalog); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s.\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// -format flag
 	switch *formatFlag {
 	case "json", "plain", "xml":
@@ -162,7 +193,7 @@ func main() {
 	}
 
 	// Ask the oracle.
-	res, err := oracle.Query(args, mode, *posFlag, ptalog, &build.Default, *reflectFlag)
+	res, err := oracle.Query(args, mode, *posFlag, ptalog, &build.Default, *reflectFlag, &oracle.Options{SummaryDir: *summariesFlag})
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %s.\n", err)
 		os.Exit(1)
@@ -190,3 +221,26 @@ func main() {
 		res.WriteTo(os.Stdout)
 	}
 }
+
+// serve runs the oracle server for the program specified by args
+// until it fails.
+func serve(args []string, ptalog io.Writer) error {
+	if *listenFlag == "" {
+		return fmt.Errorf("no address specified (-listen flag)")
+	}
+	network := "unix"
+	if _, _, err := net.SplitHostPort(*listenFlag); err == nil {
+		network = "tcp"
+	}
+
+	s, err := oracle.NewServer(args, ptalog, &build.Default, *reflectFlag, &oracle.Options{SummaryDir: *summariesFlag})
+	if err != nil {
+		return err
+	}
+	l, err := net.Listen(network, *listenFlag)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	return s.Serve(l)
+}
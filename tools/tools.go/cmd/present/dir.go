@@ -60,11 +60,42 @@ var (
 	contentTemplate map[string]*template.Template
 )
 
+// templateFuncs returns the functions used by the content templates
+// when serving documents.  A static export (see export.go) replaces
+// those that render resources differently.
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"playable":  playable,
+		"exported":  func() bool { return false },
+		"srcURL":    func(url string) string { return url },
+		"output":    func(c present.Code) string { return "" },
+		"inlineCSS": func(name string) template.CSS { return "" },
+		"inlineJS":  func(name string) template.JS { return "" },
+	}
+}
+
 func initTemplates(base string) error {
+	var err error
+	contentTemplate, err = parseContentTemplates(base, templateFuncs())
+	if err != nil {
+		return err
+	}
+
+	dirListTemplate, err = template.ParseFiles(filepath.Join(base, "templates/dir.tmpl"))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// parseContentTemplates parses the content templates found in base,
+// with the functions funcs, and returns them by file extension.
+func parseContentTemplates(base string, funcs template.FuncMap) (map[string]*template.Template, error) {
 	// Locate the template file.
 	actionTmpl := filepath.Join(base, "templates/action.tmpl")
 
-	contentTemplate = make(map[string]*template.Template)
+	templates := make(map[string]*template.Template)
 
 	for ext, contentTmpl := range map[string]string{
 		".slide":   "slides.tmpl",
@@ -74,20 +105,13 @@ func initTemplates(base string) error {
 
 		// Read and parse the input.
 		tmpl := present.Template()
-		tmpl = tmpl.Funcs(template.FuncMap{"playable": playable})
+		tmpl = tmpl.Funcs(funcs)
 		if _, err := tmpl.ParseFiles(actionTmpl, contentTmpl); err != nil {
-			return err
+			return nil, err
 		}
-		contentTemplate[ext] = tmpl
-	}
-
-	var err error
-	dirListTemplate, err = template.ParseFiles(filepath.Join(base, "templates/dir.tmpl"))
-	if err != nil {
-		return err
+		templates[ext] = tmpl
 	}
-
-	return nil
+	return templates, nil
 }
 
 // renderDoc reads the present file, gets its template representation,
@@ -12,6 +12,7 @@ code.google.com/p/go.tools repository.
 
 Usage of present:
   -base="": base path for slide template and static resources
+  -export="": write static HTML of all presentations to this directory and exit
   -http="127.0.0.1:3999": HTTP service address (e.g., '127.0.0.1:3999')
   -nacl=false: use Native Client environment playground (prevents non-Go code execution)
   -orighost="": host component of web origin URL (e.g., 'localhost')
@@ -25,6 +26,16 @@ the generated output. The supported formats are:
 	.slide        // HTML5 slide presentation
 	.article      // article format, such as a blog post
 
+With -export, present renders every presentation below the current directory
+to a self-contained HTML file in the given directory, plus an index.html that
+lists them. Style sheets, scripts and local images are inlined, and the
+programs of .play invocations are run once so that their output appears next
+to the code. Printing an exported slide deck, for instance to PDF, puts each
+slide on its own page.
+
+While presenting slides, press 'N' to open a presenter window that shows the
+speaker notes of the current slide and follows the main window.
+
 The present file format is documented by the present package:
 http://godoc.org/code.google.com/p/go.tools/present
 */
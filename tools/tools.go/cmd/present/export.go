@@ -0,0 +1,231 @@
+// Copyright 2014 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !appengine
+
+package main
+
+// This file implements the static export of presentations: each
+// document is rendered to a self-contained HTML file, with its style
+// sheets, scripts and images inlined and the output of its runnable
+// code captured at export time.
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"code.google.com/p/go.tools/present"
+)
+
+// runTimeout limits the time a program may run during an export.
+const runTimeout = 10 * time.Second
+
+// An exporter renders the documents below a directory to static HTML.
+type exporter struct {
+	base      string // base path for templates and static resources
+	templates map[string]*template.Template
+	doc       string            // file name of the document being rendered
+	outputs   map[string]string // output of each program of doc
+}
+
+// export renders all presentable documents below the current
+// directory to HTML files in out, with an index.html listing them.
+func export(base, out string) error {
+	present.PlayEnabled = false // exported documents cannot run code
+	e := &exporter{base: base}
+	funcs := templateFuncs()
+	funcs["exported"] = func() bool { return true }
+	funcs["srcURL"] = e.srcURL
+	funcs["output"] = e.output
+	funcs["inlineCSS"] = e.inlineCSS
+	funcs["inlineJS"] = e.inlineJS
+	var err error
+	if e.templates, err = parseContentTemplates(base, funcs); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(out, 0755); err != nil {
+		return err
+	}
+	absOut, err := filepath.Abs(out)
+	if err != nil {
+		return err
+	}
+
+	var index dirEntrySlice
+	const dir = "."
+	err = filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			if abs, _ := filepath.Abs(path); abs == absOut || path != dir && !showDir(fi.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !isDoc(path) {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		name := strings.TrimSuffix(rel, filepath.Ext(rel)) + ".html"
+		title, err := e.exportDoc(path, filepath.Join(out, name))
+		if err != nil {
+			return err
+		}
+		log.Printf("exported %s", name)
+		index = append(index, dirEntry{Name: rel, Path: filepath.ToSlash(name), Title: title})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(filepath.Join(out, "index.html"))
+	if err != nil {
+		return err
+	}
+	if err := exportIndexTemplate.Execute(f, index); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+var exportIndexTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Presentations</title>
+</head>
+<body>
+<h1>Presentations</h1>
+<ul>
+{{range .}}<li><a href="{{.Path}}">{{.Name}}</a>: {{.Title}}</li>
+{{end}}</ul>
+</body>
+</html>
+`))
+
+// exportDoc renders the document in file name to the HTML file out
+// and returns its title.
+func (e *exporter) exportDoc(name, out string) (string, error) {
+	doc, err := parse(name, 0)
+	if err != nil {
+		return "", err
+	}
+	e.doc = name
+	e.outputs = make(map[string]string)
+	for _, s := range doc.Sections {
+		e.runPrograms(s.Elem)
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Render(&buf, e.templates[filepath.Ext(name)]); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(out), 0755); err != nil {
+		return "", err
+	}
+	return doc.Title, ioutil.WriteFile(out, buf.Bytes(), 0644)
+}
+
+// runPrograms runs the Go programs of the runnable code in elems and
+// its subsections, and records their output.
+func (e *exporter) runPrograms(elems []present.Elem) {
+	for _, elem := range elems {
+		switch elem := elem.(type) {
+		case present.Section:
+			e.runPrograms(elem.Elem)
+		case present.Code:
+			if elem.Program != nil && elem.Ext == ".go" {
+				if _, ok := e.outputs[string(elem.Program)]; !ok {
+					e.outputs[string(elem.Program)] = runProgram(elem.Program)
+				}
+			}
+		}
+	}
+}
+
+// runProgram runs the Go program src and returns its output, followed
+// by a line that reports its failure, if it fails, as the playground
+// does.
+func runProgram(src []byte) string {
+	dir, err := ioutil.TempDir("", "present")
+	if err != nil {
+		return fmt.Sprintf("Program exited: %v\n", err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "prog.go"), src, 0644); err != nil {
+		return fmt.Sprintf("Program exited: %v\n", err)
+	}
+
+	var out bytes.Buffer
+	cmd := exec.Command("go", "run", "prog.go")
+	cmd.Dir = dir
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Start(); err != nil {
+		return fmt.Sprintf("Program exited: %v\n", err)
+	}
+	timeout := time.AfterFunc(runTimeout, func() { cmd.Process.Kill() })
+	err = cmd.Wait()
+	if !timeout.Stop() {
+		fmt.Fprintf(&out, "\nProgram exited: process took too long.\n")
+	} else if err != nil {
+		fmt.Fprintf(&out, "\nProgram exited: %v.\n", err)
+	}
+	return out.String()
+}
+
+// srcURL returns url, the source of an image, as a data URL if it
+// refers to a local file.
+func (e *exporter) srcURL(url string) (template.URL, error) {
+	if strings.Contains(url, "://") || strings.HasPrefix(url, "//") || strings.HasPrefix(url, "data:") {
+		return template.URL(url), nil
+	}
+	// Relative URLs are relative to the document, absolute ones to
+	// the current directory, as when serving it.
+	name := filepath.Join(".", filepath.FromSlash(url))
+	if !strings.HasPrefix(url, "/") {
+		name = filepath.Join(filepath.Dir(e.doc), name)
+	}
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return "", err
+	}
+	typ := mime.TypeByExtension(filepath.Ext(name))
+	if typ == "" {
+		typ = http.DetectContentType(data)
+	}
+	return template.URL("data:" + typ + ";base64," + base64.StdEncoding.EncodeToString(data)), nil
+}
+
+// output returns the output of the runnable code c.
+func (e *exporter) output(c present.Code) string {
+	return e.outputs[string(c.Program)]
+}
+
+func (e *exporter) inlineCSS(name string) (template.CSS, error) {
+	b, err := ioutil.ReadFile(filepath.Join(e.base, "static", name))
+	return template.CSS(b), err
+}
+
+func (e *exporter) inlineJS(name string) (template.JS, error) {
+	b, err := ioutil.ReadFile(filepath.Join(e.base, "static", name))
+	return template.JS(b), err
+}
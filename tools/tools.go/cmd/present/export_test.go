@@ -0,0 +1,146 @@
+// Copyright 2014 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !appengine
+
+package main
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// inTempDir calls f with a new temporary directory, holding files,
+// as the current directory, and the original one as base.
+func inTempDir(t *testing.T, files map[string]string, f func(base string)) {
+	base, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "present")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, content := range files {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(base)
+	f(base)
+}
+
+func dataURL(typ, content string) string {
+	return "data:" + typ + ";base64," + base64.StdEncoding.EncodeToString([]byte(content))
+}
+
+func TestSrcURL(t *testing.T) {
+	files := map[string]string{
+		"talks/doc.slide":   "",
+		"talks/local.png":   "local",
+		"talks/img/sub.png": "sub",
+		"top.png":           "top",
+		"talks/notes.txt":   "text",
+	}
+	inTempDir(t, files, func(string) {
+		e := &exporter{doc: filepath.Join("talks", "doc.slide")}
+		for _, test := range []struct {
+			url, want string // want is "" if srcURL fails
+		}{
+			// relative to the document
+			{"local.png", dataURL("image/png", "local")},
+			{"img/sub.png", dataURL("image/png", "sub")},
+			{"../top.png", dataURL("image/png", "top")},
+			// absolute ones are relative to the current directory
+			{"/top.png", dataURL("image/png", "top")},
+			{"/talks/local.png", dataURL("image/png", "local")},
+			{"/local.png", ""},
+			{"missing.png", ""},
+			// the content type of unknown extensions is detected
+			{"notes.txt", dataURL("text/plain; charset=utf-8", "text")},
+			// remote and data URLs are kept
+			{"http://example.com/a.png", "http://example.com/a.png"},
+			{"//example.com/a.png", "//example.com/a.png"},
+			{"data:image/png;base64,AAAA", "data:image/png;base64,AAAA"},
+		} {
+			got, err := e.srcURL(test.url)
+			if test.want == "" {
+				if err == nil {
+					t.Errorf("srcURL(%q) = %q, want error", test.url, got)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("srcURL(%q): %v", test.url, err)
+			} else if string(got) != test.want {
+				t.Errorf("srcURL(%q) = %q, want %q", test.url, got, test.want)
+			}
+		}
+	})
+}
+
+func TestExport(t *testing.T) {
+	files := map[string]string{
+		"talks/hello.slide": `Hello, exports
+A subtitle
+
+* First slide
+
+Some text.
+
+.image gopher.png 10 20
+
+: Say hello.
+`,
+		"talks/gopher.png": "gopher",
+		"README":           "not a document",
+	}
+	inTempDir(t, files, func(base string) {
+		if err := initTemplates(base); err != nil {
+			t.Fatal(err)
+		}
+		if err := export(base, "out"); err != nil {
+			t.Fatal(err)
+		}
+
+		html, err := ioutil.ReadFile(filepath.Join("out", "talks", "hello.html"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range []string{
+			"<title>Hello, exports</title>",
+			"Some text.",
+			`<img src="` + dataURL("image/png", "gopher") + `" height="10" width="20">`,
+			"Say hello.",
+		} {
+			if !strings.Contains(string(html), want) {
+				t.Errorf("exported document does not contain %q", want)
+			}
+		}
+		if strings.Contains(string(html), `src="gopher.png"`) {
+			t.Errorf("exported document refers to image file")
+		}
+
+		index, err := ioutil.ReadFile(filepath.Join("out", "index.html"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := "<ul>\n<li><a href=\"talks/hello.html\">" + filepath.Join("talks", "hello.slide") + "</a>: Hello, exports</li>\n</ul>"
+		if !strings.Contains(string(index), want) {
+			t.Errorf("index.html does not contain %q:\n%s", want, index)
+		}
+	})
+}
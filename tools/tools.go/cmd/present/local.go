@@ -32,6 +32,7 @@ func main() {
 	flag.StringVar(&basePath, "base", "", "base path for slide template and static resources")
 	flag.BoolVar(&present.PlayEnabled, "play", true, "enable playground (permit execution of arbitrary user code)")
 	nativeClient := flag.Bool("nacl", false, "use Native Client environment playground (prevents non-Go code execution)")
+	exportDir := flag.String("export", "", "write static HTML of all presentations to this directory and exit")
 	flag.Parse()
 
 	if basePath == "" {
@@ -48,6 +49,13 @@ func main() {
 		log.Fatalf("Failed to parse templates: %v", err)
 	}
 
+	if *exportDir != "" {
+		if err := export(basePath, *exportDir); err != nil {
+			log.Fatalf("Export failed: %v", err)
+		}
+		return
+	}
+
 	ln, err := net.Listen("tcp", *httpAddr)
 	if err != nil {
 		log.Fatal(err)
@@ -198,6 +198,7 @@ function updateSlides() {
   enableSlideFrames(curSlide + 2);
 
   updateHash();
+  updateNotes();
 };
 
 function prevSlide() {
@@ -254,6 +255,64 @@ function triggerLeaveEvent(no) {
   el.dispatchEvent(evt);
 };
 
+/* Speaker notes */
+
+var notesWindow = null;
+
+function toggleNotesWindow() {
+  if (notesWindow && !notesWindow.closed) {
+    notesWindow.close();
+    notesWindow = null;
+    return;
+  }
+
+  notesWindow = window.open('', 'notes', 'width=640,height=480');
+  if (!notesWindow) {
+    return; // blocked by the browser
+  }
+  var doc = notesWindow.document;
+  doc.open();
+  doc.write('<!DOCTYPE html><html><head><meta charset="utf-8">' +
+            '<style>' +
+            'body { font-family: sans-serif; font-size: 24px; margin: 20px; }' +
+            '#position, #next { color: #666; font-size: 18px; }' +
+            '#notes p { line-height: 1.4em; }' +
+            '</style></head><body>' +
+            '<div id="position"></div><div id="notes"></div><div id="next"></div>' +
+            '</body></html>');
+  doc.close();
+  doc.title = 'Notes: ' + document.title;
+
+  // Keys pressed in the presenter window move the slides too.
+  doc.addEventListener('keydown', handleBodyKeyDown, false);
+  updateNotes();
+};
+
+// updateNotes shows the notes of the current slide in the presenter
+// window, if it is open.
+function updateNotes() {
+  if (!notesWindow || notesWindow.closed) {
+    return;
+  }
+  var doc = notesWindow.document;
+
+  var html = '';
+  var el = getSlideEl(curSlide);
+  var notes = el ? el.querySelectorAll('aside.note') : [];
+  for (var i = 0; i < notes.length; i++) {
+    html += '<p>' + notes[i].innerHTML + '</p>';
+  }
+  doc.getElementById('notes').innerHTML = html || '<p><i>No notes.</i></p>';
+
+  doc.getElementById('position').textContent =
+      'Slide ' + (curSlide + 1) + ' of ' + slideEls.length;
+
+  var next = getSlideEl(curSlide + 1);
+  var title = next && next.querySelector('h1, h2, h3');
+  doc.getElementById('next').textContent =
+      title ? 'Next: ' + title.textContent : '';
+};
+
 /* Touch events */
 
 function handleTouchStart(event) {
@@ -420,16 +479,30 @@ function handleBodyKeyDown(event) {
       prevSlide();
       event.preventDefault();
       break;
+
+    case 78: // N
+      if (inCode) break;
+      toggleNotesWindow();
+      event.preventDefault();
+      break;
   }
 };
 
 function addEventListeners() {
   document.addEventListener('keydown', handleBodyKeyDown, false);
+  window.addEventListener('unload', function() {
+    if (notesWindow) notesWindow.close();
+  }, false);
 };
 
 /* Initialization */
 
+// A static export inlines its style sheets and uses no web fonts.
+var STATIC_EXPORT = window['STATIC_EXPORT'] || false;
+
 function addFontStyle() {
+  if (STATIC_EXPORT) return;
+
   var el = document.createElement('link');
   el.rel = 'stylesheet';
   el.type = 'text/css';
@@ -440,11 +513,13 @@ function addFontStyle() {
 };
 
 function addGeneralStyle() {
-  var el = document.createElement('link');
-  el.rel = 'stylesheet';
-  el.type = 'text/css';
-  el.href = PERMANENT_URL_PREFIX + 'styles.css';
-  document.body.appendChild(el);
+  if (!STATIC_EXPORT) {
+    var el = document.createElement('link');
+    el.rel = 'stylesheet';
+    el.type = 'text/css';
+    el.href = PERMANENT_URL_PREFIX + 'styles.css';
+    document.body.appendChild(el);
+  }
 
   var el = document.createElement('meta');
   el.name = 'viewport';
@@ -458,6 +533,8 @@ function addGeneralStyle() {
 };
 
 function addPrintStyle() {
+  if (STATIC_EXPORT) return;
+
   var el = document.createElement('link');
   el.rel = 'stylesheet';
   el.type = 'text/css';
@@ -0,0 +1,268 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+// The interactive debugger of -interp=D.
+//
+// Commands are read from the standard input, which the interpreted
+// program should therefore not read.  Before the program starts, the
+// debugger accepts only the commands that manage breakpoints; run
+// (or continue) starts it.  An empty line repeats the last step
+// command.
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"code.google.com/p/go.tools/go/ssa"
+	"code.google.com/p/go.tools/go/ssa/interp"
+)
+
+const debugHelp = `Commands:
+  break file:line | func   set a breakpoint (func as in "main.f" or "(*main.T).m")
+  delete n                 delete breakpoint n
+  breakpoints              list the breakpoints
+  run, continue, c         run until the next breakpoint
+  step, s                  run to the next source line, entering calls
+  next, n                  run to the next source line of this function
+  stepi, si                run to the next SSA instruction
+  finish                   run until the selected function returns
+  print, p name            print a variable, parameter, SSA register or global
+  locals                   print the parameters and local variables
+  ssa                      print the SSA code of the current block
+  stack, bt                print the stack of this goroutine
+  goroutines               print the stacks of all goroutines
+  frame n                  select frame n of the stack (0 is innermost)
+  help                     print this message
+  quit                     exit
+`
+
+type debugger struct {
+	d     *interp.Debugger
+	in    *bufio.Reader
+	out   io.Writer
+	last  string              // the last step command
+	files map[string][]string // lines of source files, for listings
+}
+
+func newDebugger(prog *ssa.Program, in io.Reader, out io.Writer) *debugger {
+	db := &debugger{
+		d:     interp.NewDebugger(prog),
+		in:    bufio.NewReader(in),
+		out:   out,
+		files: make(map[string][]string),
+	}
+	db.d.Stopped = db.stopped
+	return db
+}
+
+// readCommand prompts for a command and returns its words.  It exits
+// at the end of the input.
+func (db *debugger) readCommand() []string {
+	fmt.Fprint(db.out, "(ssadump) ")
+	line, err := db.in.ReadString('\n')
+	if err != nil && line == "" {
+		fmt.Fprintln(db.out)
+		os.Exit(0)
+	}
+	return strings.Fields(line)
+}
+
+// setup reads the commands that precede the start of the program.
+func (db *debugger) setup() {
+	for {
+		args := db.readCommand()
+		if len(args) == 0 {
+			continue
+		}
+		switch args[0] {
+		case "run", "continue", "c":
+			return
+		case "break", "delete", "breakpoints", "help", "quit":
+			db.command(nil, args)
+		default:
+			fmt.Fprintln(db.out, "the program is not running; use run to start it")
+		}
+	}
+}
+
+// stopped is the interp.Debugger's Stopped function.
+func (db *debugger) stopped(s *interp.Stop) interp.Action {
+	if s.Breakpoint != nil {
+		fmt.Fprintf(db.out, "goroutine %d stopped at %s\n", s.Goroutine(), s.Breakpoint)
+	}
+	db.printLocation(s)
+	for {
+		args := db.readCommand()
+		if len(args) == 0 {
+			if db.last == "" {
+				continue
+			}
+			args = []string{db.last}
+		}
+		if action, resume := db.command(s, args); resume {
+			return action
+		}
+	}
+}
+
+// printLocation prints the function, position and source line of the
+// selected frame of s.
+func (db *debugger) printLocation(s *interp.Stop) {
+	posn := s.Position()
+	if !posn.IsValid() {
+		fmt.Fprintf(db.out, "%s()\n", s.Func())
+		return
+	}
+	fmt.Fprintf(db.out, "%s() %s\n", s.Func(), posn)
+	if line, ok := db.sourceLine(posn.Filename, posn.Line); ok {
+		fmt.Fprintf(db.out, "%d\t%s\n", posn.Line, line)
+	}
+}
+
+func (db *debugger) sourceLine(filename string, line int) (string, bool) {
+	lines, ok := db.files[filename]
+	if !ok {
+		data, err := ioutil.ReadFile(filename)
+		if err == nil {
+			lines = strings.Split(string(data), "\n")
+		}
+		db.files[filename] = lines
+	}
+	if line < 1 || line > len(lines) {
+		return "", false
+	}
+	return lines[line-1], true
+}
+
+// command executes a command.  s is nil if the program has not
+// started.  It reports whether the stopped goroutine should resume,
+// and how.
+func (db *debugger) command(s *interp.Stop, args []string) (interp.Action, bool) {
+	switch args[0] {
+	case "help":
+		fmt.Fprint(db.out, debugHelp)
+
+	case "quit":
+		os.Exit(0)
+
+	case "break":
+		if len(args) != 2 {
+			fmt.Fprintln(db.out, "usage: break file:line | func")
+			break
+		}
+		var b *interp.Breakpoint
+		var err error
+		if i := strings.LastIndex(args[1], ":"); i >= 0 {
+			var line int
+			line, err = strconv.Atoi(args[1][i+1:])
+			if err == nil {
+				b, err = db.d.BreakLine(args[1][:i], line)
+			}
+		} else {
+			b, err = db.d.BreakFunc(args[1])
+		}
+		if err != nil {
+			fmt.Fprintln(db.out, err)
+			break
+		}
+		fmt.Fprintln(db.out, b)
+
+	case "delete":
+		id, err := strconv.Atoi(argument(args))
+		if err != nil || !db.d.Clear(id) {
+			fmt.Fprintln(db.out, "usage: delete n, for an existing breakpoint n")
+		}
+
+	case "breakpoints":
+		for _, b := range db.d.Breakpoints() {
+			fmt.Fprintln(db.out, b)
+		}
+
+	case "run", "continue", "c":
+		return interp.Continue, true
+
+	case "step", "s":
+		db.last = "step"
+		return interp.Step, true
+
+	case "next", "n":
+		db.last = "next"
+		return interp.Next, true
+
+	case "stepi", "si":
+		db.last = "stepi"
+		return interp.StepInstr, true
+
+	case "finish":
+		return interp.Finish, true
+
+	case "print", "p":
+		if len(args) != 2 {
+			fmt.Fprintln(db.out, "usage: print name")
+			break
+		}
+		v, err := s.Print(args[1])
+		if err != nil {
+			fmt.Fprintln(db.out, err)
+			break
+		}
+		fmt.Fprintf(db.out, "%s = %s\n", args[1], v)
+
+	case "locals":
+		for _, l := range s.Locals() {
+			fmt.Fprintf(db.out, "%s %s = %s\n", l.Name, l.Type, l.Value)
+		}
+
+	case "ssa":
+		b := s.Block()
+		fmt.Fprintf(db.out, "%s:\n", b)
+		for _, instr := range b.Instrs {
+			marker := "  "
+			if instr == s.Instr() {
+				marker = "=>"
+			}
+			if v, ok := instr.(ssa.Value); ok && v.Name() != "" {
+				fmt.Fprintf(db.out, "%s\t%s = %s\n", marker, v.Name(), instr)
+			} else {
+				fmt.Fprintf(db.out, "%s\t%s\n", marker, instr)
+			}
+		}
+
+	case "stack", "bt":
+		fmt.Fprint(db.out, s.Stack())
+
+	case "goroutines":
+		fmt.Fprint(db.out, s.Goroutines())
+
+	case "frame":
+		n, err := strconv.Atoi(argument(args))
+		if err == nil {
+			err = s.Frame(n)
+		}
+		if err != nil {
+			fmt.Fprintln(db.out, "usage: frame n, for a frame n of the stack")
+			break
+		}
+		db.printLocation(s)
+
+	default:
+		fmt.Fprintf(db.out, "unknown command %q; try help\n", args[0])
+	}
+	return 0, false
+}
+
+// argument returns the single argument of a command, or "".
+func argument(args []string) string {
+	if len(args) != 2 {
+		return ""
+	}
+	return args[1]
+}
@@ -0,0 +1,98 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !windows,!plan9
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"code.google.com/p/go.tools/go/loader"
+	"code.google.com/p/go.tools/go/ssa"
+	"code.google.com/p/go.tools/go/ssa/interp"
+	"code.google.com/p/go.tools/go/types"
+)
+
+// TestDebuggerScript runs the debugger on a script of commands, as
+// if typed at its prompt, and checks what it prints.
+func TestDebuggerScript(t *testing.T) {
+	var conf loader.Config
+	if err := conf.CreateFromFilenames("main", "../../go/ssa/interp/testdata/debug.go"); err != nil {
+		t.Fatalf("CreateFromFilenames failed: %s", err)
+	}
+	conf.Import("runtime")
+	iprog, err := conf.Load()
+	if err != nil {
+		t.Fatalf("conf.Load failed: %s", err)
+	}
+	prog := ssa.Create(iprog, ssa.SanityCheckFunctions|ssa.GlobalDebug)
+	prog.BuildAll()
+	mainPkg := prog.Package(iprog.Created[0].Pkg)
+
+	script := []string{
+		"break debug.go:15",
+		"break (*main.T).inc",
+		"break nosuchfunc",
+		"print x", // not running yet
+		"run",
+		"print x",
+		"next",
+		"", // repeats next
+		"print total",
+		"delete 1",
+		"continue",
+		"print d",
+		"step",
+		"print G",
+		"finish",
+		"continue",
+	}
+	var out bytes.Buffer
+	db := newDebugger(prog, strings.NewReader(strings.Join(script, "\n")+"\n"), &out)
+	interp.Debug = db.d
+	defer func() { interp.Debug = nil }()
+	db.setup()
+	if exitCode := interp.Interpret(mainPkg, 0, &types.StdSizes{WordSize: 8, MaxAlign: 8}, "debug.go", nil); exitCode != 0 {
+		t.Fatalf("exit code was %d; output: %s", exitCode, out.String())
+	}
+
+	// The expected output, in order, with the prompts omitted.
+	want := []string{
+		"debug.go:15",
+		"(*main.T).inc",
+		"nosuchfunc",
+		"the program is not running; use run to start it",
+		"stopped at",
+		"main.sum() ",
+		"15\t\t\ttotal += x // breakpoint",
+		"x = 1",
+		"main.sum() ",
+		"14\t\tfor _, x := range xs {",
+		"main.sum() ",
+		"15\t\t\ttotal += x // breakpoint",
+		"total = 1",
+		"stopped at",
+		"(*main.T).inc() ",
+		"d = 6",
+		"(*main.T).inc() ",
+		"9\t\treturn t.n",
+		"G = 7",
+		"main.main() ",
+		"25\t\tif r := t.inc(s); r != G {",
+	}
+	got := out.String()
+	for _, w := range want {
+		i := strings.Index(got, w)
+		if i < 0 {
+			t.Fatalf("debugger output does not contain %q in order; output:\n%s", w, out.String())
+		}
+		got = got[i+len(w):]
+	}
+	if strings.Contains(got, "stopped at") {
+		t.Errorf("stopped at deleted breakpoint; output:\n%s", out.String())
+	}
+}
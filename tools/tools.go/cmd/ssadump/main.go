@@ -40,8 +40,12 @@ var interpFlag = flag.String("interp", "", `Options controlling the SSA test int
 The value is a sequence of zero or more more of these letters:
 R	disable [R]ecover() from panic; show interpreter crash instead.
 T	[T]race execution of the program.  Best for single-threaded programs!
+S	run goroutines one at a time, in an order [S]eeded by -seed.
+D	[D]ebug the program interactively; implies -build=D.
 `)
 
+var seedFlag = flag.Int64("seed", 0, "Seed for the -interp=S scheduler; the same seed gives the same interleaving.")
+
 const usage = `SSA builder and interpreter.
 Usage: ssadump [<flag> ...] <args> ...
 Use -help flag to display options.
@@ -49,6 +53,7 @@ Use -help flag to display options.
 Examples:
 % ssadump -build=FPG hello.go            # quickly dump SSA form of a single package
 % ssadump -run -interp=T hello.go        # interpret a program, with tracing
+% ssadump -run -interp=D hello.go        # debug a program interactively
 % ssadump -run -test unicode -- -test.v  # interpret the unicode package's tests, verbosely
 ` + loader.FromArgsUsage +
 	`
@@ -126,12 +131,18 @@ func doMain() error {
 	}
 
 	var interpMode interp.Mode
+	var debugFlag bool
 	for _, c := range *interpFlag {
 		switch c {
 		case 'T':
 			interpMode |= interp.EnableTracing
 		case 'R':
 			interpMode |= interp.DisableRecover
+		case 'S':
+			interpMode |= interp.DeterministicScheduling
+		case 'D':
+			debugFlag = true
+			mode |= ssa.GlobalDebug // the debugger needs the names of locals
 		default:
 			return fmt.Errorf("unknown -interp option: '%c'", c)
 		}
@@ -207,6 +218,12 @@ func doMain() error {
 				build.Default.GOARCH, runtime.GOARCH)
 		}
 
+		interp.Seed = *seedFlag
+		if debugFlag {
+			db := newDebugger(prog, os.Stdin, os.Stdout)
+			interp.Debug = db.d
+			db.setup()
+		}
 		interp.Interpret(main, interpMode, conf.TypeChecker.Sizes, main.Object.Path(), args)
 	}
 	return nil
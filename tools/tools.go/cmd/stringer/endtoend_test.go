@@ -19,6 +19,14 @@ import (
 // after generating the string method for its type. The rule is that for testdata/x.go
 // we run stringer -type X and then compile and run the program. The resulting
 // binary panics if the String method for X is not correct, including for error cases.
+// The programs for the other flags are run with the flags listed in flags.
+
+// flags holds the additional stringer flags for some testdata files.
+var flags = map[string][]string{
+	"color.go":  {"-trimprefix", "Color"},
+	"op.go":     {"-linecomment"},
+	"planet.go": {"-parse", "-text", "-json", "-sql", "-values", "-isvalid"},
+}
 
 func TestEndToEnd(t *testing.T) {
 	dir, err := ioutil.TempDir("", "stringer")
@@ -47,14 +55,15 @@ func TestEndToEnd(t *testing.T) {
 		}
 		// Names are known to be ASCII and long enough.
 		typeName := fmt.Sprintf("%c%s", name[0]+'A'-'a', name[1:len(name)-len(".go")])
-		stringerCompileAndRun(t, dir, stringer, typeName, name)
+		stringerCompileAndRun(t, dir, stringer, typeName, name, flags[name]...)
 	}
 }
 
 // stringerCompileAndRun runs stringer for the named file and compiles and
 // runs the target binary in directory dir. That binary will panic if the String method is incorrect.
-func stringerCompileAndRun(t *testing.T, dir, stringer, typeName, fileName string) {
-	t.Logf("run: %s %s\n", fileName, typeName)
+// The extra flags are passed to stringer.
+func stringerCompileAndRun(t *testing.T, dir, stringer, typeName, fileName string, extra ...string) {
+	t.Logf("run: %s %s %s\n", fileName, typeName, strings.Join(extra, " "))
 	source := filepath.Join(dir, fileName)
 	err := copy(source, filepath.Join("testdata", fileName))
 	if err != nil {
@@ -62,7 +71,8 @@ func stringerCompileAndRun(t *testing.T, dir, stringer, typeName, fileName strin
 	}
 	stringSource := filepath.Join(dir, typeName+"_string.go")
 	// Run stringer in temporary directory.
-	err = run(stringer, "-type", typeName, "-output", stringSource, source)
+	args := append([]string{"-type", typeName, "-output", stringSource}, extra...)
+	err = run(stringer, append(args, source)...)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -17,17 +17,27 @@ import (
 // Golden represents a test case.
 type Golden struct {
 	name   string
+	opts   Options
 	input  string // input; the package clause is provided when running the test.
 	output string // exected output.
 }
 
 var golden = []Golden{
-	{"day", day_in, day_out},
-	{"offset", offset_in, offset_out},
-	{"gap", gap_in, gap_out},
-	{"num", num_in, num_out},
-	{"unum", unum_in, unum_out},
-	{"prime", prime_in, prime_out},
+	{"day", Options{}, day_in, day_out},
+	{"offset", Options{}, offset_in, offset_out},
+	{"gap", Options{}, gap_in, gap_out},
+	{"num", Options{}, num_in, num_out},
+	{"unum", Options{}, unum_in, unum_out},
+	{"prime", Options{}, prime_in, prime_out},
+	{"trimprefix", Options{TrimPrefix: "Day"}, trimprefix_in, day_out},
+	{"linecomment", Options{LineComment: true}, linecomment_in, linecomment_out},
+	{"parse", Options{Parse: true}, size_in, size_out + size_parse_out},
+	{"text", Options{Text: true}, size_in, size_out + size_parse_out + size_text_out},
+	{"json", Options{JSON: true}, size_in, size_out + size_parse_out + size_json_out},
+	{"sql", Options{SQL: true}, size_in, size_out + size_parse_out + size_sql_out},
+	{"values", Options{Values: true}, gap_in, gap_out + gap_values_out},
+	{"isvalid", Options{IsValid: true}, gap_in, gap_out + gap_isvalid_out},
+	{"isvalid_one_run", Options{IsValid: true}, size_in, size_out + size_isvalid_out},
 }
 
 // Each example starts with "type XXX [u]int", with a single space separating them.
@@ -269,9 +279,182 @@ func (i Prime) String() string {
 }
 `
 
+// Names with a common prefix, trimmed by the -trimprefix flag.
+const trimprefix_in = `type Day int
+const (
+	DayMonday Day = iota
+	DayTuesday
+	DayWednesday
+	DayThursday
+	DayFriday
+	DaySaturday
+	DaySunday
+)
+`
+
+// Names taken from line comments, as with the -linecomment flag.
+const linecomment_in = `type Line int
+const (
+	A Line = iota // a
+	B             // b comment
+	C
+	D /* d */
+)
+`
+
+const linecomment_out = `
+const _Line_name = "ab commentCd"
+
+var _Line_index = [...]uint8{1, 10, 11, 12}
+
+func (i Line) String() string {
+	if i < 0 || i >= Line(len(_Line_index)) {
+		return fmt.Sprintf("Line(%d)", i)
+	}
+	hi := _Line_index[i]
+	lo := uint8(0)
+	if i > 0 {
+		lo = _Line_index[i-1]
+	}
+	return _Line_name[lo:hi]
+}
+`
+
+// The additional methods. The expected outputs are the String method
+// followed by the other methods.
+const size_in = `type Size int
+const (
+	Small Size = iota
+	Medium
+	Large
+)
+`
+
+const size_out = `
+const _Size_name = "SmallMediumLarge"
+
+var _Size_index = [...]uint8{5, 11, 16}
+
+func (i Size) String() string {
+	if i < 0 || i >= Size(len(_Size_index)) {
+		return fmt.Sprintf("Size(%d)", i)
+	}
+	hi := _Size_index[i]
+	lo := uint8(0)
+	if i > 0 {
+		lo = _Size_index[i-1]
+	}
+	return _Size_name[lo:hi]
+}
+`
+
+const size_parse_out = `
+var _Size_value = map[string]Size{
+	"Small":  Small,
+	"Medium": Medium,
+	"Large":  Large,
+}
+
+// ParseSize returns the Size whose String method returns s.
+func ParseSize(s string) (Size, error) {
+	if i, ok := _Size_value[s]; ok {
+		return i, nil
+	}
+	return 0, fmt.Errorf("invalid Size: %q", s)
+}
+`
+
+const size_text_out = `
+// MarshalText implements encoding.TextMarshaler.
+func (i Size) MarshalText() ([]byte, error) {
+	return []byte(i.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (i *Size) UnmarshalText(text []byte) error {
+	var err error
+	*i, err = ParseSize(string(text))
+	return err
+}
+`
+
+const size_json_out = `
+// MarshalJSON implements json.Marshaler, encoding i as a JSON string.
+func (i Size) MarshalJSON() ([]byte, error) {
+	return json.Marshal(i.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (i *Size) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("Size should be a string, got %s", data)
+	}
+	var err error
+	*i, err = ParseSize(s)
+	return err
+}
+`
+
+const size_sql_out = `
+// Value implements driver.Valuer, storing i as its name.
+func (i Size) Value() (driver.Value, error) {
+	return i.String(), nil
+}
+
+// Scan implements sql.Scanner.
+func (i *Size) Scan(value interface{}) error {
+	var s string
+	switch v := value.(type) {
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return fmt.Errorf("cannot scan %T into Size", value)
+	}
+	var err error
+	*i, err = ParseSize(s)
+	return err
+}
+`
+
+const size_isvalid_out = `
+// IsValid reports whether i is one of the declared values of Size.
+func (i Size) IsValid() bool {
+	return 0 <= i && i <= 2
+}
+`
+
+const gap_values_out = `
+// GapValues returns the values of Gap, in increasing order.
+func GapValues() []Gap {
+	return []Gap{
+		Two,
+		Three,
+		Five,
+		Six,
+		Seven,
+		Eight,
+		Nine,
+		Eleven,
+	}
+}
+`
+
+const gap_isvalid_out = `
+// IsValid reports whether i is one of the declared values of Gap.
+func (i Gap) IsValid() bool {
+	return 2 <= i && i <= 3 ||
+		5 <= i && i <= 9 ||
+		i == 11
+}
+`
+
 func TestGolden(t *testing.T) {
 	for _, test := range golden {
 		var g Generator
+		g.opts = test.opts
 		input := "package test\n" + test.input
 		file := test.name + ".go"
 		g.parsePackage(".", []string{file}, input)
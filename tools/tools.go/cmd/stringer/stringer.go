@@ -56,6 +56,27 @@
 // where t is the lower-cased name of the first type listed. It can be overridden
 // with the -output flag.
 //
+// The -trimprefix flag removes a prefix from the names of the constants, so
+// that, given -trimprefix=Pill, a constant PillAspirin prints as "Aspirin".
+// With the -linecomment flag, a constant followed by a comment on the same
+// line prints as the text of the comment instead:
+//
+//	Paracetamol // paracetamol (acetaminophen)
+//
+// Other flags ask for additional methods and functions:
+//
+//	-parse    func ParsePill(s string) (Pill, error), the inverse of String
+//	-text     MarshalText and UnmarshalText (encoding.TextMarshaler and TextUnmarshaler)
+//	-json     MarshalJSON and UnmarshalJSON, encoding a Pill as a JSON string
+//	-sql      Value and Scan (database/sql/driver.Valuer and database/sql.Scanner)
+//	-values   func PillValues() []Pill, listing the values in increasing order
+//	-isvalid  func (Pill) IsValid() bool, reporting whether a Pill is a declared value
+//
+// The -text, -json and -sql flags imply -parse. These methods use the names
+// printed by String, so the names are the stable external representation of
+// the values: renaming a constant changes the encoding. Distinct values must
+// therefore print as distinct names; stringer reports an error otherwise.
+//
 package main
 
 import (
@@ -83,6 +104,16 @@ import (
 var (
 	typeNames = flag.String("type", "", "comma-separated list of type names; must be set")
 	output    = flag.String("output", "", "output file name; default srcdir/<type>_string.go")
+
+	trimPrefix  = flag.String("trimprefix", "", "prefix to trim from the generated constant names")
+	lineComment = flag.Bool("linecomment", false, "use line comment text as printed text when present")
+
+	genParse   = flag.Bool("parse", false, "generate a Parse<type> function")
+	genText    = flag.Bool("text", false, "generate MarshalText and UnmarshalText methods; implies -parse")
+	genJSON    = flag.Bool("json", false, "generate MarshalJSON and UnmarshalJSON methods; implies -parse")
+	genSQL     = flag.Bool("sql", false, "generate Value and Scan methods for database/sql; implies -parse")
+	genValues  = flag.Bool("values", false, "generate a <type>Values function")
+	genIsValid = flag.Bool("isvalid", false, "generate an IsValid method")
 )
 
 // Usage is a replacement usage function for the flags package.
@@ -120,6 +151,16 @@ func main() {
 		dir string
 		g   Generator
 	)
+	g.opts = Options{
+		TrimPrefix:  *trimPrefix,
+		LineComment: *lineComment,
+		Parse:       *genParse,
+		Text:        *genText,
+		JSON:        *genJSON,
+		SQL:         *genSQL,
+		Values:      *genValues,
+		IsValid:     *genIsValid,
+	}
 	if len(args) == 1 && isDirectory(args[0]) {
 		dir = args[0]
 		g.parsePackageDir(args[0])
@@ -133,7 +174,15 @@ func main() {
 	g.Printf("\n")
 	g.Printf("package %s", g.pkg.name)
 	g.Printf("\n")
-	g.Printf("import \"fmt\"\n") // Used by all methods.
+	g.Printf("import (\n")
+	if g.opts.SQL {
+		g.Printf("\t\"database/sql/driver\"\n")
+	}
+	if g.opts.JSON {
+		g.Printf("\t\"encoding/json\"\n")
+	}
+	g.Printf("\t\"fmt\"\n") // Used by all methods.
+	g.Printf(")\n")
 
 	// Run generate for each type.
 	for _, typeName := range types {
@@ -167,8 +216,23 @@ func isDirectory(name string) bool {
 // Generator holds the state of the analysis. Primarily used to buffer
 // the output for format.Source.
 type Generator struct {
-	buf bytes.Buffer // Accumulated output.
-	pkg *Package     // Package we are scanning.
+	buf  bytes.Buffer // Accumulated output.
+	pkg  *Package     // Package we are scanning.
+	opts Options      // Options for the generated code.
+}
+
+// Options selects the names printed for the constants and the methods
+// generated in addition to String.
+type Options struct {
+	TrimPrefix  string // Prefix to remove from the constant names.
+	LineComment bool   // Use the line comment, if any, as the name.
+
+	Parse   bool // Generate Parse<type>.
+	Text    bool // Generate MarshalText and UnmarshalText; implies Parse.
+	JSON    bool // Generate MarshalJSON and UnmarshalJSON; implies Parse.
+	SQL     bool // Generate Value and Scan; implies Parse.
+	Values  bool // Generate <type>Values.
+	IsValid bool // Generate IsValid.
 }
 
 func (g *Generator) Printf(format string, args ...interface{}) {
@@ -182,6 +246,9 @@ type File struct {
 	// These fields are reset for each type being generated.
 	typeName string  // Name of the constant type.
 	values   []Value // Accumulator for constant values of that type.
+
+	trimPrefix  string
+	lineComment bool
 }
 
 type Package struct {
@@ -238,7 +305,7 @@ func (g *Generator) parsePackage(directory string, names []string, text interfac
 		if !strings.HasSuffix(name, ".go") {
 			continue
 		}
-		parsedFile, err := parser.ParseFile(fs, name, text, 0)
+		parsedFile, err := parser.ParseFile(fs, name, text, parser.ParseComments)
 		if err != nil {
 			log.Fatalf("parsing package: %s: %s", name, err)
 		}
@@ -272,13 +339,16 @@ func (pkg *Package) check(fs *token.FileSet, astFiles []*ast.File) {
 	pkg.typesPkg = typesPkg
 }
 
-// generate produces the String method for the named type.
+// generate produces the String method, and any other methods
+// selected by the options, for the named type.
 func (g *Generator) generate(typeName string) {
 	values := make([]Value, 0, 100)
 	for _, file := range g.pkg.files {
 		// Set the state for this run of the walker.
 		file.typeName = typeName
 		file.values = nil
+		file.trimPrefix = g.opts.TrimPrefix
+		file.lineComment = g.opts.LineComment
 		if file.file != nil {
 			ast.Inspect(file.file, file.genDecl)
 			values = append(values, file.values...)
@@ -309,6 +379,26 @@ func (g *Generator) generate(typeName string) {
 	default:
 		g.buildMap(runs, typeName)
 	}
+
+	o := g.opts
+	if o.Parse || o.Text || o.JSON || o.SQL {
+		g.buildParse(runs, typeName)
+	}
+	if o.Text {
+		g.Printf(textMethods, typeName)
+	}
+	if o.JSON {
+		g.Printf(jsonMethods, typeName)
+	}
+	if o.SQL {
+		g.Printf(sqlMethods, typeName)
+	}
+	if o.Values {
+		g.buildValues(runs, typeName)
+	}
+	if o.IsValid {
+		g.buildIsValid(runs, typeName)
+	}
 }
 
 // splitIntoRuns breaks the values into runs of contiguous sequences.
@@ -358,7 +448,8 @@ func (g *Generator) format() []byte {
 
 // Value represents a declared constant.
 type Value struct {
-	name string // The name of the constant.
+	originalName string // The name of the constant.
+	name         string // The name with trimmed prefix, or the line comment.
 	// The value is stored as a bit pattern alone. The boolean tells us
 	// whether to interpret it as an int64 or a uint64; the only place
 	// this matters is when sorting.
@@ -452,10 +543,14 @@ func (f *File) genDecl(node ast.Node) bool {
 				u64 = uint64(i64)
 			}
 			v := Value{
-				name:   name.Name,
-				value:  u64,
-				signed: info&types.IsUnsigned == 0,
-				str:    value.String(),
+				originalName: name.Name,
+				name:         strings.TrimPrefix(name.Name, f.trimPrefix),
+				value:        u64,
+				signed:       info&types.IsUnsigned == 0,
+				str:          value.String(),
+			}
+			if c := vspec.Comment; f.lineComment && c != nil && len(c.List) == 1 {
+				v.name = strings.TrimSpace(c.Text())
 			}
 			f.values = append(f.values, v)
 		}
@@ -650,3 +745,134 @@ const stringMap = `func (i %[1]s) String() string {
 	return fmt.Sprintf("%[1]s(%%d)", i)
 }
 `
+
+// buildParse generates the Parse function, which maps the name of each
+// value back to the value. Only the names printed by String are accepted.
+func (g *Generator) buildParse(runs [][]Value, typeName string) {
+	if err := checkNames(runs); err != nil {
+		log.Fatalf("can't generate Parse%s: %s", typeName, err)
+	}
+	g.Printf("\nvar _%s_value = map[string]%s{\n", typeName, typeName)
+	for _, values := range runs {
+		for _, value := range values {
+			g.Printf("\t%q: %s,\n", value.name, value.originalName)
+		}
+	}
+	g.Printf("}\n\n")
+	g.Printf(parseFunc, typeName)
+}
+
+// checkNames returns an error if two values have the same name, as they
+// may with -linecomment, since the name would not identify a single value.
+func checkNames(runs [][]Value) error {
+	seen := make(map[string]Value)
+	for _, values := range runs {
+		for _, value := range values {
+			if prev, ok := seen[value.name]; ok {
+				return fmt.Errorf("constants %s and %s have the same name %q", prev.originalName, value.originalName, value.name)
+			}
+			seen[value.name] = value
+		}
+	}
+	return nil
+}
+
+// Argument to format is the type name.
+const parseFunc = `// Parse%[1]s returns the %[1]s whose String method returns s.
+func Parse%[1]s(s string) (%[1]s, error) {
+	if i, ok := _%[1]s_value[s]; ok {
+		return i, nil
+	}
+	return 0, fmt.Errorf("invalid %[1]s: %%q", s)
+}
+`
+
+// Argument to format is the type name.
+const textMethods = `
+// MarshalText implements encoding.TextMarshaler.
+func (i %[1]s) MarshalText() ([]byte, error) {
+	return []byte(i.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (i *%[1]s) UnmarshalText(text []byte) error {
+	var err error
+	*i, err = Parse%[1]s(string(text))
+	return err
+}
+`
+
+// Argument to format is the type name.
+const jsonMethods = `
+// MarshalJSON implements json.Marshaler, encoding i as a JSON string.
+func (i %[1]s) MarshalJSON() ([]byte, error) {
+	return json.Marshal(i.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (i *%[1]s) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("%[1]s should be a string, got %%s", data)
+	}
+	var err error
+	*i, err = Parse%[1]s(s)
+	return err
+}
+`
+
+// Argument to format is the type name.
+const sqlMethods = `
+// Value implements driver.Valuer, storing i as its name.
+func (i %[1]s) Value() (driver.Value, error) {
+	return i.String(), nil
+}
+
+// Scan implements sql.Scanner.
+func (i *%[1]s) Scan(value interface{}) error {
+	var s string
+	switch v := value.(type) {
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return fmt.Errorf("cannot scan %%T into %[1]s", value)
+	}
+	var err error
+	*i, err = Parse%[1]s(s)
+	return err
+}
+`
+
+// buildValues generates the function returning the values of the type.
+func (g *Generator) buildValues(runs [][]Value, typeName string) {
+	g.Printf("\n// %sValues returns the values of %s, in increasing order.\n", typeName, typeName)
+	g.Printf("func %sValues() []%s {\n", typeName, typeName)
+	g.Printf("\treturn []%s{\n", typeName)
+	for _, values := range runs {
+		for _, value := range values {
+			g.Printf("\t\t%s,\n", value.originalName)
+		}
+	}
+	g.Printf("\t}\n")
+	g.Printf("}\n")
+}
+
+// buildIsValid generates the IsValid method, which tests membership of each run.
+func (g *Generator) buildIsValid(runs [][]Value, typeName string) {
+	g.Printf("\n// IsValid reports whether i is one of the declared values of %s.\n", typeName)
+	g.Printf("func (i %s) IsValid() bool {\n", typeName)
+	g.Printf("\treturn ")
+	for i, values := range runs {
+		if i > 0 {
+			g.Printf(" ||\n\t\t")
+		}
+		if len(values) == 1 {
+			g.Printf("i == %s", &values[0])
+		} else {
+			g.Printf("%s <= i && i <= %s", &values[0], &values[len(values)-1])
+		}
+	}
+	g.Printf("\n}\n")
+}
@@ -0,0 +1,33 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Names with a common prefix; run with -trimprefix=Color.
+
+package main
+
+import "fmt"
+
+type Color int
+
+const (
+	ColorRed Color = iota
+	ColorGreen
+	ColorBlue
+	ColorDefault = ColorBlue // Duplicate; doesn't appear in the output.
+)
+
+func main() {
+	ck(ColorRed, "Red")
+	ck(ColorGreen, "Green")
+	ck(ColorBlue, "Blue")
+	ck(ColorDefault, "Blue")
+	ck(-1, "Color(-1)")
+	ck(3, "Color(3)")
+}
+
+func ck(color Color, str string) {
+	if fmt.Sprint(color) != str {
+		panic("color.go: " + str)
+	}
+}
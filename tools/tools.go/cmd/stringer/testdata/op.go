@@ -0,0 +1,37 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Names from line comments; run with -linecomment.
+
+package main
+
+import "fmt"
+
+type Op int
+
+const (
+	Add Op = iota + 1 // +
+	Sub               // -
+	Mul               // *
+	Quo               // /
+
+	// Not a line comment.
+	Rem
+)
+
+func main() {
+	ck(Add, "+")
+	ck(Sub, "-")
+	ck(Mul, "*")
+	ck(Quo, "/")
+	ck(Rem, "Rem")
+	ck(0, "Op(0)")
+	ck(6, "Op(6)")
+}
+
+func ck(op Op, str string) {
+	if fmt.Sprint(op) != str {
+		panic("op.go: " + str)
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// The additional methods; run with -parse -text -json -sql -values -isvalid.
+
+package main
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+type Planet int
+
+const (
+	Mercury Planet = iota + 1
+	Venus
+	Earth
+	Mars
+	Jupiter
+	Saturn
+	Uranus
+	Neptune
+	Pluto Planet = 134340 // Minor planet number.
+)
+
+var (
+	_ encoding.TextMarshaler   = Planet(0)
+	_ encoding.TextUnmarshaler = (*Planet)(nil)
+	_ json.Marshaler           = Planet(0)
+	_ json.Unmarshaler         = (*Planet)(nil)
+	_ driver.Valuer            = Planet(0)
+	_ sql.Scanner              = (*Planet)(nil)
+)
+
+func main() {
+	// String and Parse.
+	for _, p := range PlanetValues() {
+		q, err := ParsePlanet(p.String())
+		if err != nil || q != p {
+			panic(fmt.Sprintf("planet.go: ParsePlanet(%q) = %d, %v", p, q, err))
+		}
+	}
+	for _, s := range []string{"", "Vulcan", "earth", "Planet(3)"} {
+		if _, err := ParsePlanet(s); err == nil {
+			panic(fmt.Sprintf("planet.go: ParsePlanet(%q) succeeded", s))
+		}
+	}
+
+	// Values and IsValid.
+	want := []Planet{Mercury, Venus, Earth, Mars, Jupiter, Saturn, Uranus, Neptune, Pluto}
+	if got := PlanetValues(); !reflect.DeepEqual(got, want) {
+		panic(fmt.Sprintf("planet.go: PlanetValues() = %v", got))
+	}
+	for _, p := range want {
+		if !p.IsValid() {
+			panic("planet.go: invalid " + p.String())
+		}
+	}
+	for _, p := range []Planet{-1, 0, 9, 134339, 134341} {
+		if p.IsValid() {
+			panic(fmt.Sprintf("planet.go: %d is valid", p))
+		}
+	}
+
+	// Text.
+	text, err := Earth.MarshalText()
+	if err != nil || string(text) != "Earth" {
+		panic(fmt.Sprintf("planet.go: MarshalText = %q, %v", text, err))
+	}
+	var p Planet
+	if err := p.UnmarshalText([]byte("Pluto")); err != nil || p != Pluto {
+		panic(fmt.Sprintf("planet.go: UnmarshalText = %d, %v", p, err))
+	}
+	if err := p.UnmarshalText([]byte("Vulcan")); err == nil {
+		panic("planet.go: UnmarshalText(Vulcan) succeeded")
+	}
+
+	// JSON, as a map key (using the text methods) and a value.
+	type Orbit struct {
+		Planet Planet
+		Moons  map[Planet]int
+	}
+	orbit := Orbit{Mars, map[Planet]int{Earth: 1, Mars: 2}}
+	data, err := json.Marshal(orbit)
+	if err != nil {
+		panic(err)
+	}
+	if s := `{"Planet":"Mars","Moons":{"Earth":1,"Mars":2}}`; string(data) != s {
+		panic("planet.go: json.Marshal = " + string(data))
+	}
+	var orbit2 Orbit
+	if err := json.Unmarshal(data, &orbit2); err != nil || !reflect.DeepEqual(orbit, orbit2) {
+		panic(fmt.Sprintf("planet.go: json.Unmarshal = %v, %v", orbit2, err))
+	}
+	for _, s := range []string{`"Vulcan"`, `3`, `null`} {
+		if err := p.UnmarshalJSON([]byte(s)); err == nil {
+			panic("planet.go: UnmarshalJSON succeeded for " + s)
+		}
+	}
+
+	// SQL.
+	v, err := Neptune.Value()
+	if err != nil || v != "Neptune" {
+		panic(fmt.Sprintf("planet.go: Value = %v, %v", v, err))
+	}
+	if err := p.Scan("Venus"); err != nil || p != Venus {
+		panic(fmt.Sprintf("planet.go: Scan(string) = %d, %v", p, err))
+	}
+	if err := p.Scan([]byte("Saturn")); err != nil || p != Saturn {
+		panic(fmt.Sprintf("planet.go: Scan([]byte) = %d, %v", p, err))
+	}
+	for _, v := range []interface{}{int64(3), nil, "Vulcan"} {
+		if err := p.Scan(v); err == nil {
+			panic(fmt.Sprintf("planet.go: Scan(%#v) succeeded", v))
+		}
+	}
+}
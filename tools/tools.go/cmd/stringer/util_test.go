@@ -54,7 +54,7 @@ Outer:
 	for n, test := range splitTests {
 		values := make([]Value, len(test.input))
 		for i, v := range test.input {
-			values[i] = Value{"", v, test.signed, fmt.Sprint(v)}
+			values[i] = Value{"", "", v, test.signed, fmt.Sprint(v)}
 		}
 		runs := splitIntoRuns(values)
 		if len(runs) != len(test.output) {
@@ -75,3 +75,20 @@ Outer:
 		}
 	}
 }
+
+func TestCheckNames(t *testing.T) {
+	runs := [][]Value{
+		{{"Add", "+", 1, true, "1"}, {"Sub", "-", 2, true, "2"}},
+		{{"Plus", "+", 5, true, "5"}},
+	}
+	err := checkNames(runs)
+	if err == nil {
+		t.Fatal("no error for duplicate names")
+	}
+	if want := `constants Add and Plus have the same name "+"`; err.Error() != want {
+		t.Errorf("got error %q; expected %q", err, want)
+	}
+	if err := checkNames(runs[:1]); err != nil {
+		t.Errorf("unexpected error for distinct names: %s", err)
+	}
+}
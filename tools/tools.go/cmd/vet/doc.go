@@ -127,7 +127,34 @@ Range loop variables
 
 Flag: -rangeloops
 
-Incorrect uses of range loop variables in closures.
+Incorrect uses of range loop variables in closures: those deferred, and
+those started as goroutines anywhere in the loop body, unless the loop
+waits for them, as with a sync.WaitGroup or a channel receive, before the
+next iteration.
+
+Lost context cancellation
+
+Flag: -lostcancel
+
+Cancel functions returned by context.WithCancel, WithTimeout and
+WithDeadline that are discarded, or that are not called, deferred or
+passed on along every path through the function, so that the context
+may be leaked.
+
+Unclosed HTTP response bodies
+
+Flag: -httpresponse
+
+Responses returned by http.Get and similar calls that are discarded, or
+whose Body is not closed and that are not passed on to be closed
+elsewhere.
+
+WaitGroup misuse
+
+Flag: -waitgroup
+
+Calls of sync.WaitGroup.Add inside the goroutine whose completion they
+count, which may run after the corresponding Wait has returned.
 
 Unreachable code
 
@@ -165,6 +192,17 @@ These flags configure the behavior of vet:
 		Check everything; disabled if any explicit check is requested.
 	-v
 		Verbose mode
+	-json
+		Print the diagnostics to standard output as a JSON array
+		rather than to standard error as text.  Each element gives
+		the check, the position and end of the problem as
+		file:line:column, the message, and any suggested fixes as
+		lists of edits replacing byte ranges of files.
+	-fix
+		Apply the suggested fix of each diagnostic that has one,
+		rewriting the source files, instead of reporting it.
+		Currently the assign and printf checks suggest fixes for
+		self-assignments and Println calls ending with a newline.
 	-printfuncs
 		A comma-separated list of print-like functions to supplement
 		the standard list.  Each entry is in the form Name:N where N
@@ -178,5 +216,28 @@ These flags configure the behavior of vet:
 		Whether to be strict about shadowing; can be noisy.
 	-test
 		For testing only: sets -all and -shadow.
+
+Additional checks
+
+The checks are implemented as analyzers registered with the package
+code.google.com/p/go.tools/go/vet, whose Main function runs them.  To
+build a vet command with additional checks, such as those particular
+to a project, define each as a vet.Analyzer, register it with
+vet.Register in an init function, and import the package defining it
+in a main package that calls vet.Main:
+
+	package main
+
+	import (
+		"code.google.com/p/go.tools/go/vet"
+		_ "example.com/vetchecks"
+	)
+
+	func main() {
+		vet.Main()
+	}
+
+The additional checks are enabled and disabled by flags of their
+names, like the standard ones.
 */
 package main
@@ -0,0 +1,63 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file contains tests for the httpresponse checker.
+
+package testdata
+
+import (
+	"io/ioutil"
+	"net/http"
+)
+
+func ResponseClosed(url string) ([]byte, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return ioutil.ReadAll(resp.Body)
+}
+
+func ResponseNotClosed(url string) ([]byte, error) {
+	resp, err := http.Get(url) // ERROR "resp.Body is not closed"
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+func ResponseDiscarded(url string) error {
+	_, err := http.Head(url) // ERROR "response of http.Head is discarded, so its Body cannot be closed"
+	return err
+}
+
+func ResponseReturned(url string) (*http.Response, error) {
+	resp, err := http.Get(url)
+	return resp, err
+}
+
+func ResponsePassed(url string) {
+	var r, err = http.Post(url, "text/plain", nil)
+	if err == nil {
+		consumeResponse(r)
+	}
+}
+
+func ResponseClosedInClosure(url string) {
+	resp, err := http.PostForm(url, nil)
+	if err != nil {
+		return
+	}
+	func() {
+		resp.Body.Close()
+	}()
+}
+
+func consumeResponse(resp *http.Response) {
+	resp.Body.Close()
+}
@@ -0,0 +1,120 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file contains tests for the lostcancel checker.
+
+package testdata
+
+import (
+	"log"
+	"time"
+
+	"code.google.com/p/go.net/context"
+)
+
+var bg context.Context
+
+func LostCancelDeferred() {
+	ctx, cancel := context.WithCancel(bg)
+	defer cancel()
+	_ = ctx
+}
+
+func LostCancelCalled() error {
+	ctx, cancel := context.WithTimeout(bg, time.Second)
+	err := useContext(ctx)
+	cancel()
+	return err
+}
+
+func LostCancelDiscarded() {
+	ctx, _ := context.WithCancel(bg) // ERROR "the cancel function returned by context.WithCancel should be called, not discarded, to avoid a context leak"
+	_ = ctx
+}
+
+func LostCancelEarlyReturn() error {
+	ctx, cancel := context.WithTimeout(bg, time.Second) // ERROR "the cancel function is not used on all paths \(possible context leak\)"
+	if err := useContext(ctx); err != nil {
+		return err // ERROR "this return statement may be reached without using the cancel var defined on line 37"
+	}
+	cancel()
+	return nil
+}
+
+func LostCancelLoopBody(ch chan int) {
+	var ctx, stop = context.WithDeadline(bg, time.Now()) // ERROR "the stop function is not used on all paths \(possible context leak\)"
+	_ = ctx
+	for _ = range ch {
+		stop()
+	}
+}
+
+func LostCancelAllBranches(b bool) {
+	ctx, cancel := context.WithCancel(bg)
+	if b {
+		cancel()
+	} else {
+		go cancelLater(cancel)
+	}
+	_ = ctx
+}
+
+func LostCancelOneBranch(b bool) {
+	ctx, cancel := context.WithCancel(bg) // ERROR "the cancel function is not used on all paths \(possible context leak\)"
+	if b {
+		cancel()
+	}
+	_ = ctx
+}
+
+func LostCancelSwitch(n int) int {
+	ctx, cancel := context.WithCancel(bg)
+	_ = ctx
+	switch n {
+	case 0:
+		cancel()
+		return 0
+	case 1:
+		log.Fatal("unreachable")
+	}
+	defer cancel()
+	return n
+}
+
+func LostCancelLoop(ch chan int) {
+	ctx, cancel := context.WithCancel(bg)
+	_ = ctx
+	for {
+		select {
+		case <-ch:
+			cancel()
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func LostCancelClosure() {
+	ctx, cancel := context.WithCancel(bg)
+	f := func() {
+		cancel()
+	}
+	f()
+	_ = ctx
+
+	func() {
+		ctx, cancel := context.WithCancel(bg) // ERROR "the cancel function is not used on all paths \(possible context leak\)"
+		if ctx == nil {
+			return // ERROR "this return statement may be reached without using the cancel var defined on line 107"
+		}
+		cancel()
+	}()
+}
+
+func useContext(ctx context.Context) error { return nil }
+
+func cancelLater(cancel context.CancelFunc) {
+	time.Sleep(time.Second)
+	cancel()
+}
@@ -6,6 +6,8 @@
 
 package testdata
 
+import "sync"
+
 func RangeLoopTests() {
 	var s []int
 	for i, v := range s {
@@ -32,9 +34,45 @@ func RangeLoopTests() {
 	}
 	for i, v := range s {
 		go func() {
-			println(i, v)
+			println(i) // ERROR "range variable i captured by func literal"
+			println(v) // ERROR "range variable v captured by func literal"
+		}()
+		println("the goroutine may still run after this statement")
+	}
+	for i := range s {
+		defer func() {
+			println(i) // ERROR "range variable i captured by func literal"
+		}()
+		println("deferred calls always run after the loop")
+	}
+	for i := range s {
+		if i > 0 {
+			go func() {
+				println(i) // ERROR "range variable i captured by func literal"
+			}()
+		}
+	}
+	for _, v := range s {
+		func() {
+			println(v) // called directly: ok
+		}()
+	}
+	var wg sync.WaitGroup
+	for i := range s {
+		wg.Add(1)
+		go func() {
+			println(i) // the iteration waits for the goroutine: ok
+			wg.Done()
+		}()
+		wg.Wait()
+	}
+	done := make(chan bool)
+	for i := range s {
+		go func() {
+			println(i) // the iteration waits for the goroutine: ok
+			done <- true
 		}()
-		println("unfortunately, we don't catch the error above because of this statement")
+		<-done
 	}
 	for i, v := range s {
 		go func(i, v int) {
@@ -0,0 +1,60 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file contains tests for the waitgroup checker.
+
+package testdata
+
+import "sync"
+
+func WaitGroupAddBefore(s []int) {
+	var wg sync.WaitGroup
+	for _, x := range s {
+		wg.Add(1)
+		go func(x int) {
+			defer wg.Done()
+			println(x)
+		}(x)
+	}
+	wg.Wait()
+}
+
+func WaitGroupAddInside(s []int) {
+	var wg sync.WaitGroup
+	for _, x := range s {
+		go func(x int) {
+			wg.Add(1) // ERROR "WaitGroup.Add called inside the new goroutine; call it before the go statement"
+			defer wg.Done()
+			println(x)
+		}(x)
+	}
+	wg.Wait()
+}
+
+func WaitGroupPointer(wg *sync.WaitGroup) {
+	go func() {
+		wg.Add(1) // ERROR "WaitGroup.Add called inside the new goroutine; call it before the go statement"
+		wg.Done()
+	}()
+}
+
+func WaitGroupNested(wg *sync.WaitGroup) {
+	go func() {
+		// Adding for a goroutine started by this one is fine.
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+		}()
+	}()
+}
+
+type counter struct{ n int }
+
+func (c *counter) Add(n int) { c.n += n }
+
+func WaitGroupOtherAdd(c *counter) {
+	go func() {
+		c.Add(1)
+	}()
+}
@@ -9,6 +9,7 @@ package cover
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"math"
 	"os"
 	"regexp"
@@ -45,9 +46,14 @@ func ParseProfiles(fileName string) ([]*Profile, error) {
 		return nil, err
 	}
 	defer pf.Close()
+	return ParseProfilesFromReader(pf)
+}
 
+// ParseProfilesFromReader parses profile data from the Reader and
+// returns a Profile for each source file described therein.
+func ParseProfilesFromReader(rd io.Reader) ([]*Profile, error) {
 	files := make(map[string]*Profile)
-	buf := bufio.NewReader(pf)
+	buf := bufio.NewReader(rd)
 	// First line is "mode: foo", where foo is "set", "count", or "atomic".
 	// Rest of file is in the format
 	//	encoding/base64/base64.go:34.44,37.40 3 1
@@ -110,6 +116,105 @@ func (b blocksByStart) Less(i, j int) bool {
 	return bi.StartLine < bj.StartLine || bi.StartLine == bj.StartLine && bi.StartCol < bj.StartCol
 }
 
+// Coverage returns the number of statements in the profile and the
+// number of them that were executed.
+func (p *Profile) Coverage() (covered, total int64) {
+	for _, b := range p.Blocks {
+		total += int64(b.NumStmt)
+		if b.Count > 0 {
+			covered += int64(b.NumStmt)
+		}
+	}
+	return covered, total
+}
+
+// MergeProfiles merges profiles, such as those of several runs of
+// "go test -coverprofile" over the same source files, and returns a
+// Profile for each source file, sorted by file name.  The blocks of
+// a file that occur in several profiles (or several times in one) are
+// merged: in "set" mode a block is covered if it is covered in any of
+// them, and in "count" and "atomic" modes the counts are added.
+//
+// Profiles in "count" and "atomic" mode may be merged with each other;
+// the result has the mode of the first.  Profiles in "set" mode may
+// only be merged with each other, since their counts are not comparable.
+// It is an error if two versions of a block have different numbers of
+// statements, which indicates that the profiles are for different
+// versions of the source.
+func MergeProfiles(profiles []*Profile) ([]*Profile, error) {
+	if len(profiles) == 0 {
+		return nil, nil
+	}
+	mode := profiles[0].Mode
+	for _, p := range profiles {
+		if (p.Mode == "set") != (mode == "set") {
+			return nil, fmt.Errorf("cannot merge profiles in %s and %s mode", mode, p.Mode)
+		}
+	}
+
+	type extent struct{ startLine, startCol, endLine, endCol int }
+	files := make(map[string]*Profile)
+	index := make(map[string]map[extent]int) // index of each block in files[name].Blocks
+	for _, p := range profiles {
+		merged := files[p.FileName]
+		if merged == nil {
+			merged = &Profile{FileName: p.FileName, Mode: mode}
+			files[p.FileName] = merged
+			index[p.FileName] = make(map[extent]int)
+		}
+		for _, b := range p.Blocks {
+			e := extent{b.StartLine, b.StartCol, b.EndLine, b.EndCol}
+			i, ok := index[p.FileName][e]
+			if !ok {
+				index[p.FileName][e] = len(merged.Blocks)
+				merged.Blocks = append(merged.Blocks, b)
+				continue
+			}
+			mb := &merged.Blocks[i]
+			if mb.NumStmt != b.NumStmt {
+				return nil, fmt.Errorf("%s:%d.%d,%d.%d: inconsistent number of statements: %d and %d",
+					p.FileName, b.StartLine, b.StartCol, b.EndLine, b.EndCol, mb.NumStmt, b.NumStmt)
+			}
+			if mode == "set" {
+				if b.Count > mb.Count {
+					mb.Count = b.Count
+				}
+			} else {
+				mb.Count += b.Count
+			}
+		}
+	}
+
+	result := make([]*Profile, 0, len(files))
+	for _, p := range files {
+		sort.Sort(blocksByStart(p.Blocks))
+		result = append(result, p)
+	}
+	sort.Sort(byFileName(result))
+	return result, nil
+}
+
+// WriteProfiles writes profiles, which must all have the same mode,
+// in the format read by ParseProfiles.
+func WriteProfiles(w io.Writer, profiles []*Profile) error {
+	if len(profiles) == 0 {
+		return fmt.Errorf("no profiles to write")
+	}
+	mode := profiles[0].Mode
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "mode: %s\n", mode)
+	for _, p := range profiles {
+		if p.Mode != mode {
+			return fmt.Errorf("%s: mode %s differs from %s", p.FileName, p.Mode, mode)
+		}
+		for _, b := range p.Blocks {
+			fmt.Fprintf(bw, "%s:%d.%d,%d.%d %d %d\n",
+				p.FileName, b.StartLine, b.StartCol, b.EndLine, b.EndCol, b.NumStmt, b.Count)
+		}
+	}
+	return bw.Flush()
+}
+
 var lineRe = regexp.MustCompile(`^(.+):([0-9]+).([0-9]+),([0-9]+).([0-9]+) ([0-9]+) ([0-9]+)$`)
 
 func toInt(s string) int {
@@ -0,0 +1,99 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cover
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func parse(t *testing.T, data string) []*Profile {
+	profiles, err := ParseProfilesFromReader(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return profiles
+}
+
+func TestCoverage(t *testing.T) {
+	p := parse(t, `mode: set
+a.go:1.10,3.2 3 1
+a.go:3.2,5.3 2 0
+a.go:6.1,6.5 0 0
+`)[0]
+	if covered, total := p.Coverage(); covered != 3 || total != 5 {
+		t.Errorf("Coverage: want 3/5 have %d/%d", covered, total)
+	}
+}
+
+func TestMergeProfiles(t *testing.T) {
+	run1 := parse(t, `mode: count
+x/b.go:1.1,2.2 1 3
+x/a.go:1.1,2.2 2 0
+x/a.go:3.1,4.2 1 1
+`)
+	run2 := parse(t, `mode: atomic
+x/a.go:1.1,2.2 2 4
+x/a.go:5.1,6.2 1 0
+x/b.go:1.1,2.2 1 2
+`)
+	merged, err := MergeProfiles(append(run1, run2...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteProfiles(&buf, merged); err != nil {
+		t.Fatal(err)
+	}
+	want := `mode: count
+x/a.go:1.1,2.2 2 4
+x/a.go:3.1,4.2 1 1
+x/a.go:5.1,6.2 1 0
+x/b.go:1.1,2.2 1 5
+`
+	if buf.String() != want {
+		t.Errorf("merged count profiles: want\n%s\nhave\n%s", want, buf.String())
+	}
+
+	// Set mode takes the union of the covered blocks.
+	set1 := parse(t, "mode: set\na.go:1.1,2.2 1 1\na.go:3.1,4.2 1 0\n")
+	set2 := parse(t, "mode: set\na.go:1.1,2.2 1 0\na.go:3.1,4.2 1 0\n")
+	merged, err = MergeProfiles(append(set1, set2...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have := []int{merged[0].Blocks[0].Count, merged[0].Blocks[1].Count}; !reflect.DeepEqual(have, []int{1, 0}) {
+		t.Errorf("merged set profiles: want counts [1 0] have %v", have)
+	}
+}
+
+func TestMergeProfilesErrors(t *testing.T) {
+	set := parse(t, "mode: set\na.go:1.1,2.2 1 1\n")
+	count := parse(t, "mode: count\na.go:1.1,2.2 1 1\n")
+	if _, err := MergeProfiles(append(set, count...)); err == nil || !strings.Contains(err.Error(), "set and count mode") {
+		t.Errorf("merging set and count: want mode error, have %v", err)
+	}
+	other := parse(t, "mode: count\na.go:1.1,2.2 2 1\n")
+	if _, err := MergeProfiles(append(count, other...)); err == nil || !strings.Contains(err.Error(), "inconsistent number of statements") {
+		t.Errorf("merging different sources: want statement count error, have %v", err)
+	}
+}
+
+func TestWriteProfilesRoundTrip(t *testing.T) {
+	const data = `mode: atomic
+p/a.go:10.20,12.3 2 7
+p/a.go:12.3,14.2 1 0
+p/b.go:1.1,1.9 1 1
+`
+	var buf bytes.Buffer
+	if err := WriteProfiles(&buf, parse(t, data)); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != data {
+		t.Errorf("WriteProfiles: want\n%s\nhave\n%s", data, buf.String())
+	}
+}
@@ -24,13 +24,25 @@ behaviors of the program in all possible executions.  One call graph
 is more PRECISE than another if it is a smaller overapproximation of
 the dynamic behavior.
 
-All call graphs have a synthetic root node which is responsible for
-calling main() and init().
+All call graphs have a synthetic root node.  In call graphs computed
+from the main packages of a program, it is responsible for calling
+main() and init(); the graphs of the whole program computed by the
+static and cha packages have a root node that calls nothing.
 
 Calls to built-in functions (e.g. panic, println) are not represented
 in the call graph; they are treated like built-in operators of the
 language.
 
+Several algorithms compute call graphs, trading precision for speed:
+
+	static    only the static call edges; not sound.
+	cha       Class Hierarchy Analysis; sound, whole or partial programs.
+	rta       Rapid Type Analysis; sound for whole programs.
+	pointer   inclusion-based pointer analysis; sound for whole programs.
+
+Each sound algorithm in this list is more precise, and more
+expensive, than the one above it.
+
 */
 package callgraph
 
@@ -0,0 +1,148 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package callgraph_test
+
+// This test checks the static, CHA, RTA and pointer call graphs
+// against each other on the inputs of the pointer analysis tests.
+// Each algorithm trades precision for speed in a known direction, so
+// the edges found by one must be included in those found by another:
+//
+//	static ⊆ pointer ⊆ RTA ⊆ CHA
+//
+// where the static edges are restricted to the callers that the
+// pointer analysis deems reachable, and the pointer edges to those
+// that RTA deems reachable: the pointer analysis conservatively
+// treats all methods of all types with method sets as reachable,
+// whereas RTA only considers the types converted to interfaces in
+// reachable code.
+
+import (
+	"fmt"
+	"testing"
+
+	"code.google.com/p/go.tools/go/callgraph"
+	"code.google.com/p/go.tools/go/callgraph/cha"
+	"code.google.com/p/go.tools/go/callgraph/rta"
+	"code.google.com/p/go.tools/go/callgraph/static"
+	"code.google.com/p/go.tools/go/loader"
+	"code.google.com/p/go.tools/go/pointer"
+	"code.google.com/p/go.tools/go/ssa"
+)
+
+var inputs = []string{
+	"../pointer/testdata/a_test.go",
+	"../pointer/testdata/another.go",
+	"../pointer/testdata/arrays.go",
+	"../pointer/testdata/channels.go",
+	"../pointer/testdata/context.go",
+	"../pointer/testdata/conv.go",
+	"../pointer/testdata/flow.go",
+	"../pointer/testdata/fmtexcerpt.go",
+	"../pointer/testdata/func.go",
+	"../pointer/testdata/hello.go",
+	"../pointer/testdata/interfaces.go",
+	"../pointer/testdata/issue9002.go",
+	"../pointer/testdata/maps.go",
+	"../pointer/testdata/panic.go",
+	"../pointer/testdata/recur.go",
+	"../pointer/testdata/structs.go",
+}
+
+// An edge identifies a call graph edge independent of the graph
+// that contains it.
+type edge struct {
+	caller *ssa.Function
+	site   ssa.CallInstruction
+	callee *ssa.Function
+}
+
+func (e edge) String() string {
+	pos := e.caller.Prog.Fset.Position(e.site.Pos())
+	return fmt.Sprintf("%s: %s --> %s", pos, e.caller, e.callee)
+}
+
+// edges returns the set of edges of g that have a call site.
+// The edges from the root node to the roots of the analysis are
+// synthetic and are ignored.
+func edges(g *callgraph.Graph) map[edge]bool {
+	set := make(map[edge]bool)
+	callgraph.GraphVisitEdges(g, func(e *callgraph.Edge) error {
+		if e.Site != nil {
+			set[edge{e.Caller.Func, e.Site, e.Callee.Func}] = true
+		}
+		return nil
+	})
+	return set
+}
+
+// checkSubset reports an error for each edge of x that is not in y.
+func checkSubset(t *testing.T, input, xname, yname string, x, y map[edge]bool) {
+	for e := range x {
+		if !y[e] {
+			t.Errorf("%s: %s edge not found by %s: %s", input, xname, yname, e)
+		}
+	}
+}
+
+func TestCallGraphs(t *testing.T) {
+	for _, input := range inputs {
+		conf := loader.Config{SourceImports: true}
+		f, err := conf.ParseFile(input, nil)
+		if err != nil {
+			t.Errorf("%s: %s", input, err)
+			continue
+		}
+		conf.CreateFromFiles("main", f)
+		iprog, err := conf.Load()
+		if err != nil {
+			t.Errorf("%s: %s", input, err)
+			continue
+		}
+
+		prog := ssa.Create(iprog, ssa.SanityCheckFunctions)
+		prog.BuildAll()
+
+		mainpkg := prog.Package(iprog.Created[0].Pkg)
+		if mainpkg.Func("main") == nil {
+			// No main function; assume it's a test.
+			mainpkg = prog.CreateTestMainPackage(mainpkg)
+		}
+
+		ptares, err := pointer.Analyze(&pointer.Config{
+			Mains:          []*ssa.Package{mainpkg},
+			BuildCallGraph: true,
+		})
+		if err != nil {
+			t.Errorf("%s: pointer analysis failed: %s", input, err)
+			continue
+		}
+		ptr := edges(ptares.CallGraph)
+
+		// static ⊆ pointer, on the callers reachable by the pointer analysis.
+		staticEdges := make(map[edge]bool)
+		for e := range edges(static.CallGraph(prog)) {
+			if _, ok := ptares.CallGraph.Nodes[e.caller]; ok {
+				staticEdges[e] = true
+			}
+		}
+		checkSubset(t, input, "static", "pointer", staticEdges, ptr)
+
+		// pointer ⊆ RTA, on the callers reachable by RTA.
+		rtares := rta.Analyze([]*ssa.Function{
+			mainpkg.Func("init"),
+			mainpkg.Func("main"),
+		}, true)
+		rtaEdges := edges(rtares.CallGraph)
+		for e := range ptr {
+			if _, ok := rtares.Reachable[e.caller]; !ok {
+				delete(ptr, e)
+			}
+		}
+		checkSubset(t, input, "pointer", "RTA", ptr, rtaEdges)
+
+		// RTA ⊆ CHA.
+		checkSubset(t, input, "RTA", "CHA", rtaEdges, edges(cha.CallGraph(prog)))
+	}
+}
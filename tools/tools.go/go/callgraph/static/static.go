@@ -0,0 +1,42 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package static computes the call graph of a Go program containing
+// only static call edges.
+package static
+
+import (
+	"code.google.com/p/go.tools/go/callgraph"
+	"code.google.com/p/go.tools/go/ssa"
+	"code.google.com/p/go.tools/go/ssa/ssautil"
+)
+
+// CallGraph computes the call graph of the specified program
+// considering only static calls, i.e. calls of functions and
+// concrete methods named directly.  Dynamic calls of function
+// values and interface method calls have no edges.
+//
+// The resulting graph is neither sound nor rooted; its root node has
+// a nil Func.  It is cheap to compute and is a subset of the call
+// graph found by any other algorithm, restricted to the functions
+// that algorithm deems reachable.
+//
+func CallGraph(prog *ssa.Program) *callgraph.Graph {
+	cg := callgraph.New(nil)
+
+	for f := range ssautil.AllFunctions(prog) {
+		fnode := cg.CreateNode(f)
+		for _, b := range f.Blocks {
+			for _, instr := range b.Instrs {
+				if site, ok := instr.(ssa.CallInstruction); ok {
+					if g := site.Common().StaticCallee(); g != nil {
+						callgraph.AddEdge(fnode, site, cg.CreateNode(g))
+					}
+				}
+			}
+		}
+	}
+
+	return cg
+}
@@ -226,6 +226,18 @@ type Config struct {
 	// values indicate whether to augment the package by *_test.go
 	// files in a second pass.
 	ImportPkgs map[string]bool
+
+	// Reuse maps import paths to packages loaded by a previous
+	// call to Load with the same Fset, which are used in place of
+	// loading those packages again.  This allows a long-running
+	// client to reload a program after some of its files have
+	// changed without type-checking the unaffected packages.
+	//
+	// The client must ensure that a reused package and all its
+	// dependencies are unchanged since they were loaded, and that
+	// the dependencies of a reused package are also reused.
+	// Initial packages (ImportPkgs) are always loaded afresh.
+	Reuse map[string]*PackageInfo
 }
 
 type CreatePkg struct {
@@ -764,7 +776,9 @@ func (imp *importer) importPackage(path string) (*PackageInfo, error) {
 		imp.imported[path] = ii
 
 		// Find and create the actual package.
-		if _, ok := imp.conf.ImportPkgs[path]; ok || imp.conf.SourceImports {
+		if info := imp.reuse(path); info != nil {
+			ii.info, ii.err = info, nil
+		} else if _, ok := imp.conf.ImportPkgs[path]; ok || imp.conf.SourceImports {
 			ii.info, ii.err = imp.importFromSource(path)
 		} else {
 			ii.info, ii.err = imp.importFromBinary(path)
@@ -777,6 +791,31 @@ func (imp *importer) importPackage(path string) (*PackageInfo, error) {
 	return ii.info, ii.err
 }
 
+// reuse returns the package to use for path from conf.Reuse, or nil
+// if it must be loaded.  It adds the package and its dependencies,
+// which are reused too, to the program.
+//
+func (imp *importer) reuse(path string) *PackageInfo {
+	info := imp.conf.Reuse[path]
+	if info == nil {
+		return nil
+	}
+	if _, ok := imp.conf.ImportPkgs[path]; ok {
+		return nil // initial packages are always loaded afresh
+	}
+	imp.prog.AllPackages[info.Pkg] = info
+	imp.prog.ImportMap[path] = info.Pkg
+	for _, dep := range info.Pkg.Imports() {
+		if dep.Path() == "unsafe" {
+			continue
+		}
+		if depInfo, err := imp.importPackage(dep.Path()); err == nil {
+			imp.prog.ImportMap[dep.Path()] = depInfo.Pkg
+		}
+	}
+	return info
+}
+
 // importFromBinary implements package loading from the client-supplied
 // external source, e.g. object files from the gc compiler.
 //
@@ -200,6 +200,60 @@ func TestTransitivelyErrorFreeFlag(t *testing.T) {
 	}
 }
 
+func TestReuse(t *testing.T) {
+	// a --> b --> c
+	//   \
+	//    d
+	pkgs := map[string]string{
+		"a": `package a; import (_ "b"; _ "d")`,
+		"b": `package b; import _ "c"`,
+		"c": `package c;`,
+		"d": `package d;`,
+	}
+	conf := loader.Config{SourceImports: true, Build: fakeContext(pkgs)}
+	conf.Import("a")
+	prog, err := conf.Load()
+	if err != nil {
+		t.Fatalf("Load failed: %s", err)
+	}
+
+	// Reload after a change to d, reusing b and c.
+	reuse := make(map[string]*loader.PackageInfo)
+	for pkg, info := range prog.AllPackages {
+		if p := pkg.Path(); p == "b" || p == "c" {
+			reuse[p] = info
+		}
+	}
+	pkgs["d"] = `package d; var X int`
+	conf2 := loader.Config{
+		Fset:          conf.Fset,
+		SourceImports: true,
+		Build:         fakeContext(pkgs),
+		Reuse:         reuse,
+	}
+	conf2.Import("a")
+	prog2, err := conf2.Load()
+	if err != nil {
+		t.Fatalf("Load failed: %s", err)
+	}
+
+	var got []string
+	for pkg, info := range prog2.AllPackages {
+		if pkg.Path() != "a" && prog2.ImportMap[pkg.Path()] != pkg {
+			t.Errorf("ImportMap[%q] is not the loaded package", pkg.Path())
+		}
+		reused := info == reuse[pkg.Path()]
+		got = append(got, fmt.Sprintf("%s:%t", pkg.Path(), reused))
+		if pkg.Path() == "d" && pkg.Scope().Lookup("X") == nil {
+			t.Errorf("package d was not reloaded")
+		}
+	}
+	sort.Strings(got)
+	if want := "a:false b:true c:true d:false"; strings.Join(got, " ") != want {
+		t.Errorf("loaded packages (path:reused) = %s, want %s", got, want)
+	}
+}
+
 // Test that both syntax (scan/parse) and type errors are both recorded
 // (in PackageInfo.Errors) and reported (via Config.TypeChecker.Error).
 func TestErrorReporting(t *testing.T) {
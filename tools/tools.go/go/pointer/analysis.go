@@ -128,6 +128,9 @@ type analysis struct {
 	result      *Result                     // results of the analysis
 	track       track                       // pointerlike types whose aliasing we track
 	deltaSpace  []int                       // working space for iterating over PTS deltas
+	queries     []query                     // queried values, resolved after solving
+	summaries   *summaries                  // on-disk summaries; nil unless Config.SummaryDir is set
+	rec         *recorder                   // records the summary of the function being generated
 
 	// Reflection & intrinsics:
 	hasher              typeutil.Hasher // cache of type hashes
@@ -141,6 +144,15 @@ type analysis struct {
 	runtimeSetFinalizer *ssa.Function   // runtime.SetFinalizer
 }
 
+// A query records that node id holds the value of v, which the
+// client has queried, in some context.
+type query struct {
+	v        ssa.Value
+	id       nodeid
+	indirect bool   // pts(*v) was queried, not pts(v)
+	obj      nodeid // for indirect queries, the sole object to which v points, if known
+}
+
 // enclosingObj returns the first node of the addressable memory
 // object that encloses node id.  Panic ensues if that node does not
 // belong to any object.
@@ -284,6 +296,12 @@ func Analyze(config *Config) (result *Result, err error) {
 	}
 	a.computeTrackBits()
 
+	if config.SummaryDir != "" && a.log == nil {
+		if a.summaries, err = openSummaries(a, config.SummaryDir); err != nil {
+			return nil, err
+		}
+	}
+
 	a.generate()
 	a.showCounts()
 
@@ -293,6 +311,45 @@ func Analyze(config *Config) (result *Result, err error) {
 
 	N := len(a.nodes) // excludes solver-created nodes
 
+	if a.summaries.loadSolution() {
+		// The solution was cached by a previous analysis
+		// of the same program.
+	} else if err := a.solveAll(N); err != nil {
+		return nil, err
+	}
+	if a.summaries != nil && a.summaries.err != nil {
+		return nil, a.summaries.err
+	}
+
+	a.resolveQueries()
+
+	// Create callgraph.Nodes in deterministic order.
+	if cg := a.result.CallGraph; cg != nil {
+		for _, caller := range a.cgnodes {
+			cg.CreateNode(caller.fn)
+		}
+	}
+
+	// Add dynamic edges to call graph.
+	var space [100]int
+	for _, caller := range a.cgnodes {
+		for _, site := range caller.sites {
+			for _, callee := range a.nodes[site.targets].solve.pts.AppendTo(space[:0]) {
+				a.callEdge(caller, site, nodeid(callee))
+			}
+		}
+	}
+
+	return a.result, nil
+}
+
+// solveAll solves the constraint system, whose first N nodes were
+// created by the generator, and caches the solution if summaries
+// are enabled.
+func (a *analysis) solveAll(N int) error {
+	ncgnodes := len(a.cgnodes)
+	nwarnings := len(a.result.Warnings)
+
 	if optHVN {
 		if debugHVNCrossCheck {
 			// Cross-check: run the solver once without
@@ -330,28 +387,52 @@ func Analyze(config *Config) (result *Result, err error) {
 		a.dumpSolution("B.pts", N)
 
 		if !diff("A.pts", "B.pts") {
-			return nil, fmt.Errorf("internal error: optimization changed solution")
+			return fmt.Errorf("internal error: optimization changed solution")
 		}
 	}
 
-	// Create callgraph.Nodes in deterministic order.
-	if cg := a.result.CallGraph; cg != nil {
-		for _, caller := range a.cgnodes {
-			cg.CreateNode(caller.fn)
-		}
+	// A solution is reusable only if the solver created no
+	// nodes, contours or warnings, as it may for reflection.
+	if len(a.nodes) == N && len(a.cgnodes) == ncgnodes && len(a.result.Warnings) == nwarnings {
+		a.summaries.saveSolution()
 	}
+	return nil
+}
 
-	// Add dynamic edges to call graph.
-	var space [100]int
-	for _, caller := range a.cgnodes {
-		for _, site := range caller.sites {
-			for _, callee := range a.nodes[site.targets].solve.pts.AppendTo(space[:0]) {
-				a.callEdge(caller, site, nodeid(callee))
+// resolveQueries populates the Result's query maps from the queries
+// deferred by an analysis that uses summaries.  The canonical node
+// for each queried value v points to the union over all contexts of
+// pts(v), or for an indirect query, of pts(*v).
+//
+func (a *analysis) resolveQueries() {
+	for _, q := range a.queries {
+		m := a.result.Queries
+		comment := "query"
+		if q.indirect {
+			m = a.result.IndirectQueries
+			comment = "query.indirect"
+		}
+		t := q.v.Type()
+		ptr, ok := m[q.v]
+		if !ok {
+			// First time?  Create the canonical query node.
+			ptr = Pointer{a, a.addNodes(t, comment)}
+			m[q.v] = ptr
+		}
+		for i, n := 0, int(a.sizeof(t)); i < n; i++ {
+			dst := &a.nodes[ptr.n+nodeid(i)].solve.pts
+			switch {
+			case !q.indirect:
+				dst.addAll(&a.nodes[q.id+nodeid(i)].solve.pts)
+			case q.obj != 0:
+				dst.addAll(&a.nodes[q.obj+nodeid(i)].solve.pts)
+			default:
+				for _, l := range a.nodes[q.id].solve.pts.AppendTo(a.deltaSpace) {
+					dst.addAll(&a.nodes[nodeid(l)+nodeid(i)].solve.pts)
+				}
 			}
 		}
 	}
-
-	return a.result, nil
 }
 
 // callEdge is called for each edge in the callgraph.
@@ -363,6 +444,7 @@ func (a *analysis) callEdge(caller *cgnode, site *callsite, calleeid nodeid) {
 		panic(fmt.Sprintf("callEdge %s -> n%d: not a function object", site, calleeid))
 	}
 	callee := obj.cgn
+	a.rec.callEdge(site, calleeid)
 
 	if cg := a.result.CallGraph; cg != nil {
 		// TODO(adonovan): opt: I would expect duplicate edges
@@ -59,6 +59,22 @@ type Config struct {
 	// If Log is non-nil, log messages are written to it.
 	// Logging is extremely verbose.
 	Log io.Writer
+
+	// SummaryDir, if non-empty, names a directory in which the
+	// analysis caches summaries of the constraints generated for
+	// each function, keyed by a hash of the function's package
+	// and its dependencies, and the solution of the whole
+	// program.  A later analysis regenerates constraints only
+	// for functions in packages whose hash has changed, which
+	// includes the packages that import a changed package, and
+	// skips the solver entirely if no package has changed.
+	// Solving is not incremental: any change to the program
+	// requires that the whole constraint system be solved again.
+	// Results are identical to those of an analysis without
+	// summaries.
+	//
+	// Summaries are not used when Log is set.
+	SummaryDir string
 }
 
 type track uint32
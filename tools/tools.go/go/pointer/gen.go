@@ -40,9 +40,12 @@ func (a *analysis) nextNode() nodeid {
 //
 func (a *analysis) addNodes(typ types.Type, comment string) nodeid {
 	id := a.nextNode()
+	a.rec.suspend()
 	for _, fi := range a.flatten(typ) {
 		a.addOneNode(fi.typ, comment, fi)
 	}
+	a.rec.resume()
+	a.rec.nodes(typ, id)
 	if id == a.nextNode() {
 		return 0 // type contained no pointers
 	}
@@ -64,6 +67,7 @@ func (a *analysis) addOneNode(typ types.Type, comment string, subelement *fieldI
 		fmt.Fprintf(a.log, "\tcreate n%d %s for %s%s\n",
 			id, typ, comment, subelement.path())
 	}
+	a.rec.node(typ, id)
 	return id
 }
 
@@ -80,9 +84,23 @@ func (a *analysis) setValueNode(v ssa.Value, id nodeid, cgn *cgnode) {
 		fmt.Fprintf(a.log, "\tval[%s] = n%d  (%T)\n", v.Name(), id, v)
 	}
 
+	if cgn != nil {
+		a.rec.setValueNode(v, id)
+	}
+
 	// Due to context-sensitivity, we may encounter the same Value
 	// in many contexts. We merge them to a canonical node, since
 	// that's what all clients want.
+	//
+	// With summaries, the canonical nodes must not be part of the
+	// constraint system, which would then differ between analyses
+	// of the same program with different queries.  So each (v, id)
+	// relation is recorded instead, and resolveQueries merges them
+	// after solving.
+	if a.summaries != nil {
+		a.deferQueries(v, id, cgn)
+		return
+	}
 
 	// Record the (v, id) relation if the client has queried pts(v).
 	if _, ok := a.config.Queries[v]; ok {
@@ -110,6 +128,23 @@ func (a *analysis) setValueNode(v ssa.Value, id nodeid, cgn *cgnode) {
 	}
 }
 
+// deferQueries records the (v, id) relation for resolveQueries if
+// the client has queried pts(v) or pts(*v).
+func (a *analysis) deferQueries(v ssa.Value, id nodeid, cgn *cgnode) {
+	if _, ok := a.config.Queries[v]; ok {
+		a.queries = append(a.queries, query{v: v, id: id})
+	}
+	if _, ok := a.config.IndirectQueries[v]; ok {
+		q := query{v: v, id: id, indirect: true}
+		if cgn == nil {
+			q.obj = a.objectNode(nil, v)
+		}
+		// For locals, genFunc sets q.obj once the function's
+		// objects are known.
+		a.queries = append(a.queries, q)
+	}
+}
+
 // endObject marks the end of a sequence of calls to addNodes denoting
 // a single object allocation.
 //
@@ -121,7 +156,9 @@ func (a *analysis) endObject(obj nodeid, cgn *cgnode, data interface{}) *object
 	// the pad will be the object node.
 	size := uint32(a.nextNode() - obj)
 	if size == 0 {
+		a.rec.suspend()
 		a.addOneNode(tInvalid, "padding", nil)
+		a.rec.resume()
 	}
 	objNode := a.nodes[obj]
 	o := &object{
@@ -131,6 +168,7 @@ func (a *analysis) endObject(obj nodeid, cgn *cgnode, data interface{}) *object
 	}
 	objNode.obj = o
 
+	a.rec.object(obj, o)
 	return o
 }
 
@@ -148,6 +186,7 @@ func (a *analysis) makeFunctionObject(fn *ssa.Function, callersite *callsite) no
 
 	// obj is the function object (identity, params, results).
 	obj := a.nextNode()
+	a.rec.suspend()
 	cgn := a.makeCGNode(fn, obj, callersite)
 	sig := fn.Signature
 	a.addOneNode(sig, "func.cgnode", nil) // (scalar with Signature type)
@@ -165,6 +204,8 @@ func (a *analysis) makeFunctionObject(fn *ssa.Function, callersite *callsite) no
 	// Queue it up for constraint processing.
 	a.genq = append(a.genq, cgn)
 
+	a.rec.resume()
+	a.rec.contour(fn, callersite, obj)
 	return obj
 }
 
@@ -222,6 +263,7 @@ func (a *analysis) valueNode(v ssa.Value) nodeid {
 	// Value nodes for globals are created on demand.
 	id, ok := a.globalval[v]
 	if !ok {
+		a.rec.suspend()
 		var comment string
 		if a.log != nil {
 			comment = v.String()
@@ -231,7 +273,9 @@ func (a *analysis) valueNode(v ssa.Value) nodeid {
 			a.addressOf(v.Type(), id, obj)
 		}
 		a.setValueNode(v, id, nil)
+		a.rec.resume()
 	}
+	a.rec.global(opGlobalValue, v, id)
 	return id
 }
 
@@ -403,6 +447,7 @@ func (a *analysis) addConstraint(c constraint) {
 	if a.log != nil {
 		fmt.Fprintf(a.log, "\t%s\n", c)
 	}
+	a.rec.constraint(c)
 }
 
 // copyElems generates load/store constraints for *dst = *src,
@@ -791,6 +836,7 @@ func (a *analysis) objectNode(cgn *cgnode, v ssa.Value) nodeid {
 		// Global object.
 		obj, ok := a.globalobj[v]
 		if !ok {
+			a.rec.suspend()
 			switch v := v.(type) {
 			case *ssa.Global:
 				obj = a.nextNode()
@@ -811,7 +857,9 @@ func (a *analysis) objectNode(cgn *cgnode, v ssa.Value) nodeid {
 				fmt.Fprintf(a.log, "\tglobalobj[%s] = n%d\n", v, obj)
 			}
 			a.globalobj[v] = obj
+			a.rec.resume()
 		}
+		a.rec.global(opGlobalObject, v, obj)
 		return obj
 	}
 
@@ -1148,13 +1196,40 @@ func (a *analysis) genFunc(cgn *cgnode) {
 		return
 	}
 
+	a.localval = make(map[ssa.Value]nodeid)
+	a.localobj = make(map[ssa.Value]nodeid)
+	nqueries := len(a.queries)
+
+	// Reuse the function's summary if there is one;
+	// otherwise generate its constraints, recording a summary.
+	if !a.replay(cgn) {
+		a.rec = a.record(cgn)
+		a.genBody(cgn)
+		a.rec.finish()
+		a.rec = nil
+	}
+	a.genAddressTaken(fn)
+
+	// Each indirect query of a local points to its sole object,
+	// if known.
+	for i := nqueries; i < len(a.queries); i++ {
+		if q := &a.queries[i]; q.indirect {
+			q.obj = a.objectNode(cgn, q.v)
+		}
+	}
+
+	a.localval = nil
+	a.localobj = nil
+}
+
+// genBody generates constraints for the body of function cgn.fn.
+func (a *analysis) genBody(cgn *cgnode) {
+	fn := cgn.fn
+
 	if a.log != nil {
 		fmt.Fprintln(a.log, "; Creating nodes for local values")
 	}
 
-	a.localval = make(map[ssa.Value]nodeid)
-	a.localobj = make(map[ssa.Value]nodeid)
-
 	// The value nodes for the params are in the func object block.
 	params := a.funcParams(cgn.obj)
 	for _, p := range fn.Params {
@@ -1170,7 +1245,6 @@ func (a *analysis) genFunc(cgn *cgnode) {
 
 	// Create value nodes for all value instructions
 	// since SSA may contain forward references.
-	var space [10]*ssa.Value
 	for _, b := range fn.Blocks {
 		for _, instr := range b.Instrs {
 			switch instr := instr.(type) {
@@ -1186,8 +1260,23 @@ func (a *analysis) genFunc(cgn *cgnode) {
 				id := a.addNodes(instr.Type(), comment)
 				a.setValueNode(instr, id, cgn)
 			}
+		}
+	}
 
-			// Record all address-taken functions (for presolver).
+	// Generate constraints for instructions.
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			a.genInstr(cgn, instr)
+		}
+	}
+}
+
+// genAddressTaken records all address-taken functions referenced
+// by fn (for presolver).
+func (a *analysis) genAddressTaken(fn *ssa.Function) {
+	var space [10]*ssa.Value
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
 			rands := instr.Operands(space[:0])
 			if call, ok := instr.(ssa.CallInstruction); ok && !call.Common().IsInvoke() {
 				// Skip CallCommon.Value in "call" mode.
@@ -1201,16 +1290,6 @@ func (a *analysis) genFunc(cgn *cgnode) {
 			}
 		}
 	}
-
-	// Generate constraints for instructions.
-	for _, b := range fn.Blocks {
-		for _, instr := range b.Instrs {
-			a.genInstr(cgn, instr)
-		}
-	}
-
-	a.localval = nil
-	a.localobj = nil
 }
 
 // genMethodsOf generates nodes and constraints for all methods of type T.
@@ -1280,6 +1359,8 @@ func (a *analysis) generate() {
 		a.addressOf(T, a.objectNode(nil, os.Var("Args")), obj)
 	}
 
+	a.summaries.flush()
+
 	// Discard generation state, to avoid confusion after node renumbering.
 	a.panicNode = 0
 	a.globalval = nil
@@ -103,6 +103,13 @@ func (a *analysis) renumber() {
 		a.result.IndirectQueries[v] = ptr
 	}
 
+	// Renumber nodeids in deferred queries.
+	for i := range a.queries {
+		q := &a.queries[i]
+		q.id = renumbering[q.id]
+		q.obj = renumbering[q.obj]
+	}
+
 	// Renumber nodeids in global objects.
 	for v, id := range a.globalobj {
 		a.globalobj[v] = renumbering[id]
@@ -0,0 +1,456 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pointer
+
+// This file defines the on-disk cache of summaries used when
+// Config.SummaryDir is set.
+//
+// The cache holds two kinds of file:
+//
+// - <hash>.pkg holds the summary of one package: the templates (see
+//   template.go) of its functions, and the table of types they
+//   reference.  The hash covers the package's declarations, the SSA
+//   code of its functions, the hashes of the packages it imports,
+//   and the analysis options, so a summary is found only if neither
+//   the package nor its dependencies have changed.
+//
+// - <hash>.pts holds the solution of a whole program, keyed by the
+//   hashes of all its packages and its main packages, along with a
+//   fingerprint of the constraint system from which it was computed.
+//
+// Summaries spare the cost of constraint generation for unchanged
+// packages; any change to the program still requires that the whole
+// constraint system be solved again.
+//
+// Files that cannot be read or decoded are treated as missing; errors
+// writing files are reported by Analyze.
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"code.google.com/p/go.tools/go/ssa"
+	"code.google.com/p/go.tools/go/ssa/ssautil"
+	"code.google.com/p/go.tools/go/types"
+)
+
+// summaryVersion identifies the format of summary files, and the
+// behaviour of the constraint generator.  Change it whenever either
+// changes.
+const summaryVersion = 1
+
+type summaries struct {
+	a           *analysis
+	dir         string
+	err         error                        // first error writing a file
+	funcs       map[string]*ssa.Function     // functions by name; nil for ambiguous names
+	packages    map[string]*types.Package    // packages by path
+	ssaPkgs     map[string]*ssa.Package      // SSA packages by path
+	hashes      map[*types.Package]string    // memoized package hashes
+	pkgs        map[*ssa.Package]*pkgSummary // loaded package summaries
+	key         string                       // hash of the whole program
+	fingerprint []byte                       // hash of the constraint system
+}
+
+// A pkgSummary holds the templates of the functions of a package.
+type pkgSummary struct {
+	Version int
+	Path    string
+	Types   []typeEntry
+	Funcs   map[string]*template
+
+	file      string
+	dirty     bool
+	decoded   []types.Type       // decoded types, by index in Types
+	typeIndex map[types.Type]int // indices of encoded or decoded types
+}
+
+// A solution holds the solution of the constraint system of a program.
+type solution struct {
+	Version     int
+	Fingerprint []byte
+	Reps        []uint32 // index of the node whose solver state each node shares
+	PTS         [][]int  // points-to set of each representative node
+}
+
+// openSummaries returns the summaries for the analysis a, which are
+// saved in directory dir.
+func openSummaries(a *analysis, dir string) (*summaries, error) {
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return nil, err
+	}
+	s := &summaries{
+		a:        a,
+		dir:      dir,
+		funcs:    make(map[string]*ssa.Function),
+		packages: make(map[string]*types.Package),
+		ssaPkgs:  make(map[string]*ssa.Package),
+		hashes:   make(map[*types.Package]string),
+		pkgs:     make(map[*ssa.Package]*pkgSummary),
+	}
+	for fn := range ssautil.AllFunctions(a.prog) {
+		name := fn.String()
+		if _, ok := s.funcs[name]; ok {
+			s.funcs[name] = nil // ambiguous
+		} else {
+			s.funcs[name] = fn
+		}
+	}
+	var addPackage func(pkg *types.Package)
+	addPackage = func(pkg *types.Package) {
+		if _, ok := s.packages[pkg.Path()]; !ok {
+			s.packages[pkg.Path()] = pkg
+			for _, imp := range pkg.Imports() {
+				addPackage(imp)
+			}
+		}
+	}
+	for _, pkg := range a.prog.AllPackages() {
+		s.ssaPkgs[pkg.Object.Path()] = pkg
+		addPackage(pkg.Object)
+	}
+	var hashes []string
+	for _, pkg := range a.prog.AllPackages() {
+		hashes = append(hashes, s.hash(pkg.Object))
+	}
+	sort.Strings(hashes)
+
+	h := sha1.New()
+	fmt.Fprintf(h, "%s\n", s.options())
+	for _, hash := range hashes {
+		fmt.Fprintf(h, "%s\n", hash)
+	}
+	for _, main := range a.config.Mains {
+		fmt.Fprintf(h, "main %s\n", main.Object.Path())
+	}
+	s.key = fmt.Sprintf("%x", h.Sum(nil))
+	return s, nil
+}
+
+// options returns a description of the analysis options that affect
+// constraint generation.
+func (s *summaries) options() string {
+	return fmt.Sprintf("version=%d reflection=%t track=%d",
+		summaryVersion, s.a.config.Reflection, s.a.track)
+}
+
+// hash returns the hash of package pkg.
+func (s *summaries) hash(pkg *types.Package) string {
+	if hash, ok := s.hashes[pkg]; ok {
+		return hash
+	}
+
+	h := sha1.New()
+	fmt.Fprintf(h, "%s\npackage %s\n", s.options(), pkg.Path())
+
+	var imports []string
+	for _, imp := range pkg.Imports() {
+		imports = append(imports, s.hash(imp))
+	}
+	sort.Strings(imports)
+	for _, hash := range imports {
+		fmt.Fprintf(h, "import %s\n", hash)
+	}
+
+	hashScope(h, pkg.Scope())
+
+	if ssaPkg := s.ssaPkgs[pkg.Path()]; ssaPkg != nil {
+		var buf bytes.Buffer
+		for _, fn := range sourceFuncs(ssaPkg) {
+			buf.Reset()
+			ssa.WriteFunction(&buf, fn)
+			h.Write(buf.Bytes())
+			for _, p := range fn.Params {
+				fmt.Fprintf(h, "param %s\n", p.Type())
+			}
+			for _, fv := range fn.FreeVars {
+				fmt.Fprintf(h, "freevar %s\n", fv.Type())
+			}
+			for _, b := range fn.Blocks {
+				for _, instr := range b.Instrs {
+					if v, ok := instr.(ssa.Value); ok {
+						fmt.Fprintf(h, "%s %s\n", v.Name(), v.Type())
+					}
+				}
+			}
+		}
+	}
+
+	hash := fmt.Sprintf("%x", h.Sum(nil))
+	s.hashes[pkg] = hash
+	return hash
+}
+
+// hashScope writes a description of the objects declared in scope
+// and its children to w.
+func hashScope(w io.Writer, scope *types.Scope) {
+	for _, name := range scope.Names() {
+		obj := scope.Lookup(name)
+		fmt.Fprintf(w, "%T %s %s\n", obj, name, obj.Type())
+		if tn, ok := obj.(*types.TypeName); ok {
+			fmt.Fprintf(w, "\t%s\n", tn.Type().Underlying())
+			if T, ok := tn.Type().(*types.Named); ok {
+				for i := 0; i < T.NumMethods(); i++ {
+					fmt.Fprintf(w, "\tmethod %s %s\n", T.Method(i).Name(), T.Method(i).Type())
+				}
+			}
+		}
+	}
+	for i, n := 0, scope.NumChildren(); i < n; i++ {
+		fmt.Fprintf(w, "{\n")
+		hashScope(w, scope.Child(i))
+		fmt.Fprintf(w, "}\n")
+	}
+}
+
+// sourceFuncs returns the functions, methods and function literals
+// declared in pkg, sorted by name.
+func sourceFuncs(pkg *ssa.Package) []*ssa.Function {
+	var fns []*ssa.Function
+	var addAnon func(fn *ssa.Function)
+	addAnon = func(fn *ssa.Function) {
+		fns = append(fns, fn)
+		for _, anon := range fn.AnonFuncs {
+			addAnon(anon)
+		}
+	}
+	for _, mem := range pkg.Members {
+		switch mem := mem.(type) {
+		case *ssa.Function:
+			addAnon(mem)
+		case *ssa.Type:
+			if T, ok := mem.Type().(*types.Named); ok {
+				for i := 0; i < T.NumMethods(); i++ {
+					if fn := pkg.Prog.FuncValue(T.Method(i)); fn != nil {
+						addAnon(fn)
+					}
+				}
+			}
+		}
+	}
+	sort.Sort(byFuncName(fns))
+	return fns
+}
+
+type byFuncName []*ssa.Function
+
+func (s byFuncName) Len() int           { return len(s) }
+func (s byFuncName) Less(i, j int) bool { return s[i].String() < s[j].String() }
+func (s byFuncName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// pkgSummary returns the summary of the package of fn, loading it
+// if necessary, or nil if summaries are disabled or fn cannot be
+// summarized.
+func (s *summaries) pkgSummary(fn *ssa.Function) *pkgSummary {
+	if s == nil || fn.Pkg == nil || s.funcs[fn.String()] != fn {
+		return nil
+	}
+	if p, ok := s.pkgs[fn.Pkg]; ok {
+		return p
+	}
+	path := fn.Pkg.Object.Path()
+	file := filepath.Join(s.dir, s.hash(fn.Pkg.Object)+".pkg")
+	p := new(pkgSummary)
+	if !readGob(file, p) || p.Version != summaryVersion || p.Path != path {
+		p = &pkgSummary{Version: summaryVersion, Path: path}
+	}
+	if p.Funcs == nil {
+		p.Funcs = make(map[string]*template)
+	}
+	p.file = file
+	p.decoded = make([]types.Type, len(p.Types))
+	p.typeIndex = make(map[types.Type]int)
+	s.pkgs[fn.Pkg] = p
+	return p
+}
+
+// addTemplate adds the template t of the function named name to the
+// package summary.
+func (p *pkgSummary) addTemplate(name string, t *template) {
+	p.Funcs[name] = t
+	p.dirty = true
+}
+
+// resolveValue returns the value of fn denoted by ref, or nil if
+// there is none.
+func (s *summaries) resolveValue(fn *ssa.Function, ref valueRef) ssa.Value {
+	instr := func() ssa.Instruction {
+		if ref.I >= 0 && ref.I < len(fn.Blocks) {
+			if b := fn.Blocks[ref.I]; ref.J >= 0 && ref.J < len(b.Instrs) {
+				return b.Instrs[ref.J]
+			}
+		}
+		return nil
+	}
+
+	switch ref.Kind {
+	case valueParam:
+		if ref.I >= 0 && ref.I < len(fn.Params) {
+			return fn.Params[ref.I]
+		}
+
+	case valueInstr:
+		if v, ok := instr().(ssa.Value); ok {
+			return v
+		}
+
+	case valueOperand:
+		if instr := instr(); instr != nil {
+			rands := instr.Operands(nil)
+			if ref.K >= 0 && ref.K < len(rands) {
+				if c, ok := (*rands[ref.K]).(*ssa.Const); ok {
+					return c
+				}
+			}
+		}
+
+	case valueFunc:
+		if fn := s.funcs[ref.Name]; fn != nil {
+			return fn
+		}
+
+	case valueGlobal:
+		if pkg := s.ssaPkgs[ref.Name]; pkg != nil {
+			if g, ok := pkg.Members[ref.Member].(*ssa.Global); ok {
+				return g
+			}
+		}
+
+	case valueFreeVar:
+		if fn := s.funcs[ref.Name]; fn != nil && ref.I >= 0 && ref.I < len(fn.FreeVars) {
+			return fn.FreeVars[ref.I]
+		}
+	}
+	return nil
+}
+
+// flush saves the package summaries that have changed.
+func (s *summaries) flush() {
+	if s == nil {
+		return
+	}
+	for _, p := range s.pkgs {
+		if p.dirty {
+			s.writeGob(p.file, p)
+			p.dirty = false
+		}
+	}
+}
+
+// loadSolution loads the solution of the constraint system from the
+// cache, and reports whether it did so.  It must be called after
+// constraint generation.
+func (s *summaries) loadSolution() bool {
+	if s == nil {
+		return false
+	}
+	a := s.a
+	N := len(a.nodes)
+
+	h := sha1.New()
+	fmt.Fprintf(h, "%d nodes\n", N)
+	for id, n := range a.nodes {
+		fmt.Fprintf(h, "n%d %s", id, n.typ)
+		if obj := n.obj; obj != nil {
+			fmt.Fprintf(h, " obj %d %d", obj.flags, obj.size)
+		}
+		fmt.Fprintln(h)
+	}
+	for _, c := range a.constraints {
+		fmt.Fprintln(h, c)
+	}
+	s.fingerprint = h.Sum(nil)
+
+	var sol solution
+	if !readGob(filepath.Join(s.dir, s.key+".pts"), &sol) ||
+		sol.Version != summaryVersion ||
+		!bytes.Equal(sol.Fingerprint, s.fingerprint) ||
+		len(sol.Reps) != N || len(sol.PTS) != N {
+		return false
+	}
+	for i, rep := range sol.Reps {
+		if int(rep) > i {
+			return false // corrupt
+		}
+	}
+
+	for i, rep := range sol.Reps {
+		n := a.nodes[i]
+		if int(rep) < i {
+			n.solve = a.nodes[rep].solve
+			continue
+		}
+		n.solve = new(solverState)
+		for _, l := range sol.PTS[i] {
+			n.solve.pts.add(nodeid(l))
+		}
+	}
+	a.constraints = nil
+	return true
+}
+
+// saveSolution saves the solution of the constraint system.
+func (s *summaries) saveSolution() {
+	if s == nil || s.fingerprint == nil {
+		return
+	}
+	a := s.a
+	sol := solution{
+		Version:     summaryVersion,
+		Fingerprint: s.fingerprint,
+		Reps:        make([]uint32, len(a.nodes)),
+		PTS:         make([][]int, len(a.nodes)),
+	}
+	reps := make(map[*solverState]uint32)
+	for i, n := range a.nodes {
+		rep, ok := reps[n.solve]
+		if !ok {
+			rep = uint32(i)
+			reps[n.solve] = rep
+			sol.PTS[i] = n.solve.pts.AppendTo(nil)
+		}
+		sol.Reps[i] = rep
+	}
+	s.writeGob(filepath.Join(s.dir, s.key+".pts"), &sol)
+}
+
+// readGob decodes the file named filename into x, and reports
+// whether it succeeded.
+func readGob(filename string, x interface{}) bool {
+	f, err := os.Open(filename)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	return gob.NewDecoder(f).Decode(x) == nil
+}
+
+// writeGob atomically replaces the file named filename by the
+// encoding of x, recording any error in s.err.
+func (s *summaries) writeGob(filename string, x interface{}) {
+	f, err := ioutil.TempFile(s.dir, "tmp")
+	if err == nil {
+		err = gob.NewEncoder(f).Encode(x)
+		if err2 := f.Close(); err == nil {
+			err = err2
+		}
+		if err == nil {
+			err = os.Rename(f.Name(), filename)
+		}
+		if err != nil {
+			os.Remove(f.Name())
+		}
+	}
+	if err != nil && s.err == nil {
+		s.err = fmt.Errorf("saving pointer analysis summaries: %s", err)
+	}
+}
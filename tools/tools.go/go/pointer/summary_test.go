@@ -0,0 +1,283 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pointer_test
+
+// This test checks that analyses that use summaries produce the same
+// results as those that do not.
+
+import (
+	"bytes"
+	"fmt"
+	"go/build"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"code.google.com/p/go.tools/go/callgraph"
+	"code.google.com/p/go.tools/go/loader"
+	"code.google.com/p/go.tools/go/pointer"
+	"code.google.com/p/go.tools/go/ssa"
+	"code.google.com/p/go.tools/go/ssa/ssautil"
+	"code.google.com/p/go.tools/go/types"
+)
+
+// analyzeInput analyzes the program in file filename and returns a
+// description of the results: the points-to sets of all pointer-like
+// values of the main package, the call graph and the warnings.
+func analyzeInput(t *testing.T, filename string, reflection bool, summaryDir string) string {
+	conf := loader.Config{SourceImports: true}
+	f, err := conf.ParseFile(filename, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf.CreateFromFiles("main", f)
+	iprog, err := conf.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return analyzeProgram(t, iprog, iprog.Created[0].Pkg, reflection, summaryDir)
+}
+
+// analyzeProgram is like analyzeInput, for the package pkg of iprog.
+func analyzeProgram(t *testing.T, iprog *loader.Program, pkg *types.Package, reflection bool, summaryDir string) string {
+	prog := ssa.Create(iprog, 0)
+	prog.BuildAll()
+
+	mainpkg := prog.Package(pkg)
+	ptrmain := mainpkg
+	if mainpkg.Func("main") == nil {
+		ptrmain = prog.CreateTestMainPackage(mainpkg)
+	}
+
+	config := &pointer.Config{
+		Reflection:     reflection,
+		BuildCallGraph: true,
+		Mains:          []*ssa.Package{ptrmain},
+		SummaryDir:     summaryDir,
+	}
+	names := make(map[ssa.Value]string)
+	for fn := range ssautil.AllFunctions(prog) {
+		if fn.Pkg != mainpkg {
+			continue
+		}
+		for _, b := range fn.Blocks {
+			for _, instr := range b.Instrs {
+				v, ok := instr.(ssa.Value)
+				if !ok {
+					continue
+				}
+				names[v] = fmt.Sprintf("%s:%s", fn, v.Name())
+				if pointer.CanPoint(v.Type()) {
+					config.AddQuery(v)
+				}
+				if _, ok := v.(*ssa.Alloc); ok && pointer.CanPoint(v.Type().Underlying().(*types.Pointer).Elem()) {
+					config.AddIndirectQuery(v)
+				}
+			}
+		}
+	}
+	for _, mem := range mainpkg.Members {
+		if g, ok := mem.(*ssa.Global); ok && pointer.CanPoint(g.Type().Underlying().(*types.Pointer).Elem()) {
+			names[g] = g.String()
+			config.AddIndirectQuery(g)
+		}
+	}
+
+	result, err := pointer.Analyze(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var lines []string
+	dump := func(kind string, queries map[ssa.Value]pointer.Pointer) {
+		for v, ptr := range queries {
+			var labels []string
+			for _, l := range ptr.PointsTo().Labels() {
+				labels = append(labels, fmt.Sprintf("%s@%s", l, prog.Fset.Position(l.Pos())))
+			}
+			sort.Strings(labels)
+			lines = append(lines, fmt.Sprintf("%s %s: %s", kind, names[v], strings.Join(labels, ", ")))
+		}
+	}
+	dump("pts", result.Queries)
+	dump("pts*", result.IndirectQueries)
+	callgraph.GraphVisitEdges(result.CallGraph, func(e *callgraph.Edge) error {
+		lines = append(lines, fmt.Sprintf("edge %s -> %s %s@%s",
+			e.Caller.Func, e.Callee.Func, e.Description(), prog.Fset.Position(e.Pos())))
+		return nil
+	})
+	for _, w := range result.Warnings {
+		lines = append(lines, fmt.Sprintf("warning %s: %s", prog.Fset.Position(w.Pos), w.Message))
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
+
+func TestSummaries(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping in short mode")
+	}
+
+	dir, err := ioutil.TempDir("", "pointer-summaries")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, filename := range inputs {
+		for _, reflection := range []bool{false, true} {
+			want := analyzeInput(t, filename, reflection, "")
+			for _, run := range []string{"cold", "warm", "warm again"} {
+				if got := analyzeInput(t, filename, reflection, dir); got != want {
+					t.Errorf("%s (reflection=%t, %s): results with summaries differ:\n%s",
+						filename, reflection, run, diffLines(want, got))
+				}
+			}
+		}
+	}
+
+	for _, pattern := range []string{"*.pkg", "*.pts"} {
+		if files, _ := filepath.Glob(filepath.Join(dir, pattern)); len(files) == 0 {
+			t.Errorf("no %s files were saved", pattern)
+		}
+	}
+}
+
+// A program of two packages, in which the main package changes.
+const (
+	libSrc = `package lib
+
+type T struct{ p *int }
+
+func New(p *int) *T { return &T{p} }
+
+func (t *T) Get() *int { return t.p }
+`
+	appSrc = `package main
+
+import "lib"
+
+var x, y int
+
+func main() {
+	t := lib.New(&x)
+	print(t.Get())
+}
+`
+	appSrcChanged = `package main
+
+import "lib"
+
+var x, y int
+
+func main() {
+	t := lib.New(&y)
+	print(t.Get())
+}
+`
+)
+
+// TestSummariesChangedPackage checks that a change to one package
+// regenerates only the summary of that package, and that the results
+// are those of an analysis without summaries.
+func TestSummariesChangedPackage(t *testing.T) {
+	gopath, err := ioutil.TempDir("", "pointer-gopath")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(gopath)
+	dir := filepath.Join(gopath, "summaries")
+
+	writeFile := func(name, content string) {
+		name = filepath.Join(gopath, "src", filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	analyze := func(summaryDir string) string {
+		ctxt := build.Default
+		ctxt.GOPATH = gopath
+		conf := loader.Config{Build: &ctxt, SourceImports: true}
+		conf.Import("app")
+		iprog, err := conf.Load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return analyzeProgram(t, iprog, iprog.Imported["app"].Pkg, false, summaryDir)
+	}
+	// summaryFiles returns the contents of the package summaries.
+	summaryFiles := func() map[string]string {
+		files, _ := filepath.Glob(filepath.Join(dir, "*.pkg"))
+		m := make(map[string]string)
+		for _, file := range files {
+			data, err := ioutil.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			m[filepath.Base(file)] = string(data)
+		}
+		return m
+	}
+
+	writeFile("lib/lib.go", libSrc)
+	writeFile("app/app.go", appSrc)
+	if got, want := analyze(dir), analyze(""); got != want {
+		t.Fatalf("cold run: results with summaries differ:\n%s", diffLines(want, got))
+	}
+	before := summaryFiles()
+	if len(before) < 2 {
+		t.Fatalf("got %d package summaries, want at least 2", len(before))
+	}
+
+	writeFile("app/app.go", appSrcChanged)
+	want := analyze("")
+	if got := analyze(dir); got != want {
+		t.Errorf("after change: results with summaries differ:\n%s", diffLines(want, got))
+	}
+	if !strings.Contains(want, "pts app.main:t1: app.y@") {
+		t.Errorf("after change: t.Get() does not point to y:\n%s", want)
+	}
+	after := summaryFiles()
+	var added []string
+	for name, data := range after {
+		if old, ok := before[name]; !ok {
+			added = append(added, name)
+		} else if old != data {
+			t.Errorf("summary %s of an unchanged package was rewritten", name)
+		}
+	}
+	if len(added) != 1 {
+		t.Errorf("got %d new package summaries, want 1 (for the changed package)", len(added))
+	}
+}
+
+// diffLines returns the lines that appear in only one of x and y.
+func diffLines(x, y string) string {
+	count := make(map[string]int)
+	for _, line := range strings.Split(x, "\n") {
+		count[line]++
+	}
+	for _, line := range strings.Split(y, "\n") {
+		count[line]--
+	}
+	var buf bytes.Buffer
+	for _, line := range strings.Split(x, "\n") {
+		if count[line] > 0 {
+			fmt.Fprintf(&buf, "-%s\n", line)
+		}
+	}
+	for _, line := range strings.Split(y, "\n") {
+		if count[line] < 0 {
+			fmt.Fprintf(&buf, "+%s\n", line)
+		}
+	}
+	return buf.String()
+}
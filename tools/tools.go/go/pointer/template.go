@@ -0,0 +1,666 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pointer
+
+// This file defines templates, the summaries of individual functions.
+//
+// A template is a replayable record of the nodes, objects, contours,
+// call edges and constraints that genFunc creates for a function.
+// Nodes are denoted relative to the function: by the order of their
+// creation, by their offset within the function's own object block,
+// or by their offset within the result of some earlier event, such
+// as the lookup of the value node of a global.  Replaying the
+// template therefore performs the same operations in the same order
+// as genFunc would, in any context and in any later analysis of the
+// same code, and creates identical nodes and constraints.
+//
+// Templates are recorded by genFunc (see analysis.rec) and saved in
+// the summary of the function's package (see summary.go).  A
+// function for which genFunc does anything that a template cannot
+// express, such as generating a constraint for an intrinsic, is
+// simply never summarized.
+
+import (
+	"code.google.com/p/go.tools/go/ssa"
+	"code.google.com/p/go.tools/go/types"
+)
+
+// Template event opcodes.
+const (
+	opNodes        = iota // addNodes(Type)
+	opNode                // addOneNode(Type)
+	opGlobalValue         // valueNode(Value), a global
+	opGlobalObject        // objectNode(nil, Value), a global
+	opContour             // makeFunctionObject(Value, the callsite of call X)
+	opObject              // endObject(A, cgn, Value or nil if X&1 == 0), with flags X>>1
+	opCallEdge            // callEdge(cgn, the callsite of call X, A)
+	opSetValue            // setValueNode(Value, A, cgn)
+	opAddr                // addrConstraint{A, B}
+	opCopy                // copyConstraint{A, B}
+	opLoad                // loadConstraint{X, A, B}
+	opStore               // storeConstraint{X, A, B}
+	opOffsetAddr          // offsetAddrConstraint{X, A, B}
+	opTypeFilter          // typeFilterConstraint{Type, A, B}
+	opUntag               // untagConstraint{Type, A, B, X != 0}
+	opInvoke              // invokeConstraint{the method of call X, A, B}
+)
+
+// An event is a single step of a template.  Types are indices in the
+// type table of the package summary; values and calls are indices in
+// the template's Values and Calls.
+type event struct {
+	Op    uint8
+	Type  int
+	Value int
+	A, B  nodeRef
+	X     uint32
+}
+
+// nodeRef kinds.
+const (
+	nodeZero  = iota // the zero node
+	nodeLocal        // the I'th node created by the template
+	nodeSelf         // offset Off within the function's object block
+	nodePanic        // the global panic node
+	nodeEvent        // offset Off from the node returned by event I
+)
+
+// A nodeRef denotes a node relative to a template.
+type nodeRef struct {
+	Kind uint8
+	I    int
+	Off  uint32
+}
+
+// valueRef kinds.
+const (
+	valueParam   = iota // parameter I
+	valueInstr          // instruction J of block I
+	valueOperand        // operand K of instruction J of block I
+	valueFunc           // the function named Name
+	valueGlobal         // the global Member of the package whose path is Name
+	valueFreeVar        // free variable I of the function named Name
+)
+
+// A valueRef denotes an ssa.Value relative to the summarized function.
+type valueRef struct {
+	Kind    uint8
+	Name    string
+	Member  string
+	I, J, K int
+}
+
+// A template summarizes the constraint generation for one function.
+type template struct {
+	Values    []valueRef
+	Calls     []callRef
+	Events    []event
+	Sites     []siteRef  // callsites of the function, in order
+	MapValues []nodeRef  // values of makemap objects
+	LocalObjs []localObj // the localobj mapping at the end of genFunc
+}
+
+// A callRef denotes call instruction Index of block Block of the
+// summarized function.
+type callRef struct {
+	Block, Index int
+}
+
+// A siteRef describes the callsite of a call instruction.
+type siteRef struct {
+	Call    int // index in Calls
+	Targets nodeRef
+}
+
+// A localObj is an entry of the localobj mapping.
+type localObj struct {
+	Value int // index in Values
+	Obj   nodeRef
+}
+
+// ---------- Recording ----------
+
+// A recorder records the template of the function whose constraints
+// are being generated.
+type recorder struct {
+	a      *analysis
+	cgn    *cgnode
+	pkg    *pkgSummary
+	t      *template
+	depth  int                         // >0 while a global or contour is being created
+	bad    bool                        // the function cannot be summarized
+	owner  map[nodeid]nodeRef          // template-relative names of nodes
+	nlocal int                         // number of nodes created by the template
+	values map[ssa.Value]int           // indices of values in t.Values
+	posns  map[ssa.Value][3]int        // positions of the instructions and constants of cgn.fn
+	calls  map[ssa.CallInstruction]int // indices of calls in t.Calls
+	invoke map[*types.Func]int         // calls of each invoked method
+	objs   map[int]*object             // objects of opObject events, by event index
+	nmaps  int                         // len(a.mapValues) at start
+}
+
+// record returns a recorder for the constraints of cgn.fn, or nil if
+// summaries are disabled or the function cannot be summarized.
+func (a *analysis) record(cgn *cgnode) *recorder {
+	pkg := a.summaries.pkgSummary(cgn.fn)
+	if pkg == nil {
+		return nil
+	}
+	return &recorder{
+		a:      a,
+		cgn:    cgn,
+		pkg:    pkg,
+		t:      new(template),
+		owner:  make(map[nodeid]nodeRef),
+		values: make(map[ssa.Value]int),
+		objs:   make(map[int]*object),
+		nmaps:  len(a.mapValues),
+	}
+}
+
+// suspend and resume bracket the creation of global entities and
+// contours, which are not part of the template.
+func (r *recorder) suspend() {
+	if r != nil {
+		r.depth++
+	}
+}
+
+func (r *recorder) resume() {
+	if r != nil {
+		r.depth--
+	}
+}
+
+func (r *recorder) active() bool {
+	return r != nil && r.depth == 0 && !r.bad
+}
+
+func (r *recorder) emit(e event) int {
+	r.t.Events = append(r.t.Events, e)
+	return len(r.t.Events) - 1
+}
+
+// addLocals names the nodes created since node start.
+func (r *recorder) addLocals(start nodeid) {
+	for id := start; id < r.a.nextNode(); id++ {
+		if _, ok := r.owner[id]; !ok {
+			r.owner[id] = nodeRef{Kind: nodeLocal, I: r.nlocal}
+			r.nlocal++
+		}
+	}
+}
+
+// addRange names the size nodes starting at id, the result of event i.
+func (r *recorder) addRange(i int, id nodeid, size uint32) {
+	for off := uint32(0); off < size; off++ {
+		if _, ok := r.owner[id+nodeid(off)]; !ok {
+			r.owner[id+nodeid(off)] = nodeRef{Kind: nodeEvent, I: i, Off: off}
+		}
+	}
+}
+
+// objectSize returns the number of nodes of the object block at obj.
+func (a *analysis) objectSize(obj nodeid) uint32 {
+	if size := a.nodes[obj].obj.size; size > 0 {
+		return size
+	}
+	return 1 // padding
+}
+
+// ref returns the template-relative name of node id.
+func (r *recorder) ref(id nodeid) nodeRef {
+	if id == 0 {
+		return nodeRef{Kind: nodeZero}
+	}
+	if ref, ok := r.owner[id]; ok {
+		return ref
+	}
+	if id == r.a.panicNode {
+		return nodeRef{Kind: nodePanic}
+	}
+	if obj := r.cgn.obj; id >= obj && id < obj+nodeid(r.a.objectSize(obj)) {
+		return nodeRef{Kind: nodeSelf, Off: uint32(id - obj)}
+	}
+	r.bad = true // a node of unknown origin
+	return nodeRef{}
+}
+
+// typ returns the index of T in the package's type table.
+func (r *recorder) typ(T types.Type) int {
+	i, ok := r.pkg.encodeType(r.a.summaries, T)
+	if !ok {
+		r.bad = true
+	}
+	return i
+}
+
+// value returns the index of v in the template's Values.
+func (r *recorder) value(v ssa.Value) int {
+	if i, ok := r.values[v]; ok {
+		return i
+	}
+	ref, ok := r.valueRef(v)
+	if !ok {
+		r.bad = true
+		return 0
+	}
+	i := len(r.t.Values)
+	r.t.Values = append(r.t.Values, ref)
+	r.values[v] = i
+	return i
+}
+
+func (r *recorder) valueRef(v ssa.Value) (valueRef, bool) {
+	fn := r.cgn.fn
+	funcs := r.a.summaries.funcs
+	switch v := v.(type) {
+	case *ssa.Function:
+		name := v.String()
+		return valueRef{Kind: valueFunc, Name: name}, funcs[name] == v
+
+	case *ssa.Global:
+		if v.Pkg == nil || v.Pkg.Members[v.Name()] != v {
+			return valueRef{}, false
+		}
+		return valueRef{Kind: valueGlobal, Name: v.Pkg.Object.Path(), Member: v.Name()}, true
+
+	case *ssa.FreeVar:
+		parent := v.Parent()
+		name := parent.String()
+		if funcs[name] != parent {
+			return valueRef{}, false
+		}
+		for i, fv := range parent.FreeVars {
+			if fv == v {
+				return valueRef{Kind: valueFreeVar, Name: name, I: i}, true
+			}
+		}
+
+	case *ssa.Parameter:
+		for i, p := range fn.Params {
+			if p == v {
+				return valueRef{Kind: valueParam, I: i}, true
+			}
+		}
+
+	default:
+		if r.posns == nil {
+			r.posns = make(map[ssa.Value][3]int)
+			var space [10]*ssa.Value
+			for i, b := range fn.Blocks {
+				for j, instr := range b.Instrs {
+					if v, ok := instr.(ssa.Value); ok {
+						r.posns[v] = [3]int{i, j, -1}
+					}
+					for k, rand := range instr.Operands(space[:0]) {
+						if c, ok := (*rand).(*ssa.Const); ok {
+							if _, ok := r.posns[c]; !ok {
+								r.posns[c] = [3]int{i, j, k}
+							}
+						}
+					}
+				}
+			}
+		}
+		if p, ok := r.posns[v]; ok {
+			if p[2] < 0 {
+				return valueRef{Kind: valueInstr, I: p[0], J: p[1]}, true
+			}
+			return valueRef{Kind: valueOperand, I: p[0], J: p[1], K: p[2]}, true
+		}
+	}
+	return valueRef{}, false
+}
+
+// call returns the index in Calls of the call instruction of site.
+func (r *recorder) call(site *callsite) int {
+	if site == nil || site.instr == nil {
+		r.bad = true
+		return 0
+	}
+	return r.callIndex(site.instr)
+}
+
+func (r *recorder) callIndex(call ssa.CallInstruction) int {
+	r.indexCalls()
+	i, ok := r.calls[call]
+	if !ok {
+		r.bad = true // not a call of cgn.fn
+	}
+	return i
+}
+
+// indexCalls populates r.calls and r.invoke on first use.
+func (r *recorder) indexCalls() {
+	if r.calls == nil {
+		r.calls = make(map[ssa.CallInstruction]int)
+		r.invoke = make(map[*types.Func]int)
+		for i, b := range r.cgn.fn.Blocks {
+			for j, instr := range b.Instrs {
+				if call, ok := instr.(ssa.CallInstruction); ok {
+					r.calls[call] = len(r.t.Calls)
+					r.t.Calls = append(r.t.Calls, callRef{i, j})
+					if m := call.Common().Method; m != nil {
+						if _, ok := r.invoke[m]; !ok {
+							r.invoke[m] = r.calls[call]
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// invokeCall returns the index in Calls of some call instruction of
+// cgn.fn that invokes method m.
+func (r *recorder) invokeCall(m *types.Func) int {
+	r.indexCalls()
+	i, ok := r.invoke[m]
+	if !ok {
+		r.bad = true
+	}
+	return i
+}
+
+func (r *recorder) nodes(T types.Type, start nodeid) {
+	if r.active() {
+		r.emit(event{Op: opNodes, Type: r.typ(T)})
+		r.addLocals(start)
+	}
+}
+
+func (r *recorder) node(T types.Type, id nodeid) {
+	if r.active() {
+		r.emit(event{Op: opNode, Type: r.typ(T)})
+		r.addLocals(id)
+	}
+}
+
+func (r *recorder) object(obj nodeid, o *object) {
+	if !r.active() {
+		return
+	}
+	r.addLocals(obj) // padding, if any
+	e := event{Op: opObject, A: r.ref(obj)}
+	if e.A.Kind != nodeLocal || o.cgn != r.cgn {
+		r.bad = true
+	}
+	switch data := o.data.(type) {
+	case nil:
+	case ssa.Value:
+		e.Value = r.value(data)
+		e.X = 1
+	default:
+		r.bad = true
+	}
+	r.objs[r.emit(e)] = o // flags are set later
+}
+
+func (r *recorder) contour(fn *ssa.Function, site *callsite, obj nodeid) {
+	if r.active() {
+		i := r.emit(event{Op: opContour, Value: r.value(fn), X: uint32(r.call(site))})
+		r.addRange(i, obj, r.a.objectSize(obj))
+	}
+}
+
+// global records the value node (op == opGlobalValue) or object
+// (op == opGlobalObject) id of global v.
+func (r *recorder) global(op uint8, v ssa.Value, id nodeid) {
+	if r.active() {
+		i := r.emit(event{Op: op, Value: r.value(v)})
+		if id != 0 {
+			size := r.a.sizeof(v.Type())
+			if op == opGlobalObject {
+				size = r.a.objectSize(id)
+			}
+			r.addRange(i, id, size)
+		}
+	}
+}
+
+func (r *recorder) setValueNode(v ssa.Value, id nodeid) {
+	if r.active() {
+		r.emit(event{Op: opSetValue, Value: r.value(v), A: r.ref(id)})
+	}
+}
+
+func (r *recorder) callEdge(site *callsite, obj nodeid) {
+	if r.active() {
+		r.emit(event{Op: opCallEdge, X: uint32(r.call(site)), A: r.ref(obj)})
+	}
+}
+
+func (r *recorder) constraint(c constraint) {
+	if !r.active() {
+		return
+	}
+	var e event
+	switch c := c.(type) {
+	case *addrConstraint:
+		e = event{Op: opAddr, A: r.ref(c.dst), B: r.ref(c.src)}
+	case *copyConstraint:
+		e = event{Op: opCopy, A: r.ref(c.dst), B: r.ref(c.src)}
+	case *loadConstraint:
+		e = event{Op: opLoad, A: r.ref(c.dst), B: r.ref(c.src), X: c.offset}
+	case *storeConstraint:
+		e = event{Op: opStore, A: r.ref(c.dst), B: r.ref(c.src), X: c.offset}
+	case *offsetAddrConstraint:
+		e = event{Op: opOffsetAddr, A: r.ref(c.dst), B: r.ref(c.src), X: c.offset}
+	case *typeFilterConstraint:
+		e = event{Op: opTypeFilter, Type: r.typ(c.typ), A: r.ref(c.dst), B: r.ref(c.src)}
+	case *untagConstraint:
+		e = event{Op: opUntag, Type: r.typ(c.typ), A: r.ref(c.dst), B: r.ref(c.src)}
+		if c.exact {
+			e.X = 1
+		}
+	case *invokeConstraint:
+		e = event{Op: opInvoke, X: uint32(r.invokeCall(c.method)), A: r.ref(c.iface), B: r.ref(c.params)}
+	default:
+		r.bad = true // e.g. a constraint of an intrinsic
+	}
+	r.emit(e)
+}
+
+// finish completes the template and, if the function could be
+// summarized, adds it to the package summary.
+func (r *recorder) finish() {
+	if r == nil {
+		return
+	}
+	t := r.t
+	for i, o := range r.objs {
+		t.Events[i].X |= o.flags << 1
+	}
+	for _, site := range r.cgn.sites {
+		t.Sites = append(t.Sites, siteRef{Call: r.call(site), Targets: r.ref(site.targets)})
+	}
+	for _, id := range r.a.mapValues[r.nmaps:] {
+		t.MapValues = append(t.MapValues, r.ref(id))
+	}
+	for v, obj := range r.a.localobj {
+		t.LocalObjs = append(t.LocalObjs, localObj{r.value(v), r.ref(obj)})
+	}
+	if !r.bad {
+		r.pkg.addTemplate(r.cgn.fn.String(), t)
+	}
+}
+
+// ---------- Replay ----------
+
+// replay generates the constraints for cgn.fn from its template, if
+// it has one, and reports whether it did so.
+func (a *analysis) replay(cgn *cgnode) bool {
+	pkg := a.summaries.pkgSummary(cgn.fn)
+	if pkg == nil {
+		return false
+	}
+	t := pkg.Funcs[cgn.fn.String()]
+	if t == nil {
+		return false
+	}
+
+	// Resolve all values and types before changing anything,
+	// in case the template is unusable.
+	values := make([]ssa.Value, len(t.Values))
+	for i, ref := range t.Values {
+		v := a.summaries.resolveValue(cgn.fn, ref)
+		if v == nil {
+			return false
+		}
+		values[i] = v
+	}
+	calls := make([]ssa.CallInstruction, len(t.Calls))
+	for i, ref := range t.Calls {
+		if ref.Block < 0 || ref.Block >= len(cgn.fn.Blocks) {
+			return false
+		}
+		b := cgn.fn.Blocks[ref.Block]
+		if ref.Index < 0 || ref.Index >= len(b.Instrs) {
+			return false
+		}
+		call, ok := b.Instrs[ref.Index].(ssa.CallInstruction)
+		if !ok {
+			return false
+		}
+		calls[i] = call
+	}
+	call := func(i int) ssa.CallInstruction {
+		if i < len(calls) {
+			return calls[i]
+		}
+		return nil
+	}
+	var typs []types.Type
+	for _, e := range t.Events {
+		switch e.Op {
+		case opNodes, opNode, opTypeFilter, opUntag:
+			T, ok := pkg.decodeType(a.summaries, e.Type)
+			if !ok {
+				return false
+			}
+			typs = append(typs, T)
+		case opContour, opCallEdge:
+			if call(int(e.X)) == nil {
+				return false
+			}
+		case opInvoke:
+			if c := call(int(e.X)); c == nil || c.Common().Method == nil {
+				return false
+			}
+		}
+	}
+	for _, s := range t.Sites {
+		if call(s.Call) == nil {
+			return false
+		}
+	}
+
+	var locals []nodeid
+	bases := make([]nodeid, len(t.Events))
+	sites := make(map[int]*callsite)
+	site := func(i int) *callsite {
+		s := sites[i]
+		if s == nil {
+			s = &callsite{instr: call(i)}
+			sites[i] = s
+		}
+		return s
+	}
+	node := func(ref nodeRef) nodeid {
+		switch ref.Kind {
+		case nodeLocal:
+			return locals[ref.I] + nodeid(ref.Off)
+		case nodeSelf:
+			return cgn.obj + nodeid(ref.Off)
+		case nodePanic:
+			return a.panicNode
+		case nodeEvent:
+			return bases[ref.I] + nodeid(ref.Off)
+		}
+		return 0
+	}
+
+	for i, e := range t.Events {
+		start := a.nextNode()
+		switch e.Op {
+		case opNodes:
+			a.addNodes(typs[0], "")
+			typs = typs[1:]
+
+		case opNode:
+			a.addOneNode(typs[0], "", nil)
+			typs = typs[1:]
+
+		case opGlobalValue:
+			bases[i] = a.valueNode(values[e.Value])
+
+		case opGlobalObject:
+			bases[i] = a.objectNode(nil, values[e.Value])
+
+		case opContour:
+			bases[i] = a.makeFunctionObject(values[e.Value].(*ssa.Function), site(int(e.X)))
+
+		case opObject:
+			var data interface{}
+			if e.X&1 != 0 {
+				data = values[e.Value]
+			}
+			a.endObject(node(e.A), cgn, data).flags = e.X >> 1
+
+		case opCallEdge:
+			a.callEdge(cgn, site(int(e.X)), node(e.A))
+
+		case opSetValue:
+			a.setValueNode(values[e.Value], node(e.A), cgn)
+
+		case opAddr:
+			a.addConstraint(&addrConstraint{node(e.A), node(e.B)})
+
+		case opCopy:
+			a.addConstraint(&copyConstraint{node(e.A), node(e.B)})
+
+		case opLoad:
+			a.addConstraint(&loadConstraint{e.X, node(e.A), node(e.B)})
+
+		case opStore:
+			a.addConstraint(&storeConstraint{e.X, node(e.A), node(e.B)})
+
+		case opOffsetAddr:
+			a.addConstraint(&offsetAddrConstraint{e.X, node(e.A), node(e.B)})
+
+		case opTypeFilter:
+			a.addConstraint(&typeFilterConstraint{typs[0], node(e.A), node(e.B)})
+			typs = typs[1:]
+
+		case opUntag:
+			a.addConstraint(&untagConstraint{typs[0], node(e.A), node(e.B), e.X != 0})
+			typs = typs[1:]
+
+		case opInvoke:
+			method := call(int(e.X)).Common().Method
+			a.addConstraint(&invokeConstraint{method, node(e.A), node(e.B)})
+		}
+		for id := start; id < a.nextNode(); id++ {
+			switch e.Op {
+			case opNodes, opNode, opObject:
+				locals = append(locals, id)
+			}
+		}
+	}
+
+	for _, s := range t.Sites {
+		site := site(s.Call)
+		site.targets = node(s.Targets)
+		cgn.sites = append(cgn.sites, site)
+	}
+	for _, ref := range t.MapValues {
+		a.mapValues = append(a.mapValues, node(ref))
+	}
+	for _, lo := range t.LocalObjs {
+		a.localobj[values[lo.Value]] = node(lo.Obj)
+	}
+	return true
+}
@@ -0,0 +1,343 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pointer
+
+// This file defines the table of types referenced by the templates
+// of a package summary.
+//
+// Types are encoded structurally, except for named types, which are
+// encoded by the path of their package, their name, and, for types
+// declared within a function, their ordinal among the same-named
+// types of the package in a depth-first walk of its scopes.  Since a
+// summary is used only when the hash of its package and those of its
+// dependencies are unchanged, these names denote the same types in
+// the later analysis, and decoding yields types identical to the
+// original ones.
+
+import (
+	"code.google.com/p/go.tools/go/types"
+)
+
+// typeEntry kinds.
+const (
+	typeBasic = iota
+	typeNamed
+	typePointer
+	typeSlice
+	typeArray
+	typeMap
+	typeChan
+	typeStruct
+	typeTuple
+	typeNilTuple
+	typeSignature
+	typeInterface
+)
+
+// A typeEntry is the encoding of a type.  Component types are
+// denoted by their indices in the table.
+type typeEntry struct {
+	Kind     uint8
+	Name     string       // basic, named
+	Pkg      string       // named
+	Index    int          // named: 0 for package-level types, otherwise 1 + ordinal
+	Elem     int          // pointer, slice, array, map, chan; signature: 1 + receiver, or 0
+	Key      int          // map
+	Len      int64        // array: length; chan: direction; basic: kind
+	Fields   []fieldEntry // struct, tuple, interface (methods)
+	Params   int          // signature
+	Results  int          // signature
+	Variadic bool         // signature
+}
+
+// A fieldEntry is the encoding of a struct field, tuple element or
+// interface method.
+type fieldEntry struct {
+	Pkg      string
+	Name     string
+	Type     int
+	Embedded bool
+	Tag      string
+}
+
+// encodeType returns the index of T in the type table of the package
+// summary, adding it if necessary.  It reports false if T cannot be
+// encoded.
+func (p *pkgSummary) encodeType(s *summaries, T types.Type) (int, bool) {
+	if i, ok := p.typeIndex[T]; ok {
+		return i, true
+	}
+	e, ok := p.typeEntry(s, T)
+	if !ok {
+		return 0, false
+	}
+	i := len(p.Types)
+	p.Types = append(p.Types, e)
+	p.decoded = append(p.decoded, T)
+	p.typeIndex[T] = i
+	p.dirty = true
+	return i, true
+}
+
+func (p *pkgSummary) typeEntry(s *summaries, T types.Type) (e typeEntry, ok bool) {
+	ok = true
+	enc := func(T types.Type) int {
+		i, ok2 := p.encodeType(s, T)
+		ok = ok && ok2
+		return i
+	}
+	vars := func(t *types.Tuple) []fieldEntry {
+		var fields []fieldEntry
+		for i := 0; i < t.Len(); i++ {
+			fields = append(fields, p.fieldEntry(t.At(i), enc(t.At(i).Type())))
+		}
+		return fields
+	}
+
+	switch T := T.(type) {
+	case *types.Basic:
+		e = typeEntry{Kind: typeBasic, Name: T.Name(), Len: int64(T.Kind())}
+
+	case *types.Named:
+		obj := T.Obj()
+		e = typeEntry{Kind: typeNamed, Name: obj.Name()}
+		if obj.Pkg() == nil {
+			ok = types.Universe.Lookup(obj.Name()) == obj
+			break
+		}
+		e.Pkg = obj.Pkg().Path()
+		if s.packages[e.Pkg] != obj.Pkg() {
+			return e, false
+		}
+		if obj.Pkg().Scope().Lookup(obj.Name()) != obj {
+			e.Index = localTypeIndex(obj.Pkg().Scope(), obj)
+			ok = e.Index > 0
+		}
+
+	case *types.Pointer:
+		e = typeEntry{Kind: typePointer, Elem: enc(T.Elem())}
+
+	case *types.Slice:
+		e = typeEntry{Kind: typeSlice, Elem: enc(T.Elem())}
+
+	case *types.Array:
+		e = typeEntry{Kind: typeArray, Elem: enc(T.Elem()), Len: T.Len()}
+
+	case *types.Map:
+		e = typeEntry{Kind: typeMap, Key: enc(T.Key()), Elem: enc(T.Elem())}
+
+	case *types.Chan:
+		e = typeEntry{Kind: typeChan, Elem: enc(T.Elem()), Len: int64(T.Dir())}
+
+	case *types.Struct:
+		e = typeEntry{Kind: typeStruct}
+		for i := 0; i < T.NumFields(); i++ {
+			f := p.fieldEntry(T.Field(i), enc(T.Field(i).Type()))
+			f.Tag = T.Tag(i)
+			e.Fields = append(e.Fields, f)
+		}
+
+	case *types.Tuple:
+		if T == nil {
+			e = typeEntry{Kind: typeNilTuple}
+			break
+		}
+		e = typeEntry{Kind: typeTuple, Fields: vars(T)}
+
+	case *types.Signature:
+		e = typeEntry{
+			Kind:     typeSignature,
+			Params:   enc(T.Params()),
+			Results:  enc(T.Results()),
+			Variadic: T.Variadic(),
+		}
+		if recv := T.Recv(); recv != nil {
+			e.Elem = 1 + enc(recv.Type())
+		}
+
+	case *types.Interface:
+		e = typeEntry{Kind: typeInterface}
+		for i := 0; i < T.NumMethods(); i++ {
+			m := T.Method(i)
+			sig := m.Type().(*types.Signature)
+			// The receiver of an interface method refers to the
+			// interface itself, so it is omitted.
+			sig = types.NewSignature(nil, nil, sig.Params(), sig.Results(), sig.Variadic())
+			e.Fields = append(e.Fields, p.fieldEntry(m, enc(sig)))
+		}
+
+	default:
+		ok = false
+	}
+	return
+}
+
+func (p *pkgSummary) fieldEntry(obj types.Object, typ int) fieldEntry {
+	f := fieldEntry{Name: obj.Name(), Type: typ}
+	if obj.Pkg() != nil {
+		f.Pkg = obj.Pkg().Path()
+	}
+	if v, ok := obj.(*types.Var); ok {
+		f.Embedded = v.Anonymous()
+	}
+	return f
+}
+
+// localTypeIndex returns 1 + the ordinal of local type obj among the
+// types of the same name in the scopes nested within pkgScope, or
+// zero if it was not found.
+func localTypeIndex(pkgScope *types.Scope, obj *types.TypeName) int {
+	i := 0
+	var found bool
+	walkLocalTypes(pkgScope, obj.Name(), func(tn *types.TypeName) bool {
+		i++
+		found = tn == obj
+		return found
+	})
+	if !found {
+		return 0
+	}
+	return i
+}
+
+// walkLocalTypes calls f for each type named name declared in a
+// scope nested within pkgScope, in a deterministic order, until f
+// returns true.
+func walkLocalTypes(pkgScope *types.Scope, name string, f func(*types.TypeName) bool) bool {
+	for i, n := 0, pkgScope.NumChildren(); i < n; i++ {
+		child := pkgScope.Child(i)
+		if tn, ok := child.Lookup(name).(*types.TypeName); ok && f(tn) {
+			return true
+		}
+		if walkLocalTypes(child, name, f) {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeType returns the type at index i of the type table of the
+// package summary.  It reports false if the type cannot be decoded.
+func (p *pkgSummary) decodeType(s *summaries, i int) (types.Type, bool) {
+	if i < 0 || i >= len(p.Types) {
+		return nil, false
+	}
+	if T := p.decoded[i]; T != nil {
+		return T, true
+	}
+	T := p.decodeEntry(s, &p.Types[i])
+	if T == nil {
+		return nil, false
+	}
+	// Canonicalize *reflect.rtype, which the analysis
+	// compares by pointer.
+	if s.a.reflectRtypePtr != nil && types.Identical(T, s.a.reflectRtypePtr) {
+		T = s.a.reflectRtypePtr
+	}
+	p.decoded[i] = T
+	p.typeIndex[T] = i
+	return T, true
+}
+
+func (p *pkgSummary) decodeEntry(s *summaries, e *typeEntry) types.Type {
+	ok := true
+	dec := func(i int) types.Type {
+		T, ok2 := p.decodeType(s, i)
+		ok = ok && ok2
+		return T
+	}
+	vars := func(fields []fieldEntry) []*types.Var {
+		var vars []*types.Var
+		for _, f := range fields {
+			vars = append(vars, types.NewField(0, s.packages[f.Pkg], f.Name, dec(f.Type), f.Embedded))
+		}
+		return vars
+	}
+
+	var T types.Type
+	switch e.Kind {
+	case typeBasic:
+		if kind := types.BasicKind(e.Len); kind >= 0 && int(kind) < len(types.Typ) && types.Typ[kind].Name() == e.Name {
+			T = types.Typ[kind]
+		} else if tn, ok := types.Universe.Lookup(e.Name).(*types.TypeName); ok {
+			T = tn.Type() // byte, rune
+		}
+
+	case typeNamed:
+		var obj types.Object
+		if e.Pkg == "" {
+			obj = types.Universe.Lookup(e.Name)
+		} else if pkg := s.packages[e.Pkg]; pkg == nil {
+			// not found
+		} else if e.Index == 0 {
+			obj = pkg.Scope().Lookup(e.Name)
+		} else {
+			i := 0
+			walkLocalTypes(pkg.Scope(), e.Name, func(tn *types.TypeName) bool {
+				i++
+				if i == e.Index {
+					obj = tn
+					return true
+				}
+				return false
+			})
+		}
+		if tn, ok := obj.(*types.TypeName); ok {
+			T = tn.Type()
+		}
+
+	case typePointer:
+		T = types.NewPointer(dec(e.Elem))
+
+	case typeSlice:
+		T = types.NewSlice(dec(e.Elem))
+
+	case typeArray:
+		T = types.NewArray(dec(e.Elem), e.Len)
+
+	case typeMap:
+		T = types.NewMap(dec(e.Key), dec(e.Elem))
+
+	case typeChan:
+		T = types.NewChan(types.ChanDir(e.Len), dec(e.Elem))
+
+	case typeStruct:
+		var tags []string
+		for _, f := range e.Fields {
+			tags = append(tags, f.Tag)
+		}
+		T = types.NewStruct(vars(e.Fields), tags)
+
+	case typeTuple:
+		T = types.NewTuple(vars(e.Fields)...)
+
+	case typeNilTuple:
+		T = (*types.Tuple)(nil)
+
+	case typeSignature:
+		var recv *types.Var
+		if e.Elem > 0 {
+			recv = types.NewParam(0, nil, "", dec(e.Elem-1))
+		}
+		params, _ := dec(e.Params).(*types.Tuple)
+		results, _ := dec(e.Results).(*types.Tuple)
+		T = types.NewSignature(nil, recv, params, results, e.Variadic)
+
+	case typeInterface:
+		var methods []*types.Func
+		for _, f := range e.Fields {
+			sig, _ := dec(f.Type).(*types.Signature)
+			if sig == nil {
+				return nil
+			}
+			methods = append(methods, types.NewFunc(0, s.packages[f.Pkg], f.Name, sig))
+		}
+		T = types.NewInterface(methods, nil).Complete()
+	}
+	if !ok || T == nil {
+		return nil
+	}
+	return T
+}
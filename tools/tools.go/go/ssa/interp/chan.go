@@ -0,0 +1,268 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interp
+
+// Channels.
+//
+// Channels are implemented by the interpreter, rather than by Go
+// channels, so that the scheduler knows which goroutines are blocked
+// on them.  All channel state is guarded by the scheduler's mutex.
+
+import (
+	"go/token"
+	"math/rand"
+
+	"code.google.com/p/go.tools/go/types"
+)
+
+// A channel is the interpreter's representation of a Go channel.
+type channel struct {
+	cap    int
+	buf    []value   // buffered values, oldest first
+	closed bool      // close has been called
+	recvq  []*waiter // goroutines blocked receiving, in order of arrival
+	sendq  []*waiter // goroutines blocked sending, in order of arrival
+}
+
+// A waiter is a goroutine blocked on a channel operation.
+type waiter struct {
+	g      *goroutine
+	sel    *selection // the select statement the operation is a case of, if any
+	index  int        // the index of the case
+	v      value      // the value to send, or the value received
+	ok     bool       // a value was received, not a zero value from a closed channel
+	closed bool       // the channel of a blocked send was closed
+}
+
+// A selection is the state of a blocked select statement.
+type selection struct {
+	fired *waiter // the waiter of the case that proceeded, if any
+}
+
+// A selectCase is a case of a select statement.
+type selectCase struct {
+	dir  types.ChanDir // SendOnly or RecvOnly
+	c    *channel
+	send value // the value to send
+}
+
+// dequeue removes and returns the first waiter in q that may still
+// proceed, or returns nil if there is none.
+func dequeue(q *[]*waiter) *waiter {
+	for len(*q) > 0 {
+		w := (*q)[0]
+		*q = (*q)[1:]
+		if w.sel == nil || w.sel.fired == nil {
+			return w
+		}
+	}
+	return nil
+}
+
+// remove removes waiter w from q.
+func remove(q *[]*waiter, w *waiter) {
+	for i, x := range *q {
+		if x == w {
+			*q = append((*q)[:i], (*q)[i+1:]...)
+			return
+		}
+	}
+}
+
+// complete makes the goroutine of waiter w, whose operation has
+// proceeded, runnable.
+func (s *scheduler) complete(w *waiter) {
+	if w.sel != nil {
+		w.sel.fired = w
+	}
+	s.ready(w.g)
+}
+
+// trySend sends v on c if it can do so without blocking, and reports
+// whether it did.  The caller must hold s.mu.
+func (s *scheduler) trySend(c *channel, v value) bool {
+	if c.closed {
+		panic("send on closed channel")
+	}
+	if w := dequeue(&c.recvq); w != nil {
+		w.v, w.ok = v, true
+		s.complete(w)
+		return true
+	}
+	if len(c.buf) < c.cap {
+		c.buf = append(c.buf, v)
+		return true
+	}
+	return false
+}
+
+// tryRecv receives from c if it can do so without blocking.  It
+// returns the value received, whether it was sent rather than a zero
+// value from a closed channel, and whether it received at all.  The
+// zero value is nil.  The caller must hold s.mu.
+func (s *scheduler) tryRecv(c *channel) (v value, ok, done bool) {
+	if len(c.buf) > 0 {
+		v = c.buf[0]
+		c.buf = c.buf[1:]
+		if w := dequeue(&c.sendq); w != nil {
+			c.buf = append(c.buf, w.v)
+			s.complete(w)
+		}
+		return v, true, true
+	}
+	if w := dequeue(&c.sendq); w != nil {
+		s.complete(w)
+		return w.v, true, true
+	}
+	if c.closed {
+		return nil, false, true
+	}
+	return nil, false, false
+}
+
+// send sends v on channel c, blocking goroutine g until it can.
+func (s *scheduler) send(g *goroutine, c *channel, v value, pos token.Pos) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reschedule(g)
+	if c == nil {
+		s.wait(g, "chan send (nil chan)", pos) // forever
+	}
+	if s.trySend(c, v) {
+		return
+	}
+	w := &waiter{g: g, v: v}
+	c.sendq = append(c.sendq, w)
+	s.wait(g, "chan send", pos)
+	if w.closed {
+		panic("send on closed channel")
+	}
+}
+
+// recv receives from channel c, blocking goroutine g until it can.
+// It returns nil and false if c is closed.
+func (s *scheduler) recv(g *goroutine, c *channel, pos token.Pos) (value, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reschedule(g)
+	if c == nil {
+		s.wait(g, "chan receive (nil chan)", pos) // forever
+	}
+	if v, ok, done := s.tryRecv(c); done {
+		return v, ok
+	}
+	w := &waiter{g: g}
+	c.recvq = append(c.recvq, w)
+	s.wait(g, "chan receive", pos)
+	return w.v, w.ok
+}
+
+// close closes channel c, waking all goroutines blocked on it.
+func (s *scheduler) close(c *channel) {
+	if c == nil {
+		panic("close of nil channel")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c.closed {
+		panic("close of closed channel")
+	}
+	c.closed = true
+	for w := dequeue(&c.recvq); w != nil; w = dequeue(&c.recvq) {
+		s.complete(w)
+	}
+	for w := dequeue(&c.sendq); w != nil; w = dequeue(&c.sendq) {
+		w.closed = true
+		s.complete(w)
+	}
+}
+
+// chanLen returns the number of values buffered in channel c.
+func (s *scheduler) chanLen(c *channel) int {
+	if c == nil {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(c.buf)
+}
+
+// chanCap returns the capacity of channel c.
+func chanCap(c *channel) int {
+	if c == nil {
+		return 0
+	}
+	return c.cap
+}
+
+// choose executes a select statement on goroutine g.  It proceeds
+// with a case chosen at random from those that can, blocking until
+// one can unless the statement is non-blocking.  It returns the index
+// of the case, or -1 for the default case, and for a receive, the
+// value received and whether it was sent.
+func (s *scheduler) choose(g *goroutine, cases []selectCase, blocking bool, pos token.Pos) (chosen int, recv value, recvOk bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reschedule(g)
+
+	var order []int
+	if s.rand != nil {
+		order = s.rand.Perm(len(cases))
+	} else {
+		order = rand.Perm(len(cases))
+	}
+	for _, i := range order {
+		cs := &cases[i]
+		if cs.c == nil {
+			continue
+		}
+		if cs.dir == types.SendOnly {
+			if s.trySend(cs.c, cs.send) {
+				return i, nil, false
+			}
+		} else if v, ok, done := s.tryRecv(cs.c); done {
+			return i, v, ok
+		}
+	}
+	if !blocking {
+		return -1, nil, false
+	}
+
+	// Block on all the cases.
+	sel := new(selection)
+	waiters := make([]*waiter, len(cases))
+	for i, cs := range cases {
+		if cs.c == nil {
+			continue
+		}
+		w := &waiter{g: g, sel: sel, index: i, v: cs.send}
+		if cs.dir == types.SendOnly {
+			cs.c.sendq = append(cs.c.sendq, w)
+		} else {
+			cs.c.recvq = append(cs.c.recvq, w)
+		}
+		waiters[i] = w
+	}
+	status := "select"
+	if len(cases) == 0 {
+		status = "select (no cases)"
+	}
+	s.wait(g, status, pos)
+	for i, w := range waiters {
+		if w == nil || w == sel.fired {
+			continue
+		}
+		if cases[i].dir == types.SendOnly {
+			remove(&cases[i].c.sendq, w)
+		} else {
+			remove(&cases[i].c.recvq, w)
+		}
+	}
+	w := sel.fired
+	if w.closed {
+		panic("send on closed channel")
+	}
+	return w.index, w.v, w.ok
+}
@@ -0,0 +1,458 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interp
+
+// An interactive debugger.
+//
+// When Debug is set, the interpreter consults it before each
+// instruction.  A goroutine stops when it reaches a breakpoint, or
+// completes a step requested when it last stopped, and the client's
+// Stopped function is called on it to inspect its state and decide how
+// it continues.  Only one goroutine is stopped at a time; unless the
+// program is interpreted in DeterministicScheduling mode, the others
+// keep running meanwhile.
+//
+// The names and values of source-level variables are known from the
+// DebugRef instructions of the functions, so the program should be
+// built in ssa.GlobalDebug mode.
+
+import (
+	"bytes"
+	"fmt"
+	"go/token"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"code.google.com/p/go.tools/go/ssa"
+	"code.google.com/p/go.tools/go/ssa/ssautil"
+	"code.google.com/p/go.tools/go/types"
+)
+
+// Debug, if non-nil, is the debugger of the interpreted program.
+//
+// (This is a global variable shared by all interpreters in the same
+// process.)
+var Debug *Debugger
+
+// An Action tells a stopped goroutine how to continue.
+type Action int
+
+const (
+	Continue  Action = iota // Run until the next breakpoint.
+	Step                    // Stop at the next source line, entering calls.
+	Next                    // Stop at the next source line of this function or its callers.
+	Finish                  // Stop when the selected function returns.
+	StepInstr               // Stop at the next SSA instruction.
+)
+
+// A Debugger holds the breakpoints of an interpreted program.
+type Debugger struct {
+	// Stopped is called on a goroutine that has stopped, and
+	// returns how it is to continue.  The Stop is valid only
+	// until Stopped returns.
+	Stopped func(s *Stop) Action
+
+	prog        *ssa.Program
+	stop        sync.Mutex   // held while a goroutine is stopped
+	mu          sync.RWMutex // guards breakpoints
+	breakpoints []*Breakpoint
+	nextID      int
+}
+
+// A Breakpoint stops a goroutine on entry to a function or on
+// reaching a line.
+type Breakpoint struct {
+	ID   int
+	Func *ssa.Function // the function, for a function breakpoint
+	File string        // the file and line, for a line breakpoint
+	Line int
+}
+
+func (b *Breakpoint) String() string {
+	if b.Func != nil {
+		return fmt.Sprintf("breakpoint %d at %s", b.ID, b.Func)
+	}
+	return fmt.Sprintf("breakpoint %d at %s:%d", b.ID, b.File, b.Line)
+}
+
+// NewDebugger returns a debugger, without breakpoints, for prog.
+func NewDebugger(prog *ssa.Program) *Debugger {
+	return &Debugger{prog: prog}
+}
+
+func (d *Debugger) add(b *Breakpoint) *Breakpoint {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.nextID++
+	b.ID = d.nextID
+	d.breakpoints = append(d.breakpoints, b)
+	return b
+}
+
+// BreakFunc sets a breakpoint on entry to the function named name,
+// as printed by ssa.Function.String, e.g. "main.f" or
+// "(*main.T).m".
+func (d *Debugger) BreakFunc(name string) (*Breakpoint, error) {
+	for fn := range ssautil.AllFunctions(d.prog) {
+		if fn.String() == name && fn.Blocks != nil {
+			return d.add(&Breakpoint{Func: fn}), nil
+		}
+	}
+	return nil, fmt.Errorf("no function %s", name)
+}
+
+// BreakLine sets a breakpoint on the line of the named file.  file
+// may be any suffix of the file's path that starts a path element.
+func (d *Debugger) BreakLine(file string, line int) (*Breakpoint, error) {
+	files := make(map[string]bool)
+	for fn := range ssautil.AllFunctions(d.prog) {
+		for _, b := range fn.Blocks {
+			for _, instr := range b.Instrs {
+				if !instr.Pos().IsValid() {
+					continue
+				}
+				posn := d.prog.Fset.Position(instr.Pos())
+				if posn.Line == line && hasPathSuffix(posn.Filename, file) {
+					files[posn.Filename] = true
+				}
+			}
+		}
+	}
+	switch len(files) {
+	case 0:
+		return nil, fmt.Errorf("no code at %s:%d", file, line)
+	case 1:
+		for f := range files {
+			return d.add(&Breakpoint{File: f, Line: line}), nil
+		}
+	}
+	return nil, fmt.Errorf("%s is ambiguous", file)
+}
+
+// hasPathSuffix reports whether suffix is a trailing sequence of the
+// elements of path.
+func hasPathSuffix(path, suffix string) bool {
+	path, suffix = filepath.ToSlash(path), filepath.ToSlash(suffix)
+	return path == suffix || strings.HasSuffix(path, "/"+suffix)
+}
+
+// Clear deletes the breakpoint with the given ID, and reports whether
+// there was one.
+func (d *Debugger) Clear(id int) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i, b := range d.breakpoints {
+		if b.ID == id {
+			d.breakpoints = append(d.breakpoints[:i], d.breakpoints[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Breakpoints returns the breakpoints, in order of creation.
+func (d *Debugger) Breakpoints() []*Breakpoint {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return append([]*Breakpoint(nil), d.breakpoints...)
+}
+
+// check is called before fr executes instr, and stops the goroutine
+// if it has reached a breakpoint or completed a step.
+func (d *Debugger) check(fr *frame, instr ssa.Instruction) {
+	g := fr.g
+	newLine := false
+	// A φ-node belongs to the start of its block, not to the line of
+	// the variable it merges.
+	if _, ok := instr.(*ssa.Phi); !ok && instr.Pos().IsValid() {
+		pos := instr.Pos()
+		line := d.prog.Fset.Position(pos).Line
+		newLine = line != fr.line
+		fr.pos, fr.line = pos, line
+	}
+
+	var stop bool
+	switch g.step {
+	case StepInstr:
+		stop = true
+	case Step:
+		stop = newLine
+	case Next:
+		stop = newLine && depth(fr) <= g.depth
+	case Finish:
+		stop = depth(fr) < g.depth
+	}
+	var bp *Breakpoint
+	if !stop {
+		bp = d.breakpoint(fr, instr, newLine)
+		if bp == nil {
+			return
+		}
+	}
+
+	d.stop.Lock()
+	defer d.stop.Unlock()
+	s := fr.i.sched
+	s.mu.Lock()
+	status, pos := g.status, g.pos
+	g.status, g.pos = "stopped", fr.pos
+	if instr.Pos().IsValid() {
+		g.pos = instr.Pos()
+	}
+	s.mu.Unlock()
+
+	st := &Stop{d: d, g: g, top: fr, fr: fr, instr: instr, Breakpoint: bp}
+	g.step = d.Stopped(st)
+	g.depth = depth(st.fr)
+
+	s.mu.Lock()
+	g.status, g.pos = status, pos
+	s.mu.Unlock()
+}
+
+// breakpoint returns the breakpoint, if any, at which fr stops before
+// instr.  newLine indicates that instr starts a new source line.
+func (d *Debugger) breakpoint(fr *frame, instr ssa.Instruction, newLine bool) *Breakpoint {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for _, b := range d.breakpoints {
+		if b.Func != nil {
+			// The entry block has no predecessors, so its first
+			// instruction executes once per call.
+			if b.Func == fr.fn && instr == fr.fn.Blocks[0].Instrs[0] {
+				return b
+			}
+		} else if newLine && b.Line == fr.line && b.File == d.prog.Fset.Position(fr.pos).Filename {
+			return b
+		}
+	}
+	return nil
+}
+
+// depth returns the number of active frames of fr's goroutine, up to
+// and including fr.
+func depth(fr *frame) int {
+	n := 0
+	for ; fr != nil; fr = fr.caller {
+		n++
+	}
+	return n
+}
+
+// recordRef notes the DebugRef of a local variable executed by fr,
+// from which the debugger learns the variable's value.
+func (fr *frame) recordRef(ref *ssa.DebugRef) {
+	v, ok := ref.Object().(*types.Var)
+	if !ok {
+		return
+	}
+	if _, ok := ref.X.(*ssa.Global); ok {
+		return
+	}
+	if fr.refs == nil {
+		fr.refs = make(map[*types.Var]*ssa.DebugRef)
+	}
+	if fr.refs[v] == nil {
+		fr.vars = append(fr.vars, v)
+	}
+	fr.refs[v] = ref
+}
+
+// A Stop is the state of a stopped goroutine.  One of its frames is
+// selected, initially the innermost; Func, Instr, Position, Locals
+// and Print are relative to that frame.
+type Stop struct {
+	Breakpoint *Breakpoint // the breakpoint reached, or nil after a step
+
+	d     *Debugger
+	g     *goroutine
+	top   *frame // the innermost frame
+	fr    *frame // the selected frame
+	instr ssa.Instruction
+}
+
+// Goroutine returns the ID of the stopped goroutine.
+func (s *Stop) Goroutine() int { return s.g.id }
+
+// Func returns the function of the selected frame.
+func (s *Stop) Func() *ssa.Function { return s.fr.fn }
+
+// Block returns the current block of the selected frame.
+func (s *Stop) Block() *ssa.BasicBlock { return s.fr.block }
+
+// Instr returns the instruction the selected frame executes next: the
+// instruction the goroutine stopped before in the innermost frame,
+// and a call in the others.
+func (s *Stop) Instr() ssa.Instruction {
+	if s.fr == s.top {
+		return s.instr
+	}
+	callpos := s.callee().callpos
+	for _, instr := range s.fr.block.Instrs {
+		if _, ok := instr.(ssa.CallInstruction); ok && instr.Pos() == callpos {
+			return instr
+		}
+	}
+	return nil
+}
+
+// callee returns the frame called by the selected one.
+func (s *Stop) callee() *frame {
+	fr := s.top
+	for fr.caller != s.fr {
+		fr = fr.caller
+	}
+	return fr
+}
+
+// Position returns the source position of the selected frame, if
+// known.
+func (s *Stop) Position() token.Position {
+	pos := s.fr.pos
+	if s.fr != s.top {
+		pos = s.callee().callpos
+	} else if s.instr.Pos().IsValid() {
+		pos = s.instr.Pos()
+	}
+	return s.d.prog.Fset.Position(pos)
+}
+
+// Frame selects the nth frame of the stack, counting from 0 for the
+// innermost.
+func (s *Stop) Frame(n int) error {
+	fr := s.top
+	for i := 0; i < n && fr != nil; i++ {
+		fr = fr.caller
+	}
+	if n < 0 || fr == nil {
+		return fmt.Errorf("no frame %d", n)
+	}
+	s.fr = fr
+	return nil
+}
+
+// A Local is a variable of a stopped function.
+type Local struct {
+	Name  string
+	Type  types.Type
+	Value string
+}
+
+// Locals returns the parameters of the function of the selected frame,
+// followed by the local variables it has referred to so far, in order
+// of first reference.
+func (s *Stop) Locals() []Local {
+	var locals []Local
+	seen := make(map[*types.Var]bool)
+	for _, p := range s.fr.fn.Params {
+		v, _ := p.Object().(*types.Var)
+		if v != nil && s.fr.refs[v] != nil {
+			seen[v] = true
+			locals = append(locals, s.local(v))
+		} else if val, ok := s.fr.env[p]; ok {
+			locals = append(locals, Local{p.Name(), p.Type(), toString(val)})
+		}
+	}
+	for _, v := range s.fr.vars {
+		if !seen[v] {
+			locals = append(locals, s.local(v))
+		}
+	}
+	return locals
+}
+
+func (s *Stop) local(v *types.Var) Local {
+	return Local{v.Name(), v.Type(), s.refValue(s.fr.refs[v])}
+}
+
+// refValue returns the current value of the variable referred to by ref.
+func (s *Stop) refValue(ref *ssa.DebugRef) string {
+	var x value
+	switch v := ref.X.(type) {
+	case *ssa.Const:
+		x = constValue(v)
+	case *ssa.Function, *ssa.Builtin:
+		x = v
+	default:
+		var ok bool
+		if x, ok = s.fr.env[v]; !ok {
+			return "<unavailable>"
+		}
+	}
+	if ref.IsAddr {
+		return toString(*x.(*value))
+	}
+	return toString(x)
+}
+
+// Print returns the value of the named variable of the selected
+// frame: a local variable, a parameter or SSA register of its
+// function (e.g. "t3"), or a global of its package.
+func (s *Stop) Print(name string) (string, error) {
+	for i := len(s.fr.vars) - 1; i >= 0; i-- {
+		if v := s.fr.vars[i]; v.Name() == name {
+			return s.refValue(s.fr.refs[v]), nil
+		}
+	}
+	// A variable not yet referred to may be available through a
+	// DebugRef further on, e.g. a free variable of a closure.
+	for _, b := range s.fr.fn.Blocks {
+		for _, instr := range b.Instrs {
+			ref, ok := instr.(*ssa.DebugRef)
+			if !ok || ref.Object() == nil || ref.Object().Name() != name {
+				continue
+			}
+			if _, ok := ref.Object().(*types.Var); !ok {
+				continue
+			}
+			if _, ok := ref.X.(*ssa.Global); ok {
+				continue
+			}
+			if v := s.refValue(ref); v != "<unavailable>" {
+				return v, nil
+			}
+		}
+	}
+	for key, val := range s.fr.env {
+		if key.Name() == name {
+			return toString(val), nil
+		}
+	}
+	if pkg := s.fr.fn.Pkg; pkg != nil {
+		if g, ok := pkg.Members[name].(*ssa.Global); ok {
+			if p, ok := s.fr.i.globals[g]; ok {
+				return toString(*p), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no variable %s", name)
+}
+
+// Stack returns the stack of the stopped goroutine, innermost first.
+func (s *Stop) Stack() string {
+	var buf bytes.Buffer
+	sched := s.top.i.sched
+	sched.mu.Lock()
+	sched.writeStack(&buf, s.g)
+	sched.mu.Unlock()
+	return buf.String()
+}
+
+// Goroutines returns the status and stack of each goroutine; the
+// stacks of running goroutines are omitted.
+func (s *Stop) Goroutines() string {
+	var buf bytes.Buffer
+	sched := s.top.i.sched
+	sched.mu.Lock()
+	for i, g := range sched.goroutines {
+		if i > 0 {
+			buf.WriteByte('\n')
+		}
+		sched.writeStack(&buf, g)
+	}
+	sched.mu.Unlock()
+	return buf.String()
+}
@@ -31,8 +31,17 @@ var externals map[string]externalFn
 func init() {
 	// That little dot ۰ is an Arabic zero numeral (U+06F0), categories [Nd].
 	externals = map[string]externalFn{
+		"(*sync.Mutex).Lock":               ext۰sync۰Mutex۰Lock,
+		"(*sync.Mutex).Unlock":             ext۰sync۰Mutex۰Unlock,
+		"(*sync.Once).Do":                  ext۰sync۰Once۰Do,
 		"(*sync.Pool).Get":                 ext۰sync۰Pool۰Get,
 		"(*sync.Pool).Put":                 ext۰sync۰Pool۰Put,
+		"(*sync.RWMutex).Lock":             ext۰sync۰RWMutex۰Lock,
+		"(*sync.RWMutex).RLock":            ext۰sync۰RWMutex۰RLock,
+		"(*sync.RWMutex).RUnlock":          ext۰sync۰RWMutex۰RUnlock,
+		"(*sync.RWMutex).Unlock":           ext۰sync۰RWMutex۰Unlock,
+		"(*sync.WaitGroup).Add":            ext۰sync۰WaitGroup۰Add,
+		"(*sync.WaitGroup).Wait":           ext۰sync۰WaitGroup۰Wait,
 		"(reflect.Value).Bool":             ext۰reflect۰Value۰Bool,
 		"(reflect.Value).CanAddr":          ext۰reflect۰Value۰CanAddr,
 		"(reflect.Value).CanInterface":     ext۰reflect۰Value۰CanInterface,
@@ -94,6 +103,7 @@ func init() {
 		"runtime.Gosched":                  ext۰runtime۰Gosched,
 		"runtime.init":                     ext۰runtime۰init,
 		"runtime.NumCPU":                   ext۰runtime۰NumCPU,
+		"runtime.NumGoroutine":             ext۰runtime۰NumGoroutine,
 		"runtime.ReadMemStats":             ext۰runtime۰ReadMemStats,
 		"runtime.SetFinalizer":             ext۰runtime۰SetFinalizer,
 		"(*runtime.Func).Entry":            ext۰runtime۰Func۰Entry,
@@ -106,14 +116,6 @@ func init() {
 		"sync.runtime_Semrelease":          ext۰sync۰runtime_Semrelease,
 		"sync.runtime_Syncsemcheck":        ext۰sync۰runtime_Syncsemcheck,
 		"sync.runtime_registerPoolCleanup": ext۰sync۰runtime_registerPoolCleanup,
-		"sync/atomic.AddInt32":             ext۰atomic۰AddInt32,
-		"sync/atomic.AddUint32":            ext۰atomic۰AddUint32,
-		"sync/atomic.AddUint64":            ext۰atomic۰AddUint64,
-		"sync/atomic.CompareAndSwapInt32":  ext۰atomic۰CompareAndSwapInt32,
-		"sync/atomic.LoadInt32":            ext۰atomic۰LoadInt32,
-		"sync/atomic.LoadUint32":           ext۰atomic۰LoadUint32,
-		"sync/atomic.StoreInt32":           ext۰atomic۰StoreInt32,
-		"sync/atomic.StoreUint32":          ext۰atomic۰StoreUint32,
 		"syscall.Close":                    ext۰syscall۰Close,
 		"syscall.Exit":                     ext۰syscall۰Exit,
 		"syscall.Fstat":                    ext۰syscall۰Fstat,
@@ -132,6 +134,15 @@ func init() {
 		"time.Sleep":                       ext۰time۰Sleep,
 		"time.now":                         ext۰time۰now,
 	}
+	for _, T := range []string{"Int32", "Int64", "Uint32", "Uint64", "Uintptr", "Pointer"} {
+		if T != "Pointer" {
+			externals["sync/atomic.Add"+T] = ext۰atomic۰Add
+		}
+		externals["sync/atomic.CompareAndSwap"+T] = ext۰atomic۰CompareAndSwap
+		externals["sync/atomic.Load"+T] = ext۰atomic۰Load
+		externals["sync/atomic.Store"+T] = ext۰atomic۰Store
+		externals["sync/atomic.Swap"+T] = ext۰atomic۰Swap
+	}
 }
 
 // wrapError returns an interpreted 'error' interface value for err.
@@ -316,16 +327,6 @@ func ext۰sync۰runtime_registerPoolCleanup(fr *frame, args []value) value {
 	return nil
 }
 
-func ext۰sync۰runtime_Semacquire(fr *frame, args []value) value {
-	// TODO(adonovan): fix: implement.
-	return nil
-}
-
-func ext۰sync۰runtime_Semrelease(fr *frame, args []value) value {
-	// TODO(adonovan): fix: implement.
-	return nil
-}
-
 func ext۰runtime۰GOMAXPROCS(fr *frame, args []value) value {
 	return runtime.GOMAXPROCS(args[0].(int))
 }
@@ -342,7 +343,14 @@ func ext۰runtime۰GC(fr *frame, args []value) value {
 }
 
 func ext۰runtime۰Gosched(fr *frame, args []value) value {
-	runtime.Gosched()
+	if fr.i.mode&DeterministicScheduling != 0 {
+		s := fr.i.sched
+		s.mu.Lock()
+		s.reschedule(fr.g)
+		s.mu.Unlock()
+	} else {
+		runtime.Gosched()
+	}
 	return nil
 }
 
@@ -354,67 +362,15 @@ func ext۰runtime۰NumCPU(fr *frame, args []value) value {
 	return runtime.NumCPU()
 }
 
-func ext۰runtime۰ReadMemStats(fr *frame, args []value) value {
-	// TODO(adonovan): populate args[0].(Struct)
-	return nil
-}
-
-func ext۰atomic۰LoadUint32(fr *frame, args []value) value {
-	// TODO(adonovan): fix: not atomic!
-	return (*args[0].(*value)).(uint32)
+func ext۰runtime۰NumGoroutine(fr *frame, args []value) value {
+	return fr.i.sched.numGoroutine()
 }
 
-func ext۰atomic۰StoreUint32(fr *frame, args []value) value {
-	// TODO(adonovan): fix: not atomic!
-	*args[0].(*value) = args[1].(uint32)
-	return nil
-}
-
-func ext۰atomic۰LoadInt32(fr *frame, args []value) value {
-	// TODO(adonovan): fix: not atomic!
-	return (*args[0].(*value)).(int32)
-}
-
-func ext۰atomic۰StoreInt32(fr *frame, args []value) value {
-	// TODO(adonovan): fix: not atomic!
-	*args[0].(*value) = args[1].(int32)
+func ext۰runtime۰ReadMemStats(fr *frame, args []value) value {
+	// TODO(adonovan): populate args[0].(Struct)
 	return nil
 }
 
-func ext۰atomic۰CompareAndSwapInt32(fr *frame, args []value) value {
-	// TODO(adonovan): fix: not atomic!
-	p := args[0].(*value)
-	if (*p).(int32) == args[1].(int32) {
-		*p = args[2].(int32)
-		return true
-	}
-	return false
-}
-
-func ext۰atomic۰AddInt32(fr *frame, args []value) value {
-	// TODO(adonovan): fix: not atomic!
-	p := args[0].(*value)
-	newv := (*p).(int32) + args[1].(int32)
-	*p = newv
-	return newv
-}
-
-func ext۰atomic۰AddUint32(fr *frame, args []value) value {
-	// TODO(adonovan): fix: not atomic!
-	p := args[0].(*value)
-	newv := (*p).(uint32) + args[1].(uint32)
-	*p = newv
-	return newv
-}
-
-func ext۰atomic۰AddUint64(fr *frame, args []value) value {
-	// TODO(adonovan): fix: not atomic!
-	p := args[0].(*value)
-	newv := (*p).(uint64) + args[1].(uint64)
-	*p = newv
-	return newv
-}
-
 func ext۰runtime۰SetFinalizer(fr *frame, args []value) value {
 	return nil // ignore
 }
@@ -455,7 +411,7 @@ func ext۰time۰now(fr *frame, args []value) value {
 }
 
 func ext۰time۰Sleep(fr *frame, args []value) value {
-	time.Sleep(time.Duration(args[0].(int64)))
+	fr.i.sched.sleep(fr.g, args[0].(int64))
 	return nil
 }
 
@@ -20,11 +20,10 @@
 //
 // * The reflect package is only partially implemented.
 //
-// * "sync/atomic" operations are not currently atomic due to the
-// "boxed" value representation: it is not possible to read, modify
-// and write an interface value atomically.  As a consequence, Mutexes
-// are currently broken.  TODO(adonovan): provide a metacircular
-// implementation of Mutex avoiding the broken atomic primitives.
+// * sync.Cond is not supported, and the other types of package sync
+// only through the methods of Mutex, RWMutex, WaitGroup and Once,
+// which the interpreter implements itself.  Atomic operations are
+// atomic only with respect to each other.
 //
 // * recover is only partially implemented.  Also, the interpreter
 // makes no attempt to distinguish target panics from interpreter
@@ -48,7 +47,6 @@ import (
 	"fmt"
 	"go/token"
 	"os"
-	"reflect"
 	"runtime"
 
 	"code.google.com/p/go.tools/go/ssa"
@@ -67,10 +65,21 @@ const (
 type Mode uint
 
 const (
-	DisableRecover Mode = 1 << iota // Disable recover() in target programs; show interpreter crash instead.
-	EnableTracing                   // Print a trace of all instructions as they are interpreted.
+	DisableRecover          Mode = 1 << iota // Disable recover() in target programs; show interpreter crash instead.
+	EnableTracing                            // Print a trace of all instructions as they are interpreted.
+	DeterministicScheduling                  // Run one goroutine at a time, interleaving them reproducibly using Seed.
 )
 
+// Seed is the seed of the random choices of the scheduler in
+// DeterministicScheduling mode: which goroutine runs next, where
+// goroutines are preempted, and which ready case a select statement
+// proceeds with.  Runs of a program with the same seed and input
+// interleave its goroutines in the same way.
+//
+// (This is a global variable shared by all interpreters in the same
+// process.)
+var Seed int64
+
 type methodSet map[string]*ssa.Function
 
 // State shared between all interpreted goroutines.
@@ -84,6 +93,7 @@ type interpreter struct {
 	rtypeMethods       methodSet            // the method set of rtype, which implements the reflect.Type interface.
 	runtimeErrorString types.Type           // the runtime.errorString type
 	sizes              types.Sizes          // the effective type-sizing function
+	sched              *scheduler           // the goroutine scheduler
 }
 
 type deferred struct {
@@ -95,7 +105,9 @@ type deferred struct {
 
 type frame struct {
 	i                *interpreter
+	g                *goroutine
 	caller           *frame
+	callpos          token.Pos // position of the call, in caller
 	fn               *ssa.Function
 	block, prevBlock *ssa.BasicBlock
 	env              map[ssa.Value]value // dynamic values of SSA variables
@@ -104,6 +116,12 @@ type frame struct {
 	result           value
 	panicking        bool
 	panic            interface{}
+
+	// State kept for the debugger.
+	pos  token.Pos                    // the last position executed
+	line int                          // the line of pos
+	refs map[*types.Var]*ssa.DebugRef // the last DebugRef of each local variable
+	vars []*types.Var                 // the keys of refs, in order of first reference
 }
 
 func (fr *frame) get(key ssa.Value) value {
@@ -187,10 +205,12 @@ func lookupMethod(i *interpreter, typ types.Type, meth *types.Func) *ssa.Functio
 func visitInstr(fr *frame, instr ssa.Instruction) continuation {
 	switch instr := instr.(type) {
 	case *ssa.DebugRef:
-		// no-op
+		if Debug != nil {
+			fr.recordRef(instr)
+		}
 
 	case *ssa.UnOp:
-		fr.env[instr] = unop(instr, fr.get(instr.X))
+		fr.env[instr] = unop(fr, instr, fr.get(instr.X))
 
 	case *ssa.BinOp:
 		fr.env[instr] = binop(instr.Op, instr.X.Type(), fr.get(instr.X), fr.get(instr.Y))
@@ -239,7 +259,7 @@ func visitInstr(fr *frame, instr ssa.Instruction) continuation {
 		panic(targetPanic{fr.get(instr.X)})
 
 	case *ssa.Send:
-		fr.get(instr.Chan).(chan value) <- copyVal(fr.get(instr.X))
+		fr.i.sched.send(fr.g, fr.get(instr.Chan).(*channel), copyVal(fr.get(instr.X)), instr.Pos())
 
 	case *ssa.Store:
 		*fr.get(instr.Addr).(*value) = copyVal(fr.get(instr.Val))
@@ -267,10 +287,13 @@ func visitInstr(fr *frame, instr ssa.Instruction) continuation {
 
 	case *ssa.Go:
 		fn, args := prepareCall(fr, &instr.Call)
-		go call(fr.i, nil, instr.Pos(), fn, args)
+		i := fr.i
+		i.sched.spawn(fr.g, func(g *goroutine) {
+			callOn(i, g, nil, instr.Pos(), fn, args)
+		})
 
 	case *ssa.MakeChan:
-		fr.env[instr] = make(chan value, asInt(fr.get(instr.Size)))
+		fr.env[instr] = &channel{cap: asInt(fr.get(instr.Size))}
 
 	case *ssa.Alloc:
 		var addr *value
@@ -362,40 +385,26 @@ func visitInstr(fr *frame, instr ssa.Instruction) continuation {
 		}
 
 	case *ssa.Select:
-		var cases []reflect.SelectCase
-		if !instr.Blocking {
-			cases = append(cases, reflect.SelectCase{
-				Dir: reflect.SelectDefault,
-			})
-		}
+		var cases []selectCase
 		for _, state := range instr.States {
-			var dir reflect.SelectDir
-			if state.Dir == types.RecvOnly {
-				dir = reflect.SelectRecv
-			} else {
-				dir = reflect.SelectSend
-			}
-			var send reflect.Value
+			var send value
 			if state.Send != nil {
-				send = reflect.ValueOf(fr.get(state.Send))
+				send = copyVal(fr.get(state.Send))
 			}
-			cases = append(cases, reflect.SelectCase{
-				Dir:  dir,
-				Chan: reflect.ValueOf(fr.get(state.Chan)),
-				Send: send,
+			cases = append(cases, selectCase{
+				dir:  state.Dir,
+				c:    fr.get(state.Chan).(*channel),
+				send: send,
 			})
 		}
-		chosen, recv, recvOk := reflect.Select(cases)
-		if !instr.Blocking {
-			chosen-- // default case should have index -1.
-		}
+		chosen, recv, recvOk := fr.i.sched.choose(fr.g, cases, instr.Blocking, instr.Pos())
 		r := tuple{chosen, recvOk}
 		for i, st := range instr.States {
 			if st.Dir == types.RecvOnly {
 				var v value
 				if i == chosen && recvOk {
 					// No need to copy since send makes an unaliased copy.
-					v = recv.Interface().(value)
+					v = recv
 				} else {
 					v = zero(st.Chan.Type().Underlying().(*types.Chan).Elem())
 				}
@@ -449,16 +458,23 @@ func prepareCall(fr *frame, call *ssa.CallCommon) (fn value, args []value) {
 // callpos is the position of the callsite.
 //
 func call(i *interpreter, caller *frame, callpos token.Pos, fn value, args []value) value {
+	return callOn(i, caller.g, caller, callpos, fn, args)
+}
+
+// callOn is like call, for a call on goroutine g.  The outermost
+// call of a goroutine has no caller.
+//
+func callOn(i *interpreter, g *goroutine, caller *frame, callpos token.Pos, fn value, args []value) value {
 	switch fn := fn.(type) {
 	case *ssa.Function:
 		if fn == nil {
 			panic("call of nil function") // nil of func type
 		}
-		return callSSA(i, caller, callpos, fn, args, nil)
+		return callSSA(i, g, caller, callpos, fn, args, nil)
 	case *closure:
-		return callSSA(i, caller, callpos, fn.Fn, args, fn.Env)
+		return callSSA(i, g, caller, callpos, fn.Fn, args, fn.Env)
 	case *ssa.Builtin:
-		return callBuiltin(caller, callpos, fn, args)
+		return callBuiltin(i, caller, callpos, fn, args)
 	}
 	panic(fmt.Sprintf("cannot call %T", fn))
 }
@@ -470,11 +486,11 @@ func loc(fset *token.FileSet, pos token.Pos) string {
 	return " at " + fset.Position(pos).String()
 }
 
-// callSSA interprets a call to function fn with arguments args,
-// and lexical environment env, returning its result.
+// callSSA interprets a call to function fn on goroutine g with
+// arguments args, and lexical environment env, returning its result.
 // callpos is the position of the callsite.
 //
-func callSSA(i *interpreter, caller *frame, callpos token.Pos, fn *ssa.Function, args []value, env []value) value {
+func callSSA(i *interpreter, g *goroutine, caller *frame, callpos token.Pos, fn *ssa.Function, args []value, env []value) value {
 	if i.mode&EnableTracing != 0 {
 		fset := fn.Prog.Fset
 		// TODO(adonovan): fix: loc() lies for external functions.
@@ -486,17 +502,24 @@ func callSSA(i *interpreter, caller *frame, callpos token.Pos, fn *ssa.Function,
 		defer fmt.Fprintf(os.Stderr, "Leaving %s%s.\n", fn, suffix)
 	}
 	fr := &frame{
-		i:      i,
-		caller: caller, // for panic/recover
-		fn:     fn,
+		i:       i,
+		g:       g,
+		caller:  caller, // for panic/recover
+		callpos: callpos,
+		fn:      fn,
 	}
+	// g.top is restored by the caller when it returns normally, and
+	// by runFrame when it recovers from a panic.
+	g.top = fr
 	if fn.Parent() == nil {
 		name := fn.String()
 		if ext := externals[name]; ext != nil {
 			if i.mode&EnableTracing != 0 {
 				fmt.Fprintln(os.Stderr, "\t(external)")
 			}
-			return ext(fr, args)
+			result := ext(fr, args)
+			g.top = caller
+			return result
 		}
 		if fn.Blocks == nil {
 			panic("no code for function: " + name)
@@ -522,6 +545,7 @@ func callSSA(i *interpreter, caller *frame, callpos token.Pos, fn *ssa.Function,
 	for i := range fn.Locals {
 		fr.locals[i] = bad{}
 	}
+	g.top = caller
 	return fr.result
 }
 
@@ -551,6 +575,7 @@ func runFrame(fr *frame) {
 		}
 		fr.panicking = true
 		fr.panic = recover()
+		fr.g.top = fr
 		if fr.i.mode&EnableTracing != 0 {
 			fmt.Fprintf(os.Stderr, "Panicking: %T %v.\n", fr.panic, fr.panic)
 		}
@@ -564,6 +589,12 @@ func runFrame(fr *frame) {
 		}
 	block:
 		for _, instr := range fr.block.Instrs {
+			if fr.i.mode&DeterministicScheduling != 0 {
+				fr.i.sched.preempt(fr.g)
+			}
+			if Debug != nil {
+				Debug.check(fr, instr)
+			}
 			if fr.i.mode&EnableTracing != 0 {
 				if v, ok := instr.(ssa.Value); ok {
 					fmt.Fprintln(os.Stderr, "\t", v.Name(), "=", instr)
@@ -592,8 +623,11 @@ func doRecover(caller *frame) value {
 	if caller.i.mode&DisableRecover == 0 &&
 		caller != nil && !caller.panicking &&
 		caller.caller != nil && caller.caller.panicking {
-		caller.caller.panicking = false
 		p := caller.caller.panic
+		if _, ok := p.(deadlock); ok {
+			return iface{} // a deadlock cannot be recovered from
+		}
+		caller.caller.panicking = false
 		caller.caller.panic = nil
 		switch p := p.(type) {
 		case targetPanic:
@@ -647,6 +681,7 @@ func Interpret(mainpkg *ssa.Package, mode Mode, sizes types.Sizes, filename stri
 		globals: make(map[ssa.Value]*value),
 		mode:    mode,
 		sizes:   sizes,
+		sched:   newScheduler(mainpkg.Prog.Fset, mode, Seed),
 	}
 	runtimePkg := i.prog.ImportedPackage("runtime")
 	if runtimePkg == nil {
@@ -705,6 +740,10 @@ func Interpret(mainpkg *ssa.Package, mode Mode, sizes types.Sizes, filename stri
 		case exitPanic:
 			exitCode = int(p)
 			return
+		case deadlock:
+			// Like the runtime, print to the program's stderr.
+			write(2, []byte(p))
+			return
 		case targetPanic:
 			fmt.Fprintln(os.Stderr, "panic:", toString(p.v))
 		case runtime.Error:
@@ -723,9 +762,10 @@ func Interpret(mainpkg *ssa.Package, mode Mode, sizes types.Sizes, filename stri
 	}()
 
 	// Run!
-	call(i, nil, token.NoPos, mainpkg.Func("init"), nil)
+	g := i.sched.main
+	callOn(i, g, nil, token.NoPos, mainpkg.Func("init"), nil)
 	if mainFn := mainpkg.Func("main"); mainFn != nil {
-		call(i, nil, token.NoPos, mainFn, nil)
+		callOn(i, g, nil, token.NoPos, mainFn, nil)
 		exitCode = 0
 	} else {
 		fmt.Fprintln(os.Stderr, "No main function.")
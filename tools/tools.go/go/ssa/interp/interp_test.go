@@ -148,6 +148,7 @@ var testdataTests = []string{
 	"recover.go",
 	"static.go",
 	"callstack.go",
+	"goroutines.go",
 }
 
 // These are files and packages in $GOROOT/src/.
@@ -174,7 +175,7 @@ var gorootSrcTests = []string{
 
 type successPredicate func(exitcode int, output string) error
 
-func run(t *testing.T, dir, input string, success successPredicate) bool {
+func run(t *testing.T, dir, input string, mode interp.Mode, success successPredicate) bool {
 	fmt.Printf("Input: %s\n", input)
 
 	start := time.Now()
@@ -248,7 +249,7 @@ func run(t *testing.T, dir, input string, success successPredicate) bool {
 	interp.CapturedOutput = &out
 
 	hint = fmt.Sprintf("To trace execution, run:\n%% go build code.google.com/p/go.tools/cmd/ssadump && ./ssadump -build=C -run --interp=T %s\n", input)
-	exitCode := interp.Interpret(mainPkg, 0, &types.StdSizes{8, 8}, inputs[0], []string{})
+	exitCode := interp.Interpret(mainPkg, mode, &types.StdSizes{8, 8}, inputs[0], []string{})
 
 	// The definition of success varies with each file.
 	if err := success(exitCode, out.String()); err != nil {
@@ -288,7 +289,7 @@ func exitsZero(exitcode int, _ string) error {
 func TestTestdataFiles(t *testing.T) {
 	var failures []string
 	for _, input := range testdataTests {
-		if !run(t, "testdata"+slash, input, exitsZero) {
+		if !run(t, "testdata"+slash, input, 0, exitsZero) {
 			failures = append(failures, input)
 		}
 	}
@@ -314,12 +315,12 @@ func TestGorootTest(t *testing.T) {
 		return nil
 	}
 	for _, input := range gorootTestTests {
-		if !run(t, filepath.Join(build.Default.GOROOT, "test")+slash, input, success) {
+		if !run(t, filepath.Join(build.Default.GOROOT, "test")+slash, input, 0, success) {
 			failures = append(failures, input)
 		}
 	}
 	for _, input := range gorootSrcTests {
-		if !run(t, filepath.Join(build.Default.GOROOT, "src")+slash, input, success) {
+		if !run(t, filepath.Join(build.Default.GOROOT, "src")+slash, input, 0, success) {
 			failures = append(failures, input)
 		}
 	}
@@ -341,7 +342,105 @@ func TestTestmainPackage(t *testing.T) {
 		// TODO(adonovan): test benchmarks too
 		return nil
 	}
-	run(t, "testdata"+slash, "a_test.go", success)
+	run(t, "testdata"+slash, "a_test.go", 0, success)
+}
+
+// TestDeterministicScheduling runs the goroutine tests with several
+// interleavings.
+func TestDeterministicScheduling(t *testing.T) {
+	defer func() { interp.Seed = 0 }()
+	for seed := int64(1); seed <= 5; seed++ {
+		interp.Seed = seed
+		run(t, "testdata"+slash, "goroutines.go", interp.DeterministicScheduling, exitsZero)
+	}
+}
+
+// TestDeadlock checks that a deadlock is reported, with the goroutines
+// blocked in both a channel operation and a sync primitive.
+func TestDeadlock(t *testing.T) {
+	success := func(exitcode int, output string) error {
+		if exitcode != 2 {
+			return fmt.Errorf("exit code was %d", exitcode)
+		}
+		for _, want := range []string{
+			"fatal error: all goroutines are asleep - deadlock!",
+			"goroutine 1 [chan receive]:\nmain.main()\n",
+			"goroutine 2 [sync.Mutex.Lock]:\n(*sync.Mutex).Lock()\nmain.lock()\n",
+		} {
+			if !strings.Contains(output, want) {
+				return fmt.Errorf("output does not contain %q", want)
+			}
+		}
+		return nil
+	}
+	for _, mode := range []interp.Mode{0, interp.DeterministicScheduling} {
+		run(t, "testdata"+slash, "deadlock.go", mode, success)
+	}
+}
+
+func TestDebugger(t *testing.T) {
+	var conf loader.Config
+	if err := conf.CreateFromFilenames("main", "testdata/debug.go"); err != nil {
+		t.Fatalf("CreateFromFilenames failed: %s", err)
+	}
+	conf.Import("runtime")
+	iprog, err := conf.Load()
+	if err != nil {
+		t.Fatalf("conf.Load failed: %s", err)
+	}
+	prog := ssa.Create(iprog, ssa.SanityCheckFunctions|ssa.GlobalDebug)
+	prog.BuildAll()
+	mainPkg := prog.Package(iprog.Created[0].Pkg)
+
+	d := interp.NewDebugger(prog)
+	if _, err := d.BreakLine("debug.go", 15); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.BreakFunc("(*main.T).inc"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.BreakLine("debug.go", 3); err == nil {
+		t.Errorf("BreakLine succeeded on a comment")
+	}
+
+	// Record each stop as "func:line locals..."; pointers vary.
+	var stops []string
+	actions := []interp.Action{interp.Next, interp.Next, interp.Continue, interp.Finish, interp.Step, interp.Step, interp.Continue}
+	d.Stopped = func(s *interp.Stop) interp.Action {
+		stop := fmt.Sprintf("%s:%d", s.Func(), s.Position().Line)
+		for _, l := range s.Locals() {
+			if _, ok := l.Type.(*types.Pointer); !ok {
+				stop += fmt.Sprintf(" %s=%s", l.Name, l.Value)
+			}
+		}
+		stops = append(stops, stop)
+		if len(stops) > len(actions) {
+			return interp.Continue
+		}
+		return actions[len(stops)-1]
+	}
+	interp.Debug = d
+	defer func() { interp.Debug = nil }()
+
+	var out bytes.Buffer
+	interp.CapturedOutput = &out
+	defer func() { interp.CapturedOutput = nil }()
+	if exitCode := interp.Interpret(mainPkg, 0, &types.StdSizes{WordSize: 8, MaxAlign: 8}, "debug.go", nil); exitCode != 0 {
+		t.Fatalf("exit code was %d; output: %s", exitCode, out.String())
+	}
+
+	want := []string{
+		"main.sum:15 xs=[1 2 3] total=0 x=1", // breakpoint
+		"main.sum:14 xs=[1 2 3] total=1 x=2", // next
+		"main.sum:15 xs=[1 2 3] total=1 x=2", // next
+		"main.sum:15 xs=[1 2 3] total=3 x=3", // continue to breakpoint
+		"main.main:24",                       // finish
+		"main.main:25 s=6",                   // step
+		"(*main.T).inc:8 d=6",                // step into call
+	}
+	if got := strings.Join(stops, "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("stops:\n%s\nwant:\n%s", got, strings.Join(want, "\n"))
+	}
 }
 
 // CreateTestMainPackage should return nil if there were no tests.
@@ -0,0 +1,314 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interp
+
+// Goroutines and their scheduling.
+//
+// Each interpreted goroutine runs on a goroutine of the interpreter,
+// but it blocks and is woken by the scheduler below, not by the Go
+// runtime: channels and the sync primitives are implemented
+// metacircularly (see chan.go and sync.go), so the scheduler knows
+// which goroutines are blocked, and on what.  When all of them are,
+// it reports a deadlock, with the stack of each goroutine.
+//
+// By default, goroutines run in parallel.  In DeterministicScheduling
+// mode, only one goroutine runs at a time.  It runs until it blocks,
+// reaches a scheduling point (a go statement, channel operation or
+// call of a sync primitive), or is preempted before a randomly chosen
+// instruction, whereupon the scheduler chooses the next to run at
+// random.  All random choices come from a generator seeded by Seed,
+// so a run can be reproduced, and different seeds explore different
+// interleavings.  Sleeping is simulated: a sleeping goroutine runs
+// again when no other goroutine can, in order of wake-up time.
+
+import (
+	"bytes"
+	"fmt"
+	"go/token"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// preemptRate is the inverse of the probability that, in
+// deterministic mode, a goroutine is preempted before an instruction.
+const preemptRate = 32
+
+// A goroutine is an interpreted goroutine.
+type goroutine struct {
+	id     int
+	top    *frame        // innermost active frame
+	wake   chan struct{} // signalled to resume the goroutine
+	status string        // "running", "runnable", or what the goroutine is blocked on
+	pos    token.Pos     // position of the operation blocking the goroutine, if known
+	wakeAt int64         // when a sleeping goroutine becomes runnable (deterministic mode)
+	step   Action        // how the goroutine continues after stopping in the debugger
+	depth  int           // the depth of the stack when it stopped
+}
+
+// A deadlock is the panic raised in the main goroutine when all
+// goroutines are blocked.  Its value is the report to be printed.
+type deadlock string
+
+// A scheduler manages the goroutines of an interpreted program.  Its
+// mutex guards its own state and that of all channels and sync
+// primitives.
+type scheduler struct {
+	mu         sync.Mutex
+	fset       *token.FileSet
+	rand       *rand.Rand   // source of random choices; non-nil only in deterministic mode
+	main       *goroutine   // the main goroutine
+	goroutines []*goroutine // the live goroutines, in order of creation
+	nextID     int          // the id of the last goroutine created
+	blocked    int          // the number of blocked goroutines (free-running mode)
+	runnable   []*goroutine // goroutines waiting to run (deterministic mode)
+	sleeping   []*goroutine // goroutines in time.Sleep (deterministic mode)
+	now        int64        // the simulated time in nanoseconds (deterministic mode)
+	syncs      map[*value]*syncObject
+	deadlock   deadlock // the report of a deadlock, once detected
+}
+
+func newScheduler(fset *token.FileSet, mode Mode, seed int64) *scheduler {
+	s := &scheduler{
+		fset:  fset,
+		syncs: make(map[*value]*syncObject),
+	}
+	if mode&DeterministicScheduling != 0 {
+		s.rand = rand.New(rand.NewSource(seed))
+	}
+	s.main = s.newGoroutine()
+	s.main.status = "running"
+	return s
+}
+
+func (s *scheduler) newGoroutine() *goroutine {
+	s.nextID++
+	g := &goroutine{
+		id:     s.nextID,
+		wake:   make(chan struct{}, 1),
+		status: "runnable",
+	}
+	s.goroutines = append(s.goroutines, g)
+	return g
+}
+
+// spawn starts a goroutine that calls f.  parent is the goroutine
+// executing the go statement.
+func (s *scheduler) spawn(parent *goroutine, f func(g *goroutine)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g := s.newGoroutine()
+	if s.rand == nil {
+		g.status = "running"
+	} else {
+		s.runnable = append(s.runnable, g)
+	}
+	go func() {
+		defer s.exit(g)
+		if s.rand != nil {
+			<-g.wake
+		}
+		f(g)
+	}()
+	s.reschedule(parent)
+}
+
+// exit removes g, whose function has returned, from the live
+// goroutines.
+func (s *scheduler) exit(g *goroutine) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, x := range s.goroutines {
+		if x == g {
+			s.goroutines = append(s.goroutines[:i], s.goroutines[i+1:]...)
+			break
+		}
+	}
+	if s.rand != nil {
+		next := s.next()
+		if next == nil {
+			s.fail(g)
+		}
+		next.wake <- struct{}{}
+	} else if s.blocked == len(s.goroutines) {
+		s.fail(g)
+	}
+}
+
+// reschedule is a scheduling point: in deterministic mode, it lets
+// another goroutine run in place of g, at random.  The caller must
+// hold s.mu.
+func (s *scheduler) reschedule(g *goroutine) {
+	if s.rand != nil {
+		g.status = "runnable"
+		s.runnable = append(s.runnable, g)
+		s.switchFrom(g)
+	}
+}
+
+// preempt is called before each instruction in deterministic mode.
+// Occasionally, it lets another goroutine run in place of g.
+func (s *scheduler) preempt(g *goroutine) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.rand.Intn(preemptRate) == 0 {
+		s.reschedule(g)
+	}
+}
+
+// wait blocks g until another goroutine calls ready(g).  status
+// describes what g is waiting for, and pos, if valid, where.  The
+// caller must hold s.mu, which is released while g is blocked.
+//
+// If all goroutines are blocked, wait reports a deadlock: it panics
+// if g is the main goroutine, and otherwise wakes the main goroutine
+// to do so and never returns.
+func (s *scheduler) wait(g *goroutine, status string, pos token.Pos) {
+	if s.deadlock != "" {
+		panic(s.deadlock) // a deferred call is blocking during the report
+	}
+	g.status, g.pos = status, pos
+	if s.rand != nil {
+		s.switchFrom(g)
+	} else {
+		s.blocked++
+		if s.blocked == len(s.goroutines) {
+			s.fail(g)
+		}
+		s.mu.Unlock()
+		<-g.wake
+		s.mu.Lock()
+	}
+	if s.deadlock != "" {
+		panic(s.deadlock)
+	}
+}
+
+// ready makes g, which is blocked, runnable.  The caller must hold s.mu.
+func (s *scheduler) ready(g *goroutine) {
+	if s.rand != nil {
+		g.status = "runnable"
+		s.runnable = append(s.runnable, g)
+	} else {
+		g.status = "running"
+		s.blocked--
+		g.wake <- struct{}{}
+	}
+}
+
+// sleep suspends g for d nanoseconds.
+func (s *scheduler) sleep(g *goroutine, d int64) {
+	if s.rand == nil {
+		time.Sleep(time.Duration(d))
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if d <= 0 {
+		s.reschedule(g)
+		return
+	}
+	g.status, g.pos = "sleep", 0
+	g.wakeAt = s.now + d
+	s.sleeping = append(s.sleeping, g)
+	s.switchFrom(g)
+}
+
+// switchFrom, in deterministic mode, passes control from g to the
+// next goroutine to run, and returns when g is next chosen.  The
+// caller must hold s.mu.
+func (s *scheduler) switchFrom(g *goroutine) {
+	next := s.next()
+	if next == nil {
+		s.fail(g)
+	}
+	if next != g {
+		next.wake <- struct{}{}
+		s.mu.Unlock()
+		<-g.wake
+		s.mu.Lock()
+	}
+}
+
+// next removes a random goroutine from the runnable ones, and returns
+// it.  If there are none, it advances the simulated time to wake the
+// first of the sleeping goroutines.  It returns nil if all goroutines
+// are blocked.
+func (s *scheduler) next() *goroutine {
+	if len(s.runnable) == 0 && len(s.sleeping) > 0 {
+		s.now = s.sleeping[0].wakeAt
+		for _, g := range s.sleeping {
+			if g.wakeAt < s.now {
+				s.now = g.wakeAt
+			}
+		}
+		sleeping := s.sleeping[:0]
+		for _, g := range s.sleeping {
+			if g.wakeAt == s.now {
+				s.runnable = append(s.runnable, g)
+			} else {
+				sleeping = append(sleeping, g)
+			}
+		}
+		s.sleeping = sleeping
+	}
+	if len(s.runnable) == 0 {
+		return nil
+	}
+	i := s.rand.Intn(len(s.runnable))
+	g := s.runnable[i]
+	s.runnable = append(s.runnable[:i], s.runnable[i+1:]...)
+	g.status = "running"
+	return g
+}
+
+// fail records a deadlock detected by g.  If g is the main goroutine,
+// it panics.  Otherwise it wakes the main goroutine to report the
+// deadlock, and blocks forever.  The caller must hold s.mu.
+func (s *scheduler) fail(g *goroutine) {
+	s.deadlock = s.report()
+	if g == s.main {
+		panic(s.deadlock)
+	}
+	s.main.wake <- struct{}{}
+	s.mu.Unlock()
+	select {}
+}
+
+// report describes a deadlock, giving the stack of each goroutine.
+func (s *scheduler) report() deadlock {
+	var buf bytes.Buffer
+	buf.WriteString("fatal error: all goroutines are asleep - deadlock!\n")
+	for _, g := range s.goroutines {
+		buf.WriteByte('\n')
+		s.writeStack(&buf, g)
+	}
+	return deadlock(buf.String())
+}
+
+// writeStack writes the status and stack of goroutine g to buf.  The
+// stack of a running goroutine is changing, so it is omitted.  The
+// caller must hold s.mu, unless g is the calling goroutine.
+func (s *scheduler) writeStack(buf *bytes.Buffer, g *goroutine) {
+	fmt.Fprintf(buf, "goroutine %d [%s]:\n", g.id, g.status)
+	if g.status == "running" {
+		return
+	}
+	pos := g.pos
+	for fr := g.top; fr != nil; fr = fr.caller {
+		fmt.Fprintf(buf, "%s()\n", fr.fn)
+		if pos.IsValid() {
+			fmt.Fprintf(buf, "\t%s\n", s.fset.Position(pos))
+		}
+		pos = fr.callpos
+	}
+}
+
+// numGoroutine returns the number of live goroutines.
+func (s *scheduler) numGoroutine() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.goroutines)
+}
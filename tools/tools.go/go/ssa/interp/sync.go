@@ -0,0 +1,271 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interp
+
+// Emulated sync and sync/atomic operations.
+//
+// The read-modify-write of a boxed value cannot be made atomic by the
+// Go atomics, so the atomic operations are performed under the
+// scheduler's mutex.  The methods of sync.Mutex, RWMutex, WaitGroup
+// and Once are implemented in terms of the scheduler, which keeps the
+// state of each object in a table keyed by its address, and knows
+// which goroutines are blocked on it.
+
+import "go/token"
+
+// A syncObject is the state of a sync.Mutex, RWMutex, WaitGroup or
+// Once, or of a runtime semaphore.
+type syncObject struct {
+	locked  bool         // Mutex, RWMutex: held for writing; Once: f is running
+	readers int          // RWMutex: the number of readers
+	writers int          // RWMutex: the number of blocked writers
+	count   int          // WaitGroup: the counter
+	done    bool         // Once: f has returned
+	waiters []*goroutine // goroutines blocked until the state changes
+}
+
+// syncObject returns the state of the object at address p.  The
+// caller must hold s.mu.
+func (s *scheduler) syncObject(p *value) *syncObject {
+	o := s.syncs[p]
+	if o == nil {
+		o = new(syncObject)
+		s.syncs[p] = o
+	}
+	return o
+}
+
+// await blocks g until the state of o changes.  The caller must hold
+// s.mu and should then check the state again.
+func (s *scheduler) await(g *goroutine, o *syncObject, status string) {
+	o.waiters = append(o.waiters, g)
+	s.wait(g, status, token.NoPos)
+}
+
+// broadcast wakes the goroutines waiting for the state of o to change.
+// The caller must hold s.mu.
+func (s *scheduler) broadcast(o *syncObject) {
+	for _, g := range o.waiters {
+		s.ready(g)
+	}
+	o.waiters = nil
+}
+
+// syncOp calls f, which may block, with the state of the object at
+// address p, holding s.mu, after a scheduling point.
+func syncOp(fr *frame, p value, f func(s *scheduler, o *syncObject)) {
+	s := fr.i.sched
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reschedule(fr.g)
+	f(s, s.syncObject(p.(*value)))
+}
+
+func ext۰sync۰Mutex۰Lock(fr *frame, args []value) value {
+	syncOp(fr, args[0], func(s *scheduler, o *syncObject) {
+		for o.locked {
+			s.await(fr.g, o, "sync.Mutex.Lock")
+		}
+		o.locked = true
+	})
+	return nil
+}
+
+func ext۰sync۰Mutex۰Unlock(fr *frame, args []value) value {
+	syncOp(fr, args[0], func(s *scheduler, o *syncObject) {
+		if !o.locked {
+			panic("sync: unlock of unlocked mutex")
+		}
+		o.locked = false
+		s.broadcast(o)
+	})
+	return nil
+}
+
+func ext۰sync۰RWMutex۰Lock(fr *frame, args []value) value {
+	syncOp(fr, args[0], func(s *scheduler, o *syncObject) {
+		// A blocked writer excludes new readers.
+		o.writers++
+		for o.locked || o.readers > 0 {
+			s.await(fr.g, o, "sync.RWMutex.Lock")
+		}
+		o.writers--
+		o.locked = true
+	})
+	return nil
+}
+
+func ext۰sync۰RWMutex۰Unlock(fr *frame, args []value) value {
+	syncOp(fr, args[0], func(s *scheduler, o *syncObject) {
+		if !o.locked {
+			panic("sync: Unlock of unlocked RWMutex")
+		}
+		o.locked = false
+		s.broadcast(o)
+	})
+	return nil
+}
+
+func ext۰sync۰RWMutex۰RLock(fr *frame, args []value) value {
+	syncOp(fr, args[0], func(s *scheduler, o *syncObject) {
+		for o.locked || o.writers > 0 {
+			s.await(fr.g, o, "sync.RWMutex.RLock")
+		}
+		o.readers++
+	})
+	return nil
+}
+
+func ext۰sync۰RWMutex۰RUnlock(fr *frame, args []value) value {
+	syncOp(fr, args[0], func(s *scheduler, o *syncObject) {
+		if o.readers == 0 {
+			panic("sync: RUnlock of unlocked RWMutex")
+		}
+		o.readers--
+		if o.readers == 0 {
+			s.broadcast(o)
+		}
+	})
+	return nil
+}
+
+func ext۰sync۰WaitGroup۰Add(fr *frame, args []value) value {
+	syncOp(fr, args[0], func(s *scheduler, o *syncObject) {
+		o.count += args[1].(int)
+		if o.count < 0 {
+			panic("sync: negative WaitGroup counter")
+		}
+		if o.count == 0 {
+			s.broadcast(o)
+		}
+	})
+	return nil
+}
+
+func ext۰sync۰WaitGroup۰Wait(fr *frame, args []value) value {
+	syncOp(fr, args[0], func(s *scheduler, o *syncObject) {
+		for o.count > 0 {
+			s.await(fr.g, o, "sync.WaitGroup.Wait")
+		}
+	})
+	return nil
+}
+
+func ext۰sync۰Once۰Do(fr *frame, args []value) value {
+	// func (o *Once) Do(f func())
+	var first bool
+	var once *syncObject
+	syncOp(fr, args[0], func(s *scheduler, o *syncObject) {
+		for o.locked {
+			s.await(fr.g, o, "sync.Once.Do")
+		}
+		first = !o.done
+		o.locked = first
+		once = o
+	})
+	if !first {
+		return nil
+	}
+	defer func() {
+		// Like the real Once, consider f done even if it panics.
+		s := fr.i.sched
+		s.mu.Lock()
+		once.locked, once.done = false, true
+		s.broadcast(once)
+		s.mu.Unlock()
+	}()
+	call(fr.i, fr, token.NoPos, args[1], nil)
+	return nil
+}
+
+func ext۰sync۰runtime_Semacquire(fr *frame, args []value) value {
+	// func runtime_Semacquire(s *uint32)
+	syncOp(fr, args[0], func(s *scheduler, o *syncObject) {
+		p := args[0].(*value)
+		for (*p).(uint32) == 0 {
+			s.await(fr.g, o, "semacquire")
+		}
+		*p = (*p).(uint32) - 1
+	})
+	return nil
+}
+
+func ext۰sync۰runtime_Semrelease(fr *frame, args []value) value {
+	// func runtime_Semrelease(s *uint32)
+	syncOp(fr, args[0], func(s *scheduler, o *syncObject) {
+		p := args[0].(*value)
+		*p = (*p).(uint32) + 1
+		s.broadcast(o)
+	})
+	return nil
+}
+
+// atomicOp calls f holding the scheduler's mutex.
+func atomicOp(fr *frame, f func()) {
+	s := fr.i.sched
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f()
+}
+
+func ext۰atomic۰Add(fr *frame, args []value) value {
+	// func AddT(addr *T, delta T) (new T)
+	p := args[0].(*value)
+	var v value
+	atomicOp(fr, func() {
+		switch x := (*p).(type) {
+		case int32:
+			v = x + args[1].(int32)
+		case int64:
+			v = x + args[1].(int64)
+		case uint32:
+			v = x + args[1].(uint32)
+		case uint64:
+			v = x + args[1].(uint64)
+		case uintptr:
+			v = x + args[1].(uintptr)
+		default:
+			panic("atomic add of non-integer")
+		}
+		*p = v
+	})
+	return v
+}
+
+func ext۰atomic۰CompareAndSwap(fr *frame, args []value) value {
+	// func CompareAndSwapT(addr *T, old, new T) (swapped bool)
+	p := args[0].(*value)
+	var swapped bool
+	atomicOp(fr, func() {
+		if *p == args[1] {
+			*p = args[2]
+			swapped = true
+		}
+	})
+	return swapped
+}
+
+func ext۰atomic۰Load(fr *frame, args []value) value {
+	// func LoadT(addr *T) (val T)
+	p := args[0].(*value)
+	var v value
+	atomicOp(fr, func() { v = *p })
+	return v
+}
+
+func ext۰atomic۰Store(fr *frame, args []value) value {
+	// func StoreT(addr *T, val T)
+	p := args[0].(*value)
+	atomicOp(fr, func() { *p = args[1] })
+	return nil
+}
+
+func ext۰atomic۰Swap(fr *frame, args []value) value {
+	// func SwapT(addr *T, new T) (old T)
+	p := args[0].(*value)
+	var old value
+	atomicOp(fr, func() { old, *p = *p, args[1] })
+	return old
+}
@@ -0,0 +1,19 @@
+package main
+
+// The goroutines of this program deadlock: the interpreter should
+// report it, with the stack of each goroutine.
+
+import "sync"
+
+func lock(mu *sync.Mutex, ch chan int) {
+	mu.Lock()
+	ch <- 1
+}
+
+func main() {
+	var mu sync.Mutex
+	ch := make(chan int)
+	mu.Lock()
+	go lock(&mu, ch)
+	<-ch
+}
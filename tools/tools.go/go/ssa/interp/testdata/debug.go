@@ -0,0 +1,28 @@
+package main
+
+// Tests of the debugger: see TestDebugger.
+
+type T struct{ n int }
+
+func (t *T) inc(d int) int {
+	t.n += d
+	return t.n
+}
+
+func sum(xs []int) int {
+	total := 0
+	for _, x := range xs {
+		total += x // breakpoint
+	}
+	return total
+}
+
+var G = 7
+
+func main() {
+	t := &T{1}
+	s := sum([]int{1, 2, 3})
+	if r := t.inc(s); r != G {
+		panic(r)
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+// Tests of goroutines, channels and the sync primitives.
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+func mutex() {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	n := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				mu.Lock()
+				n++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if n != 1000 {
+		panic(n)
+	}
+}
+
+func rwmutex() {
+	var mu sync.RWMutex
+	var wg sync.WaitGroup
+	m := make(map[int]int)
+	for i := 0; i < 5; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			mu.Lock()
+			m[i] = i * i
+			mu.Unlock()
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			mu.RLock()
+			_ = m[i]
+			mu.RUnlock()
+		}(i)
+	}
+	wg.Wait()
+	if len(m) != 5 || m[4] != 16 {
+		panic(m)
+	}
+}
+
+func once() {
+	var once sync.Once
+	var wg sync.WaitGroup
+	n := 0
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			once.Do(func() { n++ })
+		}()
+	}
+	wg.Wait()
+	if n != 1 {
+		panic(n)
+	}
+}
+
+func atomics() {
+	var n32 int32
+	var n64 uint64
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				atomic.AddInt32(&n32, 1)
+				for {
+					old := atomic.LoadUint64(&n64)
+					if atomic.CompareAndSwapUint64(&n64, old, old+2) {
+						break
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if n32 != 1000 || n64 != 2000 {
+		panic("atomics")
+	}
+	if old := atomic.SwapInt32(&n32, 7); old != 1000 || atomic.LoadInt32(&n32) != 7 {
+		panic("swap")
+	}
+}
+
+func channels() {
+	// Unbuffered, with close.
+	ch := make(chan int)
+	done := make(chan bool)
+	sum := 0
+	go func() {
+		for x := range ch {
+			sum += x
+		}
+		done <- true
+	}()
+	for i := 1; i <= 10; i++ {
+		ch <- i
+	}
+	close(ch)
+	<-done
+	if sum != 55 {
+		panic(sum)
+	}
+	if v, ok := <-ch; v != 0 || ok {
+		panic("receive from closed channel")
+	}
+
+	// Buffered.
+	buf := make(chan string, 2)
+	buf <- "a"
+	buf <- "b"
+	if len(buf) != 2 || cap(buf) != 2 {
+		panic("len/cap")
+	}
+	if <-buf+<-buf != "ab" {
+		panic("order")
+	}
+
+	// Select.
+	select {
+	case s := <-buf:
+		panic(s)
+	default:
+	}
+	results := make(chan int)
+	quit := make(chan bool)
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case results <- i:
+			case <-quit:
+				close(results)
+				return
+			}
+		}
+	}()
+	for i := 0; i < 5; i++ {
+		if x := <-results; x != i {
+			panic(x)
+		}
+	}
+	quit <- true
+	for _ = range results {
+	}
+
+	// Send on a closed channel.
+	defer func() {
+		if recover() == nil {
+			panic("send on closed channel did not panic")
+		}
+	}()
+	c := make(chan int, 1)
+	close(c)
+	c <- 1
+}
+
+func main() {
+	mutex()
+	rwmutex()
+	once()
+	atomics()
+	channels()
+}
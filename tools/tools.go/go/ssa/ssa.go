@@ -1516,6 +1516,8 @@ func (s *Jump) Pos() token.Pos      { return token.NoPos }
 func (s *RunDefers) Pos() token.Pos { return token.NoPos }
 func (s *DebugRef) Pos() token.Pos  { return s.Expr.Pos() }
 
+func (s *DebugRef) Object() types.Object { return s.object }
+
 // Operands.
 
 func (v *Alloc) Operands(rands []*Value) []*Value {
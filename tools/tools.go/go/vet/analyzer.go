@@ -0,0 +1,305 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file defines the API through which the checks (analyzers) are
+// registered, visit the syntax trees, and report their diagnostics.
+
+package vet
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+
+	"code.google.com/p/go.tools/go/types"
+)
+
+var (
+	jsonFlag = flag.Bool("json", false, "print diagnostics as JSON to standard output")
+	fixFlag  = flag.Bool("fix", false, "apply the suggested fixes of diagnostics instead of reporting them")
+)
+
+// An Analyzer describes a check performed by vet.
+//
+// For each Go source file of a package, the Run function of each
+// enabled Analyzer is called for every node of the file's syntax tree
+// whose type is one of Types.  The elements of Types are nil pointers
+// of the node types, such as (*ast.CallExpr)(nil); to visit each file
+// once, use (*ast.File)(nil).
+type Analyzer struct {
+	Name         string                    // name of the check, and of the flag enabling it
+	Doc          string                    // usage message of the flag
+	Types        []ast.Node                // node types to visit
+	Run          func(f *File, n ast.Node) // the check; nil for checks the driver runs itself
+	Experimental bool                      // if set, the check must be requested explicitly
+}
+
+// analyzers holds the registered analyzers, in order of registration.
+var analyzers []*Analyzer
+
+// Register adds the analyzer to those run by Main and defines the
+// flag that enables it.  It is typically called from an init function
+// of the package defining the analyzer, so that a vet command with
+// additional checks is built by importing their packages:
+//
+//	package main
+//
+//	import (
+//		"code.google.com/p/go.tools/go/vet"
+//		_ "example.com/vetchecks"
+//	)
+//
+//	func main() {
+//		vet.Main()
+//	}
+//
+// Register panics if the analyzer's name is already in use.
+func Register(a *Analyzer) {
+	if report[a.Name] != nil {
+		panic(fmt.Sprintf("vet: duplicate analyzer %q", a.Name))
+	}
+	for _, typ := range a.Types {
+		if typ == nil {
+			panic(fmt.Sprintf("vet: analyzer %q: untyped nil in Types", a.Name))
+		}
+	}
+	report[a.Name] = triStateFlag(a.Name, unset, a.Doc)
+	analyzers = append(analyzers, a)
+}
+
+// Analyzers returns the registered analyzers, in order of registration.
+func Analyzers() []*Analyzer {
+	return append([]*Analyzer(nil), analyzers...)
+}
+
+// enabledCheckers returns the Run functions of the enabled analyzers,
+// indexed by the node types they visit.
+func enabledCheckers() map[reflect.Type][]*Analyzer {
+	chk := make(map[reflect.Type][]*Analyzer)
+	for _, a := range analyzers {
+		if a.Run == nil || !vet(a.Name) {
+			continue
+		}
+		for _, typ := range a.Types {
+			t := reflect.TypeOf(typ)
+			chk[t] = append(chk[t], a)
+		}
+	}
+	return chk
+}
+
+// A Diagnostic is a problem reported by an analyzer, at the source
+// range [Pos, End).  End may be token.NoPos.
+type Diagnostic struct {
+	Pos, End token.Pos
+	Message  string
+	Fixes    []SuggestedFix // alternative fixes; -fix applies the first
+}
+
+// A SuggestedFix is a change to the source that resolves a Diagnostic.
+type SuggestedFix struct {
+	Message string
+	Edits   []TextEdit
+}
+
+// A TextEdit replaces the source range [Pos, End) by NewText.
+// To insert text, use Pos == End.
+type TextEdit struct {
+	Pos, End token.Pos
+	NewText  []byte
+}
+
+// FileSet returns the file set of the package being checked.
+func (f *File) FileSet() *token.FileSet { return f.fset }
+
+// Name returns the name of the file.
+func (f *File) Name() string { return f.name }
+
+// Content returns the source of the file.
+func (f *File) Content() []byte { return f.content }
+
+// AST returns the syntax tree of the file, or nil if it is not a Go file.
+func (f *File) AST() *ast.File { return f.file }
+
+// Pkg returns the type-checked package.  If type checking failed, the
+// package and the type information may be incomplete.
+func (f *File) Pkg() *types.Package { return f.pkg.typesPkg }
+
+// Info returns the type information of the package.
+func (f *File) Info() *types.Info { return f.pkg.info }
+
+// Report reports a diagnostic of the running analyzer, and sets the
+// exit code.  By default the diagnostic is printed to standard error;
+// with -json it is gathered for printing as JSON when vet exits; and
+// with -fix, the first suggested fix, if there is one, is applied
+// instead.
+func (f *File) Report(d Diagnostic) {
+	name := ""
+	if f.analyzer != nil {
+		name = f.analyzer.Name
+	}
+	jd := jsonDiagnostic{
+		Analyzer: name,
+		Posn:     f.posn(d.Pos),
+		End:      f.posn(d.End),
+		Message:  d.Message,
+	}
+	for _, fix := range d.Fixes {
+		jf := jsonFix{Message: fix.Message}
+		for _, e := range fix.Edits {
+			je, ok := f.edit(e)
+			if !ok {
+				warnf("%s%s: invalid range in suggested fix", f.loc(d.Pos), name)
+				jf.Edits = nil
+				break
+			}
+			jf.Edits = append(jf.Edits, je)
+		}
+		if len(jf.Edits) > 0 {
+			jd.Fixes = append(jd.Fixes, jf)
+		}
+	}
+
+	if *fixFlag && len(jd.Fixes) > 0 {
+		fixes = append(fixes, jd.Fixes[0].Edits...)
+		if !*jsonFlag {
+			return
+		}
+	}
+	setExit(1)
+	if *jsonFlag {
+		diagnostics = append(diagnostics, jd)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "%s%s\n", f.loc(d.Pos), d.Message)
+}
+
+// Reportf reports a diagnostic with a formatted message at pos.
+func (f *File) Reportf(pos token.Pos, format string, args ...interface{}) {
+	f.Report(Diagnostic{Pos: pos, Message: fmt.Sprintf(format, args...)})
+}
+
+// posn returns the position as file:line:column, or "" if it is not valid.
+func (f *File) posn(pos token.Pos) string {
+	if !pos.IsValid() {
+		return ""
+	}
+	return f.fset.Position(pos).String()
+}
+
+// edit converts the edit to file offsets.
+func (f *File) edit(e TextEdit) (jsonEdit, bool) {
+	if !e.Pos.IsValid() || !e.End.IsValid() {
+		return jsonEdit{}, false
+	}
+	start, end := f.fset.Position(e.Pos), f.fset.Position(e.End)
+	if start.Filename != end.Filename || start.Offset > end.Offset {
+		return jsonEdit{}, false
+	}
+	return jsonEdit{Filename: start.Filename, Start: start.Offset, End: end.Offset, New: string(e.NewText)}, true
+}
+
+// The JSON form of diagnostics, printed by -json.
+type jsonDiagnostic struct {
+	Analyzer string    `json:"analyzer"`
+	Posn     string    `json:"posn"`
+	End      string    `json:"end,omitempty"`
+	Message  string    `json:"message"`
+	Fixes    []jsonFix `json:"suggested_fixes,omitempty"`
+}
+
+type jsonFix struct {
+	Message string     `json:"message"`
+	Edits   []jsonEdit `json:"edits"`
+}
+
+// A jsonEdit replaces the bytes [Start, End) of the file.
+type jsonEdit struct {
+	Filename string `json:"filename"`
+	Start    int    `json:"start"`
+	End      int    `json:"end"`
+	New      string `json:"new"`
+}
+
+var (
+	diagnostics = []jsonDiagnostic{} // gathered by -json
+	fixes       []jsonEdit           // gathered by -fix
+)
+
+// printJSON prints the diagnostics gathered by -json.
+func printJSON() {
+	data, err := json.MarshalIndent(diagnostics, "", "\t")
+	if err != nil {
+		errorf("%s", err)
+	}
+	os.Stdout.Write(data)
+	os.Stdout.Write(nl)
+}
+
+type editsByOffset []jsonEdit
+
+func (e editsByOffset) Len() int      { return len(e) }
+func (e editsByOffset) Swap(i, j int) { e[i], e[j] = e[j], e[i] }
+func (e editsByOffset) Less(i, j int) bool {
+	return e[i].Start < e[j].Start || e[i].Start == e[j].Start && e[i].End < e[j].End
+}
+
+// applyFixes applies the edits gathered by -fix and rewrites the files.
+// An edit that overlaps one earlier in the file is skipped with a warning;
+// duplicate edits, such as those of a file checked twice, are applied once.
+func applyFixes() {
+	byFile := make(map[string][]jsonEdit)
+	for _, e := range fixes {
+		byFile[e.Filename] = append(byFile[e.Filename], e)
+	}
+	var names []string
+	for name := range byFile {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		edits := byFile[name]
+		sort.Stable(editsByOffset(edits))
+		fi, err := os.Stat(name)
+		if err != nil {
+			warnf("%s", err)
+			continue
+		}
+		src, err := ioutil.ReadFile(name)
+		if err != nil {
+			warnf("%s", err)
+			continue
+		}
+		var out bytes.Buffer
+		pos := 0
+		var prev *jsonEdit
+		for i := range edits {
+			e := &edits[i]
+			if prev != nil && *e == *prev {
+				continue
+			}
+			if e.Start < pos || e.End > len(src) {
+				warnf("%s: skipping fix at offset %d that conflicts with another", name, e.Start)
+				continue
+			}
+			out.Write(src[pos:e.Start])
+			out.WriteString(e.New)
+			pos = e.End
+			prev = e
+		}
+		out.Write(src[pos:])
+		if err := ioutil.WriteFile(name, out.Bytes(), fi.Mode()); err != nil {
+			warnf("%s", err)
+			continue
+		}
+		Println("Fixed file", name)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vet
+
+import (
+	"go/ast"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"code.google.com/p/go.tools/go/types"
+)
+
+// printlnAnalyzer reports calls of the println built-in, suggesting print.
+var printlnAnalyzer = &Analyzer{
+	Name:  "testprintln",
+	Doc:   "check for calls of println (for testing)",
+	Types: []ast.Node{(*ast.CallExpr)(nil)},
+	Run: func(f *File, n ast.Node) {
+		id, ok := n.(*ast.CallExpr).Fun.(*ast.Ident)
+		if !ok {
+			return
+		}
+		if _, ok := f.Info().Uses[id].(*types.Builtin); !ok || id.Name != "println" {
+			return
+		}
+		f.Report(Diagnostic{
+			Pos:     id.Pos(),
+			End:     id.End(),
+			Message: "call of println",
+			Fixes: []SuggestedFix{{
+				Message: "call print",
+				Edits:   []TextEdit{{Pos: id.Pos(), End: id.End(), NewText: []byte("print")}},
+			}},
+		})
+	},
+}
+
+func init() {
+	Register(printlnAnalyzer)
+}
+
+func TestAnalyzer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "x.go")
+	const src = "package x\n\nfunc f() {\n\tprintln(1)\n\tprint(2)\n\tprintln(3)\n}\n"
+	if err := ioutil.WriteFile(name, []byte(src), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	// Run only the test analyzer, gathering JSON diagnostics and fixes.
+	defer func(json, fix bool) {
+		*jsonFlag, *fixFlag = json, fix
+		*report["testprintln"] = unset
+		diagnostics, fixes, exitCode = []jsonDiagnostic{}, nil, 0
+	}(*jsonFlag, *fixFlag)
+	*jsonFlag, *fixFlag = true, true
+	*report["testprintln"] = setTrue
+
+	if !doPackage(dir, []string{name}) {
+		t.Fatal("no files checked")
+	}
+	if len(diagnostics) != 2 {
+		t.Fatalf("got %d diagnostics, want 2: %+v", len(diagnostics), diagnostics)
+	}
+	d := diagnostics[0]
+	if d.Analyzer != "testprintln" || d.Posn != name+":4:2" || d.End != name+":4:9" || d.Message != "call of println" {
+		t.Errorf("unexpected diagnostic %+v", d)
+	}
+	if exitCode != 1 {
+		t.Errorf("exit code: got %d, want 1", exitCode)
+	}
+
+	applyFixes()
+	got, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "package x\n\nfunc f() {\n\tprint(1)\n\tprint(2)\n\tprint(3)\n}\n"
+	if string(got) != want {
+		t.Errorf("after -fix, got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRegisterDuplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a duplicate analyzer did not panic")
+		}
+	}()
+	Register(&Analyzer{Name: "printf"})
+}
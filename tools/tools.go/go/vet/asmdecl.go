@@ -4,7 +4,7 @@
 
 // Identify mismatches between assembly files and Go func declarations.
 
-package main
+package vet
 
 import (
 	"bytes"
@@ -87,6 +87,16 @@ var (
 	power64Suff  = re(`([BHWD])(ZU|Z|U|BR)?$`)
 )
 
+// asmdeclAnalyzer is run by doPackage for the assembly files of each package.
+var asmdeclAnalyzer = &Analyzer{
+	Name: "asmdecl",
+	Doc:  "check assembly against Go declarations",
+}
+
+func init() {
+	Register(asmdeclAnalyzer)
+}
+
 func asmCheck(pkg *Package) {
 	if !vet("asmdecl") {
 		return
@@ -115,6 +125,7 @@ func asmCheck(pkg *Package) {
 			continue
 		}
 		Println("Checking file", f.name)
+		f.analyzer = asmdeclAnalyzer
 
 		// Determine architecture from file name if possible.
 		var arch string
@@ -6,19 +6,22 @@
 This file contains the code to check for useless assignments.
 */
 
-package main
+package vet
 
 import (
+	"fmt"
 	"go/ast"
 	"go/token"
 	"reflect"
 )
 
 func init() {
-	register("assign",
-		"check for useless assignments",
-		checkAssignStmt,
-		assignStmt)
+	Register(&Analyzer{
+		Name:  "assign",
+		Doc:   "check for useless assignments",
+		Types: []ast.Node{assignStmt},
+		Run:   checkAssignStmt,
+	})
 }
 
 // TODO: should also check for assignments to struct fields inside methods
@@ -43,7 +46,18 @@ func checkAssignStmt(f *File, node ast.Node) {
 		le := f.gofmt(lhs)
 		re := f.gofmt(rhs)
 		if le == re {
-			f.Badf(stmt.Pos(), "self-assignment of %s to %s", re, le)
+			d := Diagnostic{
+				Pos:     stmt.Pos(),
+				End:     stmt.End(),
+				Message: fmt.Sprintf("self-assignment of %s to %s", re, le),
+			}
+			if len(stmt.Lhs) == 1 {
+				d.Fixes = []SuggestedFix{{
+					Message: "remove self-assignment",
+					Edits:   []TextEdit{{Pos: stmt.Pos(), End: stmt.End()}},
+				}}
+			}
+			f.Report(d)
 		}
 	}
 }
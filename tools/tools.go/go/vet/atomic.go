@@ -2,7 +2,7 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-package main
+package vet
 
 import (
 	"go/ast"
@@ -10,10 +10,12 @@ import (
 )
 
 func init() {
-	register("atomic",
-		"check for common mistaken usages of the sync/atomic package",
-		checkAtomicAssignment,
-		assignStmt)
+	Register(&Analyzer{
+		Name:  "atomic",
+		Doc:   "check for common mistaken usages of the sync/atomic package",
+		Types: []ast.Node{assignStmt},
+		Run:   checkAtomicAssignment,
+	})
 }
 
 // checkAtomicAssignment walks the assignment statement checking for common
@@ -4,7 +4,7 @@
 
 // This file contains boolean condition tests.
 
-package main
+package vet
 
 import (
 	"go/ast"
@@ -12,10 +12,12 @@ import (
 )
 
 func init() {
-	register("bool",
-		"check for mistakes involving boolean operators",
-		checkBool,
-		binaryExpr)
+	Register(&Analyzer{
+		Name:  "bool",
+		Doc:   "check for mistakes involving boolean operators",
+		Types: []ast.Node{binaryExpr},
+		Run:   checkBool,
+	})
 }
 
 func checkBool(f *File, n ast.Node) {
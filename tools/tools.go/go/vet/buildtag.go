@@ -2,12 +2,11 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-package main
+package vet
 
 import (
 	"bytes"
-	"fmt"
-	"os"
+	"go/token"
 	"strings"
 	"unicode"
 )
@@ -18,12 +17,25 @@ var (
 	plusBuild  = []byte("+build")
 )
 
+// buildtagsAnalyzer is run by doPackage for each file, Go or not.
+var buildtagsAnalyzer = &Analyzer{
+	Name: "buildtags",
+	Doc:  "check that +build tags are valid",
+}
+
+func init() {
+	Register(buildtagsAnalyzer)
+}
+
 // checkBuildTag checks that build tags are in the correct location and well-formed.
-func checkBuildTag(name string, data []byte) {
+func (f *File) checkBuildTag() {
 	if !vet("buildtags") {
 		return
 	}
-	lines := bytes.SplitAfter(data, nl)
+	f.analyzer = buildtagsAnalyzer
+	defer func() { f.analyzer = nil }()
+	name := f.name
+	lines := bytes.SplitAfter(f.content, nl)
 
 	// Determine cutpoint where +build comments are no longer valid.
 	// They are valid in leading // comments in the file followed by
@@ -51,12 +63,11 @@ func checkBuildTag(name string, data []byte) {
 			fields := bytes.Fields(text)
 			if !bytes.Equal(fields[0], plusBuild) {
 				// Comment is something like +buildasdf not +build.
-				fmt.Fprintf(os.Stderr, "%s:%d: possible malformed +build comment\n", name, i+1)
+				f.Warnf(token.NoPos, "%s:%d: possible malformed +build comment", name, i+1)
 				continue
 			}
 			if i >= cutoff {
-				fmt.Fprintf(os.Stderr, "%s:%d: +build comment must appear before package clause and be followed by a blank line\n", name, i+1)
-				setExit(1)
+				f.Badf(token.NoPos, "%s:%d: +build comment must appear before package clause and be followed by a blank line", name, i+1)
 				continue
 			}
 			// Check arguments.
@@ -64,8 +75,7 @@ func checkBuildTag(name string, data []byte) {
 			for _, arg := range fields[1:] {
 				for _, elem := range strings.Split(string(arg), ",") {
 					if strings.HasPrefix(elem, "!!") {
-						fmt.Fprintf(os.Stderr, "%s:%d: invalid double negative in build constraint: %s\n", name, i+1, arg)
-						setExit(1)
+						f.Badf(token.NoPos, "%s:%d: invalid double negative in build constraint: %s", name, i+1, arg)
 						break Args
 					}
 					if strings.HasPrefix(elem, "!") {
@@ -73,8 +83,7 @@ func checkBuildTag(name string, data []byte) {
 					}
 					for _, c := range elem {
 						if !unicode.IsLetter(c) && !unicode.IsDigit(c) && c != '_' && c != '.' {
-							fmt.Fprintf(os.Stderr, "%s:%d: invalid non-alphanumeric build constraint: %s\n", name, i+1, arg)
-							setExit(1)
+							f.Badf(token.NoPos, "%s:%d: invalid non-alphanumeric build constraint: %s", name, i+1, arg)
 							break Args
 						}
 					}
@@ -84,7 +93,7 @@ func checkBuildTag(name string, data []byte) {
 		}
 		// Comment with +build but not at beginning.
 		if bytes.Contains(line, plusBuild) && i < cutoff {
-			fmt.Fprintf(os.Stderr, "%s:%d: possible malformed +build comment\n", name, i+1)
+			f.Warnf(token.NoPos, "%s:%d: possible malformed +build comment", name, i+1)
 			continue
 		}
 	}
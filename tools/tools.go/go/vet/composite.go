@@ -4,7 +4,7 @@
 
 // This file contains the test for unkeyed struct literals.
 
-package main
+package vet
 
 import (
 	"flag"
@@ -17,10 +17,12 @@ import (
 var compositeWhiteList = flag.Bool("compositewhitelist", true, "use composite white list; for testing only")
 
 func init() {
-	register("composites",
-		"check that composite literals used field-keyed elements",
-		checkUnkeyedLiteral,
-		compositeLit)
+	Register(&Analyzer{
+		Name:  "composites",
+		Doc:   "check that composite literals used field-keyed elements",
+		Types: []ast.Node{compositeLit},
+		Run:   checkUnkeyedLiteral,
+	})
 }
 
 // checkUnkeyedLiteral checks if a composite literal is a struct literal with
@@ -4,7 +4,7 @@
 
 // This file contains the code to check that locks are not passed by value.
 
-package main
+package vet
 
 import (
 	"bytes"
@@ -16,10 +16,12 @@ import (
 )
 
 func init() {
-	register("copylocks",
-		"check that locks are not passed by value",
-		checkCopyLocks,
-		funcDecl, rangeStmt)
+	Register(&Analyzer{
+		Name:  "copylocks",
+		Doc:   "check that locks are not passed by value",
+		Types: []ast.Node{funcDecl, rangeStmt},
+		Run:   checkCopyLocks,
+	})
 }
 
 // checkCopyLocks checks whether node might
@@ -4,7 +4,7 @@
 
 // Check for syntactically unreachable code.
 
-package main
+package vet
 
 import (
 	"go/ast"
@@ -12,10 +12,12 @@ import (
 )
 
 func init() {
-	register("unreachable",
-		"check for unreachable code",
-		checkUnreachable,
-		funcDecl, funcLit)
+	Register(&Analyzer{
+		Name:  "unreachable",
+		Doc:   "check for unreachable code",
+		Types: []ast.Node{funcDecl, funcLit},
+		Run:   checkUnreachable,
+	})
 }
 
 type deadState struct {
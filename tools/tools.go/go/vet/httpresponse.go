@@ -0,0 +1,162 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+This file contains the code to check that the Body of an http.Response
+is closed.  Until it is, the connection that carries it cannot be reused
+or released.
+
+For example:
+
+	resp, err := http.Get(url)
+	if err != nil {
+		return err
+	}
+	return decode(resp.Body) // resp.Body is never closed
+
+A response is assumed to be closed elsewhere if the function passes it
+to another, returns it, or stores it, rather than only selecting its
+fields.
+*/
+
+package vet
+
+import (
+	"go/ast"
+
+	"code.google.com/p/go.tools/go/types"
+)
+
+func init() {
+	Register(&Analyzer{
+		Name:  "httpresponse",
+		Doc:   "check that the bodies of http.Responses are closed",
+		Types: []ast.Node{funcDecl, funcLit},
+		Run:   checkHTTPResponse,
+	})
+}
+
+func checkHTTPResponse(f *File, node ast.Node) {
+	var body *ast.BlockStmt
+	switch n := node.(type) {
+	case *ast.FuncDecl:
+		body = n.Body
+	case *ast.FuncLit:
+		body = n.Body
+	}
+	if body == nil {
+		return
+	}
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false // checked separately
+		case *ast.AssignStmt:
+			if len(n.Lhs) == 2 && len(n.Rhs) == 1 {
+				f.checkResponseVar(body, n.Lhs[0], n.Rhs[0])
+			}
+		case *ast.ValueSpec:
+			if len(n.Names) == 2 && len(n.Values) == 1 {
+				f.checkResponseVar(body, n.Names[0], n.Values[0])
+			}
+		}
+		return true
+	})
+}
+
+// checkResponseVar checks the use, in the body of a function, of the
+// variable lhs, if rhs is a call returning an *http.Response.
+func (f *File) checkResponseVar(body *ast.BlockStmt, lhs, rhs ast.Expr) {
+	call, ok := rhs.(*ast.CallExpr)
+	if !ok || !f.returnsResponse(call) {
+		return
+	}
+	id, ok := lhs.(*ast.Ident)
+	if !ok {
+		return
+	}
+	if id.Name == "_" {
+		f.Badf(call.Pos(), "response of %s is discarded, so its Body cannot be closed", f.gofmt(call.Fun))
+		return
+	}
+	if id.Obj == nil {
+		return
+	}
+
+	// Look for resp.Body.Close(), or a use of resp other than
+	// the selection of a field or method.
+	closed := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.CallExpr:
+			if sel, ok := n.Fun.(*ast.SelectorExpr); ok && sel.Sel.Name == "Close" {
+				if x, ok := sel.X.(*ast.SelectorExpr); ok && x.Sel.Name == "Body" && isObj(x.X, id.Obj) {
+					closed = true
+				}
+			}
+		case *ast.SelectorExpr:
+			if isObj(n.X, id.Obj) {
+				return false
+			}
+		case *ast.AssignStmt:
+			// An assignment to the variable is not a use of it.
+			for _, x := range n.Lhs {
+				if isObj(x, id.Obj) {
+					for _, x := range n.Rhs {
+						ast.Inspect(x, func(n ast.Node) bool {
+							if isObj(n, id.Obj) {
+								closed = true
+							}
+							return !closed
+						})
+					}
+					return false
+				}
+			}
+		case *ast.Ident:
+			if n.Obj == id.Obj && n != id {
+				closed = true // passed, returned or stored
+			}
+		}
+		return !closed
+	})
+	if !closed {
+		f.Badf(id.Pos(), "%s.Body is not closed", id.Name)
+	}
+}
+
+// isObj reports whether the node is an identifier denoting obj.
+func isObj(n ast.Node, obj *ast.Object) bool {
+	id, ok := n.(*ast.Ident)
+	return ok && id.Obj == obj
+}
+
+// returnsResponse reports whether the call returns an *http.Response
+// and an error.  Without type information, it recognizes the calls of
+// the functions of package http that do.
+func (f *File) returnsResponse(call *ast.CallExpr) bool {
+	if tuple, ok := f.pkg.types[call].Type.(*types.Tuple); ok {
+		if tuple.Len() != 2 {
+			return false
+		}
+		ptr, ok := tuple.At(0).Type().(*types.Pointer)
+		if !ok {
+			return false
+		}
+		named, ok := ptr.Elem().(*types.Named)
+		return ok && named.Obj().Name() == "Response" && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == "net/http"
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	if x, ok := sel.X.(*ast.Ident); !ok || x.Name != "http" {
+		return false
+	}
+	switch sel.Sel.Name {
+	case "Get", "Head", "Post", "PostForm":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,377 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+This file contains the code to check that the cancel function returned by
+context.WithCancel, WithTimeout or WithDeadline is used on all paths.
+Until it is called, or its context's parent is cancelled, the new context
+and its goroutines and timers are not released.
+
+For example:
+
+	func f() error {
+		ctx, cancel := context.WithTimeout(parent, time.Second)
+		if err := g(ctx); err != nil {
+			return err // cancel is not called on this path
+		}
+		cancel()
+		return nil
+	}
+
+A use of the cancel variable, such as a call, a defer statement, or
+passing it to another function, counts as calling it.  The check is
+syntactic: it follows the statements of the function, and assumes that
+each branch of an if, switch or select statement may be taken, that the
+body of a loop may not be executed, and that break, continue and goto
+statements leave the paths to be checked.
+*/
+
+package vet
+
+import (
+	"go/ast"
+	"go/token"
+	"strings"
+
+	"code.google.com/p/go.tools/go/types"
+)
+
+func init() {
+	Register(&Analyzer{
+		Name:  "lostcancel",
+		Doc:   "check that the cancel functions of contexts are called on all paths",
+		Types: []ast.Node{funcDecl, funcLit},
+		Run:   checkLostCancel,
+	})
+}
+
+func checkLostCancel(f *File, node ast.Node) {
+	var body *ast.BlockStmt
+	switch n := node.(type) {
+	case *ast.FuncDecl:
+		body = n.Body
+	case *ast.FuncLit:
+		body = n.Body
+	}
+	if body == nil {
+		return
+	}
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false // checked separately
+		case *ast.AssignStmt:
+			if len(n.Lhs) == 2 && len(n.Rhs) == 1 {
+				f.checkCancelVar(body, n, n.Lhs[1], n.Rhs[0])
+			}
+		case *ast.ValueSpec:
+			if len(n.Names) == 2 && len(n.Values) == 1 {
+				f.checkCancelVar(body, n, n.Names[1], n.Values[0])
+			}
+		}
+		return true
+	})
+}
+
+// checkCancelVar checks the use of the variable lhs, defined by the
+// statement or spec def in the body of a function, if rhs is a call
+// of a context function returning a cancel function.
+func (f *File) checkCancelVar(body *ast.BlockStmt, def ast.Node, lhs, rhs ast.Expr) {
+	call, ok := rhs.(*ast.CallExpr)
+	if !ok {
+		return
+	}
+	name := f.contextFunc(call)
+	if name == "" {
+		return
+	}
+	id, ok := lhs.(*ast.Ident)
+	if !ok {
+		return // assigned to a field or element: it may be called elsewhere
+	}
+	if id.Name == "_" {
+		f.Badf(id.Pos(), "the cancel function returned by context.%s should be called, not discarded, to avoid a context leak", name)
+		return
+	}
+	if id.Obj == nil {
+		return
+	}
+
+	// Follow the paths from the definition: through the rest of the
+	// statement list containing it, then the lists enclosing that one.
+	// A definition within the header of an if, switch or for statement
+	// is not checked.
+	path := stmtPath(body.List, def)
+	if len(path) == 0 {
+		return
+	}
+	last := path[len(path)-1]
+	if _, ok := last.list[last.index].(*ast.DeclStmt); !ok && last.list[last.index] != def {
+		return
+	}
+	c := &cancelCheck{f: f, obj: id.Obj, name: id.Name, line: f.fset.Position(id.Pos()).Line}
+	done := false
+	for i := len(path) - 1; i >= 0 && !done; i-- {
+		done = c.stmts(path[i].list[path[i].index+1:])
+	}
+	if !done || c.leaks {
+		f.Badf(id.Pos(), "the %s function is not used on all paths (possible context leak)", id.Name)
+	}
+}
+
+// contextFunc returns the name of the function if the call is of
+// context.WithCancel, WithTimeout or WithDeadline, and "" otherwise.
+func (f *File) contextFunc(call *ast.CallExpr) string {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return ""
+	}
+	switch sel.Sel.Name {
+	case "WithCancel", "WithTimeout", "WithDeadline":
+	default:
+		return ""
+	}
+	x, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return ""
+	}
+	if pkg, ok := f.pkg.uses[x].(*types.PkgName); ok {
+		path := pkg.Pkg().Path()
+		if path != "context" && !strings.HasSuffix(path, "/context") {
+			return ""
+		}
+	} else if x.Name != "context" {
+		// No type information; go by the name.
+		return ""
+	}
+	return sel.Sel.Name
+}
+
+// A stmtPathElem locates a statement in a statement list.
+type stmtPathElem struct {
+	list  []ast.Stmt
+	index int
+}
+
+// stmtPath returns the statement lists enclosing the node, outermost
+// first, and the index in each of the statement leading to the node.
+// It does not look inside function literals.
+func stmtPath(list []ast.Stmt, node ast.Node) []stmtPathElem {
+	for i, s := range list {
+		if s.Pos() > node.Pos() || node.End() > s.End() {
+			continue
+		}
+		elem := stmtPathElem{list, i}
+		for _, inner := range innerLists(s) {
+			if path := stmtPath(inner, node); path != nil {
+				return append([]stmtPathElem{elem}, path...)
+			}
+		}
+		return []stmtPathElem{elem}
+	}
+	return nil
+}
+
+// innerLists returns the statement lists directly within the statement.
+func innerLists(s ast.Stmt) [][]ast.Stmt {
+	switch s := s.(type) {
+	case *ast.BlockStmt:
+		return [][]ast.Stmt{s.List}
+	case *ast.LabeledStmt:
+		return [][]ast.Stmt{{s.Stmt}}
+	case *ast.IfStmt:
+		lists := [][]ast.Stmt{s.Body.List}
+		if s.Else != nil {
+			lists = append(lists, []ast.Stmt{s.Else})
+		}
+		return lists
+	case *ast.ForStmt:
+		return [][]ast.Stmt{s.Body.List}
+	case *ast.RangeStmt:
+		return [][]ast.Stmt{s.Body.List}
+	case *ast.SwitchStmt:
+		return [][]ast.Stmt{s.Body.List}
+	case *ast.TypeSwitchStmt:
+		return [][]ast.Stmt{s.Body.List}
+	case *ast.SelectStmt:
+		return [][]ast.Stmt{s.Body.List}
+	case *ast.CaseClause:
+		return [][]ast.Stmt{s.Body}
+	case *ast.CommClause:
+		return [][]ast.Stmt{s.Body}
+	}
+	return nil
+}
+
+// A cancelCheck follows the paths from the definition of a cancel variable.
+type cancelCheck struct {
+	f     *File
+	obj   *ast.Object // the cancel variable
+	name  string      // its name
+	line  int         // the line of its definition
+	leaks bool        // a return statement was reached without a use
+}
+
+// uses reports whether the node mentions the cancel variable.
+func (c *cancelCheck) uses(n ast.Node) bool {
+	found := false
+	ast.Inspect(n, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && id.Obj == c.obj {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// stmts follows the paths through the statements, reporting return
+// statements reached without a use of the variable.  It reports
+// whether every path through the statements uses the variable or
+// leaves them other than by falling through.
+func (c *cancelCheck) stmts(list []ast.Stmt) bool {
+	for _, s := range list {
+		if c.stmt(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// stmt is like stmts, for a single statement.
+func (c *cancelCheck) stmt(s ast.Stmt) bool {
+	switch s := s.(type) {
+	case *ast.ReturnStmt:
+		if !c.uses(s) {
+			c.f.Badf(s.Pos(), "this return statement may be reached without using the %s var defined on line %d", c.name, c.line)
+			c.leaks = true
+		}
+		return true
+	case *ast.BranchStmt:
+		return s.Tok != token.FALLTHROUGH
+	case *ast.BlockStmt:
+		return c.stmts(s.List)
+	case *ast.LabeledStmt:
+		return c.stmt(s.Stmt)
+	case *ast.IfStmt:
+		if s.Init != nil && c.uses(s.Init) || c.uses(s.Cond) {
+			return true
+		}
+		done := c.stmts(s.Body.List)
+		if s.Else == nil {
+			return false
+		}
+		return c.stmt(s.Else) && done
+	case *ast.SwitchStmt:
+		if s.Init != nil && c.uses(s.Init) || s.Tag != nil && c.uses(s.Tag) {
+			return true
+		}
+		return c.clauses(s.Body, true)
+	case *ast.TypeSwitchStmt:
+		if s.Init != nil && c.uses(s.Init) || c.uses(s.Assign) {
+			return true
+		}
+		return c.clauses(s.Body, true)
+	case *ast.SelectStmt:
+		return c.clauses(s.Body, false)
+	case *ast.ForStmt:
+		if s.Init != nil && c.uses(s.Init) || s.Cond != nil && c.uses(s.Cond) {
+			return true
+		}
+		c.stmts(s.Body.List)
+		// A loop without a condition is left only by break.
+		return s.Cond == nil && !hasBreak(s.Body)
+	case *ast.RangeStmt:
+		if c.uses(s.X) {
+			return true
+		}
+		c.stmts(s.Body.List)
+		return false
+	case *ast.ExprStmt:
+		return c.uses(s) || isNoReturnCall(s.X)
+	}
+	return c.uses(s)
+}
+
+// clauses follows the paths through the clauses of a switch or select
+// statement.  A switch without a default clause may take none of them.
+func (c *cancelCheck) clauses(body *ast.BlockStmt, isSwitch bool) bool {
+	done, hasDefault := true, false
+	for _, cl := range body.List {
+		switch cl := cl.(type) {
+		case *ast.CaseClause:
+			if cl.List == nil {
+				hasDefault = true
+			}
+			for _, x := range cl.List {
+				if c.uses(x) {
+					return true
+				}
+			}
+			if !c.stmts(cl.Body) {
+				done = false
+			}
+		case *ast.CommClause:
+			if cl.Comm == nil {
+				hasDefault = true
+			} else if c.uses(cl.Comm) {
+				return true
+			}
+			if !c.stmts(cl.Body) {
+				done = false
+			}
+		}
+	}
+	return done && (hasDefault || !isSwitch)
+}
+
+// hasBreak reports whether the loop body contains a break statement
+// that may leave the loop: an unlabeled one not within a nested loop,
+// switch or select, or a labeled one.
+func hasBreak(body *ast.BlockStmt) bool {
+	found := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.ForStmt, *ast.RangeStmt, *ast.SwitchStmt, *ast.TypeSwitchStmt, *ast.SelectStmt:
+			// Only labeled breaks within these can leave the loop.
+			ast.Inspect(n, func(n ast.Node) bool {
+				if b, ok := n.(*ast.BranchStmt); ok && b.Tok == token.BREAK && b.Label != nil {
+					found = true
+				}
+				return !found
+			})
+			return false
+		case *ast.BranchStmt:
+			if n.Tok == token.BREAK {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// isNoReturnCall reports whether the expression is a call of panic,
+// os.Exit or log.Fatal and its variants, which do not return.
+func isNoReturnCall(x ast.Expr) bool {
+	call, ok := x.(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	switch fn := call.Fun.(type) {
+	case *ast.Ident:
+		return fn.Name == "panic"
+	case *ast.SelectorExpr:
+		pkg, ok := fn.X.(*ast.Ident)
+		if !ok {
+			return false
+		}
+		switch pkg.Name + "." + fn.Sel.Name {
+		case "os.Exit", "log.Fatal", "log.Fatalf", "log.Fatalln", "log.Panic", "log.Panicf", "log.Panicln":
+			return true
+		}
+	}
+	return false
+}
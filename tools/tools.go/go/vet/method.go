@@ -4,7 +4,7 @@
 
 // This file contains the code to check canonical methods.
 
-package main
+package vet
 
 import (
 	"fmt"
@@ -14,10 +14,12 @@ import (
 )
 
 func init() {
-	register("methods",
-		"check that canonically named methods are canonically defined",
-		checkCanonicalMethod,
-		funcDecl, interfaceType)
+	Register(&Analyzer{
+		Name:  "methods",
+		Doc:   "check that canonically named methods are canonically defined",
+		Types: []ast.Node{funcDecl, interfaceType},
+		Run:   checkCanonicalMethod,
+	})
 }
 
 type MethodSig struct {
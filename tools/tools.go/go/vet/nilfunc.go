@@ -7,7 +7,7 @@ This file contains the code to check for useless function comparisons.
 A useless comparison is one like f == nil as opposed to f() == nil.
 */
 
-package main
+package vet
 
 import (
 	"go/ast"
@@ -17,10 +17,12 @@ import (
 )
 
 func init() {
-	register("nilfunc",
-		"check for comparisons between functions and nil",
-		checkNilFuncComparison,
-		binaryExpr)
+	Register(&Analyzer{
+		Name:  "nilfunc",
+		Doc:   "check for comparisons between functions and nil",
+		Types: []ast.Node{binaryExpr},
+		Run:   checkNilFuncComparison,
+	})
 }
 
 func checkNilFuncComparison(f *File, node ast.Node) {
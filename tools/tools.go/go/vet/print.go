@@ -4,11 +4,12 @@
 
 // This file contains the printf-checker.
 
-package main
+package vet
 
 import (
 	"bytes"
 	"flag"
+	"fmt"
 	"go/ast"
 	"go/token"
 	"strconv"
@@ -22,10 +23,12 @@ import (
 var printfuncs = flag.String("printfuncs", "", "comma-separated list of print function names to check")
 
 func init() {
-	register("printf",
-		"check printf-like invocations",
-		checkFmtPrintfCall,
-		funcDecl, callExpr)
+	Register(&Analyzer{
+		Name:  "printf",
+		Doc:   "check printf-like invocations",
+		Types: []ast.Node{funcDecl, callExpr},
+		Run:   checkFmtPrintfCall,
+	})
 }
 
 // printfList records the formatted-print functions. The value is the location
@@ -545,7 +548,19 @@ func (f *File) checkPrint(call *ast.CallExpr, name string, firstArg int) {
 		arg = args[len(call.Args)-1]
 		if lit, ok := arg.(*ast.BasicLit); ok && lit.Kind == token.STRING {
 			if strings.HasSuffix(lit.Value, `\n"`) {
-				f.Badf(call.Pos(), "%s call ends with newline", name)
+				f.Report(Diagnostic{
+					Pos:     call.Pos(),
+					End:     call.End(),
+					Message: fmt.Sprintf("%s call ends with newline", name),
+					Fixes: []SuggestedFix{{
+						Message: "remove trailing newline",
+						Edits: []TextEdit{{
+							Pos:     lit.Pos(),
+							End:     lit.End(),
+							NewText: []byte(strings.TrimSuffix(lit.Value, `\n"`) + `"`),
+						}},
+					}},
+				})
 			}
 		}
 	}
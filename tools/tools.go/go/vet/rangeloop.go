@@ -0,0 +1,151 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+This file contains the code to check range loop variables bound inside function
+literals that are deferred or launched in new goroutines.
+
+A deferred function literal runs after the loop has finished, so it always
+sees the final values of the variables.  A goroutine may run at any time, so
+it may see the variables of a later iteration, unless the iteration waits for
+it: we do not report a go statement that is followed, in the same iteration,
+by a call of a method named Wait or by a channel receive.  A function literal
+called directly, rather than deferred or launched, is not reported.
+
+For example:
+
+	for i, v := range s {
+		go func() {
+			println(i, v) // not what you might expect
+		}()
+	}
+
+See: http://golang.org/doc/go_faq.html#closures_and_goroutines
+*/
+
+package vet
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+func init() {
+	Register(&Analyzer{
+		Name:  "rangeloops",
+		Doc:   "check that range loop variables are used correctly",
+		Types: []ast.Node{rangeStmt},
+		Run:   checkRangeLoop,
+	})
+}
+
+// checkRangeLoop walks the body of the provided range statement, checking if
+// its index or value variables are used unsafely inside goroutines or deferred
+// function literals.
+func checkRangeLoop(f *File, node ast.Node) {
+	n := node.(*ast.RangeStmt)
+	key, _ := n.Key.(*ast.Ident)
+	val, _ := n.Value.(*ast.Ident)
+	if key == nil && val == nil {
+		return
+	}
+	r := &rangeLoop{f: f, key: key, val: val}
+	r.stmts(n.Body.List, false)
+}
+
+// A rangeLoop holds the state of the check of one range statement.
+type rangeLoop struct {
+	f        *File
+	key, val *ast.Ident
+}
+
+// stmts checks the statements of the loop body, or of a block within it.
+// waited reports whether the iteration waits after the statements.
+func (r *rangeLoop) stmts(list []ast.Stmt, waited bool) {
+	for i, s := range list {
+		r.stmt(s, waited || waits(list[i+1:]))
+	}
+}
+
+func (r *rangeLoop) stmt(s ast.Stmt, waited bool) {
+	switch s := s.(type) {
+	case *ast.GoStmt:
+		if !waited {
+			r.captures(s.Call)
+		}
+	case *ast.DeferStmt:
+		r.captures(s.Call)
+	case *ast.BlockStmt:
+		r.stmts(s.List, waited)
+	case *ast.LabeledStmt:
+		r.stmt(s.Stmt, waited)
+	case *ast.IfStmt:
+		r.stmts(s.Body.List, waited)
+		if s.Else != nil {
+			r.stmt(s.Else, waited)
+		}
+	case *ast.ForStmt:
+		r.stmts(s.Body.List, waited)
+	case *ast.RangeStmt:
+		r.stmts(s.Body.List, waited)
+	case *ast.SwitchStmt:
+		r.stmts(s.Body.List, waited)
+	case *ast.TypeSwitchStmt:
+		r.stmts(s.Body.List, waited)
+	case *ast.SelectStmt:
+		r.stmts(s.Body.List, waited)
+	case *ast.CaseClause:
+		r.stmts(s.Body, waited)
+	case *ast.CommClause:
+		r.stmts(s.Body, waited)
+	}
+}
+
+// captures reports the uses of the loop variables in the function
+// literal, if any, called by the go or defer statement.
+func (r *rangeLoop) captures(call *ast.CallExpr) {
+	lit, ok := call.Fun.(*ast.FuncLit)
+	if !ok {
+		return
+	}
+	ast.Inspect(lit.Body, func(n ast.Node) bool {
+		id, ok := n.(*ast.Ident)
+		if !ok || id.Obj == nil {
+			return true
+		}
+		if r.key != nil && id.Obj == r.key.Obj || r.val != nil && id.Obj == r.val.Obj {
+			r.f.Bad(id.Pos(), "range variable", id.Name, "captured by func literal")
+		}
+		return true
+	})
+}
+
+// waits reports whether the statements contain a call of a method
+// named Wait, such as sync.WaitGroup.Wait, or a channel receive,
+// outside function literals.
+func waits(list []ast.Stmt) bool {
+	found := false
+	for _, s := range list {
+		ast.Inspect(s, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.FuncLit:
+				return false
+			case *ast.UnaryExpr:
+				if n.Op == token.ARROW {
+					found = true
+				}
+			case *ast.CommClause:
+				if n.Comm != nil {
+					found = true
+				}
+			case *ast.CallExpr:
+				if sel, ok := n.Fun.(*ast.SelectorExpr); ok && sel.Sel.Name == "Wait" {
+					found = true
+				}
+			}
+			return !found
+		})
+	}
+	return found
+}
@@ -28,7 +28,7 @@ For example:
 
 */
 
-package main
+package vet
 
 import (
 	"flag"
@@ -41,11 +41,13 @@ import (
 var strictShadowing = flag.Bool("shadowstrict", false, "whether to be strict about shadowing; can be noisy")
 
 func init() {
-	register("shadow",
-		"check for shadowed variables (experimental; must be set explicitly)",
-		checkShadow,
-		assignStmt, genDecl)
-	experimental["shadow"] = true
+	Register(&Analyzer{
+		Name:         "shadow",
+		Doc:          "check for shadowed variables (experimental; must be set explicitly)",
+		Types:        []ast.Node{assignStmt, genDecl},
+		Run:          checkShadow,
+		Experimental: true,
+	})
 }
 
 func checkShadow(f *File, node ast.Node) {
@@ -6,7 +6,7 @@
 This file contains the code to check for suspicious shifts.
 */
 
-package main
+package vet
 
 import (
 	"go/ast"
@@ -17,10 +17,12 @@ import (
 )
 
 func init() {
-	register("shift",
-		"check for useless shifts",
-		checkShift,
-		binaryExpr, assignStmt)
+	Register(&Analyzer{
+		Name:  "shift",
+		Doc:   "check for useless shifts",
+		Types: []ast.Node{binaryExpr, assignStmt},
+		Run:   checkShift,
+	})
 }
 
 func checkShift(f *File, node ast.Node) {
@@ -4,7 +4,7 @@
 
 // This file contains the test for canonical struct tags.
 
-package main
+package vet
 
 import (
 	"go/ast"
@@ -13,10 +13,12 @@ import (
 )
 
 func init() {
-	register("structtags",
-		"check that struct field tags have canonical format and apply to exported fields as needed",
-		checkCanonicalFieldTag,
-		field)
+	Register(&Analyzer{
+		Name:  "structtags",
+		Doc:   "check that struct field tags have canonical format and apply to exported fields as needed",
+		Types: []ast.Node{field},
+		Run:   checkCanonicalFieldTag,
+	})
 }
 
 // checkCanonicalFieldTag checks a struct field tag.
@@ -4,7 +4,7 @@
 
 // This file contains the pieces of the tool that use typechecking from the go/types package.
 
-package main
+package vet
 
 import (
 	"go/ast"
@@ -65,6 +65,7 @@ func (pkg *Package) check(fs *token.FileSet, astFiles []*ast.File) error {
 		Defs:  pkg.defs,
 		Uses:  pkg.uses,
 	}
+	pkg.info = info
 	typesPkg, err := config.Check(pkg.path, fs, astFiles, info)
 	pkg.typesPkg = typesPkg
 	// update spans
@@ -4,7 +4,7 @@
 
 // Check for invalid uintptr -> unsafe.Pointer conversions.
 
-package main
+package vet
 
 import (
 	"go/ast"
@@ -14,10 +14,12 @@ import (
 )
 
 func init() {
-	register("unsafeptr",
-		"check for misuse of unsafe.Pointer",
-		checkUnsafePointer,
-		callExpr)
+	Register(&Analyzer{
+		Name:  "unsafeptr",
+		Doc:   "check for misuse of unsafe.Pointer",
+		Types: []ast.Node{callExpr},
+		Run:   checkUnsafePointer,
+	})
 }
 
 func checkUnsafePointer(f *File, node ast.Node) {
@@ -0,0 +1,102 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+This file contains the code to check for calls of sync.WaitGroup.Add
+inside the goroutines whose completion they count.  The call may happen
+after the Wait it should have held up, which then returns early.
+
+For example:
+
+	for _, x := range s {
+		go func(x int) {
+			wg.Add(1) // should be called before the go statement
+			defer wg.Done()
+			process(x)
+		}(x)
+	}
+	wg.Wait()
+*/
+
+package vet
+
+import (
+	"go/ast"
+
+	"code.google.com/p/go.tools/go/types"
+)
+
+func init() {
+	Register(&Analyzer{
+		Name:  "waitgroup",
+		Doc:   "check for calls of sync.WaitGroup.Add inside the goroutines it waits for",
+		Types: []ast.Node{goStmt},
+		Run:   checkWaitGroupAdd,
+	})
+}
+
+func checkWaitGroupAdd(f *File, node ast.Node) {
+	lit, ok := node.(*ast.GoStmt).Call.Fun.(*ast.FuncLit)
+	if !ok {
+		return
+	}
+	// A goroutine that starts others may be adding for them.
+	nested := false
+	ast.Inspect(lit.Body, func(n ast.Node) bool {
+		if _, ok := n.(*ast.GoStmt); ok {
+			nested = true
+		}
+		return !nested
+	})
+	if nested {
+		return
+	}
+	ast.Inspect(lit.Body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			// Calls in a closure need not happen before the goroutine ends.
+			return false
+		case *ast.CallExpr:
+			sel, ok := n.Fun.(*ast.SelectorExpr)
+			if ok && sel.Sel.Name == "Add" && f.isWaitGroup(sel.X) {
+				f.Badf(n.Pos(), "WaitGroup.Add called inside the new goroutine; call it before the go statement")
+			}
+		}
+		return true
+	})
+}
+
+// isWaitGroup reports whether x is a sync.WaitGroup or a pointer to one.
+// Without type information, it recognizes variables declared with
+// those types.
+func (f *File) isWaitGroup(x ast.Expr) bool {
+	typ := f.pkg.types[x].Type
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	if typ != nil && typ != types.Typ[types.Invalid] {
+		named, ok := typ.(*types.Named)
+		return ok && named.Obj().Name() == "WaitGroup" && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == "sync"
+	}
+	id, ok := x.(*ast.Ident)
+	if !ok || id.Obj == nil {
+		return false
+	}
+	var expr ast.Expr
+	switch decl := id.Obj.Decl.(type) {
+	case *ast.ValueSpec:
+		expr = decl.Type
+	case *ast.Field:
+		expr = decl.Type
+	}
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	sel, ok := expr.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	return ok && pkg.Name == "sync" && sel.Sel.Name == "WaitGroup"
+}
@@ -4,7 +4,13 @@
 
 package analysis
 
-// This file defines types used by client-side JavaScript.
+// This file defines types used by client-side JavaScript and by
+// clients of godoc's JSON API.
+
+import (
+	"strconv"
+	"strings"
+)
 
 type anchorJSON struct {
 	Text string // HTML
@@ -67,3 +73,41 @@ type PCGNodeJSON struct {
 	Func    anchorJSON
 	Callees []int // indices within CALLGRAPH of nodes called by this one
 }
+
+// A LinkJSON is the JSON form of a Link, for clients that render
+// source files themselves.  If Data is non-negative, it is the index
+// within FileInfo.Data of the value displayed when the link is
+// clicked, and Kind indicates its type: "callers", "callees",
+// "comm" or "typeinfo".  Error links have kind "error".
+type LinkJSON struct {
+	Start, End int
+	Title      string `json:",omitempty"` // hover text, or the error message
+	Href       string `json:",omitempty"` // URL
+	Kind       string `json:",omitempty"`
+	Data       int
+}
+
+// LinksJSON returns the JSON form of each link.
+func LinksJSON(links []Link) []LinkJSON {
+	result := make([]LinkJSON, 0, len(links))
+	for _, link := range links {
+		l := LinkJSON{Start: link.Start(), End: link.End(), Data: -1}
+		switch link := link.(type) {
+		case aLink:
+			l.Title = link.title
+			l.Href = link.href
+			// onclick has the form "onClickKind(index)".
+			if i := strings.Index(link.onclick, "("); i > 0 {
+				if data, err := strconv.Atoi(strings.TrimSuffix(link.onclick[i+1:], ")")); err == nil {
+					l.Kind = strings.ToLower(strings.TrimPrefix(link.onclick[:i], "onClick"))
+					l.Data = data
+				}
+			}
+		case errorLink:
+			l.Title = link.msg
+			l.Kind = "error"
+		}
+		result = append(result, l)
+	}
+	return result
+}
@@ -0,0 +1,414 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godoc
+
+// This file implements godoc's JSON API.
+//
+// All endpoints are served below /api/v1/; an incompatible change to
+// any response requires a new version.
+//
+//	/api/v1/pkg/<path>            documentation of package <path>
+//	/api/v1/cmd/<path>            documentation of command <path>
+//	/api/v1/search?q=<query>      identifier and full-text search results
+//	/api/v1/analysis/pkg/<path>   call graph and type information of package <path>
+//	/api/v1/analysis/file/<file>  links and data for source file <file>, e.g. src/fmt/print.go
+//
+// The documentation endpoints accept the same "m" modes as the HTML
+// pages, except "src" and "text".  Errors are reported as an
+// ErrorJSON with an HTTP error status.
+
+import (
+	"bytes"
+	"fmt"
+	"go/doc"
+	"go/printer"
+	"net/http"
+	pathpkg "path"
+	"strings"
+
+	"code.google.com/p/go.tools/godoc/analysis"
+)
+
+// APIVersion is the version of the JSON API.
+const APIVersion = 1
+
+var apiPrefix = fmt.Sprintf("/api/v%d/", APIVersion)
+
+// ErrorJSON is the response to an API request that failed.
+type ErrorJSON struct {
+	Error string
+}
+
+// PackageJSON is the documentation of a package or command.
+type PackageJSON struct {
+	ImportPath string
+	Name       string  `json:",omitempty"`
+	Synopsis   string  `json:",omitempty"`
+	Doc        string  `json:",omitempty"`
+	IsMain     bool    `json:",omitempty"`
+	Module     *Module `json:",omitempty"` // module containing the package, if any
+	Consts     []*ValueJSON
+	Vars       []*ValueJSON
+	Funcs      []*FuncJSON
+	Types      []*TypeJSON
+	Examples   []*ExampleJSON         // package examples
+	Notes      map[string][]*NoteJSON `json:",omitempty"` // notes matching Presentation.NotesRx
+	Dirs       []*DirJSON             // subdirectories
+}
+
+// ValueJSON is the documentation of a const or var declaration.
+type ValueJSON struct {
+	Names []string
+	Doc   string
+	Decl  string // source text of the declaration
+	URL   string // link to the declaration in the source
+}
+
+// FuncJSON is the documentation of a function or method.
+type FuncJSON struct {
+	Name     string
+	Recv     string `json:",omitempty"` // receiver type, for methods
+	Doc      string
+	Decl     string
+	URL      string
+	Examples []*ExampleJSON
+}
+
+// TypeJSON is the documentation of a type, with its associated
+// consts, vars, factory functions and methods.
+type TypeJSON struct {
+	Name     string
+	Doc      string
+	Decl     string
+	URL      string
+	Consts   []*ValueJSON
+	Vars     []*ValueJSON
+	Funcs    []*FuncJSON
+	Methods  []*FuncJSON
+	Examples []*ExampleJSON
+}
+
+// ExampleJSON is an example function.
+type ExampleJSON struct {
+	Name   string // name of the example, e.g. "Foo_bar"
+	Suffix string `json:",omitempty"` // suffix of the name, e.g. "bar"
+	Doc    string `json:",omitempty"`
+	Code   string
+	Output string `json:",omitempty"`
+}
+
+// NoteJSON is a marked comment, e.g. "BUG(uid): text".
+type NoteJSON struct {
+	UID  string
+	Body string
+	URL  string
+}
+
+// DirJSON is a subdirectory of a package directory.
+type DirJSON struct {
+	Path     string // relative to the package directory
+	Name     string
+	Depth    int
+	HasPkg   bool
+	Synopsis string `json:",omitempty"`
+}
+
+// SearchResultJSON is the result of a search.
+type SearchResultJSON struct {
+	Query    string
+	Alert    string             `json:",omitempty"` // error or warning message
+	Packages []*PakJSON         // packages named Query
+	Decls    []*HitJSON         // declarations of Query
+	Uses     []*HitJSON         // other occurrences of Query
+	Alts     []string           // alternative spellings of Query
+	Idents   map[string][]Ident // exported identifiers, by kind
+	Found    int                // number of textual occurrences found
+	Textual  []FileLines        // textual occurrences of Query
+	Complete bool               // Textual reports all occurrences
+}
+
+// PakJSON identifies a package.
+type PakJSON struct {
+	Path string // directory containing the package
+	Name string
+}
+
+// HitJSON is an occurrence of an identifier.
+type HitJSON struct {
+	Package PakJSON
+	File    string
+	Kind    string // e.g. "Functions", "Uses"
+	Line    int    // 0 if unknown
+	Snippet string `json:",omitempty"` // HTML
+}
+
+// PackageAnalysisJSON is the call graph and type information of a
+// package, as computed by godoc/analysis.
+type PackageAnalysisJSON struct {
+	Status         string                   // status of the analysis
+	CallGraph      []*analysis.PCGNodeJSON  // intra-package call graph; element 0 is "all external callers"
+	CallGraphIndex map[string]int           // maps func name to index in CallGraph
+	Types          []*analysis.TypeInfoJSON // exported types
+}
+
+// FileAnalysisJSON is the analysis information of a source file, as
+// computed by godoc/analysis.
+type FileAnalysisJSON struct {
+	Status string              // status of the analysis
+	Data   []interface{}       // values referenced by LinkJSON.Data
+	Links  []analysis.LinkJSON // in order of start offset
+}
+
+func (p *Presentation) serveAPI(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, apiPrefix)
+	switch {
+	case path == "search":
+		p.serveJSON(w, p.searchJSON(strings.TrimSpace(r.FormValue("q"))))
+	case strings.HasPrefix(path, "pkg/"):
+		p.servePackageJSON(w, r, &p.pkgHandler, path[len("pkg/"):])
+	case strings.HasPrefix(path, "cmd/"):
+		p.servePackageJSON(w, r, &p.cmdHandler, path[len("cmd/"):])
+	case strings.HasPrefix(path, "analysis/pkg/"):
+		relpath := pathpkg.Clean(path[len("analysis/pkg/"):])
+		pi := p.Corpus.Analysis.PackageInfo(relpath)
+		p.serveJSON(w, &PackageAnalysisJSON{
+			Status:         p.Corpus.Analysis.Status(),
+			CallGraph:      pi.CallGraph,
+			CallGraphIndex: pi.CallGraphIndex,
+			Types:          pi.Types,
+		})
+	case strings.HasPrefix(path, "analysis/file/"):
+		abspath := pathpkg.Clean("/" + path[len("analysis/file/"):])
+		if fi, err := p.Corpus.fs.Stat(abspath); err != nil || fi.IsDir() {
+			p.serveJSONError(w, http.StatusNotFound, fmt.Sprintf("no such file: %s", abspath))
+			return
+		}
+		fi := p.Corpus.Analysis.FileInfo(abspath)
+		p.serveJSON(w, &FileAnalysisJSON{
+			Status: p.Corpus.Analysis.Status(),
+			Data:   fi.Data,
+			Links:  analysis.LinksJSON(fi.Links),
+		})
+	default:
+		p.serveJSONError(w, http.StatusNotFound, fmt.Sprintf("unknown API endpoint: %s", r.URL.Path))
+	}
+}
+
+func (p *Presentation) servePackageJSON(w http.ResponseWriter, r *http.Request, h *handlerServer, relpath string) {
+	relpath = pathpkg.Clean(relpath)
+	abspath := pathpkg.Join(h.fsRoot, relpath)
+	mode := p.GetPageInfoMode(r) &^ (ShowSource | NoHTML)
+	if relpath == builtinPkgPath {
+		mode = NoFiltering | NoTypeAssoc
+	}
+	info := h.GetPageInfo(abspath, relpath, mode)
+	if info.Err != nil {
+		p.serveJSONError(w, http.StatusNotFound, info.Err.Error())
+		return
+	}
+	p.serveJSON(w, p.packageJSON(info, relpath))
+}
+
+func (p *Presentation) serveJSON(w http.ResponseWriter, x interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Write(marshalJSON(x))
+}
+
+func (p *Presentation) serveJSONError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(marshalJSON(&ErrorJSON{msg}))
+}
+
+// packageJSON returns the documentation in info of the package whose
+// path relative to the file system root of its handler is relpath.
+func (p *Presentation) packageJSON(info *PageInfo, relpath string) *PackageJSON {
+	pkg := &PackageJSON{
+		ImportPath: relpath,
+		IsMain:     info.IsMain,
+		Module:     info.Module,
+		Consts:     []*ValueJSON{},
+		Vars:       []*ValueJSON{},
+		Funcs:      []*FuncJSON{},
+		Types:      []*TypeJSON{},
+		Examples:   []*ExampleJSON{},
+		Dirs:       []*DirJSON{},
+	}
+	if d := info.PDoc; d != nil {
+		pkg.ImportPath = d.ImportPath
+		pkg.Name = d.Name
+		pkg.Synopsis = doc.Synopsis(d.Doc)
+		pkg.Doc = d.Doc
+		pkg.Consts = p.valuesJSON(info, d.Consts)
+		pkg.Vars = p.valuesJSON(info, d.Vars)
+		pkg.Funcs = p.funcsJSON(info, d.Funcs, "")
+		for _, t := range d.Types {
+			pkg.Types = append(pkg.Types, &TypeJSON{
+				Name:     t.Name,
+				Doc:      t.Doc,
+				Decl:     p.nodeFunc(info, t.Decl),
+				URL:      p.posLinkURL(info, t.Decl),
+				Consts:   p.valuesJSON(info, t.Consts),
+				Vars:     p.valuesJSON(info, t.Vars),
+				Funcs:    p.funcsJSON(info, t.Funcs, ""),
+				Methods:  p.funcsJSON(info, t.Methods, t.Name),
+				Examples: p.examplesJSON(info, t.Name),
+			})
+		}
+		pkg.Examples = p.examplesJSON(info, "")
+		for marker, notes := range info.Notes {
+			if pkg.Notes == nil {
+				pkg.Notes = make(map[string][]*NoteJSON)
+			}
+			for _, n := range notes {
+				pkg.Notes[marker] = append(pkg.Notes[marker], &NoteJSON{
+					UID:  n.UID,
+					Body: n.Body,
+					URL:  p.posLinkURL(info, n),
+				})
+			}
+		}
+	}
+	if info.Dirs != nil {
+		for _, d := range info.Dirs.List {
+			pkg.Dirs = append(pkg.Dirs, &DirJSON{
+				Path:     d.Path,
+				Name:     d.Name,
+				Depth:    d.Depth,
+				HasPkg:   d.HasPkg,
+				Synopsis: d.Synopsis,
+			})
+		}
+	}
+	return pkg
+}
+
+func (p *Presentation) valuesJSON(info *PageInfo, values []*doc.Value) []*ValueJSON {
+	result := []*ValueJSON{}
+	for _, v := range values {
+		result = append(result, &ValueJSON{
+			Names: v.Names,
+			Doc:   v.Doc,
+			Decl:  p.nodeFunc(info, v.Decl),
+			URL:   p.posLinkURL(info, v.Decl),
+		})
+	}
+	return result
+}
+
+// funcsJSON returns the documentation of funcs, which are methods of
+// type recv, if non-empty.
+func (p *Presentation) funcsJSON(info *PageInfo, funcs []*doc.Func, recv string) []*FuncJSON {
+	result := []*FuncJSON{}
+	for _, f := range funcs {
+		name := f.Name
+		if recv != "" {
+			name = recv + "_" + f.Name // the naming convention of examples
+		}
+		result = append(result, &FuncJSON{
+			Name:     f.Name,
+			Recv:     f.Recv,
+			Doc:      f.Doc,
+			Decl:     p.nodeFunc(info, f.Decl),
+			URL:      p.posLinkURL(info, f.Decl),
+			Examples: p.examplesJSON(info, name),
+		})
+	}
+	return result
+}
+
+// examplesJSON returns the examples of funcName, or of the package
+// if funcName is empty.
+func (p *Presentation) examplesJSON(info *PageInfo, funcName string) []*ExampleJSON {
+	result := []*ExampleJSON{}
+	if !p.ShowExamples {
+		return result
+	}
+	for _, eg := range info.Examples {
+		if stripExampleSuffix(eg.Name) != funcName {
+			continue
+		}
+		var buf bytes.Buffer
+		p.writeNode(&buf, info.FSet, &printer.CommentedNode{Node: eg.Code, Comments: eg.Comments})
+		code := buf.String()
+		// Remove the braces and indentation of a function body.
+		if n := len(code); n >= 2 && code[0] == '{' && code[n-1] == '}' {
+			code = strings.Replace(code[1:n-1], "\n    ", "\n", -1)
+		}
+		_, suffix := splitExampleName(eg.Name)
+		result = append(result, &ExampleJSON{
+			Name:   eg.Name,
+			Suffix: suffix,
+			Doc:    eg.Doc,
+			Code:   strings.Trim(code, "\n"),
+			Output: eg.Output,
+		})
+	}
+	return result
+}
+
+// posLinkURL returns the URL of the source of n, an ast.Node or *doc.Note.
+func (p *Presentation) posLinkURL(info *PageInfo, n interface{}) string {
+	return p.FuncMap()["posLink_url"].(func(info *PageInfo, n interface{}) string)(info, n)
+}
+
+// searchJSON returns the results of query.
+func (p *Presentation) searchJSON(query string) *SearchResultJSON {
+	result := p.Corpus.Lookup(query)
+	r := &SearchResultJSON{
+		Query:    result.Query,
+		Alert:    result.Alert,
+		Packages: []*PakJSON{},
+		Decls:    []*HitJSON{},
+		Uses:     []*HitJSON{},
+		Alts:     []string{},
+		Idents:   make(map[string][]Ident),
+		Found:    result.Found,
+		Textual:  result.Textual,
+		Complete: result.Complete,
+	}
+	if r.Textual == nil {
+		r.Textual = []FileLines{}
+	}
+	for _, run := range result.Pak {
+		r.Packages = append(r.Packages, &PakJSON{run.Pak.Path, run.Pak.Name})
+	}
+	if hit := result.Hit; hit != nil {
+		r.Decls = p.hitsJSON(hit.Decls)
+		r.Uses = p.hitsJSON(hit.Others)
+	}
+	if result.Alt != nil {
+		r.Alts = result.Alt.Alts
+	}
+	for kind, idents := range result.Idents {
+		if len(idents) > 0 {
+			r.Idents[kind.Name()] = idents
+		}
+	}
+	return r
+}
+
+func (p *Presentation) hitsJSON(list HitList) []*HitJSON {
+	hits := []*HitJSON{}
+	for _, pak := range list {
+		for _, file := range pak.Files {
+			for _, group := range file.Groups {
+				for _, info := range group {
+					hit := &HitJSON{
+						Package: PakJSON{pak.Pak.Path, pak.Pak.Name},
+						File:    file.File.Path(),
+						Kind:    info.Kind().Name(),
+						Line:    p.infoLineFunc(info),
+					}
+					if info.IsIndex() {
+						hit.Snippet = p.infoSnippet_htmlFunc(info)
+					}
+					hits = append(hits, hit)
+				}
+			}
+		}
+	}
+	return hits
+}
@@ -0,0 +1,174 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godoc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"code.google.com/p/go.tools/godoc/vfs/mapfs"
+)
+
+// getJSON requests url from p's API and decodes the response into x.
+func getJSON(t *testing.T, p *Presentation, url string, wantStatus int, x interface{}) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, req)
+	if w.Code != wantStatus {
+		t.Errorf("GET %s: got status %d, want %d", url, w.Code, wantStatus)
+	}
+	if ct := w.HeaderMap.Get("Content-Type"); ct != "application/json; charset=utf-8" {
+		t.Errorf("GET %s: got Content-Type %q", url, ct)
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), x); err != nil {
+		t.Errorf("GET %s: invalid JSON: %v\n%s", url, err, w.Body.String())
+	}
+}
+
+func TestAPISearch(t *testing.T) {
+	c := newCorpus(t)
+	c.IndexFullText = true
+	c.UpdateIndex()
+	p := NewPresentation(c)
+
+	var r SearchResultJSON
+	getJSON(t, p, "/api/v1/search?q=Foo", http.StatusOK, &r)
+	if r.Query != "Foo" {
+		t.Errorf("Query = %q, want Foo", r.Query)
+	}
+	var decl *HitJSON
+	for _, hit := range r.Decls {
+		if hit.File == "/src/foo/foo.go" && hit.Kind == "Types" {
+			decl = hit
+		}
+	}
+	if decl == nil {
+		t.Fatalf("no type declaration of Foo in /src/foo/foo.go among %d decls", len(r.Decls))
+	}
+	if decl.Package != (PakJSON{"/src/foo", "foo"}) || decl.Line != 11 {
+		t.Errorf("Foo declared in %v at line %d, want {/src/foo foo} at line 11", decl.Package, decl.Line)
+	}
+	if len(r.Uses) == 0 {
+		t.Errorf("no uses of Foo")
+	}
+	if r.Found == 0 || len(r.Textual) == 0 {
+		t.Errorf("no textual occurrences of Foo")
+	}
+}
+
+func TestAPIPackage(t *testing.T) {
+	c := NewCorpus(mapfs.New(map[string]string{
+		"src/num/num.go": `// Package num does arithmetic.
+package num
+
+// Max is the largest N.
+const Max = 10
+
+// Verbose enables logging.
+var Verbose bool
+
+// N is a number.
+type N int
+
+// Double returns twice n.
+func (n N) Double() N { return 2 * n }
+
+// Sum returns the sum of ns.
+func Sum(ns ...N) int { return 0 }
+`,
+		"src/num/example_test.go": `package num_test
+
+import "num"
+
+func Example() {}
+
+func ExampleN_Double() {
+	println(num.N(2).Double())
+	// Output: 4
+}
+`,
+		"src/num/sub/sub.go": "// Package sub is nested.\npackage sub\n",
+		"src/cmd/hello/doc.go": `// Hello greets.
+package main
+`,
+	}))
+	if err := c.Init(); err != nil {
+		t.Fatal(err)
+	}
+	p := NewPresentation(c)
+	p.ShowExamples = true
+
+	var pkg PackageJSON
+	getJSON(t, p, "/api/v1/pkg/num", http.StatusOK, &pkg)
+	if pkg.ImportPath != "num" || pkg.Name != "num" || pkg.Synopsis != "Package num does arithmetic." || pkg.IsMain {
+		t.Errorf("got package %q (name %q, synopsis %q, main %t)", pkg.ImportPath, pkg.Name, pkg.Synopsis, pkg.IsMain)
+	}
+	if len(pkg.Consts) != 1 || pkg.Consts[0].Names[0] != "Max" || pkg.Consts[0].Doc != "Max is the largest N.\n" {
+		t.Errorf("got consts %+v, want Max", pkg.Consts)
+	}
+	if len(pkg.Vars) != 1 || pkg.Vars[0].Names[0] != "Verbose" || pkg.Vars[0].Decl != "var Verbose bool" {
+		t.Errorf("got vars %+v, want Verbose", pkg.Vars)
+	}
+	if len(pkg.Funcs) != 1 || pkg.Funcs[0].Name != "Sum" || pkg.Funcs[0].URL == "" {
+		t.Errorf("got funcs %+v, want Sum", pkg.Funcs)
+	}
+	if len(pkg.Types) != 1 || pkg.Types[0].Name != "N" {
+		t.Fatalf("got types %+v, want N", pkg.Types)
+	}
+	methods := pkg.Types[0].Methods
+	if len(methods) != 1 || methods[0].Name != "Double" || methods[0].Recv != "N" {
+		t.Fatalf("got methods %+v, want Double", methods)
+	}
+	if egs := methods[0].Examples; len(egs) != 1 || egs[0].Name != "N_Double" || egs[0].Output != "4\n" {
+		t.Errorf("got examples %+v of N.Double, want N_Double", egs)
+	}
+	if len(pkg.Examples) != 1 || pkg.Examples[0].Name != "" {
+		t.Errorf("got package examples %+v, want one", pkg.Examples)
+	}
+	var sub *DirJSON
+	for _, d := range pkg.Dirs {
+		if d.Name == "sub" {
+			sub = d
+		}
+	}
+	if sub == nil || !sub.HasPkg || sub.Synopsis != "Package sub is nested." {
+		t.Errorf("got dirs %+v, want sub", pkg.Dirs)
+	}
+
+	var cmd PackageJSON
+	getJSON(t, p, "/api/v1/cmd/hello", http.StatusOK, &cmd)
+	if !cmd.IsMain || cmd.Doc != "Hello greets.\n" {
+		t.Errorf("got command %+v, want main package hello", cmd)
+	}
+}
+
+func TestAPIErrors(t *testing.T) {
+	c := newCorpus(t)
+	p := NewPresentation(c)
+
+	for _, url := range []string{
+		"/api/v1/nonesuch",
+		"/api/v1/pkg/nonesuch",
+		"/api/v1/analysis/file/src/foo/nonesuch.go",
+	} {
+		var e ErrorJSON
+		getJSON(t, p, url, http.StatusNotFound, &e)
+		if e.Error == "" {
+			t.Errorf("GET %s: no error message", url)
+		}
+	}
+
+	// Without analysis, a file has no links.
+	var fa FileAnalysisJSON
+	getJSON(t, p, "/api/v1/analysis/file/src/foo/foo.go", http.StatusOK, &fa)
+	if len(fa.Links) != 0 {
+		t.Errorf("got %d links, want none", len(fa.Links))
+	}
+}
@@ -86,6 +86,11 @@ type Corpus struct {
 	// If nil, all directories are indexed if indexing is enabled.
 	IndexDirectory func(dir string) bool
 
+	// Modules lists the Go modules whose directories are bound into
+	// the file system (see BindModules).  It is used to show the
+	// module and version of their packages.
+	Modules []*Module
+
 	testDir string // TODO(bradfitz,adg): migrate old godoc flag? looks unused.
 
 	// Send a value on this channel to trigger a metadata refresh.
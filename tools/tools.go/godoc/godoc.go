@@ -285,6 +285,7 @@ type PageInfo struct {
 	PAst       map[string]*ast.File   // nil if no AST with package exports
 	IsMain     bool                   // true for package main
 	IsFiltered bool                   // true if results were filtered
+	Module     *Module                // module containing the package, if any
 
 	// analysis info
 	TypeInfoIndex  map[string]int  // index of JSON datum for type T (if -analysis=type)
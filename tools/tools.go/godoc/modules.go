@@ -0,0 +1,372 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godoc
+
+// This file implements support for serving the documentation of Go
+// modules.  A module is a tree of packages rooted at a directory
+// containing a go.mod file; its packages are served below
+// /src/<module path> regardless of where the module lives on disk.
+
+import (
+	"fmt"
+	"os"
+	pathpkg "path"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"code.google.com/p/go.tools/godoc/vfs"
+)
+
+// A Module is a Go module found in a file system.
+type Module struct {
+	Path    string // module path, e.g. "example.com/m"
+	Version string // version required by another module found, if any
+	Dir     string `json:"-"` // directory containing go.mod, in the file system searched
+}
+
+func (m *Module) String() string {
+	if m.Version == "" {
+		return m.Path
+	}
+	return m.Path + " " + m.Version
+}
+
+// FindModules returns the modules in the directory trees rooted at
+// roots within fs, sorted by path.  Modules that are the target of a
+// replace directive of a module found, such as
+//
+//	replace example.com/greetings => ../greetings
+//
+// are included too, under the replaced module path and with the
+// required version.  If several directories declare the same module
+// path, the first one found is used.
+func FindModules(fs vfs.FileSystem, roots ...string) ([]*Module, error) {
+	f := &moduleFinder{
+		fs:    fs,
+		byDir: make(map[string]*Module),
+		mods:  make(map[*Module]*goModFile),
+	}
+	for _, root := range roots {
+		if err := f.walk(pathpkg.Clean(root)); err != nil {
+			return nil, err
+		}
+	}
+
+	// Resolve replace directives to local directories, which may be
+	// outside roots.  Each replacement may add a module with more
+	// replacements.
+	for i := 0; i < len(f.found); i++ {
+		m := f.found[i]
+		for _, r := range f.mods[m].replace {
+			if !isLocalPath(r.new) {
+				continue // replacement by another module version
+			}
+			dir := r.new
+			if !pathpkg.IsAbs(dir) {
+				dir = pathpkg.Join(m.Dir, dir)
+			}
+			target := f.byDir[dir]
+			if target == nil {
+				var err error
+				if target, err = f.add(dir); err != nil {
+					return nil, err
+				}
+				if target == nil {
+					return nil, fmt.Errorf("%s: replacement directory %s has no go.mod file", f.goModPath(m.Dir), dir)
+				}
+			}
+			target.Path = r.old
+			if target.Version == "" {
+				target.Version = f.mods[m].require[r.old]
+			}
+		}
+	}
+
+	seen := make(map[string]bool)
+	var mods []*Module
+	for _, m := range f.found {
+		if !seen[m.Path] {
+			seen[m.Path] = true
+			mods = append(mods, m)
+		}
+	}
+	sort.Sort(modulesByPath(mods))
+	return mods, nil
+}
+
+type modulesByPath []*Module
+
+func (s modulesByPath) Len() int           { return len(s) }
+func (s modulesByPath) Less(i, j int) bool { return s[i].Path < s[j].Path }
+func (s modulesByPath) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// A moduleFinder finds the go.mod files in a file system.
+type moduleFinder struct {
+	fs    vfs.FileSystem
+	found []*Module              // in order of discovery
+	byDir map[string]*Module     // maps Module.Dir to module
+	mods  map[*Module]*goModFile // parsed go.mod file of each module
+}
+
+func (f *moduleFinder) goModPath(dir string) string {
+	return pathpkg.Join(dir, "go.mod")
+}
+
+// walk adds the modules in the tree rooted at dir.
+func (f *moduleFinder) walk(dir string) error {
+	if _, err := f.add(dir); err != nil {
+		return err
+	}
+	list, err := f.fs.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, fi := range list {
+		if isPkgDir(fi) && fi.Name() != testdataDirName && fi.Name() != "vendor" {
+			if err := f.walk(pathpkg.Join(dir, fi.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// add adds the module in dir, if dir contains a go.mod file, and
+// returns it.
+func (f *moduleFinder) add(dir string) (*Module, error) {
+	if m := f.byDir[dir]; m != nil {
+		return m, nil
+	}
+	filename := f.goModPath(dir)
+	data, err := vfs.ReadFile(f.fs, filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	file, err := parseGoMod(filename, data)
+	if err != nil {
+		return nil, err
+	}
+	m := &Module{Path: file.module, Dir: dir}
+	f.mods[m] = file
+	f.byDir[dir] = m
+	f.found = append(f.found, m)
+	return m, nil
+}
+
+// isLocalPath reports whether the target of a replace directive is a
+// directory rather than a module path.
+func isLocalPath(path string) bool {
+	return pathpkg.IsAbs(path) || path == "." || path == ".." ||
+		strings.HasPrefix(path, "./") || strings.HasPrefix(path, "../")
+}
+
+// BindModules binds the directory of each module in mods within fs
+// to /src/<module path> in ns, after any existing bindings.  The
+// directories of modules nested within another module are not part
+// of the outer module and are hidden from its tree.
+func BindModules(ns vfs.NameSpace, fs vfs.FileSystem, mods []*Module) {
+	for _, m := range mods {
+		var nested []string
+		for _, n := range mods {
+			if n.Dir != m.Dir && withinDir(n.Dir, m.Dir) {
+				nested = append(nested, n.Dir)
+			}
+		}
+		mfs := fs
+		if nested != nil {
+			mfs = &moduleFS{fs, nested}
+		}
+		ns.Bind(pathpkg.Join("/src", m.Path), mfs, m.Dir, vfs.BindAfter)
+	}
+}
+
+// withinDir reports whether path is dir or a path below dir.
+func withinDir(path, dir string) bool {
+	return path == dir || strings.HasPrefix(path, strings.TrimSuffix(dir, "/")+"/")
+}
+
+// A moduleFS is the file system of a module, without the directories
+// of nested modules.
+type moduleFS struct {
+	vfs.FileSystem
+	nested []string // nested module directories
+}
+
+func (fs *moduleFS) String() string {
+	return fmt.Sprintf("module(%s)", fs.FileSystem.String())
+}
+
+// hidden reports whether path is part of a nested module.
+func (fs *moduleFS) hidden(path string) bool {
+	for _, dir := range fs.nested {
+		if withinDir(path, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+func (fs *moduleFS) notExist(op, path string) error {
+	return &os.PathError{Op: op, Path: path, Err: os.ErrNotExist}
+}
+
+func (fs *moduleFS) Open(path string) (vfs.ReadSeekCloser, error) {
+	if fs.hidden(path) {
+		return nil, fs.notExist("open", path)
+	}
+	return fs.FileSystem.Open(path)
+}
+
+func (fs *moduleFS) Lstat(path string) (os.FileInfo, error) {
+	if fs.hidden(path) {
+		return nil, fs.notExist("lstat", path)
+	}
+	return fs.FileSystem.Lstat(path)
+}
+
+func (fs *moduleFS) Stat(path string) (os.FileInfo, error) {
+	if fs.hidden(path) {
+		return nil, fs.notExist("stat", path)
+	}
+	return fs.FileSystem.Stat(path)
+}
+
+func (fs *moduleFS) ReadDir(path string) ([]os.FileInfo, error) {
+	if fs.hidden(path) {
+		return nil, fs.notExist("readdir", path)
+	}
+	list, err := fs.FileSystem.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	// The underlying file system may return a list it keeps,
+	// so don't filter it in place.
+	var filtered []os.FileInfo
+	for _, fi := range list {
+		if !fs.hidden(pathpkg.Join(path, fi.Name())) {
+			filtered = append(filtered, fi)
+		}
+	}
+	return filtered, nil
+}
+
+// ModuleFor returns the module among c.Modules that contains the
+// package with the given import path, or nil.
+func (c *Corpus) ModuleFor(importPath string) *Module {
+	var found *Module
+	for _, m := range c.Modules {
+		if withinDir(importPath, m.Path) &&
+			(found == nil || len(m.Path) > len(found.Path)) {
+			found = m
+		}
+	}
+	return found
+}
+
+// ----------------------------------------------------------------------------
+// go.mod files
+
+// A goModFile holds the directives of a go.mod file that godoc uses.
+type goModFile struct {
+	module  string
+	require map[string]string // maps module path to version
+	replace []goModReplace
+}
+
+type goModReplace struct {
+	old, new string // module paths, or a directory for new
+}
+
+// parseGoMod parses the go.mod file filename with contents data.
+func parseGoMod(filename string, data []byte) (*goModFile, error) {
+	file := &goModFile{require: make(map[string]string)}
+	var block string // verb of the enclosing "verb (...)" block, if any
+	for i, line := range strings.Split(string(data), "\n") {
+		errorf := func(format string, args ...interface{}) error {
+			return fmt.Errorf("%s:%d: %s", filename, i+1, fmt.Sprintf(format, args...))
+		}
+		if j := strings.Index(line, "//"); j >= 0 {
+			line = line[:j]
+		}
+		args, err := goModFields(line)
+		if err != nil {
+			return nil, errorf("%s", err)
+		}
+		if len(args) == 0 {
+			continue
+		}
+		if block != "" {
+			if args[0] == ")" {
+				block = ""
+				continue
+			}
+			args = append([]string{block}, args...)
+		} else if len(args) == 2 && args[1] == "(" {
+			block = args[0]
+			continue
+		}
+		switch args[0] {
+		case "module":
+			if len(args) != 2 {
+				return nil, errorf("usage: module module/path")
+			}
+			file.module = args[1]
+		case "require":
+			if len(args) != 3 {
+				return nil, errorf("usage: require module/path v1.2.3")
+			}
+			file.require[args[1]] = args[2]
+		case "replace":
+			// replace old [v1.2.3] => new [v1.2.4]
+			arrow := 2
+			if len(args) >= 4 && args[2] != "=>" {
+				arrow = 3
+			}
+			if len(args) < arrow+2 || len(args) > arrow+3 || args[arrow] != "=>" {
+				return nil, errorf("usage: replace module/path [v1.2.3] => other/module v1.4 or directory")
+			}
+			file.replace = append(file.replace, goModReplace{args[1], args[arrow+1]})
+		}
+	}
+	if file.module == "" {
+		return nil, fmt.Errorf("%s: no module directive", filename)
+	}
+	return file, nil
+}
+
+// goModFields splits a go.mod line into fields, unquoting quoted ones.
+func goModFields(line string) ([]string, error) {
+	var fields []string
+	for {
+		line = strings.TrimLeftFunc(line, unicode.IsSpace)
+		if line == "" {
+			return fields, nil
+		}
+		if line[0] == '"' || line[0] == '`' {
+			end := strings.IndexByte(line[1:], line[0])
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string %s", line)
+			}
+			s, err := strconv.Unquote(line[:end+2])
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, s)
+			line = line[end+2:]
+			continue
+		}
+		end := strings.IndexFunc(line, unicode.IsSpace)
+		if end < 0 {
+			end = len(line)
+		}
+		fields = append(fields, line[:end])
+		line = line[end:]
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godoc
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"code.google.com/p/go.tools/godoc/vfs"
+	"code.google.com/p/go.tools/godoc/vfs/mapfs"
+)
+
+// A workspace containing module example.com/m, the nested module
+// example.com/greetings it replaces by a local directory, and a
+// module outside the searched root it replaces too.
+var workspace = mapfs.New(map[string]string{
+	"work/hello/go.mod": `module example.com/m
+
+go 1.16
+
+require (
+	example.com/greetings v0.0.0-00010101000000-000000000000
+	"example.com/other" v1.2.3 // indirect
+	rsc.io/quote v1.5.2
+)
+
+replace example.com/greetings => ./greetings
+
+replace (
+	example.com/other v1.2.3 => ../../other
+	rsc.io/quote => rsc.io/quote v1.5.3
+)
+`,
+	"work/hello/hello.go": `// Command hello greets.
+package main
+
+func main() {}
+`,
+	"work/hello/util/util.go": `// Package util is a helper.
+package util
+
+// Helper helps.
+func Helper() {}
+`,
+	"work/hello/greetings/go.mod": "module example.com/greetings\n",
+	"work/hello/greetings/greetings.go": `// Package greetings says hello.
+package greetings
+
+// Hello returns a greeting.
+func Hello(name string) string { return "Hi, " + name }
+`,
+	"work/hello/testdata/go.mod": "module example.com/ignored\n",
+	"other/go.mod":               "module example.com/other\n",
+	"other/other.go":             "package other\n",
+})
+
+func TestFindModules(t *testing.T) {
+	mods, err := FindModules(workspace, "/work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, m := range mods {
+		got = append(got, fmt.Sprintf("%s@%s", m, m.Dir))
+	}
+	want := []string{
+		"example.com/greetings v0.0.0-00010101000000-000000000000@/work/hello/greetings",
+		"example.com/m@/work/hello",
+		"example.com/other v1.2.3@/other",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("FindModules returned:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if _, err := FindModules(mapfs.New(map[string]string{
+		"m/go.mod": "module example.com/m\nreplace example.com/x => ../x\n",
+	}), "/"); err == nil {
+		t.Errorf("FindModules succeeded with a missing replacement directory")
+	}
+}
+
+func TestBindModules(t *testing.T) {
+	mods, err := FindModules(workspace, "/work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ns := make(vfs.NameSpace)
+	ns.Bind("/", mapfs.New(map[string]string{"src/fmt/fmt.go": "package fmt\n"}), "/", vfs.BindReplace)
+	BindModules(ns, workspace, mods)
+
+	for _, tc := range []struct {
+		path   string
+		exists bool
+	}{
+		{"/src/fmt/fmt.go", true},
+		{"/src/example.com", true}, // leads to mount points
+		{"/src/example.com/m/hello.go", true},
+		{"/src/example.com/m/util/util.go", true},
+		{"/src/example.com/m/greetings", false}, // nested module
+		{"/src/example.com/m/greetings/greetings.go", false},
+		{"/src/example.com/greetings/greetings.go", true},
+		{"/src/example.com/other/other.go", true},
+	} {
+		if _, err := ns.Stat(tc.path); (err == nil) != tc.exists {
+			t.Errorf("Stat(%s): exists = %t, want %t", tc.path, err == nil, tc.exists)
+		}
+	}
+	list, err := ns.ReadDir("/src/example.com/m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fi := range list {
+		if fi.Name() == "greetings" {
+			t.Errorf("ReadDir lists nested module directory greetings")
+		}
+	}
+
+	c := NewCorpus(ns)
+	c.Modules = mods
+	for path, want := range map[string]string{
+		"example.com/m":         "example.com/m",
+		"example.com/m/util":    "example.com/m",
+		"example.com/greetings": "example.com/greetings v0.0.0-00010101000000-000000000000",
+		"example.com/mm":        "<nil>",
+		"fmt":                   "<nil>",
+		"example.com/other/sub": "example.com/other v1.2.3",
+	} {
+		if got := fmt.Sprint(c.ModuleFor(path)); got != want {
+			t.Errorf("ModuleFor(%s) = %s, want %s", path, got, want)
+		}
+	}
+
+	// Modules are indexed like all other packages.
+	c.IndexEnabled = true
+	if err := c.Init(); err != nil {
+		t.Fatal(err)
+	}
+	c.UpdateIndex()
+	result := c.Lookup("Hello")
+	if result.Hit == nil || len(result.Hit.Decls) != 1 {
+		t.Fatalf("Lookup(Hello) found no unique declaration")
+	}
+	if path := result.Hit.Decls[0].Pak.Path; path != "/src/example.com/greetings" {
+		t.Errorf("Hello declared in %s, want /src/example.com/greetings", path)
+	}
+}
+
+// keptDirFS is a file system whose ReadDir returns the same list,
+// which it keeps, on each call.
+type keptDirFS struct {
+	vfs.FileSystem
+	list []os.FileInfo
+	err  error
+}
+
+func (fs *keptDirFS) ReadDir(path string) ([]os.FileInfo, error) {
+	return fs.list, fs.err
+}
+
+func TestModuleFSReadDir(t *testing.T) {
+	underlying := mapfs.New(map[string]string{
+		"m/a.go":             "package m",
+		"m/nested/go.mod":    "module example.com/m/nested",
+		"m/z/z.go":           "package z",
+		"m/nested/nested.go": "package nested",
+	})
+	list, err := underlying.ReadDir("/m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept := &keptDirFS{FileSystem: underlying, list: list}
+	fs := &moduleFS{FileSystem: kept, nested: []string{"/m/nested"}}
+
+	names := func(list []os.FileInfo) string {
+		var names []string
+		for _, fi := range list {
+			names = append(names, fi.Name())
+		}
+		return strings.Join(names, " ")
+	}
+	for i := 0; i < 2; i++ {
+		got, err := fs.ReadDir("/m")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if names(got) != "a.go z" {
+			t.Errorf("ReadDir(/m) = %s, want a.go z", names(got))
+		}
+		if names(kept.list) != "a.go nested z" {
+			t.Errorf("ReadDir(/m) modified the underlying list: %s", names(kept.list))
+		}
+	}
+
+	kept.err = os.ErrPermission
+	if got, err := fs.ReadDir("/m"); got != nil || err != os.ErrPermission {
+		t.Errorf("ReadDir(/m) = %s, %v, want no entries and %v", names(got), err, os.ErrPermission)
+	}
+}
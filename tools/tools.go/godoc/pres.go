@@ -133,6 +133,7 @@ func NewPresentation(c *Corpus) *Presentation {
 	p.mux.HandleFunc("/", p.ServeFile)
 	p.mux.HandleFunc("/search", p.HandleSearch)
 	p.mux.HandleFunc("/opensearch.xml", p.serveSearchDesc)
+	p.mux.HandleFunc(apiPrefix, p.serveAPI)
 	return p
 }
 
@@ -56,6 +56,9 @@ func (s *handlerServer) registerWithMux(mux *http.ServeMux) {
 //
 func (h *handlerServer) GetPageInfo(abspath, relpath string, mode PageInfoMode) *PageInfo {
 	info := &PageInfo{Dirname: abspath}
+	if h.c.Modules != nil {
+		info.Module = h.c.ModuleFor(strings.TrimPrefix(abspath, "/src/"))
+	}
 
 	// Restrict to the package files that would be used when building
 	// the package on this system.  This makes sure that if there are
@@ -66,6 +69,10 @@ func (h *handlerServer) GetPageInfo(abspath, relpath string, mode PageInfoMode)
 	// set ctxt.GOOS and ctxt.GOARCH before calling ctxt.ImportDir.
 	ctxt := build.Default
 	ctxt.IsAbsPath = pathpkg.IsAbs
+	ctxt.IsDir = func(path string) bool {
+		fi, err := h.c.fs.Stat(filepath.ToSlash(path))
+		return err == nil && fi.IsDir()
+	}
 	ctxt.ReadDir = func(dir string) ([]os.FileInfo, error) {
 		f, err := h.c.fs.ReadDir(filepath.ToSlash(dir))
 		filtered := make([]os.FileInfo, 0, len(f))
@@ -280,6 +287,9 @@ func (h *handlerServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 	title += tabtitle
+	if info.Module != nil && subtitle == "" {
+		subtitle = "Module " + info.Module.String()
+	}
 
 	// special cases for top-level package/command directories
 	switch tabtitle {
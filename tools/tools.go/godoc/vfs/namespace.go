@@ -246,6 +246,12 @@ func (ns NameSpace) stat(path string, f func(FileSystem, string) (os.FileInfo, e
 			err = err1
 		}
 	}
+	// A directory needed to reach a mount point exists, as in ReadDir.
+	for old := range ns {
+		if hasPathPrefix(old, path) && old != path {
+			return dirInfo(pathpkg.Base(path)), nil
+		}
+	}
 	if err == nil {
 		err = &os.PathError{Op: "stat", Path: path, Err: os.ErrNotExist}
 	}
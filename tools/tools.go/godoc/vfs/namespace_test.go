@@ -0,0 +1,61 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vfs_test
+
+import (
+	"os"
+	"testing"
+
+	"code.google.com/p/go.tools/godoc/vfs"
+	"code.google.com/p/go.tools/godoc/vfs/mapfs"
+)
+
+// TestStatMountPointAncestors checks that the directories needed to
+// reach a mount point stat as directories, even if no file system
+// bound in the name space contains them.
+func TestStatMountPointAncestors(t *testing.T) {
+	ns := vfs.NameSpace{}
+	ns.Bind("/", mapfs.New(map[string]string{
+		"doc/x.html": "x",
+	}), "/", vfs.BindReplace)
+	ns.Bind("/src/pkg/mod", mapfs.New(map[string]string{
+		"mod.go": "package mod",
+	}), "/", vfs.BindReplace)
+
+	for _, test := range []struct {
+		path  string
+		isDir bool // if false, the path doesn't exist
+	}{
+		{"/", true},
+		{"/doc", true},
+		{"/src", true},     // ancestor of a mount point only
+		{"/src/pkg", true}, // ditto
+		{"/src/pkg/mod", true},
+		{"/src/other", false},
+		{"/sr", false}, // a prefix of an ancestor is not an ancestor
+	} {
+		for _, stat := range []func(string) (os.FileInfo, error){ns.Stat, ns.Lstat} {
+			fi, err := stat(test.path)
+			if !test.isDir {
+				if !os.IsNotExist(err) {
+					t.Errorf("stat(%q) = %v, %v, want not exist error", test.path, fi, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("stat(%q): %v", test.path, err)
+				continue
+			}
+			if !fi.IsDir() {
+				t.Errorf("stat(%q) is not a directory", test.path)
+			}
+		}
+	}
+
+	fi, err := ns.Stat("/src/pkg/mod/mod.go")
+	if err != nil || fi.IsDir() {
+		t.Errorf("Stat(/src/pkg/mod/mod.go) = %v, %v, want a file", fi, err)
+	}
+}
@@ -39,6 +39,11 @@ package oracle
 // of the analysis scope, retaining full debug information and all
 // typed ASTs.
 //
+// The Server type (server.go) is this package's own "long running"
+// client, used by 'oracle serve'.  It also accepts the contents of
+// unsaved editor buffers and, after a change, reloads only the
+// affected packages before rebuilding the SSA form.
+//
 // TODO(adonovan): experiment with inverting the control flow by
 // making each mode consist of two functions: a "one-shot setup"
 // function and the existing "impl" function.  The one-shot setup
@@ -72,6 +77,14 @@ type Oracle struct {
 	typeInfo  map[*types.Package]*loader.PackageInfo // type info for all ASTs in the program [needRetainTypeInfo]
 }
 
+// Options holds the optional settings of Query and NewServer.
+// A nil *Options is equivalent to a zero Options.
+type Options struct {
+	// SummaryDir, if non-empty, is the directory of
+	// pointer-analysis summaries; see pointer.Config.SummaryDir.
+	SummaryDir string
+}
+
 // A set of bits indicating the analytical requirements of each mode.
 //
 // Typed ASTs for the whole program are always constructed
@@ -188,6 +201,7 @@ func (res *Result) Serial() *serial.Result {
 // ptalog is the (optional) pointer-analysis log file.
 // buildContext is the go/build configuration for locating packages.
 // reflection determines whether to model reflection soundly (currently slow).
+// opts holds optional settings; it may be nil.
 //
 // Clients that intend to perform multiple queries against the same
 // analysis scope should use this pattern instead:
@@ -211,7 +225,7 @@ func (res *Result) Serial() *serial.Result {
 // TODO(adonovan): the ideal 'needsExact' parameter for ParseQueryPos
 // depends on the query mode; how should we expose this?
 //
-func Query(args []string, mode, pos string, ptalog io.Writer, buildContext *build.Context, reflection bool) (*Result, error) {
+func Query(args []string, mode, pos string, ptalog io.Writer, buildContext *build.Context, reflection bool, opts *Options) (*Result, error) {
 	if mode == "what" {
 		// Bypass package loading, type checking, SSA construction.
 		return what(pos, buildContext)
@@ -252,7 +266,7 @@ func Query(args []string, mode, pos string, ptalog io.Writer, buildContext *buil
 		return nil, err
 	}
 
-	o, err := newOracle(iprog, ptalog, minfo.needs, reflection)
+	o, err := newOracle(iprog, ptalog, minfo.needs, reflection, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -342,10 +356,10 @@ func pkgContainsFile(bp *build.Package, filename string) bool {
 // reflection determines whether to model reflection soundly (currently slow).
 //
 func New(iprog *loader.Program, ptalog io.Writer, reflection bool) (*Oracle, error) {
-	return newOracle(iprog, ptalog, needAll, reflection)
+	return newOracle(iprog, ptalog, needAll, reflection, nil)
 }
 
-func newOracle(iprog *loader.Program, ptalog io.Writer, needs int, reflection bool) (*Oracle, error) {
+func newOracle(iprog *loader.Program, ptalog io.Writer, needs int, reflection bool, opts *Options) (*Oracle, error) {
 	o := &Oracle{fset: iprog.Fset}
 
 	// Retain type info for all ASTs in the program.
@@ -385,6 +399,9 @@ func newOracle(iprog *loader.Program, ptalog io.Writer, needs int, reflection bo
 		}
 		o.ptaConfig.Log = ptalog
 		o.ptaConfig.Reflection = reflection
+		if opts != nil {
+			o.ptaConfig.SummaryDir = opts.SummaryDir
+		}
 		o.ptaConfig.Mains = mains
 
 		o.prog = prog
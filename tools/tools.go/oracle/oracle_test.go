@@ -175,7 +175,8 @@ func doQuery(out io.Writer, q *query, useJson bool) {
 		q.queryPos,
 		nil, // ptalog,
 		&buildContext,
-		true) // reflection
+		true, // reflection
+		nil)  // opts
 	if err != nil {
 		fmt.Fprintf(out, "\nError: %s\n", err)
 		return
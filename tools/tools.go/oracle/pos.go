@@ -67,13 +67,15 @@ func parsePosFlag(posFlag string) (filename string, startOffset, endOffset int,
 // returns an error if the file was not found or the offsets were out
 // of bounds.
 //
+// If fset contains several versions of the file, as it does after a
+// Server has reloaded it, the most recently added one is used.
+//
 func findQueryPos(fset *token.FileSet, filename string, startOffset, endOffset int) (start, end token.Pos, err error) {
 	var file *token.File
 	fset.Iterate(func(f *token.File) bool {
 		if sameFile(filename, f.Name()) {
 			// (f.Name() is absolute)
 			file = f
-			return false // done
 		}
 		return true // continue
 	})
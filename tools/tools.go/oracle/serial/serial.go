@@ -254,3 +254,28 @@ type Result struct {
 
 	Warnings []PTAWarning `json:"warnings,omitempty"` // warnings from pointer analysis
 }
+
+// A Request is a message sent by a client to the oracle server
+// ("oracle serve").  Op selects the operation:
+//
+//	query       run the query Mode at position Pos.
+//	overlay     use Content in place of the contents of File,
+//	            e.g. an unsaved editor buffer; a null Content
+//	            removes the overlay.
+//	invalidate  File has changed on disk.
+//
+// The server sends one Response for each Request.
+type Request struct {
+	Op      string  `json:"op"`                // operation: "query", "overlay" or "invalidate"
+	Mode    string  `json:"mode,omitempty"`    // query mode, e.g. "callers" [query]
+	Pos     string  `json:"pos,omitempty"`     // query position, as for the -pos flag [query]
+	File    string  `json:"file,omitempty"`    // name of the changed file [overlay, invalidate]
+	Content *string `json:"content,omitempty"` // new contents of File [overlay]
+}
+
+// A Response is the oracle server's reply to a Request.
+// Error is empty on success.
+type Response struct {
+	Result *Result `json:"result,omitempty"` // result of a query
+	Error  string  `json:"error,omitempty"`  // reason for failure
+}
@@ -0,0 +1,366 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package oracle
+
+// This file defines Server, a long-running oracle that keeps the
+// analyzed program in memory between queries.
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/build"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"code.google.com/p/go.tools/go/loader"
+	"code.google.com/p/go.tools/go/types"
+	"code.google.com/p/go.tools/oracle/serial"
+)
+
+// A Server answers a sequence of oracle queries about a program that
+// it loads once and keeps in memory.
+//
+// Clients report changes to the program's files with SetOverlay,
+// RemoveOverlay and Invalidate.  The next query then reloads the
+// program, re-type-checking only the packages containing a changed
+// file and the packages that transitively import them; the SSA form
+// and the pointer analysis, being whole-program, are recomputed.
+//
+// An overlay supplies the contents of a file, such as an editor
+// buffer with unsaved changes, in place of the contents on disk.
+// Only files that exist on disk may be overlaid.
+//
+// The "what" query is not supported.
+//
+// Reloads add the new versions of the files to the file set of the
+// previous program, since the reused packages refer to it.  Once the
+// superseded versions outnumber the files of the program, the next
+// reload starts afresh with a new file set and reuses no packages,
+// which bounds the growth of the file set.
+//
+// A Server is safe for concurrent use; queries are serialized.
+//
+type Server struct {
+	mu         sync.Mutex
+	args       []string      // initial packages, in (*loader.Config).FromArgs syntax
+	build      build.Context // the client's build context, with overlays
+	openFile   func(string) (io.ReadCloser, error)
+	ptalog     io.Writer // the (optional) pointer-analysis log file
+	reflection bool      // whether to model reflection soundly
+	opts       Options   // optional settings
+
+	overlay map[string][]byte // contents of overlaid files, by absolute name
+	changed map[string]bool   // files changed since the last load, by absolute name
+
+	iprog  *loader.Program // the loaded program
+	oracle *Oracle         // the oracle for iprog
+}
+
+// NewServer returns a Server for the program specified by args, in
+// (*loader.Config).FromArgs syntax, which it loads immediately.
+//
+// ptalog is the (optional) pointer-analysis log file.
+// buildContext is the go/build configuration for locating packages.
+// reflection determines whether to model reflection soundly (currently slow).
+// opts holds optional settings; it may be nil.  Its SummaryDir spares
+// the reanalysis of unchanged packages after a reload.
+//
+func NewServer(args []string, ptalog io.Writer, buildContext *build.Context, reflection bool, opts *Options) (*Server, error) {
+	s := &Server{
+		args:       args,
+		build:      *buildContext, // copy
+		openFile:   buildContext.OpenFile,
+		ptalog:     ptalog,
+		reflection: reflection,
+		overlay:    make(map[string][]byte),
+		changed:    make(map[string]bool),
+	}
+	if opts != nil {
+		s.opts = *opts
+	}
+	s.build.OpenFile = s.open
+	err := s.load()
+	s.flushLog()
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// flushLog flushes the pointer-analysis log, if it is buffered,
+// so that it is complete after each query.
+func (s *Server) flushLog() {
+	if f, ok := s.ptalog.(interface {
+		Flush() error
+	}); ok {
+		f.Flush() // ignore error
+	}
+}
+
+// open opens a file for the loader, reading the overlay if any.
+// It implements build.Context.OpenFile.
+func (s *Server) open(filename string) (io.ReadCloser, error) {
+	if content, ok := s.overlay[absPath(filename)]; ok {
+		return ioutil.NopCloser(bytes.NewReader(content)), nil
+	}
+	if s.openFile != nil {
+		return s.openFile(filename)
+	}
+	return os.Open(filename)
+}
+
+// absPath returns the absolute form of filename,
+// or filename itself if that fails.
+func absPath(filename string) string {
+	if abs, err := filepath.Abs(filename); err == nil {
+		return abs
+	}
+	return filename
+}
+
+// SetOverlay makes the server use content as the contents of the
+// named file, e.g. an editor buffer with unsaved changes.
+func (s *Server) SetOverlay(filename string, content []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	filename = absPath(filename)
+	s.overlay[filename] = content
+	s.changed[filename] = true
+}
+
+// RemoveOverlay makes the server read the named file from disk again,
+// e.g. after the editor buffer was saved or discarded.
+func (s *Server) RemoveOverlay(filename string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	filename = absPath(filename)
+	delete(s.overlay, filename)
+	s.changed[filename] = true
+}
+
+// Invalidate informs the server that the named file has changed on disk.
+func (s *Server) Invalidate(filename string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.changed[absPath(filename)] = true
+}
+
+// Query runs the query of the specified mode at position pos, in the
+// syntax of the -pos flag, reloading the program first if any of its
+// files changed.
+//
+func (s *Server) Query(mode, pos string) (*Result, error) {
+	minfo := findMode(mode)
+	if minfo == nil {
+		if mode == "what" {
+			return nil, fmt.Errorf("the %q query is not supported by the server", mode)
+		}
+		return nil, fmt.Errorf("invalid mode type: %q", mode)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.flushLog()
+
+	if len(s.changed) > 0 {
+		if err := s.load(); err != nil {
+			return nil, err
+		}
+	}
+
+	qpos, err := ParseQueryPos(s.iprog, pos, minfo.needs&needExactPos != 0)
+	if err != nil && minfo.needs&(needPos|needExactPos) != 0 {
+		return nil, err
+	}
+	return s.oracle.query(minfo, qpos)
+}
+
+// load (re)loads the program, reusing the packages of the previous
+// load that are unaffected by the changed files.  On failure, the
+// previous program is retained and the next query tries again.
+//
+// Precondition: s.mu is held (or s is not yet shared).
+//
+func (s *Server) load() error {
+	fset := token.NewFileSet()
+	var reuse map[string]*loader.PackageInfo
+	if s.iprog != nil && !s.fsetFull() {
+		fset = s.iprog.Fset
+		reuse = s.reusable()
+	}
+	conf := loader.Config{
+		Fset:          fset,
+		Build:         &s.build,
+		SourceImports: true,
+		Reuse:         reuse,
+	}
+	args, err := conf.FromArgs(s.args, true)
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		return fmt.Errorf("surplus arguments: %q", args)
+	}
+	iprog, err := conf.Load()
+	if err != nil {
+		return err
+	}
+	o, err := newOracle(iprog, s.ptalog, needAll, s.reflection, &s.opts)
+	if err != nil {
+		return err
+	}
+
+	s.iprog = iprog
+	s.oracle = o
+	s.changed = make(map[string]bool)
+	return nil
+}
+
+// fsetFull reports whether the file set of the current program holds
+// more superseded versions of files, from earlier loads, than files
+// of the program itself.
+func (s *Server) fsetFull() bool {
+	live := 0
+	for _, info := range s.iprog.AllPackages {
+		live += len(info.Files)
+	}
+	total := 0
+	s.iprog.Fset.Iterate(func(*token.File) bool {
+		total++
+		return true
+	})
+	return total-live > live
+}
+
+// reusable returns the importable packages of the current program
+// that are unaffected by the changed files: those that neither
+// contain a changed file nor transitively import a package that
+// does.  It returns nil if some changed file belongs to no package,
+// e.g. a new file, since its effect on the program is unknown.
+//
+func (s *Server) reusable() map[string]*loader.PackageInfo {
+	if s.iprog == nil {
+		return nil
+	}
+
+	// Find the packages containing a changed file,
+	// and build the transpose of the import graph.
+	importedBy := make(map[*types.Package][]*types.Package)
+	var stale []*types.Package
+	found := make(map[string]bool)
+	for pkg, info := range s.iprog.AllPackages {
+		for _, imp := range pkg.Imports() {
+			importedBy[imp] = append(importedBy[imp], pkg)
+		}
+		for _, f := range info.Files {
+			filename := absPath(s.iprog.Fset.File(f.Pos()).Name())
+			if s.changed[filename] {
+				found[filename] = true
+				stale = append(stale, pkg)
+			}
+		}
+	}
+	if len(found) < len(s.changed) {
+		return nil
+	}
+
+	// Mark the stale packages and all their importers as affected.
+	affected := make(map[*types.Package]bool)
+	var visit func(*types.Package)
+	visit = func(pkg *types.Package) {
+		if !affected[pkg] {
+			affected[pkg] = true
+			for _, client := range importedBy[pkg] {
+				visit(client)
+			}
+		}
+	}
+	for _, pkg := range stale {
+		visit(pkg)
+	}
+
+	reuse := make(map[string]*loader.PackageInfo)
+	for pkg, info := range s.iprog.AllPackages {
+		if info.Importable && !affected[pkg] {
+			reuse[pkg.Path()] = info
+		}
+	}
+	return reuse
+}
+
+// ---------- Protocol ----------
+
+// Serve accepts connections on l and serves each one in its own
+// goroutine using ServeConn.  It returns when Accept fails.
+//
+func (s *Server) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			s.ServeConn(conn) // ignore error
+		}()
+	}
+}
+
+// ServeConn reads a stream of JSON-encoded serial.Request messages
+// from conn and writes a JSON-encoded serial.Response to conn for
+// each of them.  It returns nil at the end of the stream, or an error
+// if a message could not be read or written.
+//
+func (s *Server) ServeConn(conn io.ReadWriter) error {
+	dec := json.NewDecoder(conn)
+	enc := json.NewEncoder(conn)
+	for {
+		var req serial.Request
+		if err := dec.Decode(&req); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if err := enc.Encode(s.handle(&req)); err != nil {
+			return err
+		}
+	}
+}
+
+// handle performs a single request.
+func (s *Server) handle(req *serial.Request) *serial.Response {
+	resp := new(serial.Response)
+	switch req.Op {
+	case "query":
+		res, err := s.Query(req.Mode, req.Pos)
+		if err != nil {
+			resp.Error = err.Error()
+		} else {
+			resp.Result = res.Serial()
+		}
+
+	case "overlay", "invalidate":
+		if req.File == "" {
+			resp.Error = fmt.Sprintf("no file specified for %q request", req.Op)
+		} else if req.Op == "invalidate" {
+			s.Invalidate(req.File)
+		} else if req.Content == nil {
+			s.RemoveOverlay(req.File)
+		} else {
+			s.SetOverlay(req.File, []byte(*req.Content))
+		}
+
+	default:
+		resp.Error = fmt.Sprintf("invalid op: %q", req.Op)
+	}
+	return resp
+}
@@ -0,0 +1,58 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package oracle
+
+import (
+	"fmt"
+	"go/build"
+	"go/token"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// TestServerFileSetBounded checks that repeated reloads don't grow
+// the server's file set without bound, and that queries see the
+// latest version of a file.
+func TestServerFileSetBounded(t *testing.T) {
+	var buildContext = build.Default
+	buildContext.GOPATH = "testdata"
+	filename := "testdata/src/main/multi.go"
+	orig, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewServer([]string{filename}, nil, &buildContext, false, nil)
+	if err != nil {
+		t.Fatalf("NewServer failed: %s", err)
+	}
+
+	live := 0
+	for _, info := range s.iprog.AllPackages {
+		live += len(info.Files)
+	}
+	for i := 0; i < 10; i++ {
+		// Shift the selection by i bytes in each version.
+		src := strings.Repeat("\n", i) + string(orig)
+		s.SetOverlay(filename, []byte(src))
+		j := strings.Index(src, "g(x) //")
+		res, err := s.Query("freevars", fmt.Sprintf("%s:#%d,#%d", filename, j, j+len("g(x)")))
+		if err != nil {
+			t.Fatalf("reload %d: freevars query failed: %s", i, err)
+		}
+		if got := len(res.Serial().Freevars); got != 1 {
+			t.Errorf("reload %d: got %d free variables, want 1", i, got)
+		}
+
+		total := 0
+		s.iprog.Fset.Iterate(func(*token.File) bool {
+			total++
+			return true
+		})
+		if total > 3*live {
+			t.Errorf("reload %d: file set holds %d files, program has %d", i, total, live)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package oracle
+
+import (
+	"fmt"
+	"go/build"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"code.google.com/p/go.tools/go/loader"
+)
+
+// TestServerReusesImports checks that a reload after an edit of the
+// main package reuses its unchanged dependency, and that an edit of
+// the dependency reloads it.
+func TestServerReusesImports(t *testing.T) {
+	var buildContext = build.Default
+	buildContext.GOPATH = "testdata"
+	filename := "testdata/src/main/reload.go"
+	libFilename := "testdata/src/lib/lib.go"
+	orig, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	libOrig, err := ioutil.ReadFile(libFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewServer([]string{filename}, nil, &buildContext, false, nil)
+	if err != nil {
+		t.Fatalf("NewServer failed: %s", err)
+	}
+
+	// packages returns the packages of the current program by path.
+	packages := func() map[string]*loader.PackageInfo {
+		m := make(map[string]*loader.PackageInfo)
+		for pkg, info := range s.iprog.AllPackages {
+			m[pkg.Path()] = info
+		}
+		return m
+	}
+	// describe describes lib.Const in the given version of the main
+	// file, and checks that it is declared at the given line of lib.go.
+	describe := func(src string, line int) {
+		i := strings.Index(src, "lib.Const") + len("lib.")
+		res, err := s.Query("describe", fmt.Sprintf("%s:#%d", filename, i))
+		if err != nil {
+			t.Fatalf("describe query failed: %s", err)
+		}
+		want := fmt.Sprintf("lib.go:%d:", line)
+		if d := res.Serial().Describe; d == nil || d.Value == nil || !strings.Contains(d.Value.ObjPos, want) {
+			t.Errorf("describe query returned %+v, want object at %s", d, want)
+		}
+	}
+
+	before := packages()
+	if before["lib"] == nil {
+		t.Fatalf("program lacks the imported package: %v", before)
+	}
+
+	// Edit the main package only.
+	src := "\n" + string(orig)
+	s.SetOverlay(filename, []byte(src))
+	describe(src, 12)
+	after := packages()
+	if after["lib"] != before["lib"] {
+		t.Errorf("package lib was reloaded after an edit of the main package")
+	}
+	if after["main"] == before["main"] {
+		t.Errorf("package main was reused after an edit of it")
+	}
+	if len(after) != len(before) {
+		t.Errorf("program has %d packages after reload, want %d", len(after), len(before))
+	}
+
+	// Edit lib: it is reloaded, and so is main, which imports it.
+	before = after
+	s.SetOverlay(libFilename, append([]byte("\n"), libOrig...))
+	describe(src, 13)
+	after = packages()
+	if after["lib"] == before["lib"] {
+		t.Errorf("package lib was reused after an edit of it")
+	}
+	if after["main"] == before["main"] {
+		t.Errorf("package main was reused after an edit of lib")
+	}
+}
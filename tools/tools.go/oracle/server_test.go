@@ -0,0 +1,130 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package oracle_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/build"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+
+	"code.google.com/p/go.tools/oracle"
+	"code.google.com/p/go.tools/oracle/serial"
+)
+
+// TestServer checks that the server answers queries over its JSON
+// protocol, and reloads the program after a file is overlaid.
+func TestServer(t *testing.T) {
+	var buildContext = build.Default
+	buildContext.GOPATH = "testdata"
+	filename := "testdata/src/main/multi.go"
+	s, err := oracle.NewServer([]string{filename}, nil, &buildContext, true, nil)
+	if err != nil {
+		t.Fatalf("NewServer failed: %s", err)
+	}
+
+	client, server := net.Pipe()
+	defer client.Close()
+	go s.ServeConn(server)
+	enc := json.NewEncoder(client)
+	dec := json.NewDecoder(client)
+	call := func(req serial.Request) *serial.Response {
+		if err := enc.Encode(req); err != nil {
+			t.Fatalf("sending %+v: %s", req, err)
+		}
+		resp := new(serial.Response)
+		if err := dec.Decode(resp); err != nil {
+			t.Fatalf("receiving response to %+v: %s", req, err)
+		}
+		return resp
+	}
+
+	// freevars returns the free variables of the selection "g(x)"
+	// in the program whose multi.go file is src.
+	freevars := func(src string) string {
+		i := strings.Index(src, "g(x) //")
+		resp := call(serial.Request{
+			Op:   "query",
+			Mode: "freevars",
+			Pos:  fmt.Sprintf("%s:#%d,#%d", filename, i, i+len("g(x)")),
+		})
+		if resp.Error != "" {
+			t.Errorf("freevars query failed: %s", resp.Error)
+			return ""
+		}
+		var vars []string
+		for _, v := range resp.Result.Freevars {
+			vars = append(vars, v.Kind+" "+v.Ref+" "+v.Type)
+		}
+		return strings.Join(vars, "; ")
+	}
+
+	orig, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := freevars(string(orig)), "var x int"; got != want {
+		t.Errorf("freevars = %q, want %q", got, want)
+	}
+
+	// Overlay an edited version of the file.
+	edited := strings.Replace(string(orig), "x := 1", "x := 1.5", 1)
+	edited = strings.Replace(edited, "g(x int)", "g(x float64)", 1)
+	if resp := call(serial.Request{Op: "overlay", File: filename, Content: &edited}); resp.Error != "" {
+		t.Errorf("overlay failed: %s", resp.Error)
+	}
+	if got, want := freevars(edited), "var x float64"; got != want {
+		t.Errorf("freevars after overlay = %q, want %q", got, want)
+	}
+
+	// Remove the overlay.
+	if resp := call(serial.Request{Op: "overlay", File: filename}); resp.Error != "" {
+		t.Errorf("removing overlay failed: %s", resp.Error)
+	}
+	if got, want := freevars(string(orig)), "var x int"; got != want {
+		t.Errorf("freevars after removing overlay = %q, want %q", got, want)
+	}
+
+	// Errors.
+	for _, test := range []struct {
+		req  serial.Request
+		want string
+	}{
+		{serial.Request{Op: "frobnicate"}, `invalid op: "frobnicate"`},
+		{serial.Request{Op: "invalidate"}, `no file specified for "invalidate" request`},
+		{serial.Request{Op: "query", Mode: "what", Pos: filename + ":#0"}, `the "what" query is not supported by the server`},
+		{serial.Request{Op: "query", Mode: "describe", Pos: "nosuchfile.go:#0"}, `couldn't find file containing position`},
+	} {
+		if got := call(test.req).Error; got != test.want {
+			t.Errorf("%+v: got error %q, want %q", test.req, got, test.want)
+		}
+	}
+}
+
+// TestServerFlushesLog checks that the server flushes a buffered
+// pointer-analysis log after each query, not just at exit.
+func TestServerFlushesLog(t *testing.T) {
+	var buildContext = build.Default
+	buildContext.GOPATH = "testdata"
+	filename := "testdata/src/main/multi.go"
+	var log bytes.Buffer
+	ptalog := bufio.NewWriterSize(&log, 1<<20)
+	s, err := oracle.NewServer([]string{filename}, ptalog, &buildContext, false, nil)
+	if err != nil {
+		t.Fatalf("NewServer failed: %s", err)
+	}
+	if _, err := s.Query("callgraph", ""); err != nil {
+		t.Fatalf("callgraph query failed: %s", err)
+	}
+	if ptalog.Buffered() > 0 || log.Len() == 0 {
+		t.Errorf("pointer-analysis log not flushed after query: %d bytes written, %d buffered",
+			log.Len(), ptalog.Buffered())
+	}
+}
@@ -0,0 +1,11 @@
+package main
+
+import "lib"
+
+// Tests of the server's reuse of packages across reloads.
+// See go.tools/oracle/server_reload_test.go.
+
+func main() {
+	const c = lib.Const
+	print(c)
+}
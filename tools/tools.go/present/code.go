@@ -34,6 +34,7 @@ type Code struct {
 	FileName string // file name
 	Ext      string // file extension
 	Raw      []byte // content of the file
+	Program  []byte // complete program of a .play invocation, for running it
 }
 
 func (c Code) TemplateName() string { return "code" }
@@ -114,12 +115,18 @@ func parseCode(ctx *Context, sourceFile string, sourceLine int, cmd string) (Ele
 	if err := codeTemplate.Execute(&buf, data); err != nil {
 		return nil, err
 	}
+	raw := rawCode(lines)
+	var program []byte
+	if command == "play" {
+		program = append(append(append(program, textBytes[:lo]...), raw...), textBytes[hi:]...)
+	}
 	return Code{
 		Text:     template.HTML(buf.String()),
 		Play:     play,
 		FileName: filepath.Base(filename),
 		Ext:      filepath.Ext(filename),
-		Raw:      rawCode(lines),
+		Raw:      raw,
+		Program:  program,
 	}, nil
 }
 
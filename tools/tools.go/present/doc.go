@@ -62,6 +62,7 @@ After that come slides/sections, each after a blank line:
 	.link http://foo label
 	.html file.html
 	.caption _Gopher_ by [[http://www.reneefrench.com][Renée French]]
+	.note Speaker notes for this slide
 
 	Again, more text
 
@@ -201,5 +202,23 @@ It is your responsibilty to make sure the included HTML is valid and safe.
 
 	.html file.html
 
+note:
+
+The function "note" adds a speaker note to the current slide. Notes are
+not shown on the slide; pressing 'N' in the browser opens a presenter
+window that shows the notes of the current slide and follows the
+slides as they change.
+
+	.note Mention the benchmark results.
+
+A line beginning with a colon and a space is a shorthand for a note,
+and consecutive such lines form a single note:
+
+	: Mention the benchmark results.
+	: They are on the next slide.
+
+Lines of text in existing files that happen to begin with ": " are
+therefore notes too, and are no longer shown on their slides.
+
 */
 package present
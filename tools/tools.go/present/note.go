@@ -0,0 +1,24 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package present
+
+import "strings"
+
+func init() {
+	Register("note", parseNote)
+}
+
+// Note is a speaker note.  It is not shown on the slide itself, only
+// in the presenter window.
+type Note struct {
+	Text string
+}
+
+func (n Note) TemplateName() string { return "note" }
+
+func parseNote(_ *Context, _ string, _ int, text string) (Elem, error) {
+	text = strings.TrimSpace(strings.TrimPrefix(text, ".note"))
+	return Note{text}, nil
+}
@@ -0,0 +1,45 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package present
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNotes(t *testing.T) {
+	const input = `Title
+
+Author
+
+* Slide
+
+Some text
+: A note
+: continued.
+
+.note Another note.
+
+More text
+`
+	doc, err := Parse(strings.NewReader(input), "test.slide", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Sections) != 1 {
+		t.Fatalf("got %d sections, want 1", len(doc.Sections))
+	}
+	got := doc.Sections[0].Elem
+	want := []Elem{
+		Text{Lines: []string{"Some text"}},
+		Note{"A note continued."},
+		Note{"Another note."},
+		Text{Lines: []string{"More text"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got elements %#v, want %#v", got, want)
+	}
+}
@@ -347,6 +347,19 @@ func parseSections(ctx *Context, name string, lines *Lines, number []int, doc *D
 				for _, ss := range subsecs {
 					section.Elem = append(section.Elem, ss)
 				}
+			case strings.HasPrefix(text, ": "):
+				// Short form of .note; consecutive lines form one note.
+				var n []string
+				for ok && strings.HasPrefix(text, ": ") {
+					n = append(n, strings.TrimSpace(text[2:]))
+					text, ok = lines.next()
+				}
+				lines.back()
+				t, err := parsers[".note"](ctx, name, lines.line, ".note "+strings.Join(n, " "))
+				if err != nil {
+					return nil, err
+				}
+				e = t
 			case strings.HasPrefix(text, "."):
 				args := strings.Fields(text)
 				parser := parsers[args[0]]
@@ -361,7 +374,8 @@ func parseSections(ctx *Context, name string, lines *Lines, number []int, doc *D
 			default:
 				var l []string
 				for ok && strings.TrimSpace(text) != "" {
-					if text[0] == '.' { // Command breaks text block.
+					if text[0] == '.' || strings.HasPrefix(text, ": ") { // Command or note breaks text block.
+						lines.back()
 						break
 					}
 					if strings.HasPrefix(text, `\.`) { // Backslash escapes initial period.